@@ -45,6 +45,76 @@ const (
 	HeaderSchemaSignOff             = "Tigris-Schema-Sign-Off"
 	HeaderBypassAuthCache           = "Tigris-Bypass-Auth-Cache" // #nosec G101
 	HeaderReadSearchDataFromStorage = "Tigris-Search-Read-From-Storage"
+	// HeaderReadMessagesEnd is the inclusive end of a channel read, either a message id or a time in unix milliseconds.
+	// The end of the request takes precedence over it.
+	HeaderReadMessagesEnd = "Tigris-Read-Messages-End"
+	// HeaderReadMessagesReverse set to "true" reads the channel newest first.
+	HeaderReadMessagesReverse = "Tigris-Read-Messages-Reverse"
+	// HeaderReadMessagesEventTimes is the trailer of a channel read with the event time, in unix milliseconds, of every
+	// message sent, in the order of the messages. It is the ingestion time if the publisher didn't supply one.
+	HeaderReadMessagesEventTimes = "Tigris-Read-Messages-Event-Times"
+	// HeaderReadMessagesIngestionTimes is the trailer of a channel read with the time, in unix milliseconds, at which
+	// the server published every message sent, in the order of the messages.
+	HeaderReadMessagesIngestionTimes = "Tigris-Read-Messages-Ingestion-Times"
+	// HeaderMetricsMaxStaleness is the maximum age of a cached metrics query result the caller accepts, as a duration
+	// like "30s". Zero always fetches fresh data.
+	HeaderMetricsMaxStaleness = "Tigris-Metrics-Max-Staleness"
+	// HeaderMetricsPercentile set to a percentile like "95" returns the percentile of the data points of each series,
+	// computed by the server, in place of the data points.
+	HeaderMetricsPercentile = "Tigris-Metrics-Percentile"
+	// HeaderMetricsProject is the project to query the metrics of, across all its databases. A db set in the query
+	// narrows it down to that database within the project. A caller with a project scoped token can only query the
+	// metrics of its project.
+	HeaderMetricsProject = "Tigris-Metrics-Project"
+	// HeaderMetricsTimeAggregation is how the points within each interval of a metrics query are combined, as
+	// "<method>" or "<method>:<interval in seconds>" like "max:60". The method is one of avg, max, min, last or sum.
+	// It can't be combined with a rollup in the additional functions of the query.
+	HeaderMetricsTimeAggregation = "Tigris-Metrics-Time-Aggregation"
+	// HeaderMetricsTimestampFormat is the format of the timestamps of the data points of a metrics query, one of "s"
+	// for unix seconds, "ms" for unix milliseconds, the default, or "rfc3339". The RFC 3339 strings are only returned
+	// by the HTTP endpoints, the gRPC response carries the timestamps in unix milliseconds.
+	HeaderMetricsTimestampFormat = "Tigris-Metrics-Timestamp-Format"
+	// HeaderMetricsWindow is a window ending now to query the metrics over, like "1h", "7d" or "last 30m", resolved
+	// against the clock of the server. It takes precedence over the from and to of the request.
+	HeaderMetricsWindow = "Tigris-Metrics-Window"
+	// HeaderIdempotencyKeys are the comma separated idempotency keys of the published messages, in the order of the
+	// messages. A message with a key already published to the channel within the configured window isn't published
+	// again.
+	HeaderIdempotencyKeys = "Tigris-Idempotency-Keys"
+	// HeaderAtomicPublish set to "true" publishes the messages of a publish request all together or none of them.
+	HeaderAtomicPublish = "Tigris-Atomic-Publish"
+	// HeaderMessageEventTimes are the comma separated times of the events of the published messages, in unix
+	// milliseconds and in the order of the messages. A message with an empty time has no event time.
+	HeaderMessageEventTimes = "Tigris-Message-Event-Times"
+	// HeaderPublishedIds is returned by a failed publish with the comma separated ids of the messages published before
+	// the failure, in the order of the messages. The id of a message sent to the dead-letter channel is empty.
+	HeaderPublishedIds = "Tigris-Published-Ids"
+	// HeaderPublishedTimestamps is returned by a publish with the comma separated times, in unix milliseconds, at which
+	// the server assigned the ids of the messages, in the order of the ids. It is the time the channel orders the
+	// messages by.
+	HeaderPublishedTimestamps = "Tigris-Published-Timestamps"
+	// HeaderChannelsPageSize is the maximum number of channels returned by a channels listing. A page can have fewer
+	// channels, even none, while the HeaderChannelsNextPageToken is set.
+	HeaderChannelsPageSize = "Tigris-Channels-Page-Size"
+	// HeaderChannelsPageToken is the token of the page of channels to list, as returned in the
+	// HeaderChannelsNextPageToken of the previous page.
+	HeaderChannelsPageToken = "Tigris-Channels-Page-Token"
+	// HeaderChannelsNextPageToken is returned by a channels listing with the token of the next page, it is not set on
+	// the last page.
+	HeaderChannelsNextPageToken = "Tigris-Channels-Next-Page-Token"
+	// HeaderChannelsPattern is the glob pattern of the channels listed by a channels listing, "*" matches any sequence
+	// of characters and "?" a single character, like "chat-*" for the channels starting with "chat-".
+	HeaderChannelsPattern = "Tigris-Channels-Pattern"
+	// HeaderChannelsWatchers is returned by a channels listing with the comma separated number of subscribers of the
+	// channels, in the order of the channels.
+	HeaderChannelsWatchers = "Tigris-Channels-Watchers"
+	// HeaderChannelsLastActivity is returned by a channels listing with the comma separated times, in unix
+	// milliseconds, of the newest message of the channels, in the order of the channels. It is zero for a channel
+	// without messages.
+	HeaderChannelsLastActivity = "Tigris-Channels-Last-Activity"
+	// HeaderExplainPlan is returned by an explain with the JSON description of how the secondary index would serve
+	// the filter of the query.
+	HeaderExplainPlan = "Tigris-Explain-Plan"
 )
 
 func CustomMatcher(key string) (string, bool) {
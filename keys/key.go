@@ -17,6 +17,8 @@ package keys
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"math/big"
 	"unsafe"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
@@ -39,11 +41,18 @@ type Key interface {
 	// may use this as simply to form a key without any significance of index identifier.
 	IndexParts() []interface{}
 	// SerializeToBytes follows the ordering of how the Key is persisted in database so to compare a Key call this method
-	// get bytes and compare it with raw bytes stored in database.
+	// get bytes and compare it with raw bytes stored in database. It panics if an index part is of an unsupported type,
+	// use Serialize when the parts are not known to be valid.
 	SerializeToBytes() []byte
+	// Serialize is same as SerializeToBytes but returns an error instead of panicking if an index part is of a type
+	// that can't be packed.
+	Serialize() ([]byte, error)
 	// CompareBytes compares the serialized form of keys. It returns 0 if p == input, -1 if p < input, and +1 if p > input.
 	// A nil argument is equivalent to an empty slice.
 	CompareBytes(input []byte) int
+	// Hash returns the 64-bit FNV-1a hash of the serialized key, it is stable across processes so that it can be used
+	// to partition the keys between nodes.
+	Hash() uint64
 }
 
 type tableKey struct {
@@ -82,12 +91,51 @@ func (p *tableKey) SerializeToBytes() []byte {
 	return sb.Pack(*(*tuple.Tuple)(unsafe.Pointer(&p.indexParts)))
 }
 
+func (p *tableKey) Serialize() ([]byte, error) {
+	if err := validateParts(p.indexParts); err != nil {
+		return nil, err
+	}
+
+	return p.SerializeToBytes(), nil
+}
+
+// validateParts checks that all the parts can be packed in a tuple, the tuple layer panics on the types it doesn't
+// support.
+func validateParts(parts []interface{}) error {
+	for i, part := range parts {
+		// tuple.Tuple is also a fdb.KeyConvertible so it needs to be matched first
+		switch t := part.(type) {
+		case tuple.Tuple:
+			if err := validateParts(*(*[]interface{})(unsafe.Pointer(&t))); err != nil {
+				return err
+			}
+		case tuple.Versionstamp:
+			if t.TransactionVersion == tuple.IncompleteVersionstamp(0).TransactionVersion {
+				return fmt.Errorf("incomplete versionstamp at index %d of the key", i)
+			}
+		case nil, bool, string, []byte, int, int64, uint, uint64, float32, float64, *big.Int, big.Int,
+			tuple.UUID, fdb.KeyConvertible:
+		default:
+			return fmt.Errorf("unsupported type '%T' at index %d of the key", part, i)
+		}
+	}
+
+	return nil
+}
+
 // CompareBytes compares the serialized form of keys. It returns 0 if p == input, -1 if p < input, and +1 if p > input.
 // A nil argument is equivalent to an empty slice.
 func (p *tableKey) CompareBytes(input []byte) int {
 	return bytes.Compare(p.SerializeToBytes(), input)
 }
 
+func (p *tableKey) Hash() uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(p.SerializeToBytes())
+
+	return h.Sum64()
+}
+
 func FromBinary(table []byte, fdbKey []byte) (Key, error) {
 	sb := subspace.FromBytes(table)
 	tp, err := sb.Unpack(fdb.Key(fdbKey))
@@ -17,6 +17,7 @@ package keys
 import (
 	"testing"
 
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +30,67 @@ func TestKey(t *testing.T) {
 	require.Equal(t, []interface{}{int64(5)}, k.IndexParts())
 	require.Equal(t, []byte("foo"), k.Table())
 }
+
+func TestKeySerialize(t *testing.T) {
+	k := NewKey([]byte("foo"), "a", int64(5), []byte("b"), tuple.Tuple{"c", nil})
+	b, err := k.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, k.SerializeToBytes(), b)
+
+	_, err = NewKey([]byte("foo"), "a", map[string]string{"b": "c"}).Serialize()
+	require.EqualError(t, err, "unsupported type 'map[string]string' at index 1 of the key")
+
+	_, err = NewKey([]byte("foo"), tuple.Tuple{int32(1)}).Serialize()
+	require.EqualError(t, err, "unsupported type 'int32' at index 0 of the key")
+
+	_, err = NewKey([]byte("foo"), tuple.IncompleteVersionstamp(0)).Serialize()
+	require.EqualError(t, err, "incomplete versionstamp at index 0 of the key")
+}
+
+func TestKeyHash(t *testing.T) {
+	k := NewKey([]byte("foo"), "a", int64(5))
+	require.Equal(t, k.Hash(), NewKey([]byte("foo"), "a", int64(5)).Hash())
+	require.NotEqual(t, k.Hash(), NewKey([]byte("foo"), "a", int64(6)).Hash())
+	require.NotEqual(t, k.Hash(), NewKey([]byte("bar"), "a", int64(5)).Hash())
+}
+
+func TestRange(t *testing.T) {
+	table := []byte("t1")
+	bound := func(v int64) Key { return NewKey(table, "a", v) }
+	// index keys are the value followed by the position and the primary key
+	indexKey := func(v int64) []byte { return NewKey(table, "a", v, 0, "pk").SerializeToBytes() }
+	inRange := func(r *Range, key []byte) bool {
+		start, end := r.ScanKeys()
+		return start.CompareBytes(key) <= 0 && end.CompareBytes(key) > 0
+	}
+
+	cases := []struct {
+		name string
+		r    *Range
+		in   []int64
+		out  []int64
+	}{
+		{"gt_lt", NewRange(OpenBound(bound(5)), OpenBound(bound(10))), []int64{6, 9}, []int64{4, 5, 10, 11}},
+		{"gte_lt", NewRange(ClosedBound(bound(5)), OpenBound(bound(10))), []int64{5, 6, 9}, []int64{4, 10, 11}},
+		{"gt_lte", NewRange(OpenBound(bound(5)), ClosedBound(bound(10))), []int64{6, 9, 10}, []int64{4, 5, 11}},
+		{"gte_lte", NewRange(ClosedBound(bound(5)), ClosedBound(bound(10))), []int64{5, 6, 9, 10}, []int64{4, 11}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, v := range c.in {
+				require.True(t, inRange(c.r, indexKey(v)), "%d must be in range", v)
+			}
+			for _, v := range c.out {
+				require.False(t, inRange(c.r, indexKey(v)), "%d must not be in range", v)
+			}
+		})
+	}
+
+	start, end := NewRange(ClosedBound(bound(5)), OpenBound(bound(10))).ScanKeys()
+	require.Equal(t, bound(5), start)
+	require.Equal(t, bound(10), end)
+
+	start, end = NewRange(OpenBound(bound(5)), ClosedBound(bound(10))).ScanKeys()
+	require.Equal(t, NewKey(table, "a", int64(5), 0xFF), start)
+	require.Equal(t, NewKey(table, "a", int64(10), 0xFF), end)
+}
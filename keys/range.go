@@ -0,0 +1,72 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keys
+
+// boundAfter is appended to the index parts of a bound key to position it after all the keys that are prefixed with
+// the bound key. For the secondary index these are the keys with the same value, which is followed by the position and
+// the primary key.
+const boundAfter = 0xFF
+
+// Bound is one end of a Range. A closed bound includes the keys prefixed by the bound key, an open bound excludes them.
+type Bound struct {
+	Key    Key
+	Closed bool
+}
+
+func ClosedBound(key Key) Bound {
+	return Bound{Key: key, Closed: true}
+}
+
+func OpenBound(key Key) Bound {
+	return Bound{Key: key, Closed: false}
+}
+
+// Range is a range of keys where each of the bounds is either open or closed. This is how filters like `x > 5` and
+// `x >= 5` are represented, before converting them to the keys of a scan.
+type Range struct {
+	Start Bound
+	End   Bound
+}
+
+func NewRange(start Bound, end Bound) *Range {
+	return &Range{
+		Start: start,
+		End:   end,
+	}
+}
+
+// ScanKeys returns the keys of the range in the form the store expects i.e. an inclusive start and an exclusive end
+// key. An open start and a closed end are moved right after the keys prefixed by the bound key.
+func (r *Range) ScanKeys() (Key, Key) {
+	start := r.Start.Key
+	if !r.Start.Closed {
+		start = keyAfter(start)
+	}
+
+	end := r.End.Key
+	if r.End.Closed {
+		end = keyAfter(end)
+	}
+
+	return start, end
+}
+
+func keyAfter(key Key) Key {
+	parts := make([]interface{}, 0, len(key.IndexParts())+1)
+	parts = append(parts, key.IndexParts()...)
+	parts = append(parts, boundAfter)
+
+	return NewKey(key.Table(), parts...)
+}
@@ -0,0 +1,72 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snowflake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	workerBits   = 10
+	sequenceBits = 12
+
+	// MaxWorker is the highest worker id, the ids generated by different workers never collide.
+	MaxWorker   = 1<<workerBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// Epoch is the time the timestamps of the ids are relative to, the 41 bits of milliseconds last about 69 years.
+var Epoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator generates unique positive int64 ids made of, from the most significant bits, 41 bits of milliseconds
+// since the Epoch, 10 bits of worker id and a 12 bits counter of the ids generated within the millisecond. The ids of
+// a generator are strictly increasing, if the counter overflows or the clock goes backward the timestamp is moved
+// ahead of the clock. The ids of different generators are unique as long as their worker ids are.
+type Generator struct {
+	sync.Mutex
+
+	worker int64
+	ms     int64
+	seq    int64
+	now    func() time.Time
+}
+
+func NewGenerator(worker int64) (*Generator, error) {
+	if worker < 0 || worker > MaxWorker {
+		return nil, fmt.Errorf("worker id '%d' must be between 0 and %d", worker, MaxWorker)
+	}
+
+	return &Generator{
+		worker: worker,
+		now:    time.Now,
+	}, nil
+}
+
+// Next returns the next id.
+func (g *Generator) Next() int64 {
+	g.Lock()
+	defer g.Unlock()
+
+	ms := g.now().Sub(Epoch).Milliseconds()
+	if ms > g.ms {
+		g.ms, g.seq = ms, 0
+	} else if g.seq++; g.seq > maxSequence {
+		g.ms, g.seq = g.ms+1, 0
+	}
+
+	return g.ms<<(workerBits+sequenceBits) | g.worker<<sequenceBits | g.seq
+}
@@ -0,0 +1,86 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator(t *testing.T) {
+	_, err := NewGenerator(MaxWorker + 1)
+	require.Error(t, err)
+	_, err = NewGenerator(-1)
+	require.Error(t, err)
+
+	g, err := NewGenerator(5)
+	require.NoError(t, err)
+
+	now := Epoch.Add(time.Second)
+	g.now = func() time.Time { return now }
+
+	require.Equal(t, int64(1000)<<22|5<<12, g.Next())
+	require.Equal(t, int64(1000)<<22|5<<12|1, g.Next())
+
+	// the clock going backward doesn't move the ids back
+	now = Epoch
+	require.Equal(t, int64(1000)<<22|5<<12|2, g.Next())
+
+	// the counter overflows into the next millisecond
+	g.seq = maxSequence
+	require.Equal(t, int64(1001)<<22|5<<12, g.Next())
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	workers := []*Generator{}
+	for _, w := range []int64{1, 2} {
+		g, err := NewGenerator(w)
+		require.NoError(t, err)
+		workers = append(workers, g)
+	}
+
+	const goroutines, perGoroutine = 16, 5000
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		seen = make(map[int64]struct{}, 2*goroutines*perGoroutine)
+	)
+	for _, g := range workers {
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func(g *Generator) {
+				defer wg.Done()
+
+				ids := make([]int64, 0, perGoroutine)
+				for j := 0; j < perGoroutine; j++ {
+					ids = append(ids, g.Next())
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, id := range ids {
+					seen[id] = struct{}{}
+				}
+			}(g)
+		}
+	}
+	wg.Wait()
+
+	require.Len(t, seen, 2*goroutines*perGoroutine)
+}
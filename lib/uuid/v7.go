@@ -0,0 +1,96 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uuid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	uuid2 "github.com/google/uuid"
+)
+
+// crockford is the base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// v7Clock keeps the timestamp and the counter of the last generated version 7 UUID, so that the UUIDs generated
+// within the same millisecond are still ordered.
+var v7Clock struct {
+	sync.Mutex
+	ms  uint64
+	seq uint16
+}
+
+// NewV7 returns a time ordered UUID as defined by the version 7 layout, the first 48 bits are the unix time in
+// milliseconds followed by a 12 bits counter and random bits. The counter is seeded randomly every millisecond and
+// incremented for every UUID generated within it, once it overflows the timestamp is moved to the next millisecond,
+// so the UUIDs generated by the process are strictly increasing even if the clock goes backward.
+func NewV7() uuid2.UUID {
+	var u uuid2.UUID
+	_, _ = rand.Read(u[6:])
+
+	ms, seq := nextV7(uint64(time.Now().UnixMilli()), binary.BigEndian.Uint16(u[6:8]))
+	putMillis(u[:6], ms)
+
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u
+}
+
+// nextV7 returns the timestamp and the counter of the next UUID, seed is the random value to start the counter of a
+// new millisecond with. The seed keeps the top bit of the counter clear so that there is room to increment it.
+func nextV7(now uint64, seed uint16) (uint64, uint16) {
+	v7Clock.Lock()
+	defer v7Clock.Unlock()
+
+	if now > v7Clock.ms {
+		v7Clock.ms, v7Clock.seq = now, seed&0x7ff
+	} else if v7Clock.seq++; v7Clock.seq > 0xfff {
+		v7Clock.ms, v7Clock.seq = v7Clock.ms+1, seed&0x7ff
+	}
+
+	return v7Clock.ms, v7Clock.seq
+}
+
+func NewV7AsString() string {
+	return NewV7().String()
+}
+
+// NewULIDAsString returns a lexicographically sortable identifier made of 48 bits of unix time in milliseconds and
+// 80 random bits encoded as 26 characters of Crockford's base32.
+func NewULIDAsString() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	putMillis(b[:6], uint64(time.Now().UnixMilli()))
+
+	out := make([]byte, 26)
+	// 128 bits are encoded in 26 characters of 5 bits each, the first character only carries 3 bits.
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+func putMillis(b []byte, ms uint64) {
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uuid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewV7(t *testing.T) {
+	u := NewV7()
+	require.Equal(t, 7, int(u.Version()))
+	require.Equal(t, "RFC4122", u.Variant().String())
+
+	first := NewV7AsString()
+	time.Sleep(2 * time.Millisecond)
+	require.Less(t, first, NewV7AsString())
+}
+
+func TestNewV7Monotonic(t *testing.T) {
+	// many UUIDs are generated within the same millisecond, they must still be ordered
+	prev := NewV7AsString()
+	for i := 0; i < 10000; i++ {
+		next := NewV7AsString()
+		require.Less(t, prev, next)
+		prev = next
+	}
+}
+
+func TestNextV7(t *testing.T) {
+	v7Clock.ms, v7Clock.seq = 0, 0
+
+	ms, seq := nextV7(1000, 0xffff)
+	require.Equal(t, uint64(1000), ms)
+	require.Equal(t, uint16(0x7ff), seq)
+
+	// same millisecond and a clock going backward, increment the counter
+	ms, seq = nextV7(1000, 0)
+	require.Equal(t, uint64(1000), ms)
+	require.Equal(t, uint16(0x800), seq)
+	ms, seq = nextV7(999, 0)
+	require.Equal(t, uint64(1000), ms)
+	require.Equal(t, uint16(0x801), seq)
+
+	// the counter overflows into the next millisecond
+	v7Clock.seq = 0xfff
+	ms, seq = nextV7(1000, 5)
+	require.Equal(t, uint64(1001), ms)
+	require.Equal(t, uint16(5), seq)
+}
+
+func TestNewULIDAsString(t *testing.T) {
+	first := NewULIDAsString()
+	require.Len(t, first, 26)
+	require.LessOrEqual(t, first[0], byte('7'))
+
+	time.Sleep(2 * time.Millisecond)
+	require.Less(t, first, NewULIDAsString())
+}
@@ -255,6 +255,7 @@ func (factory *Factory) ParseSelector(k []byte, v []byte, dataType jsonparser.Va
 	if field == nil {
 		return nil, errors.InvalidArgument("querying on non schema field '%s'", string(k))
 	}
+	fieldCollation := factory.fieldCollation(field)
 
 	switch dataType {
 	case jsonparser.Boolean, jsonparser.Number, jsonparser.String, jsonparser.Array, jsonparser.Null:
@@ -270,8 +271,8 @@ func (factory *Factory) ParseSelector(k []byte, v []byte, dataType jsonparser.Va
 
 		var val value.Value
 		var err error
-		if factory.collation != nil {
-			val, err = value.NewValueUsingCollation(tigrisType, v, factory.collation)
+		if fieldCollation != nil {
+			val, err = value.NewValueUsingCollation(tigrisType, v, fieldCollation)
 		} else {
 			val, err = value.NewValue(tigrisType, v)
 		}
@@ -279,9 +280,9 @@ func (factory *Factory) ParseSelector(k []byte, v []byte, dataType jsonparser.Va
 			return nil, err
 		}
 
-		return NewSelector(field, NewEqualityMatcher(val), factory.collation), nil
+		return NewSelector(field, NewEqualityMatcher(val), fieldCollation), nil
 	case jsonparser.Object:
-		valueMatcher, collation, err := buildValueMatcher(v, field, factory.collation, factory.buildForSecondaryIndex)
+		valueMatcher, collation, err := buildValueMatcher(v, field, fieldCollation, factory.buildForSecondaryIndex)
 		if err != nil {
 			return nil, err
 		}
@@ -289,12 +290,24 @@ func (factory *Factory) ParseSelector(k []byte, v []byte, dataType jsonparser.Va
 		if collation != nil {
 			return NewSelector(field, valueMatcher, collation), nil
 		}
-		return NewSelector(field, valueMatcher, factory.collation), nil
+		return NewSelector(field, valueMatcher, fieldCollation), nil
 	default:
 		return nil, errors.InvalidArgument("unable to parse the comparison operator")
 	}
 }
 
+// fieldCollation returns the collation to use for the field. A field with a case-insensitive secondary index is
+// compared case-insensitively unless the query sets its own collation, so that the result doesn't depend on whether
+// the secondary index is used. For the secondary index the sort key collation is always used, as the index keys of
+// such a field are lowercased while building the query plan.
+func (factory *Factory) fieldCollation(field *schema.QueryableField) *value.Collation {
+	if factory.buildForSecondaryIndex || !field.IndexCaseInsensitive {
+		return factory.collation
+	}
+
+	return value.NewCollationFrom(&api.Collation{Case: api.SupportedCollations[api.CaseInsensitive]})
+}
+
 // buildValueMatcher is a helper method to create a value matcher object when the value of a Selector is an object
 // instead of a simple JSON value. Apart from comparison operators, this object can have its own collation, which
 // needs to be honored at the field level. Therefore, the caller needs to check if the collation returned by the
@@ -145,3 +145,27 @@ func TestFiltersWithCollation(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, filters)
 }
+
+func TestFiltersWithCaseInsensitiveIndex(t *testing.T) {
+	factory := NewFactory([]*schema.QueryableField{
+		{FieldName: "email", DataType: schema.StringType, Indexed: true, IndexCaseInsensitive: true},
+		{FieldName: "name", DataType: schema.StringType, Indexed: true},
+	}, nil)
+
+	doc := []byte(`{"email": "foo@example.com", "name": "foo"}`)
+	for _, c := range []struct {
+		filter  []byte
+		matches bool
+	}{
+		{[]byte(`{"email": "foo@example.com"}`), true},
+		{[]byte(`{"email": "Foo@Example.com"}`), true},
+		{[]byte(`{"email": {"$eq": "FOO@EXAMPLE.COM"}}`), true},
+		{[]byte(`{"email": {"$eq": "Foo@Example.com", "collation": {"case": "cs"}}}`), false},
+		{[]byte(`{"email": "bar@example.com"}`), false},
+		{[]byte(`{"name": "Foo"}`), false},
+	} {
+		filters, err := factory.Factorize(c.filter)
+		require.NoError(t, err)
+		require.Equal(t, c.matches, filters[0].Matches(doc), string(c.filter))
+	}
+}
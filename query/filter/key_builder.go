@@ -15,6 +15,7 @@
 package filter
 
 import (
+	"bytes"
 	"sort"
 
 	"github.com/tigrisdata/tigris/errors"
@@ -41,11 +42,26 @@ const (
 	FULLRANGE
 )
 
+func (q QueryPlanType) String() string {
+	switch q {
+	case EQUAL:
+		return "EQUAL"
+	case RANGE:
+		return "RANGE"
+	case FULLRANGE:
+		return "FULLRANGE"
+	}
+	return "UNKNOWN"
+}
+
 // The KeyBuilder returns a QueryPlan that contains the keys and type of query against fdb.
 type QueryPlan struct {
 	QueryType QueryPlanType
 	DataType  schema.FieldType
 	Keys      []keys.Key
+	// Range is set for the range plans, it has the bounds the Keys of the plan are built from. An equality plan can also
+	// have the range of the keys prefixed by its key, which is then scanned instead of reading the key.
+	Range *keys.Range
 }
 
 func newQueryPlan(queryType QueryPlanType, dataType schema.FieldType, keys []keys.Key) QueryPlan {
@@ -53,18 +69,86 @@ func newQueryPlan(queryType QueryPlanType, dataType schema.FieldType, keys []key
 		queryType,
 		dataType,
 		keys,
+		nil,
+	}
+}
+
+func newRangeQueryPlan(queryType QueryPlanType, dataType schema.FieldType, r *keys.Range) QueryPlan {
+	start, end := r.ScanKeys()
+	return QueryPlan{
+		queryType,
+		dataType,
+		[]keys.Key{start, end},
+		r,
 	}
 }
 
 // Sort these by QueryPlanType. This creates a simple way to choose a best query plan
-// based on the queryType.
+// based on the queryType. Plans with the same data type are ordered by their keys, which include the field name, so
+// that the order is deterministic for a given filter.
 func SortQueryPlans(queries []QueryPlan) []QueryPlan {
-	sort.Slice(queries, func(i, j int) bool {
-		return queries[i].DataType < queries[j].DataType
+	sort.SliceStable(queries, func(i, j int) bool {
+		if queries[i].DataType != queries[j].DataType {
+			return queries[i].DataType < queries[j].DataType
+		}
+		return compareKeys(queries[i].Keys, queries[j].Keys) < 0
 	})
 	return queries
 }
 
+// MergeAdjacentRanges merges the consecutive range plans whose scans touch or overlap, i.e. the end key of a plan is
+// at or after the start key of the next plan, into a single plan scanning both, which saves a scan per merged plan.
+// The plans are expected to be sorted with SortQueryPlans so that the ranges follow each other by start key. The
+// other plans are returned as is.
+func MergeAdjacentRanges(queries []QueryPlan) []QueryPlan {
+	merged := make([]QueryPlan, 0, len(queries))
+	for _, q := range queries {
+		if n := len(merged); n > 0 && isRangePlan(merged[n-1]) && isRangePlan(q) && merged[n-1].DataType == q.DataType &&
+			merged[n-1].Keys[1].CompareBytes(q.Keys[0].SerializeToBytes()) >= 0 {
+			merged[n-1] = mergeRangePlans(merged[n-1], q)
+			continue
+		}
+		merged = append(merged, q)
+	}
+
+	return merged
+}
+
+func isRangePlan(q QueryPlan) bool {
+	return (q.QueryType == RANGE || q.QueryType == FULLRANGE) && len(q.Keys) == 2
+}
+
+// mergeRangePlans returns the plan scanning from the start of a to the furthest end of a and b, b starting within a.
+func mergeRangePlans(a QueryPlan, b QueryPlan) QueryPlan {
+	if a.Keys[1].CompareBytes(b.Keys[1].SerializeToBytes()) >= 0 {
+		return a
+	}
+
+	merged := QueryPlan{
+		QueryType: RANGE,
+		DataType:  a.DataType,
+		Keys:      []keys.Key{a.Keys[0], b.Keys[1]},
+	}
+	if a.QueryType == FULLRANGE || b.QueryType == FULLRANGE {
+		merged.QueryType = FULLRANGE
+	}
+	if a.Range != nil && b.Range != nil {
+		merged.Range = keys.NewRange(a.Range.Start, b.Range.End)
+	}
+
+	return merged
+}
+
+func compareKeys(a []keys.Key, b []keys.Key) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := bytes.Compare(a[i].SerializeToBytes(), b[i].SerializeToBytes()); c != 0 {
+			return c
+		}
+	}
+
+	return len(a) - len(b)
+}
+
 func (q QueryPlan) GetKeyInterfaceParts() [][]interface{} {
 	keys := make([][]interface{}, len(q.Keys))
 	for i, key := range q.Keys {
@@ -315,59 +399,48 @@ func NewRangeKeyComposer[F fieldable](keyEncodingFunc KeyEncodingFunc, buildInde
 }
 
 func (s *RangeKeyComposer[F]) Compose(selectors []*Selector, userDefinedKeys []F, parent LogicalOP) ([]QueryPlan, error) {
-	var err error
 	var queryPlans []QueryPlan
 	for _, k := range userDefinedKeys {
-		var begin, end keys.Key
-		rangeType := FULLRANGE
+		var start, end *keys.Bound
 		for _, sel := range selectors {
 			if k.Name() == sel.Field.Name() && s.isRange(sel) {
-				indexParts := s.buildIndexPartsFunc(sel.Field.Name(), sel.Matcher.GetValue())
+				key, err := s.keyEncodingFunc(s.buildIndexPartsFunc(sel.Field.Name(), sel.Matcher.GetValue())...)
+				if err != nil {
+					return nil, err
+				}
+
+				// $gte and $lte include the value, $gt and $lt exclude it
 				if s.isGreater(sel) {
-					if sel.Matcher.Type() == GT {
-						indexParts = append(indexParts, 0xFF)
-					}
-
-					begin, err = s.keyEncodingFunc(indexParts...)
-					if err != nil {
-						return nil, err
-					}
-
-					if end == nil {
-						lessIndexParts := s.buildIndexPartsFunc(sel.Field.Name(), value.MaxOrderValue())
-						end, err = s.keyEncodingFunc(lessIndexParts...)
-						if err != nil {
-							return nil, err
-						}
-					} else {
-						rangeType = RANGE
-					}
+					start = &keys.Bound{Key: key, Closed: sel.Matcher.Type() == GTE}
 				} else {
-					if sel.Matcher.Type() == LTE {
-						indexParts = append(indexParts, 0xFF)
-					}
-
-					end, err = s.keyEncodingFunc(indexParts...)
-					if err != nil {
-						return nil, err
-					}
-
-					if begin == nil {
-						greaterIndexParts := s.buildIndexPartsFunc(sel.Field.Name(), value.MinOrderValue())
-						begin, err = s.keyEncodingFunc(greaterIndexParts...)
-						if err != nil {
-							return nil, err
-						}
-					} else {
-						rangeType = RANGE
-					}
+					end = &keys.Bound{Key: key, Closed: sel.Matcher.Type() == LTE}
 				}
 			}
 		}
 
-		if begin != nil && end != nil {
-			queryPlans = append(queryPlans, newQueryPlan(rangeType, k.Type(), []keys.Key{begin, end}))
+		if start == nil && end == nil {
+			continue
+		}
+
+		rangeType := RANGE
+		if start == nil {
+			minKey, err := s.keyEncodingFunc(s.buildIndexPartsFunc(k.Name(), value.MinOrderValue())...)
+			if err != nil {
+				return nil, err
+			}
+			start = &keys.Bound{Key: minKey, Closed: true}
+			rangeType = FULLRANGE
 		}
+		if end == nil {
+			maxKey, err := s.keyEncodingFunc(s.buildIndexPartsFunc(k.Name(), value.MaxOrderValue())...)
+			if err != nil {
+				return nil, err
+			}
+			end = &keys.Bound{Key: maxKey, Closed: false}
+			rangeType = FULLRANGE
+		}
+
+		queryPlans = append(queryPlans, newRangeQueryPlan(rangeType, k.Type(), keys.NewRange(*start, *end)))
 	}
 
 	if len(queryPlans) == 0 {
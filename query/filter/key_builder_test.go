@@ -351,6 +351,44 @@ func TestKeyBuilderRangeKey(t *testing.T) {
 	}
 }
 
+func TestKeyBuilderRangeBounds(t *testing.T) {
+	userFields := []*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}}
+	userKeys := []*schema.Field{{FieldName: "a", DataType: schema.Int64Type}}
+	// the index key of a document has the value followed by the position and the primary key
+	indexKey := func(v int64) []byte {
+		return keys.NewKey(nil, value.ToSecondaryOrder(schema.Int64Type, nil), "a", v, 0, int64(100)).SerializeToBytes()
+	}
+
+	cases := []struct {
+		filter   []byte
+		included []int64
+		excluded []int64
+	}{
+		{[]byte(`{"a": {"$gt": 5}}`), []int64{6, 100}, []int64{4, 5}},
+		{[]byte(`{"a": {"$gte": 5}}`), []int64{5, 6, 100}, []int64{4}},
+		{[]byte(`{"a": {"$lt": 5}}`), []int64{-100, 4}, []int64{5, 6}},
+		{[]byte(`{"a": {"$lte": 5}}`), []int64{-100, 4, 5}, []int64{6}},
+		{[]byte(`{"$and": [{"a": {"$gt": 5}}, {"a": {"$lte": 10}}]}`), []int64{6, 10}, []int64{5, 11}},
+		{[]byte(`{"$and": [{"a": {"$gte": 5}}, {"a": {"$lt": 10}}]}`), []int64{5, 9}, []int64{4, 10}},
+	}
+
+	for _, c := range cases {
+		b := NewKeyBuilder[*schema.Field](NewRangeKeyComposer[*schema.Field](dummyEncodeFunc, dummyBuildIndexParts), false)
+		queryPlans, err := b.Build(testFilters(t, userFields, c.filter, true), userKeys)
+		require.NoError(t, err)
+		require.Len(t, queryPlans, 1)
+		require.NotNil(t, queryPlans[0].Range)
+
+		start, end := queryPlans[0].Keys[0], queryPlans[0].Keys[1]
+		for _, v := range c.included {
+			require.True(t, start.CompareBytes(indexKey(v)) <= 0 && end.CompareBytes(indexKey(v)) > 0, "%s must include %d", c.filter, v)
+		}
+		for _, v := range c.excluded {
+			require.False(t, start.CompareBytes(indexKey(v)) <= 0 && end.CompareBytes(indexKey(v)) > 0, "%s must exclude %d", c.filter, v)
+		}
+	}
+}
+
 func TestKeyBuilderMultipleRangeKey(t *testing.T) {
 	userFields := []*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}}
 	userKeys := []*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}}
@@ -366,6 +404,53 @@ func TestKeyBuilderMultipleRangeKey(t *testing.T) {
 	assert.Equal(t, []keys.Key{keys.NewKey(nil, value.ToSecondaryOrder(schema.Int64Type, nil), "b", int64(3), 0xFF), keys.NewKey(nil, value.ToSecondaryOrder(schema.Int64Type, nil), "b", int64(30), 0xFF)}, keyReads[1].Keys)
 }
 
+func TestSortQueryPlans(t *testing.T) {
+	planA := newQueryPlan(RANGE, schema.Int64Type, []keys.Key{keys.NewKey(nil, "a", int64(1)), keys.NewKey(nil, "a", int64(10))})
+	planB := newQueryPlan(RANGE, schema.Int64Type, []keys.Key{keys.NewKey(nil, "b", int64(1)), keys.NewKey(nil, "b", int64(10))})
+	planStr := newQueryPlan(RANGE, schema.StringType, []keys.Key{keys.NewKey(nil, "c", "x"), keys.NewKey(nil, "c", "y")})
+
+	expected := []QueryPlan{planA, planB, planStr}
+	for _, plans := range [][]QueryPlan{
+		{planA, planB, planStr},
+		{planB, planA, planStr},
+		{planStr, planB, planA},
+		{planB, planStr, planA},
+	} {
+		require.Equal(t, expected, SortQueryPlans(plans))
+	}
+}
+
+func TestMergeAdjacentRanges(t *testing.T) {
+	key := func(v int64) keys.Key { return keys.NewKey(nil, "a", v) }
+	plan := func(start int64, end int64) QueryPlan {
+		return newQueryPlan(RANGE, schema.Int64Type, []keys.Key{key(start), key(end)})
+	}
+
+	// the end key of a range is exclusive, so [1, 5) and [5, 10) are adjacent
+	require.Equal(t, []QueryPlan{plan(1, 10)}, MergeAdjacentRanges([]QueryPlan{plan(1, 5), plan(5, 10)}))
+	// overlapping and contained ranges
+	require.Equal(t, []QueryPlan{plan(1, 12)}, MergeAdjacentRanges([]QueryPlan{plan(1, 8), plan(5, 10), plan(6, 12)}))
+	require.Equal(t, []QueryPlan{plan(1, 10)}, MergeAdjacentRanges([]QueryPlan{plan(1, 10), plan(2, 3)}))
+	// non-adjacent ranges are kept apart
+	require.Equal(t, []QueryPlan{plan(1, 5), plan(6, 10)}, MergeAdjacentRanges([]QueryPlan{plan(1, 5), plan(6, 10)}))
+	require.Equal(t, []QueryPlan{plan(1, 10), plan(20, 30)},
+		MergeAdjacentRanges(SortQueryPlans([]QueryPlan{plan(20, 30), plan(5, 10), plan(1, 5)})))
+
+	// the equality plans and the ranges of other types are never merged
+	eq := newQueryPlan(EQUAL, schema.Int64Type, []keys.Key{key(5)})
+	str := newQueryPlan(RANGE, schema.StringType, []keys.Key{key(5), key(10)})
+	require.Equal(t, []QueryPlan{plan(1, 5), eq, str}, MergeAdjacentRanges([]QueryPlan{plan(1, 5), eq, str}))
+	require.Equal(t, []QueryPlan{plan(1, 5), str}, MergeAdjacentRanges([]QueryPlan{plan(1, 5), str}))
+
+	bounded := func(start int64, end int64) QueryPlan {
+		return newRangeQueryPlan(RANGE, schema.Int64Type, keys.NewRange(keys.ClosedBound(key(start)), keys.OpenBound(key(end))))
+	}
+	merged := MergeAdjacentRanges([]QueryPlan{bounded(1, 5), bounded(5, 10)})
+	require.Len(t, merged, 1)
+	require.Equal(t, keys.NewRange(keys.ClosedBound(key(1)), keys.OpenBound(key(10))), merged[0].Range)
+	require.Equal(t, []keys.Key{key(1), key(10)}, merged[0].Keys)
+}
+
 func BenchmarkStrictEqKeyComposer_Compose(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		kb := NewKeyBuilder[*schema.Field](NewStrictEqKeyComposer[*schema.Field](dummyEncodeFunc, PKBuildIndexPartsFunc, true), true)
@@ -0,0 +1,163 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"time"
+
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// AutoGenerateStrategyKey is the collection level schema property to configure how the values of the auto-generated
+// fields are generated. It is a map of the field type to the strategy, for example,
+//
+//	"auto_generate_strategy": {"string": "ulid", "int64": "sequence"}
+//
+// Field types that are not part of the map are using the default strategy of the type. An auto-generated field can
+// also override the strategy of its type with the "autoGenerateStrategy" property, for example,
+//
+//	"id": {"type": "string", "format": "uuid", "autoGenerate": true, "autoGenerateStrategy": "uuidv7"}
+const AutoGenerateStrategyKey = "auto_generate_strategy"
+
+// StrictAutoGenerateKey is the collection level schema property to reject the inserted documents that set a value,
+// even the zero value of its type or null, for an auto-generated field, for example,
+//
+//	"strict_auto_generate": true
+//
+// By default, a value is generated for the auto-generated fields that are missing or set to the zero value of their
+// type, and the other values are kept. Only the inserts are checked. A replace is not, because replacing an existing
+// document means setting its primary key, auto-generated fields included, so a replace can still set any value for
+// an auto-generated field and create a document with it.
+const StrictAutoGenerateKey = "strict_auto_generate"
+
+type AutoGenerateStrategy string
+
+const (
+	AutoGenerateDefault   AutoGenerateStrategy = ""
+	AutoGenerateUUIDv4    AutoGenerateStrategy = "uuidv4"
+	AutoGenerateUUIDv7    AutoGenerateStrategy = "uuidv7"
+	AutoGenerateULID      AutoGenerateStrategy = "ulid"
+	AutoGenerateTimestamp AutoGenerateStrategy = "timestamp"
+	AutoGenerateSequence  AutoGenerateStrategy = "sequence"
+	// AutoGenerateSnowflake generates int64 values made of a timestamp, the worker id of the server and a counter,
+	// which are unique as long as every server is configured with its own worker id.
+	AutoGenerateSnowflake AutoGenerateStrategy = "snowflake"
+)
+
+// supportedAutoGenerateStrategies are the strategies allowed for a field type, the first one is the default.
+var supportedAutoGenerateStrategies = map[FieldType][]AutoGenerateStrategy{
+	StringType: {AutoGenerateUUIDv4, AutoGenerateUUIDv7, AutoGenerateULID},
+	UUIDType:   {AutoGenerateUUIDv4, AutoGenerateUUIDv7},
+	Int64Type:  {AutoGenerateTimestamp, AutoGenerateSequence, AutoGenerateSnowflake},
+}
+
+// AutoGenerateStrategies keeps the auto-generate strategy configured for the field types of a collection.
+type AutoGenerateStrategies map[FieldType]AutoGenerateStrategy
+
+// Get returns the strategy for the field type, AutoGenerateDefault if the collection doesn't configure one.
+func (a AutoGenerateStrategies) Get(tp FieldType) AutoGenerateStrategy {
+	if s, ok := a[tp]; ok {
+		return s
+	}
+	return AutoGenerateDefault
+}
+
+// For returns the strategy to generate the values of the field, the one set on the field takes precedence over the
+// one configured in the collection for the type of the field.
+func (a AutoGenerateStrategies) For(field *Field) AutoGenerateStrategy {
+	if field.AutoGenerateStrategy != AutoGenerateDefault {
+		return field.AutoGenerateStrategy
+	}
+	return a.Get(field.Type())
+}
+
+func buildAutoGenerateStrategies(strategies map[string]string) (AutoGenerateStrategies, error) {
+	if len(strategies) == 0 {
+		return nil, nil
+	}
+
+	result := make(AutoGenerateStrategies, len(strategies))
+	for typeName, strategy := range strategies {
+		tp := toAutoGenerateFieldType(typeName)
+		if err := validateAutoGenerateStrategy(tp, typeName, AutoGenerateStrategy(strategy)); err != nil {
+			return nil, err
+		}
+
+		result[tp] = AutoGenerateStrategy(strategy)
+	}
+
+	return result, nil
+}
+
+func validateAutoGenerateStrategy(tp FieldType, typeName string, strategy AutoGenerateStrategy) error {
+	supported, ok := supportedAutoGenerateStrategies[tp]
+	if !ok {
+		return errors.InvalidArgument("auto-generate strategy is not supported for type '%s'", typeName)
+	}
+
+	for _, s := range supported {
+		if s == strategy {
+			return nil
+		}
+	}
+
+	return errors.InvalidArgument("unsupported auto-generate strategy '%s' for type '%s'", strategy, typeName)
+}
+
+func toAutoGenerateFieldType(typeName string) FieldType {
+	for tp, name := range FieldNames {
+		if name == typeName {
+			return FieldType(tp)
+		}
+	}
+	return UnknownType
+}
+
+// DateTimePrecision is the precision of the values generated for an auto-generated date-time field, set with the
+// "autoGeneratePrecision" property of the field, for example,
+//
+//	"created": {"type": "string", "format": "date-time", "autoGenerate": true, "autoGeneratePrecision": "millis"}
+//
+// The fractional seconds of the millis and micros precisions have a fixed number of digits, so that the values
+// compare equal as strings, while the default nanos precision trims the trailing zeros.
+type DateTimePrecision string
+
+const (
+	DateTimePrecisionDefault DateTimePrecision = ""
+	DateTimePrecisionSeconds DateTimePrecision = "seconds"
+	DateTimePrecisionMillis  DateTimePrecision = "millis"
+	DateTimePrecisionMicros  DateTimePrecision = "micros"
+	DateTimePrecisionNanos   DateTimePrecision = "nanos"
+)
+
+var dateTimePrecisionLayouts = map[DateTimePrecision]string{
+	DateTimePrecisionDefault: time.RFC3339Nano,
+	DateTimePrecisionSeconds: time.RFC3339,
+	DateTimePrecisionMillis:  "2006-01-02T15:04:05.000Z07:00",
+	DateTimePrecisionMicros:  "2006-01-02T15:04:05.000000Z07:00",
+	DateTimePrecisionNanos:   time.RFC3339Nano,
+}
+
+// Format returns the UTC time formatted with the precision.
+func (p DateTimePrecision) Format(t time.Time) string {
+	return t.UTC().Format(dateTimePrecisionLayouts[p])
+}
+
+func validateDateTimePrecision(p DateTimePrecision) error {
+	if _, ok := dateTimePrecisionLayouts[p]; !ok || p == DateTimePrecisionDefault {
+		return errors.InvalidArgument("unsupported auto-generate precision '%s', it must be one of 'seconds', 'millis', 'micros' or 'nanos'", p)
+	}
+	return nil
+}
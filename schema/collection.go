@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -81,6 +82,14 @@ type DefaultCollection struct {
 
 	fieldsWithInsertDefaults map[string]struct{}
 	fieldsWithUpdateDefaults map[string]struct{}
+
+	// AutoGenerateStrategies is the strategy configured per field type to generate the values of auto-generated fields.
+	AutoGenerateStrategies AutoGenerateStrategies
+	// StrictAutoGenerate rejects the inserted documents that set a value for an auto-generated field, the replaced
+	// documents are not checked.
+	StrictAutoGenerate bool
+	// TTL is the age after which the documents are expired, zero if they never expire.
+	TTL time.Duration
 }
 
 type CollectionType string
@@ -188,6 +197,9 @@ func NewDefaultCollection(id uint32, schVer int, factory *Factory, schemas Versi
 		SchemaDeltas:             schemaDeltas,
 		FieldVersions:            fieldVersions,
 		int64FieldsPath:          buildInt64Path(factory.Fields),
+		AutoGenerateStrategies:   factory.AutoGenerateStrategies,
+		StrictAutoGenerate:       factory.StrictAutoGenerate,
+		TTL:                      factory.TTL,
 	}
 
 	// set fieldDefaulter for default fields
@@ -266,6 +266,10 @@ var SupportedFieldProperties = container.NewHashSet(
 	"contentEncoding",
 	"properties",
 	"autoGenerate",
+	"autoGenerateStrategy",
+	"autoGenerateStart",
+	"autoGenerateStep",
+	"autoGeneratePrecision",
 	"sorted",
 	"sort",
 	"index",
@@ -281,6 +285,7 @@ var SupportedFieldProperties = container.NewHashSet(
 	"searchIndex",
 	"maxItems",
 	"additionalProperties",
+	"indexCaseInsensitive",
 	"dimensions",
 	"id",
 )
@@ -405,8 +410,13 @@ type FieldBuilder struct {
 	MaxLength            *int32              `json:"maxLength,omitempty"`
 	MaxItems             *int32              `json:"maxItems,omitempty"`
 	Auto                 *bool               `json:"autoGenerate,omitempty"`
+	AutoStrategy         string              `json:"autoGenerateStrategy,omitempty"`
+	AutoStart            *int32              `json:"autoGenerateStart,omitempty"`
+	AutoStep             *int32              `json:"autoGenerateStep,omitempty"`
+	AutoPrecision        string              `json:"autoGeneratePrecision,omitempty"`
 	Sorted               *bool               `json:"sort,omitempty"`
 	Index                *bool               `json:"index,omitempty"`
+	IndexCaseInsensitive *bool               `json:"indexCaseInsensitive,omitempty"`
 	Facet                *bool               `json:"facet,omitempty"`
 	ID                   *bool               `json:"id,omitempty"`
 	SearchIndex          *bool               `json:"searchIndex,omitempty"`
@@ -442,10 +452,15 @@ func (f *FieldBuilder) Build(setSearchDefaults bool) (*Field, error) {
 		Fields:               f.Fields,
 		Sorted:               f.Sorted,
 		Indexed:              f.Index,
+		IndexCaseInsensitive: f.IndexCaseInsensitive,
 		Faceted:              f.Facet,
 		SearchIndexed:        f.SearchIndex,
 		PrimaryKeyField:      f.Primary,
 		AutoGenerated:        f.Auto,
+		AutoGenerateStrategy: AutoGenerateStrategy(f.AutoStrategy),
+		AutoGenerateStart:    f.AutoStart,
+		AutoGenerateStep:     f.AutoStep,
+		DateTimePrecision:    DateTimePrecision(f.AutoPrecision),
 		Dimensions:           f.Dimensions,
 		AdditionalProperties: f.AdditionalProperties,
 		SearchIdField:        f.ID,
@@ -485,6 +500,18 @@ type Field struct {
 	// Nested fields are the fields where we know the schema of nested attributes like if properties are
 	Fields               []*Field
 	AdditionalProperties *bool
+	// IndexCaseInsensitive is only applicable to string fields with a secondary index. The strings are lowercased
+	// when building the index keys, so that equality on the field is case-insensitive.
+	IndexCaseInsensitive *bool
+	// AutoGenerateStrategy is the strategy to generate the values of an auto-generated field, it overrides the one
+	// configured in the collection for the type of the field.
+	AutoGenerateStrategy AutoGenerateStrategy
+	// AutoGenerateStart and AutoGenerateStep are the first value and the increment of the counter of an auto-generated
+	// integer field, they are both 1 if not set.
+	AutoGenerateStart *int32
+	AutoGenerateStep  *int32
+	// DateTimePrecision is the precision of the values of an auto-generated date-time field.
+	DateTimePrecision DateTimePrecision
 }
 
 func (f *Field) Name() string {
@@ -503,6 +530,22 @@ func (f *Field) IsAutoGenerated() bool {
 	return f.AutoGenerated != nil && *f.AutoGenerated
 }
 
+// CounterStart returns the first value of the counter of an auto-generated integer field.
+func (f *Field) CounterStart() int32 {
+	if f.AutoGenerateStart != nil {
+		return *f.AutoGenerateStart
+	}
+	return 1
+}
+
+// CounterStep returns the increment of the counter of an auto-generated integer field.
+func (f *Field) CounterStep() int32 {
+	if f.AutoGenerateStep != nil {
+		return *f.AutoGenerateStep
+	}
+	return 1
+}
+
 func (f *Field) IsSorted() bool {
 	return f.Sorted != nil && *f.Sorted
 }
@@ -511,6 +554,10 @@ func (f *Field) IsIndexed() bool {
 	return f.Indexed != nil && *f.Indexed
 }
 
+func (f *Field) IsIndexCaseInsensitive() bool {
+	return f.IndexCaseInsensitive != nil && *f.IndexCaseInsensitive
+}
+
 func (f *Field) IsSearchId() bool {
 	return f.SearchIdField != nil && *f.SearchIdField
 }
@@ -536,6 +583,10 @@ func (f *Field) IsCompatible(keyPath string, f1 *Field) error {
 		return errors.InvalidArgument("primary key changes are not allowed %q", keyPath+f.FieldName)
 	}
 
+	if f.IsIndexed() && f1.IsIndexed() && f.IsIndexCaseInsensitive() != f1.IsIndexCaseInsensitive() {
+		return errors.InvalidArgument("changing case sensitivity of an existing index is not allowed %q", keyPath+f.FieldName)
+	}
+
 	if f.MaxLength != nil && f1.MaxLength != nil {
 		if *f.MaxLength > *f1.MaxLength && !config.DefaultConfig.Schema.AllowIncompatible {
 			return errors.InvalidArgument("reducing length of an existing field is not allowed %q", keyPath+f.FieldName)
@@ -132,9 +132,14 @@ func TestIndexableFieldsAreChecked(t *testing.T) {
 			errors.InvalidArgument("Cannot enable index on object 'obj' or object fields"),
 		},
 		{
-			// cannot index a subfield on an object
-			[]byte(`{"title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string", "index": true}, "obj_fail": {"type": "object", "properties":{"name": {"type": "string", "index": true}}}}}`),
-			errors.InvalidArgument("Cannot enable index on nested field 'name'"),
+			// can index a subfield on an object
+			[]byte(`{"title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string", "index": true}, "obj": {"type": "object", "properties":{"name": {"type": "string", "index": true}}}}}`),
+			nil,
+		},
+		{
+			// cannot index an array subfield on an object
+			[]byte(`{"title": "t1", "properties": { "id": { "type": "integer"}, "s": { "type": "string", "index": true}, "obj_fail": {"type": "object", "properties":{"names": {"type": "array", "items":{"type": "string"}, "index": true}}}}}`),
+			errors.InvalidArgument("Cannot enable index on nested field 'names'"),
 		},
 		{
 			// cannot index a subfield on an array
@@ -149,6 +154,38 @@ func TestIndexableFieldsAreChecked(t *testing.T) {
 	}
 }
 
+func TestIndexCaseInsensitive(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"email": { "type": "string", "index": true, "indexCaseInsensitive": true },
+			"name": { "type": "string", "index": true },
+			"age": { "type": "integer", "index": true, "indexCaseInsensitive": true },
+			"city": { "type": "string", "indexCaseInsensitive": true }
+		}
+	}`)
+
+	factory, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+	require.NoError(t, err)
+
+	caseInsensitive := map[string]bool{}
+	for _, q := range NewQueryableFieldsBuilder().BuildQueryableFields(factory.Fields, nil) {
+		caseInsensitive[q.Name()] = q.IndexCaseInsensitive
+	}
+	require.True(t, caseInsensitive["email"])
+	require.False(t, caseInsensitive["name"])
+	// only applicable to indexed string fields
+	require.False(t, caseInsensitive["age"])
+	require.False(t, caseInsensitive["city"])
+
+	email := GetField(factory.Fields, "email")
+	name := GetField(factory.Fields, "name")
+	require.Equal(t, errors.InvalidArgument("changing case sensitivity of an existing index is not allowed %q", "email"),
+		email.IsCompatible("", &Field{FieldName: "email", DataType: StringType, Indexed: &boolTrue}))
+	require.NoError(t, name.IsCompatible("", &Field{FieldName: "name", DataType: StringType, Indexed: &boolTrue}))
+}
+
 func TestQueryableField_ShouldPack(t *testing.T) {
 	// reserved fields should never be packed
 	for _, f := range ReservedFields {
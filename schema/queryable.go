@@ -37,6 +37,8 @@ type QueryableField struct {
 	DoNotFlatten  bool
 	Dimensions    *int
 	SearchIdField bool
+	// IndexCaseInsensitive is set when the secondary index of this field stores the strings lowercased
+	IndexCaseInsensitive bool
 }
 
 // InMemoryName returns key name that is used to index this field in the indexing store. For example, an "id" key is indexed with
@@ -131,6 +133,9 @@ func (builder *QueryableFieldsBuilder) NewQueryableField(name string, f *Field,
 	if searchIndexed != nil && *searchIndexed {
 		q.SearchIndexed = true
 	}
+	if q.Indexed && f.IsIndexCaseInsensitive() && (f.DataType == StringType || subType == StringType) {
+		q.IndexCaseInsensitive = true
+	}
 	if sortable != nil && *sortable {
 		q.Sortable = true
 	}
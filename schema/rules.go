@@ -271,6 +271,33 @@ func ValidateFieldBuilder(f FieldBuilder) error {
 		}
 	}
 
+	if len(f.AutoStrategy) > 0 {
+		if f.Auto == nil || !*f.Auto {
+			return errors.InvalidArgument("auto-generate strategy is only allowed on auto-generated fields '%s'", f.FieldName)
+		}
+		if err := validateAutoGenerateStrategy(fieldType, FieldNames[fieldType], AutoGenerateStrategy(f.AutoStrategy)); err != nil {
+			return err
+		}
+	}
+
+	if f.AutoStart != nil || f.AutoStep != nil {
+		if f.Auto == nil || !*f.Auto || (fieldType != Int32Type && fieldType != Int64Type) {
+			return errors.InvalidArgument("counter start and step are only allowed on auto-generated integer fields '%s'", f.FieldName)
+		}
+		if f.AutoStart != nil && *f.AutoStart < 1 || f.AutoStep != nil && *f.AutoStep < 1 {
+			return errors.InvalidArgument("counter start and step of field '%s' must be positive", f.FieldName)
+		}
+	}
+
+	if len(f.AutoPrecision) > 0 {
+		if f.Auto == nil || !*f.Auto || fieldType != DateTimeType {
+			return errors.InvalidArgument("auto-generate precision is only allowed on auto-generated date-time fields '%s'", f.FieldName)
+		}
+		if err := validateDateTimePrecision(DateTimePrecision(f.AutoPrecision)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -347,7 +374,9 @@ func validateObjectFields(f *Field, notSupported bool) error {
 				return err
 			}
 		} else {
-			if nested.IsIndexed() {
+			// fields of an object can be indexed using their flattened name, but not the ones that are part of an
+			// array of objects or that are arrays themselves.
+			if nested.IsIndexed() && (notSupported || nested.DataType == ArrayType) {
 				return errors.InvalidArgument("Cannot enable index on nested field '%s'", nested.Name())
 			}
 			if nested.DataType == ArrayType && nested.Fields[0].DataType == ObjectType && hasIndexingAttributes(nested) {
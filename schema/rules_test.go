@@ -324,6 +324,9 @@ func TestIndexAttributeOnFields(t *testing.T) {
 		}, {
 			[]byte(`{"title":"test","properties":{"obj":{"type":"object","index":true}}}`),
 			"Cannot enable index on object 'obj' or object fields",
+		}, {
+			[]byte(`{"title":"test","properties":{"obj":{"type":"object","properties":{"city":{"type":"string","index":true},"nested":{"type":"object","properties":{"zip":{"type":"integer","index":true}}}}}}}`),
+			"",
 		}, {
 			[]byte(`{"title":"test","properties":{"obj":{"type":"object","properties":{"nested_arr":{"type":"array","items":{"type":"string"},"index":true},"nested_arr_obj":{"type":"array","items":{"type":"object","properties":{"n_id":{"type":"integer"}}}}}}}}`),
 			"Cannot enable index on nested field 'nested_arr'",
@@ -119,6 +119,10 @@ type JSONSchema struct {
 	CollectionType  string              `json:"collection_type,omitempty"`
 	IndexingVersion string              `json:"indexing_version,omitempty"`
 	Version         int32               `json:"version,omitempty"`
+
+	AutoGenerateStrategy map[string]string `json:"auto_generate_strategy,omitempty"`
+	StrictAutoGenerate   bool              `json:"strict_auto_generate,omitempty"`
+	TTL                  string            `json:"ttl,omitempty"`
 }
 
 // Factory is used as an intermediate step so that collection can be initialized with properly encoded values.
@@ -139,6 +143,13 @@ type Factory struct {
 	CollectionType  CollectionType
 	IndexingVersion string
 	Version         int32
+	// AutoGenerateStrategies is the strategy configured per field type to generate the values of auto-generated fields.
+	AutoGenerateStrategies AutoGenerateStrategies
+	// StrictAutoGenerate rejects the inserted documents that set a value for an auto-generated field, the replaced
+	// documents are not checked.
+	StrictAutoGenerate bool
+	// TTL is the age after which the documents are expired, zero if they never expire.
+	TTL time.Duration
 }
 
 func (f *Factory) SecondaryIndexes() []*Index {
@@ -209,6 +220,11 @@ func (fb *FactoryBuilder) Build(collection string, reqSchema jsoniter.RawMessage
 		return nil, errors.InvalidArgument("missing primary key field in schema")
 	}
 
+	autoGenerateStrategies, err := buildAutoGenerateStrategies(schema.AutoGenerateStrategy)
+	if err != nil {
+		return nil, err
+	}
+
 	primaryKeysSet := container.NewHashSet(schema.PrimaryKeys...)
 	fields, err := fb.deserializeProperties(schema.Properties, &primaryKeysSet, nil)
 	if err != nil {
@@ -230,6 +246,11 @@ func (fb *FactoryBuilder) Build(collection string, reqSchema jsoniter.RawMessage
 		}
 	}
 
+	ttl, err := buildTTL(schema.TTL, primaryKeyFields)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the secondary indexes with an unknown state
 	// to determine the state, tigris will need to read from the index metadata
 	secondaryIndex := []*Index{
@@ -244,11 +265,7 @@ func (fb *FactoryBuilder) Build(collection string, reqSchema jsoniter.RawMessage
 			State:   UNKNOWN,
 		},
 	}
-	for _, field := range fields {
-		if field.Indexed != nil && *field.Indexed {
-			secondaryIndex = append(secondaryIndex, &Index{Name: field.Name(), IdxType: SECONDARY_INDEX, State: UNKNOWN, Fields: []*Field{field}})
-		}
-	}
+	secondaryIndex = append(secondaryIndex, buildSecondaryIndexes("", fields)...)
 
 	factory := &Factory{
 		Fields: fields,
@@ -266,6 +283,10 @@ func (fb *FactoryBuilder) Build(collection string, reqSchema jsoniter.RawMessage
 		CollectionType:  cType,
 		IndexingVersion: schema.IndexingVersion,
 		Version:         schema.Version,
+
+		AutoGenerateStrategies: autoGenerateStrategies,
+		StrictAutoGenerate:     schema.StrictAutoGenerate,
+		TTL:                    ttl,
 	}
 
 	if fb.onUserRequest {
@@ -277,6 +298,29 @@ func (fb *FactoryBuilder) Build(collection string, reqSchema jsoniter.RawMessage
 	return factory, nil
 }
 
+// buildSecondaryIndexes returns the secondary indexes for the indexed fields. The fields of nested objects are
+// named by their flattened name i.e. "address.city" the same way as their queryable fields.
+func buildSecondaryIndexes(parent string, fields []*Field) []*Index {
+	var indexes []*Index
+	for _, field := range fields {
+		name := field.Name()
+		if len(parent) > 0 {
+			name = parent + ObjFlattenDelimiter + name
+		}
+
+		if field.DataType == ObjectType {
+			indexes = append(indexes, buildSecondaryIndexes(name, field.Fields)...)
+			continue
+		}
+
+		if field.Indexed != nil && *field.Indexed {
+			indexes = append(indexes, &Index{Name: name, IdxType: SECONDARY_INDEX, State: UNKNOWN, Fields: []*Field{field}})
+		}
+	}
+
+	return indexes
+}
+
 func (fb *FactoryBuilder) validateSchema(factory *Factory) error {
 	for _, f := range factory.Fields {
 		if err := ValidateFieldAttributes(false, f); err != nil {
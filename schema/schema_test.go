@@ -16,6 +16,7 @@ package schema
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -585,3 +586,108 @@ func TestGetCollectionType(t *testing.T) {
 	require.Equal(t, DocumentsType, ty)
 	require.NoError(t, err)
 }
+
+func TestAutoGenerateStrategy(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		reqSchema := []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true}},"primary_key":["id"]}`)
+		schF, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.NoError(t, err)
+
+		c, err := NewDefaultCollection(1, 1, schF, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, AutoGenerateDefault, c.AutoGenerateStrategies.Get(StringType))
+	})
+	t.Run("configured", func(t *testing.T) {
+		reqSchema := []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true},"seq":{"type":"integer"}},"primary_key":["id"],"auto_generate_strategy":{"string":"ulid","int64":"sequence"}}`)
+		schF, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.NoError(t, err)
+
+		c, err := NewDefaultCollection(1, 1, schF, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, AutoGenerateULID, c.AutoGenerateStrategies.Get(StringType))
+		require.Equal(t, AutoGenerateSequence, c.AutoGenerateStrategies.Get(Int64Type))
+		require.Equal(t, AutoGenerateDefault, c.AutoGenerateStrategies.Get(UUIDType))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		reqSchema := []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true}},"primary_key":["id"],"auto_generate_strategy":{"uuid":"ulid"}}`)
+		_, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.Equal(t, errors.InvalidArgument("unsupported auto-generate strategy 'ulid' for type 'uuid'"), err)
+
+		reqSchema = []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true}},"primary_key":["id"],"auto_generate_strategy":{"bool":"sequence"}}`)
+		_, err = NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.Equal(t, errors.InvalidArgument("auto-generate strategy is not supported for type 'bool'"), err)
+	})
+	t.Run("field", func(t *testing.T) {
+		reqSchema := []byte(`{"title":"t1","properties":{"id":{"type":"string","format":"uuid","autoGenerate":true,"autoGenerateStrategy":"uuidv7"},"name":{"type":"string","autoGenerate":true}},"primary_key":["id","name"],"auto_generate_strategy":{"string":"ulid"}}`)
+		schF, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.NoError(t, err)
+
+		c, err := NewDefaultCollection(1, 1, schF, nil, nil)
+		require.NoError(t, err)
+
+		pk := c.GetPrimaryKey().Fields
+		require.Equal(t, AutoGenerateUUIDv7, pk[0].AutoGenerateStrategy)
+		require.Equal(t, AutoGenerateUUIDv7, c.AutoGenerateStrategies.For(pk[0]))
+		require.Equal(t, AutoGenerateDefault, pk[1].AutoGenerateStrategy)
+		require.Equal(t, AutoGenerateULID, c.AutoGenerateStrategies.For(pk[1]))
+	})
+	t.Run("invalid_field", func(t *testing.T) {
+		reqSchema := []byte(`{"title":"t1","properties":{"id":{"type":"string","format":"uuid","autoGenerate":true,"autoGenerateStrategy":"ulid"}},"primary_key":["id"]}`)
+		_, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.Equal(t, errors.InvalidArgument("unsupported auto-generate strategy 'ulid' for type 'uuid'"), err)
+
+		reqSchema = []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerateStrategy":"uuidv7"}},"primary_key":["id"]}`)
+		_, err = NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.Equal(t, errors.InvalidArgument("auto-generate strategy is only allowed on auto-generated fields 'id'"), err)
+	})
+}
+
+func TestAutoGenerateCounter(t *testing.T) {
+	reqSchema := []byte(`{"title":"t1","properties":{"id":{"type":"integer","format":"int32","autoGenerate":true,"autoGenerateStart":100000,"autoGenerateStep":5}},"primary_key":["id"]}`)
+	schF, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+	require.NoError(t, err)
+	require.Equal(t, int32(100000), schF.Fields[0].CounterStart())
+	require.Equal(t, int32(5), schF.Fields[0].CounterStep())
+
+	reqSchema = []byte(`{"title":"t1","properties":{"id":{"type":"integer","format":"int32","autoGenerate":true}},"primary_key":["id"]}`)
+	schF, err = NewFactoryBuilder(true).Build("t1", reqSchema)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), schF.Fields[0].CounterStart())
+	require.Equal(t, int32(1), schF.Fields[0].CounterStep())
+
+	reqSchema = []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true,"autoGenerateStart":10}},"primary_key":["id"]}`)
+	_, err = NewFactoryBuilder(true).Build("t1", reqSchema)
+	require.Equal(t, errors.InvalidArgument("counter start and step are only allowed on auto-generated integer fields 'id'"), err)
+
+	reqSchema = []byte(`{"title":"t1","properties":{"id":{"type":"integer","format":"int32","autoGenerate":true,"autoGenerateStep":0}},"primary_key":["id"]}`)
+	_, err = NewFactoryBuilder(true).Build("t1", reqSchema)
+	require.Equal(t, errors.InvalidArgument("counter start and step of field 'id' must be positive"), err)
+}
+
+func TestTTL(t *testing.T) {
+	t.Run("configured", func(t *testing.T) {
+		reqSchema := []byte(`{"title":"t1","properties":{"created":{"type":"string","format":"date-time","autoGenerate":true},"id":{"type":"string"}},"primary_key":["created","id"],"ttl":"24h"}`)
+		schF, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+		require.NoError(t, err)
+
+		c, err := NewDefaultCollection(1, 1, schF, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 24*time.Hour, c.TTL)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, ttl := range []string{"1d", "10s", "-1h"} {
+			reqSchema := []byte(`{"title":"t1","properties":{"created":{"type":"string","format":"date-time","autoGenerate":true}},"primary_key":["created"],"ttl":"` + ttl + `"}`)
+			_, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+			require.Equal(t, errors.InvalidArgument("invalid ttl '%s', it must be a duration of at least '1m0s'", ttl), err)
+		}
+
+		for _, reqSchema := range [][]byte{
+			[]byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true}},"primary_key":["id"],"ttl":"1h"}`),
+			[]byte(`{"title":"t1","properties":{"created":{"type":"string","format":"date-time"}},"primary_key":["created"],"ttl":"1h"}`),
+			[]byte(`{"title":"t1","properties":{"id":{"type":"string"},"created":{"type":"string","format":"date-time","autoGenerate":true}},"primary_key":["id","created"],"ttl":"1h"}`),
+		} {
+			_, err := NewFactoryBuilder(true).Build("t1", reqSchema)
+			require.Equal(t, errors.InvalidArgument("ttl requires the first primary key field to be an auto-generated date-time"), err)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"time"
+
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// TTLKey is the collection level schema property to expire the documents once they are older than the duration, for
+// example,
+//
+//	"ttl": "720h"
+//
+// The age of a document is the value of its first primary key field, which must be an auto-generated date-time so
+// that the keys are ordered by time and the expired documents are a range at the start of the collection.
+const TTLKey = "ttl"
+
+// minTTL is the shortest retention allowed, the documents are expired by a background job so a shorter retention
+// wouldn't be honored anyway.
+const minTTL = time.Minute
+
+func buildTTL(ttl string, primaryKeyFields []*Field) (time.Duration, error) {
+	if len(ttl) == 0 {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d < minTTL {
+		return 0, errors.InvalidArgument("invalid ttl '%s', it must be a duration of at least '%s'", ttl, minTTL)
+	}
+
+	if len(primaryKeyFields) == 0 || primaryKeyFields[0].Type() != DateTimeType || !primaryKeyFields[0].IsAutoGenerated() {
+		return 0, errors.InvalidArgument("ttl requires the first primary key field to be an auto-generated date-time")
+	}
+
+	return d, nil
+}
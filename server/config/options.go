@@ -32,6 +32,8 @@ type ServerConfig struct {
 	Type         string `mapstructure:"type" yaml:"type" json:"type"`
 	FDBHardDrop  bool   `mapstructure:"fdb_hard_drop" yaml:"fdb_hard_drop" json:"fdb_hard_drop"`
 	RealtimePort int16  `mapstructure:"realtime_port" yaml:"realtime_port" json:"realtime_port"`
+	// CompressIndexMetadata gzips the stored metadata of the collection indexes when it makes the payload smaller.
+	CompressIndexMetadata bool `mapstructure:"compress_index_metadata" yaml:"compress_index_metadata" json:"compress_index_metadata"`
 }
 
 type Config struct {
@@ -53,6 +55,7 @@ type Config struct {
 	Management      ManagementConfig    `yaml:"management" json:"management"`
 	GlobalStatus    GlobalStatusConfig  `yaml:"global_status" json:"global_status"`
 	Schema          SchemaConfig
+	Realtime        RealtimeConfig `yaml:"realtime" json:"realtime"`
 }
 
 type Gotrue struct {
@@ -228,6 +231,46 @@ type ObservabilityConfig struct {
 	ApiKey      string `mapstructure:"api_key" yaml:"api_key" json:"api_key"`
 	AppKey      string `mapstructure:"app_key" yaml:"app_key" json:"app_key"`
 	ProviderUrl string `mapstructure:"provider_url" yaml:"provider_url" json:"provider_url"`
+	// AuthHeaders are the headers authenticating the queries sent to the provider, by header name. A value can refer
+	// to environment variables as $VAR or ${VAR}. When empty, the ApiKey and the AppKey are sent in the headers of
+	// Datadog.
+	AuthHeaders map[string]string `mapstructure:"auth_headers" yaml:"auth_headers" json:"auth_headers"`
+	// AllowedMetrics is the list of metric names that can be queried. An entry ending with "*" allows all the
+	// metrics with that prefix. Any metric can be queried if the list is empty.
+	AllowedMetrics []string `mapstructure:"allowed_metrics" yaml:"allowed_metrics" json:"allowed_metrics"`
+	// MaxSeries is the maximum number of series a query can return, a query returning more series, usually because
+	// of a high-cardinality group by, is rejected. Zero disables the check.
+	MaxSeries int `mapstructure:"max_series" yaml:"max_series" json:"max_series"`
+	// CacheTTL is how long the results of the metrics queries are cached, zero disables the cache. A request can
+	// ask for fresher results with the Tigris-Metrics-Max-Staleness header.
+	CacheTTL time.Duration `mapstructure:"cache_ttl" yaml:"cache_ttl" json:"cache_ttl"`
+	// CacheMaxEntries is the maximum number of query results cached, the oldest result is evicted to cache a new one
+	// once it is reached. Zero leaves the cache unbounded.
+	CacheMaxEntries int `mapstructure:"cache_max_entries" yaml:"cache_max_entries" json:"cache_max_entries"`
+	// QueryMaxAttempts is the maximum number of times a query is sent to the provider when it fails with a rate-limit,
+	// a server or a network error. One disables the retries.
+	QueryMaxAttempts int `mapstructure:"query_max_attempts" yaml:"query_max_attempts" json:"query_max_attempts"`
+	// QueryRetryBaseDelay is the delay before the first retry of a query, it doubles with every following retry.
+	QueryRetryBaseDelay time.Duration `mapstructure:"query_retry_base_delay" yaml:"query_retry_base_delay" json:"query_retry_base_delay"`
+	// MaxConcurrentQueries is the maximum number of queries sent to the provider concurrently on behalf of a single
+	// request.
+	MaxConcurrentQueries int `mapstructure:"max_concurrent_queries" yaml:"max_concurrent_queries" json:"max_concurrent_queries"`
+	// QueryTimeout bounds every attempt of a query sent to the provider, zero means no timeout. The deadline of the
+	// request bounds the query as a whole.
+	QueryTimeout time.Duration `mapstructure:"query_timeout" yaml:"query_timeout" json:"query_timeout"`
+	// MaxIdleConnsPerHost is the maximum number of idle connections to the provider kept for reuse by the queries.
+	MaxIdleConnsPerHost int `mapstructure:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+	// IdleConnTimeout is how long an idle connection to the provider is kept before being closed.
+	IdleConnTimeout time.Duration `mapstructure:"idle_conn_timeout" yaml:"idle_conn_timeout" json:"idle_conn_timeout"`
+	// QueryPostThreshold is the length of a query above which it is sent to the timeseries endpoint of the provider,
+	// in the body of a POST, instead of the URL of a GET, which is rejected by the provider when too long. Zero
+	// disables it.
+	QueryPostThreshold int `mapstructure:"query_post_threshold" yaml:"query_post_threshold" json:"query_post_threshold"`
+	// QueryAlwaysPost sends every query in the body of a POST, irrespective of its length.
+	QueryAlwaysPost bool `mapstructure:"query_always_post" yaml:"query_always_post" json:"query_always_post"`
+	// MaxQueryLookback is the longest relative window a query can ask for through the Tigris-Metrics-Window header.
+	// Zero means no limit.
+	MaxQueryLookback time.Duration `mapstructure:"max_query_lookback" yaml:"max_query_lookback" json:"max_query_lookback"`
 }
 
 type GlobalStatusConfig struct {
@@ -308,9 +351,10 @@ var DefaultConfig = Config{
 		Chunking:       true,
 	},
 	SecondaryIndex: SecondaryIndexConfig{
-		ReadEnabled:   false,
-		WriteEnabled:  false,
-		MutateEnabled: false,
+		ReadEnabled:           false,
+		WriteEnabled:          false,
+		MutateEnabled:         false,
+		MaxEntriesPerDocument: 1000,
 	},
 	Cache: CacheConfig{
 		Host:    "0.0.0.0",
@@ -449,15 +493,31 @@ var DefaultConfig = Config{
 		},
 	},
 	Observability: ObservabilityConfig{
-		Enabled:     false,
-		Provider:    "datadog",
-		ProviderUrl: "us3.datadoghq.com",
+		Enabled:              false,
+		Provider:             "datadog",
+		ProviderUrl:          "us3.datadoghq.com",
+		AllowedMetrics:       []string{"tigris.*"},
+		QueryMaxAttempts:     3,
+		QueryRetryBaseDelay:  200 * time.Millisecond,
+		MaxConcurrentQueries: 4,
+		QueryTimeout:         30 * time.Second,
+		MaxIdleConnsPerHost:  16,
+		IdleConnTimeout:      90 * time.Second,
+		QueryPostThreshold:   4096,
+		MaxQueryLookback:     30 * 24 * time.Hour,
+		CacheMaxEntries:      1000,
 	},
 	Management: ManagementConfig{
 		Enabled: true,
 	},
 	Schema: SchemaConfig{
 		AllowIncompatible: false,
+		TTLInterval:       time.Minute,
+	},
+	Realtime: RealtimeConfig{
+		MaxMessagesPerPublish: 1000,
+		MaxMessageSize:        1024 * 1024,
+		IdempotencyWindow:     10 * time.Minute,
 	},
 	GlobalStatus: GlobalStatusConfig{
 		Enabled:     true,
@@ -483,6 +543,32 @@ type SchemaConfig struct {
 	//  * reducing max_length of the string fields
 	//  * setting "required" property
 	AllowIncompatible bool `mapstructure:"allow_incompatible" json:"allow_incompatible" yaml:"allow_incompatible"`
+	// TTLInterval is how often the documents of the collections with a ttl are expired. Zero disables the expiry.
+	TTLInterval time.Duration `mapstructure:"ttl_interval" json:"ttl_interval" yaml:"ttl_interval"`
+	// AutoGenerateWorkerID is the worker id embedded in the int64 values auto-generated with the snowflake strategy,
+	// it must be unique for every server of the cluster for the values to be unique.
+	AutoGenerateWorkerID int64 `mapstructure:"auto_generate_worker_id" json:"auto_generate_worker_id" yaml:"auto_generate_worker_id"`
+}
+
+// RealtimeConfig contains realtime related settings.
+type RealtimeConfig struct {
+	// MaxMessagesPerPublish is the maximum number of messages accepted in a single publish request. Zero disables
+	// the check.
+	MaxMessagesPerPublish int `mapstructure:"max_messages_per_publish" json:"max_messages_per_publish" yaml:"max_messages_per_publish"`
+	// MaxMessageSize is the maximum size in bytes of the data of a published message, as stored in the channel.
+	// Zero disables the check.
+	MaxMessageSize int `mapstructure:"max_message_size" json:"max_message_size" yaml:"max_message_size"`
+	// IdempotencyWindow is how long the idempotency key of a published message is remembered, a message published
+	// again with the same key within the window is not published twice. Zero disables the idempotency keys.
+	IdempotencyWindow time.Duration `mapstructure:"idempotency_window" json:"idempotency_window" yaml:"idempotency_window"`
+	// ReadHeartbeatInterval is how long a channel read can go without sending anything before an empty response is
+	// sent to keep the stream open through the proxies. Zero disables the heartbeats.
+	ReadHeartbeatInterval time.Duration `mapstructure:"read_heartbeat_interval" json:"read_heartbeat_interval" yaml:"read_heartbeat_interval"`
+	// Nodes are the ids of the nodes of the cluster the channels are spread over, a channel is only served by the
+	// node owning it. Empty, this node serves all the channels.
+	Nodes []string `mapstructure:"nodes" json:"nodes" yaml:"nodes"`
+	// NodeID is the id of this node, one of the Nodes.
+	NodeID string `mapstructure:"node_id" json:"node_id" yaml:"node_id"`
 }
 
 // FoundationDBConfig keeps FoundationDB configuration parameters.
@@ -506,6 +592,9 @@ type SecondaryIndexConfig struct {
 	ReadEnabled   bool `mapstructure:"read_enabled" yaml:"read_enabled" json:"read_enabled"`
 	WriteEnabled  bool `mapstructure:"write_enabled" yaml:"write_enabled" json:"write_enabled"`
 	MutateEnabled bool `mapstructure:"mutate_enabled" yaml:"mutate_iterator" json:"mutate_enabled"`
+	// MaxEntriesPerDocument is the maximum number of secondary index entries a single document can generate. Writes
+	// of documents generating more entries are rejected. Zero means no limit.
+	MaxEntriesPerDocument int `mapstructure:"max_entries_per_document" yaml:"max_entries_per_document" json:"max_entries_per_document"`
 }
 
 type CacheConfig struct {
@@ -16,6 +16,7 @@ package metadata
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tigrisdata/tigris/errors"
@@ -28,6 +29,12 @@ type ClusterMetadata struct {
 	ID uuid.UUID
 }
 
+// ClusterLease is a lease on a cluster wide background task, it is held by a single server until it expires.
+type ClusterLease struct {
+	Owner  string    `json:"owner"`
+	Expiry time.Time `json:"expiry"`
+}
+
 // ClusterSubspace is used to store metadata about Tigris clusters.
 type ClusterSubspace struct {
 	metadataSubspace
@@ -36,6 +43,7 @@ type ClusterSubspace struct {
 var (
 	clusterID          = uuid.Nil.String()
 	clusterMetadataKey = "cluster"
+	leaseKeyPrefix     = "lease_"
 )
 
 const (
@@ -93,6 +101,33 @@ func (u *ClusterSubspace) delete(ctx context.Context, tx transaction.Tx) error {
 	)
 }
 
+// ObtainLease obtains the lease of the task for the owner until the duration has elapsed, or extends it if the owner
+// already holds it. It returns false if another owner holds a lease that hasn't expired. The lease is only obtained
+// once the transaction is committed, the transactions of two servers obtaining it at the same time conflict.
+func (u *ClusterSubspace) ObtainLease(ctx context.Context, tx transaction.Tx, task string, owner string,
+	duration time.Duration,
+) (bool, error) {
+	key := u.getKey(clusterID, leaseKeyPrefix+task)
+
+	var lease ClusterLease
+	err := u.getMetadata(ctx, tx, u.validateArgs(clusterID, &task, nil), key, &lease)
+	if err != nil && err != errors.ErrNotFound {
+		return false, err
+	}
+
+	now := time.Now()
+	if err == nil && lease.Owner != owner && now.Before(lease.Expiry) {
+		return false, nil
+	}
+
+	lease = ClusterLease{Owner: owner, Expiry: now.Add(duration)}
+	if err = u.updateMetadata(ctx, tx, nil, key, clusterMetaValueVersion, &lease); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 func (u *ClusterSubspace) validateArgs(clusterID string, metadataKey *string, metadata **ClusterMetadata) error {
 	if clusterID == "" {
 		return errors.InvalidArgument("invalid empty clusterID")
@@ -134,3 +134,39 @@ func TestClusterSubspace(t *testing.T) {
 		require.Equal(t, errors.ErrNotFound, err)
 	})
 }
+
+func TestClusterLease(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	u, tx, cleanup := initClusterTest(t)
+	defer cleanup()
+
+	ok, err := u.ObtainLease(ctx, tx, "task", "server1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// held by another server
+	ok, err = u.ObtainLease(ctx, tx, "task", "server2", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// extended by its owner
+	ok, err = u.ObtainLease(ctx, tx, "task", "server1", time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the leases of the tasks are independent
+	ok, err = u.ObtainLease(ctx, tx, "other", "server2", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// expired
+	time.Sleep(10 * time.Millisecond)
+	ok, err = u.ObtainLease(ctx, tx, "task", "server2", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = u.ObtainLease(ctx, tx, "", "server2", time.Minute)
+	require.Equal(t, errors.InvalidArgument("invalid empty metadataKey"), err)
+}
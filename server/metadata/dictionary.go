@@ -404,7 +404,9 @@ func (k *Dictionary) CreatePrimaryIndex(ctx context.Context, tx transaction.Tx,
 		return nil, err
 	}
 
-	meta := &PrimaryIndexMetadata{ID: id}
+	// the primary key is always unique
+	unique := true
+	meta := &PrimaryIndexMetadata{ID: id, Unique: &unique}
 
 	err = k.PrimaryIndex().insert(ctx, tx, namespaceId, dbId, collId, name, meta)
 	if err != nil {
@@ -175,6 +175,9 @@ func (d *DictKeyEncoder) DecodeCacheTableName(name string) (uint32, uint32, stri
 		return 0, 0, "", false
 	}
 	allParts := strings.Split(name, ":")
+	if len(allParts) < 4 {
+		return 0, 0, "", false
+	}
 	nsId, _ := strconv.ParseInt(allParts[1], 10, 64)
 	pid, _ := strconv.ParseInt(allParts[2], 10, 64)
 
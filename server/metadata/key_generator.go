@@ -16,7 +16,9 @@ package metadata
 
 import (
 	"context"
+	"math"
 
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/keys"
 	"github.com/tigrisdata/tigris/server/transaction"
@@ -39,8 +41,9 @@ func NewTableKeyGenerator() *TableKeyGenerator {
 
 // GenerateCounter is used to generate an id in a transaction for int32 field only. This is mainly used to guarantee
 // uniqueness with auto-incremented ids, so what we are doing is reserving this id in storage before returning to the
-// caller so that only one id is assigned to one caller.
-func (g *TableKeyGenerator) GenerateCounter(ctx context.Context, txMgr *transaction.Manager, table []byte) (int32, error) {
+// caller so that only one id is assigned to one caller. The first id of the table is start and the next ones are
+// incremented by step.
+func (g *TableKeyGenerator) GenerateCounter(ctx context.Context, txMgr *transaction.Manager, table []byte, start int32, step int32) (int32, error) {
 	for {
 		tx, err := txMgr.StartTx(ctx)
 		if err != nil {
@@ -48,8 +51,9 @@ func (g *TableKeyGenerator) GenerateCounter(ctx context.Context, txMgr *transact
 		}
 
 		var valueI32 int32
-		if valueI32, err = g.generateCounter(ctx, tx, table); err != nil {
+		if valueI32, err = g.generateCounter(ctx, tx, table, start, step); err != nil {
 			_ = tx.Rollback(ctx)
+			return -1, err
 		}
 
 		if err = tx.Commit(ctx); err == nil {
@@ -64,29 +68,56 @@ func (g *TableKeyGenerator) GenerateCounter(ctx context.Context, txMgr *transact
 // generateCounter as it is used to generate int32 value, we are simply maintaining a counter. There is a contention to
 // generate a counter if it is concurrently getting executed but the generation should be fast then it is best to start
 // with this approach.
-func (g *TableKeyGenerator) generateCounter(ctx context.Context, tx transaction.Tx, table []byte) (int32, error) {
+//
+// The step is persisted along with the last id when the counter is created, so that it keeps being used even if the
+// one in the schema changes. The counters created before the step was configurable only have the last id and are
+// incremented by one.
+func (g *TableKeyGenerator) generateCounter(ctx context.Context, tx transaction.Tx, table []byte, start int32, step int32) (int32, error) {
+	if start < 1 || step < 1 {
+		return 0, errors.InvalidArgument("invalid counter start '%d' and step '%d', they must be positive", start, step)
+	}
+
 	key := keys.NewKey([]byte(generatorSubspaceKey), table, int32IdKey)
 	it, err := tx.Read(ctx, key)
 	if err != nil {
 		return 0, err
 	}
 
-	id := uint32(1)
+	id := int64(start)
 	var row kv.KeyValue
 	if it.Next(&row) {
-		id = ByteToUInt32(row.Data.RawData) + uint32(1)
+		var last int64
+		last, step = decodeCounter(row.Data.RawData)
+		id = last + int64(step)
 	}
 	if err := it.Err(); err != nil {
 		return 0, err
 	}
 
-	if err := tx.Replace(ctx, key, internal.NewTableData(UInt32ToByte(id)), false); err != nil {
+	if id > math.MaxInt32 {
+		return 0, errors.ResourceExhausted("auto-generated int32 counter is exhausted")
+	}
+
+	if err := tx.Replace(ctx, key, internal.NewTableData(encodeCounter(int32(id), step)), false); err != nil {
 		return 0, err
 	}
 
 	return int32(id), nil
 }
 
+// encodeCounter returns the stored value of a counter, the last generated id followed by the step.
+func encodeCounter(id int32, step int32) []byte {
+	return append(UInt32ToByte(uint32(id)), UInt32ToByte(uint32(step))...)
+}
+
+// decodeCounter returns the last generated id and the step of a counter, the step of a counter stored without one is 1.
+func decodeCounter(b []byte) (int64, int32) {
+	if len(b) < 8 {
+		return int64(ByteToUInt32(b)), 1
+	}
+	return int64(ByteToUInt32(b[:4])), int32(ByteToUInt32(b[4:8]))
+}
+
 func (g *TableKeyGenerator) removeCounter(ctx context.Context, tx transaction.Tx, table []byte) error {
 	key := keys.NewKey([]byte(generatorSubspaceKey), table, int32IdKey)
 	if err := tx.Delete(ctx, key); err != nil {
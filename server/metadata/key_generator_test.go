@@ -0,0 +1,81 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+func TestGenerateCounter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = kvStore.DropTable(ctx, []byte(generatorSubspaceKey))
+
+	tm := transaction.NewManager(kvStore)
+	g := NewTableKeyGenerator()
+
+	t.Run("start_and_step", func(t *testing.T) {
+		table := []byte("counter_step")
+		for _, expected := range []int32{100000, 100005, 100010} {
+			id, err := g.GenerateCounter(ctx, tm, table, 100000, 5)
+			require.NoError(t, err)
+			require.Equal(t, expected, id)
+		}
+
+		// the persisted step is used even if the one requested changes
+		id, err := g.GenerateCounter(ctx, tm, table, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, int32(100015), id)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		table := []byte("counter_legacy")
+
+		// a counter stored before the step was persisted is incremented by one
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		key := keys.NewKey([]byte(generatorSubspaceKey), table, int32IdKey)
+		require.NoError(t, tx.Replace(ctx, key, internal.NewTableData(UInt32ToByte(7)), false))
+		require.NoError(t, tx.Commit(ctx))
+
+		id, err := g.GenerateCounter(ctx, tm, table, 100, 10)
+		require.NoError(t, err)
+		require.Equal(t, int32(8), id)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := g.GenerateCounter(ctx, tm, []byte("counter_invalid"), 1, 0)
+		require.Equal(t, errors.InvalidArgument("invalid counter start '%d' and step '%d', they must be positive", 1, 0), err)
+	})
+}
+
+func TestCounterEncoding(t *testing.T) {
+	id, step := decodeCounter(encodeCounter(42, 3))
+	require.Equal(t, int64(42), id)
+	require.Equal(t, int32(3), step)
+
+	id, step = decodeCounter(UInt32ToByte(42))
+	require.Equal(t, int64(42), id)
+	require.Equal(t, int32(1), step)
+}
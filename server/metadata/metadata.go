@@ -186,6 +186,9 @@ func (m *metadataSubspace) softDeleteMetadata(ctx context.Context, tx transactio
 		return err
 	}
 
+	// the updated timestamp of the dropped entry records when it was dropped
+	row.Data.UpdatedAt = internal.NewTimestamp()
+
 	return tx.Replace(ctx, toKey, row.Data, false)
 }
 
@@ -200,26 +203,36 @@ func (m *metadataSubspace) listMetadata(ctx context.Context, tx transaction.Tx,
 	var v kv.KeyValue
 
 	for it.Next(&v) {
-		if len(v.Key) != keyLen {
-			log.Error().Interface("key", v.Key).Str("subspace", string(m.SubspaceName)).Msg("invalid key")
-			return errors.Internal("not a valid key %v", v.Key)
-		}
-
-		// format: <..., Name, keyEnd>
-		end, ok := v.Key[keyLen-1].(string)
-		if !ok || (end != keyEnd && end != keyDroppedEnd) {
-			return errors.Internal("key trailer is missing %v", v.Key)
-		}
-
-		name, ok := v.Key[keyLen-2].(string)
-		if !ok {
-			return errors.Internal("name not found %T %v", v.Key[keyLen-2], v.Key[keyLen-2])
+		dropped, name, err := m.parseMetadataKey(v.Key, keyLen)
+		if err != nil {
+			return err
 		}
 
-		if err = fn(end == keyDroppedEnd, name, v.Data); err != nil {
+		if err = fn(dropped, name, v.Data); err != nil {
 			return err
 		}
 	}
 
 	return it.Err()
 }
+
+// parseMetadataKey returns the name of the entry stored under the key and whether the entry is soft-deleted.
+func (m *metadataSubspace) parseMetadataKey(key kv.Key, keyLen int) (bool, string, error) {
+	if len(key) != keyLen {
+		log.Error().Interface("key", key).Str("subspace", string(m.SubspaceName)).Msg("invalid key")
+		return false, "", errors.Internal("not a valid key %v", key)
+	}
+
+	// format: <..., Name, keyEnd>
+	end, ok := key[keyLen-1].(string)
+	if !ok || (end != keyEnd && end != keyDroppedEnd) {
+		return false, "", errors.Internal("key trailer is missing %v", key)
+	}
+
+	name, ok := key[keyLen-2].(string)
+	if !ok {
+		return false, "", errors.Internal("name not found %T %v", key[keyLen-2], key[keyLen-2])
+	}
+
+	return end == keyDroppedEnd, name, nil
+}
@@ -15,14 +15,22 @@
 package metadata
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"io"
+	"sort"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
 	ulog "github.com/tigrisdata/tigris/util/log"
 )
 
@@ -35,9 +43,28 @@ type PrimaryIndexSubspace struct {
 type PrimaryIndexMetadata struct {
 	ID   uint32 `json:"id"`
 	Name string `json:"name"`
+	// Unique is nil for the indexes persisted before the uniqueness was recorded, use IsUnique to read it.
+	Unique *bool `json:"unique,omitempty"`
+	// UniqueFields are the fields the uniqueness is enforced on, empty means all the fields of the index key.
+	UniqueFields []string `json:"unique_fields,omitempty"`
 }
 
-const indexMetaValueVersion int32 = 1
+// IsUnique returns whether the index with the given name enforces uniqueness of its keys. Indexes that don't have
+// the flag persisted default to unique only if they are the primary key index, any other index defaults to
+// non-unique.
+func (m *PrimaryIndexMetadata) IsUnique(name string) bool {
+	if m.Unique != nil {
+		return *m.Unique
+	}
+
+	return name == schema.PrimaryKeyIndexName
+}
+
+const (
+	indexMetaValueVersion int32 = 1
+	// indexMetaCompressedValueVersion is the version of the gzipped JSON metadata.
+	indexMetaCompressedValueVersion int32 = 2
+)
 
 func newPrimaryIndexStore(nameRegistry *NameRegistry) *PrimaryIndexSubspace {
 	return &PrimaryIndexSubspace{
@@ -57,22 +84,78 @@ func (c *PrimaryIndexSubspace) getKey(nsID uint32, dbID uint32, collID uint32, n
 }
 
 func (c *PrimaryIndexSubspace) insert(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32, name string, metadata *PrimaryIndexMetadata) error {
-	return c.insertMetadata(ctx, tx,
-		c.validateArgs(nsID, dbID, collID, name, &metadata),
-		c.getKey(nsID, dbID, collID, name),
-		indexMetaValueVersion,
-		metadata,
-	)
+	if err := c.validateArgs(nsID, dbID, collID, name, &metadata); err != nil {
+		return err
+	}
+
+	payload, ver, err := c.encodeMetadata(metadata, config.DefaultConfig.Server.CompressIndexMetadata)
+	if err != nil {
+		return err
+	}
+
+	return c.insertPayload(ctx, tx, nil, c.getKey(nsID, dbID, collID, name), ver, payload)
 }
 
-func (c *PrimaryIndexSubspace) decodeMetadata(_ string, payload *internal.TableData) (*PrimaryIndexMetadata, error) {
-	if payload.Ver == 0 {
-		return &PrimaryIndexMetadata{ID: ByteToUInt32(payload.RawData)}, nil
+// encodeMetadata marshals the metadata and returns the payload along with its value version. With compress set the
+// payload is gzipped, unless the compressed payload is not smaller than the plain one, which is the case for the
+// indexes with little metadata.
+func (_ *PrimaryIndexSubspace) encodeMetadata(metadata *PrimaryIndexMetadata, compress bool) ([]byte, int32, error) {
+	payload, err := jsoniter.Marshal(metadata)
+	if ulog.E(err) {
+		return nil, 0, errors.Internal("failed to marshal index metadata")
+	}
+
+	if !compress {
+		return payload, indexMetaValueVersion, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err = w.Write(payload); ulog.E(err) {
+		return nil, 0, errors.Internal("failed to compress index metadata")
+	}
+	if err = w.Close(); ulog.E(err) {
+		return nil, 0, errors.Internal("failed to compress index metadata")
+	}
+
+	if buf.Len() >= len(payload) {
+		return payload, indexMetaValueVersion, nil
+	}
+
+	return buf.Bytes(), indexMetaCompressedValueVersion, nil
+}
+
+// decodeMetadata decodes the payload according to its value version. A version newer than indexMetaCompressedValueVersion
+// is written by a newer server and is rejected, as unmarshaling it into the current struct would silently drop the
+// fields this server doesn't know about.
+func (c *PrimaryIndexSubspace) decodeMetadata(name string, payload *internal.TableData) (*PrimaryIndexMetadata, error) {
+	raw := payload.RawData
+
+	switch payload.Ver {
+	case 0:
+		// the id only, persisted before the metadata was JSON encoded
+		if len(raw) != 4 {
+			return nil, errors.Internal("invalid index metadata of '%s', expected a 4 bytes id, got %d bytes", name, len(raw))
+		}
+		return &PrimaryIndexMetadata{ID: ByteToUInt32(raw)}, nil
+	case indexMetaValueVersion:
+	case indexMetaCompressedValueVersion:
+		r, err := gzip.NewReader(bytes.NewReader(raw))
+		if ulog.E(err) {
+			return nil, errors.Internal("failed to decompress index metadata")
+		}
+
+		if raw, err = io.ReadAll(r); ulog.E(err) {
+			return nil, errors.Internal("failed to decompress index metadata")
+		}
+	default:
+		return nil, errors.Unimplemented("unsupported index metadata version %d of '%s', the latest supported version is %d",
+			payload.Ver, name, indexMetaCompressedValueVersion)
 	}
 
 	var metadata PrimaryIndexMetadata
 
-	if err := jsoniter.Unmarshal(payload.RawData, &metadata); ulog.E(err) {
+	if err := jsoniter.Unmarshal(raw, &metadata); ulog.E(err) {
 		return nil, errors.Internal("failed to unmarshal collection metadata")
 	}
 
@@ -96,12 +179,99 @@ func (c *PrimaryIndexSubspace) Get(ctx context.Context, tx transaction.Tx, nsID
 }
 
 func (c *PrimaryIndexSubspace) Update(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32, name string, metadata *PrimaryIndexMetadata) error {
-	return c.updateMetadata(ctx, tx,
-		c.validateArgs(nsID, dbID, collID, name, &metadata),
-		c.getKey(nsID, dbID, collID, name),
-		indexMetaValueVersion,
-		metadata,
-	)
+	if err := c.validateArgs(nsID, dbID, collID, name, &metadata); err != nil {
+		return err
+	}
+
+	payload, ver, err := c.encodeMetadata(metadata, config.DefaultConfig.Server.CompressIndexMetadata)
+	if err != nil {
+		return err
+	}
+
+	return c.updatePayload(ctx, tx, nil, c.getKey(nsID, dbID, collID, name), ver, payload)
+}
+
+// bulkUpdate writes the metadata of several indexes of the collection in the transaction, keyed by index name. All
+// the entries are validated before anything is written, so an invalid entry fails the whole batch. The batch is also
+// checked against the existing entries of the collection so that it can't introduce the retrogression list fails
+// on, an index can't be assigned an id that is not bigger than the id of its dropped entry, and it can't introduce
+// two live indexes with the same id.
+func (c *PrimaryIndexSubspace) bulkUpdate(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32,
+	updates map[string]*PrimaryIndexMetadata,
+) error {
+	names := make([]string, 0, len(updates))
+	for name := range updates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		metadata := updates[name]
+		if err := c.validateArgs(nsID, dbID, collID, name, &metadata); err != nil {
+			return err
+		}
+	}
+
+	live := make(map[string]uint32)
+	dropped := make(map[string]uint32)
+	if err := c.listMetadata(ctx, tx, c.getKey(nsID, dbID, collID, ""), 7,
+		func(isDropped bool, name string, data *internal.TableData) error {
+			m, err := c.decodeMetadata(name, data)
+			if err != nil {
+				return err
+			}
+
+			if isDropped {
+				dropped[name] = m.ID
+			} else {
+				live[name] = m.ID
+			}
+
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		live[name] = updates[name].ID
+		if droppedID, ok := dropped[name]; ok && droppedID >= updates[name].ID {
+			return errors.InvalidArgument(
+				"retrogression in the update of index [%s], droppedValue [%d] updatedValue [%d]",
+				name, droppedID, updates[name].ID)
+		}
+	}
+
+	owners := make(map[uint32]string, len(live))
+	for _, name := range sortedKeys(live) {
+		if owner, ok := owners[live[name]]; ok {
+			return errors.InvalidArgument("indexes [%s] and [%s] are assigned the same value [%d]", owner, name, live[name])
+		}
+		owners[live[name]] = name
+	}
+
+	for _, name := range names {
+		payload, ver, err := c.encodeMetadata(updates[name], config.DefaultConfig.Server.CompressIndexMetadata)
+		if err != nil {
+			return err
+		}
+
+		if err = c.updatePayload(ctx, tx, nil, c.getKey(nsID, dbID, collID, name), ver, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]uint32) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
 }
 
 func (c *PrimaryIndexSubspace) delete(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32, name string) error {
@@ -121,6 +291,68 @@ func (c *PrimaryIndexSubspace) softDelete(ctx context.Context, tx transaction.Tx
 	)
 }
 
+// compactDropped permanently removes the soft-deleted index entries of the collection that were dropped before
+// olderThan. A dropped entry is only removed once there is a live entry with the same name and a bigger id, as until
+// then it is what the retrogression check in list relies on to detect a reused id. It returns the number of entries
+// removed.
+func (c *PrimaryIndexSubspace) compactDropped(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32,
+	collID uint32, olderThan time.Time,
+) (int, error) {
+	type droppedIndex struct {
+		id        uint32
+		droppedAt int64
+	}
+
+	live := make(map[string]uint32)
+	dropped := make(map[string]droppedIndex)
+
+	if err := c.listMetadata(ctx, tx, c.getKey(nsID, dbID, collID, ""), 7,
+		func(isDropped bool, name string, data *internal.TableData) error {
+			m, err := c.decodeMetadata(name, data)
+			if err != nil {
+				return err
+			}
+
+			if !isDropped {
+				live[name] = m.ID
+				return nil
+			}
+
+			var droppedAt int64
+			switch {
+			case data.UpdatedAt != nil:
+				droppedAt = data.UpdatedAt.UnixNano()
+			case data.CreatedAt != nil:
+				// dropped before the drop time was recorded, it was dropped after it was created
+				droppedAt = data.CreatedAt.UnixNano()
+			}
+			dropped[name] = droppedIndex{id: m.ID, droppedAt: droppedAt}
+
+			return nil
+		},
+	); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for name, d := range dropped {
+		if d.droppedAt >= olderThan.UnixNano() {
+			continue
+		}
+		if liveID, ok := live[name]; !ok || liveID <= d.id {
+			continue
+		}
+
+		droppedKey := keys.NewKey(c.SubspaceName, c.KeyVersion, UInt32ToByte(nsID), UInt32ToByte(dbID), UInt32ToByte(collID), indexKey, name, keyDroppedEnd)
+		if err := c.deleteMetadata(ctx, tx, c.validateArgs(nsID, dbID, collID, name, nil), droppedKey); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
 func (_ *PrimaryIndexSubspace) validateArgs(nsID uint32, dbID uint32, collID uint32, name string, metadata **PrimaryIndexMetadata) error {
 	if nsID == 0 || dbID == 0 || collID == 0 {
 		return errors.InvalidArgument("invalid id")
@@ -137,6 +369,9 @@ func (_ *PrimaryIndexSubspace) validateArgs(nsID uint32, dbID uint32, collID uin
 	return nil
 }
 
+// list returns the live indexes of the collection. A name can have both a live and a soft-deleted entry while the
+// dropped entry is waiting to be garbage collected, in which case only the live entry is returned irrespective of
+// the order the entries are scanned in. The dropped entries are only used for the retrogression check.
 func (c *PrimaryIndexSubspace) list(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbID uint32, collId uint32,
 ) (map[string]*PrimaryIndexMetadata, error) {
 	indexes := make(map[string]*PrimaryIndexMetadata)
@@ -175,3 +410,78 @@ func (c *PrimaryIndexSubspace) list(ctx context.Context, tx transaction.Tx, name
 
 	return indexes, nil
 }
+
+// listPage returns at most limit live indexes of the collection, in the order of their names, starting after the
+// index named cursor, or at the first index if the cursor is empty. The returned cursor is the name to pass to get the
+// next page, it is empty once there are no more indexes. Only the page is decoded, so unlike list it doesn't perform
+// the retrogression check which needs all the entries of the collection.
+func (c *PrimaryIndexSubspace) listPage(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbID uint32, collId uint32,
+	cursor string, limit int,
+) ([]*PrimaryIndexMetadata, string, error) {
+	if limit <= 0 {
+		return nil, "", errors.InvalidArgument("invalid limit '%d'", limit)
+	}
+
+	// an integer part sorts after any string part, so it bounds the entries of a name or of all the names
+	start := c.getKey(namespaceId, dbID, collId, "")
+	if cursor != "" {
+		start = keys.NewKey(c.SubspaceName, c.KeyVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbID), UInt32ToByte(collId), indexKey, cursor, 0xFF)
+	}
+	end := keys.NewKey(c.SubspaceName, c.KeyVersion, UInt32ToByte(namespaceId), UInt32ToByte(dbID), UInt32ToByte(collId), indexKey, 0xFF)
+
+	it, err := tx.ReadRange(ctx, start, end, false, kv.WithStreamingMode(kv.StreamingModeSmall))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		v    kv.KeyValue
+		page []*PrimaryIndexMetadata
+		last string
+	)
+	for it.Next(&v) {
+		dropped, name, err := c.parseMetadataKey(v.Key, 7)
+		if err != nil {
+			return nil, "", err
+		}
+		if dropped {
+			continue
+		}
+
+		if len(page) == limit {
+			// there is at least one more live index after the page
+			return page, last, nil
+		}
+
+		m, err := c.decodeMetadata(name, v.Data)
+		if err != nil {
+			return nil, "", err
+		}
+		page = append(page, m)
+		last = name
+	}
+
+	return page, "", it.Err()
+}
+
+// listNames returns the sorted names of the live indexes of the collection. The names are extracted from the keys so
+// unlike list it doesn't decode the metadata of the indexes. A name that only has a soft-deleted entry is excluded.
+// As the ids are not decoded, the retrogression check of list is not performed.
+func (c *PrimaryIndexSubspace) listNames(ctx context.Context, tx transaction.Tx, namespaceId uint32, dbID uint32, collId uint32,
+) ([]string, error) {
+	var names []string
+	if err := c.listMetadata(ctx, tx, c.getKey(namespaceId, dbID, collId, ""), 7,
+		func(dropped bool, name string, _ *internal.TableData) error {
+			if !dropped {
+				names = append(names, name)
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -21,7 +21,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
 	"github.com/tigrisdata/tigris/server/transaction"
 )
 
@@ -153,6 +155,205 @@ func TestIndexSubspace(t *testing.T) {
 	})
 }
 
+func TestIndexSubspaceLiveAndDropped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, tm := initIndexTest(t, ctx)
+	defer func() {
+		_ = kvStore.DropTable(ctx, c.SubspaceName)
+	}()
+
+	tx, cleanupTx := initTx(t, ctx, tm)
+	defer cleanupTx()
+
+	dropped := &PrimaryIndexMetadata{ID: 10, Name: "name1"}
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name1", dropped))
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name1"))
+
+	live := &PrimaryIndexMetadata{ID: 11, Name: "name1"}
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name1", live))
+
+	other := &PrimaryIndexMetadata{ID: 12, Name: "name2"}
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name2", other))
+
+	indexes, err := c.list(ctx, tx, 1, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*PrimaryIndexMetadata{
+		"name1": live,
+		"name2": other,
+	}, indexes)
+
+	index, err := c.Get(ctx, tx, 1, 1, 1, "name1")
+	require.NoError(t, err)
+	require.Equal(t, live, index)
+
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name0", &PrimaryIndexMetadata{ID: 13, Name: "name0"}))
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name0"))
+
+	names, err := c.listNames(ctx, tx, 1, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"name1", "name2"}, names)
+}
+
+func TestIndexSubspaceListPage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, tm := initIndexTest(t, ctx)
+	defer func() {
+		_ = kvStore.DropTable(ctx, c.SubspaceName)
+	}()
+
+	tx, cleanupTx := initTx(t, ctx, tm)
+	defer cleanupTx()
+
+	for i, name := range []string{"name1", "name2", "name3", "name4", "name5"} {
+		require.NoError(t, c.insert(ctx, tx, 1, 1, 1, name, &PrimaryIndexMetadata{ID: uint32(10 + i), Name: name}))
+	}
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name3"))
+	// an index of another collection is not listed
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 2, "name0", &PrimaryIndexMetadata{ID: 20, Name: "name0"}))
+
+	page, cursor, err := c.listPage(ctx, tx, 1, 1, 1, "", 2)
+	require.NoError(t, err)
+	require.Equal(t, []*PrimaryIndexMetadata{{ID: 10, Name: "name1"}, {ID: 11, Name: "name2"}}, page)
+	require.Equal(t, "name2", cursor)
+
+	// the dropped index is skipped
+	page, cursor, err = c.listPage(ctx, tx, 1, 1, 1, cursor, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*PrimaryIndexMetadata{{ID: 13, Name: "name4"}, {ID: 14, Name: "name5"}}, page)
+	require.Equal(t, "", cursor)
+
+	page, cursor, err = c.listPage(ctx, tx, 1, 1, 1, "", 10)
+	require.NoError(t, err)
+	require.Len(t, page, 4)
+	require.Equal(t, "", cursor)
+
+	page, cursor, err = c.listPage(ctx, tx, 1, 1, 1, "name5", 10)
+	require.NoError(t, err)
+	require.Empty(t, page)
+	require.Equal(t, "", cursor)
+
+	_, _, err = c.listPage(ctx, tx, 1, 1, 1, "", 0)
+	require.Equal(t, errors.InvalidArgument("invalid limit '0'"), err)
+}
+
+func TestIndexSubspaceBulkUpdate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, tm := initIndexTest(t, ctx)
+	defer func() {
+		_ = kvStore.DropTable(ctx, c.SubspaceName)
+	}()
+
+	tx, cleanupTx := initTx(t, ctx, tm)
+	defer cleanupTx()
+
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name1", &PrimaryIndexMetadata{ID: 10, Name: "name1"}))
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name1"))
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name1", &PrimaryIndexMetadata{ID: 11, Name: "name1"}))
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name2", &PrimaryIndexMetadata{ID: 12, Name: "name2"}))
+
+	expectUnchanged := func() {
+		indexes, err := c.list(ctx, tx, 1, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, map[string]*PrimaryIndexMetadata{
+			"name1": {ID: 11, Name: "name1"},
+			"name2": {ID: 12, Name: "name2"},
+		}, indexes)
+	}
+
+	// an invalid entry fails the whole batch
+	require.Equal(t, errors.InvalidArgument("invalid nil payload"), c.bulkUpdate(ctx, tx, 1, 1, 1,
+		map[string]*PrimaryIndexMetadata{"name2": {ID: 20, Name: "name2"}, "name3": nil}))
+	require.Equal(t, errors.InvalidArgument("empty index name"), c.bulkUpdate(ctx, tx, 1, 1, 1,
+		map[string]*PrimaryIndexMetadata{"": {ID: 20}, "name2": {ID: 20, Name: "name2"}}))
+	expectUnchanged()
+
+	// the id of an index can't go back to the id of its dropped entry
+	require.Equal(t, errors.InvalidArgument(
+		"retrogression in the update of index [name1], droppedValue [10] updatedValue [10]"),
+		c.bulkUpdate(ctx, tx, 1, 1, 1, map[string]*PrimaryIndexMetadata{
+			"name1": {ID: 10, Name: "name1"},
+			"name2": {ID: 20, Name: "name2"},
+		}))
+	expectUnchanged()
+
+	// two live indexes can't share an id
+	require.Equal(t, errors.InvalidArgument("indexes [name1] and [name2] are assigned the same value [11]"),
+		c.bulkUpdate(ctx, tx, 1, 1, 1, map[string]*PrimaryIndexMetadata{"name2": {ID: 11, Name: "name2"}}))
+	expectUnchanged()
+
+	require.NoError(t, c.bulkUpdate(ctx, tx, 1, 1, 1, map[string]*PrimaryIndexMetadata{
+		"name1": {ID: 21, Name: "name1"},
+		"name2": {ID: 11, Name: "name2"},
+		"name3": {ID: 23, Name: "name3"},
+	}))
+
+	indexes, err := c.list(ctx, tx, 1, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*PrimaryIndexMetadata{
+		"name1": {ID: 21, Name: "name1"},
+		"name2": {ID: 11, Name: "name2"},
+		"name3": {ID: 23, Name: "name3"},
+	}, indexes)
+}
+
+func TestIndexSubspaceCompactDropped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, tm := initIndexTest(t, ctx)
+	defer func() {
+		_ = kvStore.DropTable(ctx, c.SubspaceName)
+	}()
+
+	tx, cleanupTx := initTx(t, ctx, tm)
+	defer cleanupTx()
+
+	// recreated with a bigger id, the dropped entry is not needed anymore
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name1", &PrimaryIndexMetadata{ID: 10}))
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name1"))
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name1", &PrimaryIndexMetadata{ID: 11}))
+
+	// not recreated yet, the dropped entry is needed by the retrogression check
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name2", &PrimaryIndexMetadata{ID: 20}))
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name2"))
+
+	// dropped after the threshold
+	beforeDrop := time.Now()
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name3", &PrimaryIndexMetadata{ID: 30}))
+	require.NoError(t, c.softDelete(ctx, tx, 1, 1, 1, "name3"))
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name3", &PrimaryIndexMetadata{ID: 31}))
+
+	removed, err := c.compactDropped(ctx, tx, 1, 1, 1, beforeDrop)
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	_, err = c.getPayload(ctx, tx, nil, keys.NewKey(c.SubspaceName, c.KeyVersion, UInt32ToByte(1), UInt32ToByte(1), UInt32ToByte(1), indexKey, "name1", keyDroppedEnd))
+	require.Equal(t, errors.ErrNotFound, err)
+
+	removed, err = c.compactDropped(ctx, tx, 1, 1, 1, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+
+	indexes, err := c.list(ctx, tx, 1, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*PrimaryIndexMetadata{
+		"name1": {ID: 11},
+		"name3": {ID: 31},
+	}, indexes)
+
+	// the dropped entry of name2 is kept, so a retrogressed id is still detected
+	require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "name2", &PrimaryIndexMetadata{ID: 5}))
+	_, err = c.list(ctx, tx, 1, 1, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "retrogression found in indexes")
+}
+
 func TestIndexSubspaceNegative(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -234,3 +435,128 @@ func TestIndexSubspaceMigrationV1(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, &PrimaryIndexMetadata{ID: 123, Name: "name333"}, meta)
 }
+
+func TestIndexUniqueness(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, tm := initIndexTest(t, ctx)
+	defer func() {
+		_ = kvStore.DropTable(ctx, c.SubspaceName)
+	}()
+
+	tx, cleanupTx := initTx(t, ctx, tm)
+	defer cleanupTx()
+
+	t.Run("persisted", func(t *testing.T) {
+		unique, nonUnique := true, false
+		uniqueMeta := &PrimaryIndexMetadata{ID: 1, Name: "idx1", Unique: &unique, UniqueFields: []string{"email"}}
+		nonUniqueMeta := &PrimaryIndexMetadata{ID: 2, Name: schema.PrimaryKeyIndexName, Unique: &nonUnique}
+		require.NoError(t, c.insert(ctx, tx, 1, 1, 1, "idx1", uniqueMeta))
+		require.NoError(t, c.insert(ctx, tx, 1, 1, 2, schema.PrimaryKeyIndexName, nonUniqueMeta))
+
+		meta, err := c.Get(ctx, tx, 1, 1, 1, "idx1")
+		require.NoError(t, err)
+		require.Equal(t, uniqueMeta, meta)
+		require.True(t, meta.IsUnique("idx1"))
+
+		meta, err = c.Get(ctx, tx, 1, 1, 2, schema.PrimaryKeyIndexName)
+		require.NoError(t, err)
+		require.False(t, meta.IsUnique(schema.PrimaryKeyIndexName))
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		// legacy v0 format
+		require.NoError(t, c.insertPayload(ctx, tx, nil, c.getKey(2, 1, 1, schema.PrimaryKeyIndexName), 0, UInt32ToByte(123)))
+		require.NoError(t, c.insertPayload(ctx, tx, nil, c.getKey(2, 1, 1, "idx1"), 0, UInt32ToByte(124)))
+		// v1 format without the uniqueness
+		require.NoError(t, c.insertPayload(ctx, tx, nil, c.getKey(2, 1, 2, schema.PrimaryKeyIndexName), 1, []byte(`{"id":125}`)))
+
+		meta, err := c.Get(ctx, tx, 2, 1, 1, schema.PrimaryKeyIndexName)
+		require.NoError(t, err)
+		require.Nil(t, meta.Unique)
+		require.True(t, meta.IsUnique(schema.PrimaryKeyIndexName))
+
+		meta, err = c.Get(ctx, tx, 2, 1, 1, "idx1")
+		require.NoError(t, err)
+		require.False(t, meta.IsUnique("idx1"))
+
+		meta, err = c.Get(ctx, tx, 2, 1, 2, schema.PrimaryKeyIndexName)
+		require.NoError(t, err)
+		require.True(t, meta.IsUnique(schema.PrimaryKeyIndexName))
+	})
+}
+
+func TestIndexMetadataCompression(t *testing.T) {
+	c := &PrimaryIndexSubspace{}
+
+	unique := true
+	meta := &PrimaryIndexMetadata{ID: 1234, Name: "orders_by_customer_address", Unique: &unique}
+	for _, f := range []string{"street", "city", "state", "zip", "country"} {
+		for _, p := range []string{"billing", "shipping", "previous_billing", "previous_shipping"} {
+			meta.UniqueFields = append(meta.UniqueFields, "customer.address."+p+"."+f)
+		}
+	}
+
+	t.Run("compressed", func(t *testing.T) {
+		plain, ver, err := c.encodeMetadata(meta, false)
+		require.NoError(t, err)
+		require.Equal(t, indexMetaValueVersion, ver)
+
+		compressed, ver, err := c.encodeMetadata(meta, true)
+		require.NoError(t, err)
+		require.Equal(t, indexMetaCompressedValueVersion, ver)
+		require.Less(t, len(compressed), len(plain)/2, "plain %d bytes, compressed %d bytes", len(plain), len(compressed))
+
+		decoded, err := c.decodeMetadata("idx", internal.NewTableDataWithVersion(compressed, ver))
+		require.NoError(t, err)
+		require.Equal(t, meta, decoded)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		plain, _, err := c.encodeMetadata(meta, false)
+		require.NoError(t, err)
+
+		decoded, err := c.decodeMetadata("idx", internal.NewTableDataWithVersion(plain, indexMetaValueVersion))
+		require.NoError(t, err)
+		require.Equal(t, meta, decoded)
+	})
+
+	t.Run("small_stays_plain", func(t *testing.T) {
+		payload, ver, err := c.encodeMetadata(&PrimaryIndexMetadata{ID: 1}, true)
+		require.NoError(t, err)
+		require.Equal(t, indexMetaValueVersion, ver)
+		require.Equal(t, []byte(`{"id":1,"name":""}`), payload)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		_, err := c.decodeMetadata("idx", internal.NewTableDataWithVersion([]byte("not gzip"), indexMetaCompressedValueVersion))
+		require.Equal(t, errors.Internal("failed to decompress index metadata"), err)
+	})
+}
+
+func TestIndexMetadataDecodeVersions(t *testing.T) {
+	c := &PrimaryIndexSubspace{}
+
+	t.Run("v0", func(t *testing.T) {
+		decoded, err := c.decodeMetadata("idx", internal.NewTableDataWithVersion(UInt32ToByte(123), 0))
+		require.NoError(t, err)
+		require.Equal(t, &PrimaryIndexMetadata{ID: 123}, decoded)
+
+		_, err = c.decodeMetadata("idx", internal.NewTableDataWithVersion([]byte{1, 2}, 0))
+		require.Equal(t, errors.Internal("invalid index metadata of 'idx', expected a 4 bytes id, got 2 bytes"), err)
+	})
+
+	t.Run("current", func(t *testing.T) {
+		decoded, err := c.decodeMetadata("idx", internal.NewTableDataWithVersion(
+			[]byte(`{"id":12,"name":"idx","unique_fields":["a"]}`), indexMetaValueVersion))
+		require.NoError(t, err)
+		require.Equal(t, &PrimaryIndexMetadata{ID: 12, Name: "idx", UniqueFields: []string{"a"}}, decoded)
+	})
+
+	t.Run("forward", func(t *testing.T) {
+		_, err := c.decodeMetadata("idx", internal.NewTableDataWithVersion(
+			[]byte(`{"id":12,"name":"idx","new_field":true}`), indexMetaCompressedValueVersion+1))
+		require.Equal(t, errors.Unimplemented("unsupported index metadata version 3 of 'idx', the latest supported version is 2"), err)
+	})
+}
@@ -85,7 +85,7 @@ func NewQueueItem(priority int64, data []byte) *QueueItem {
 	}
 }
 
-func newQueueStore(nameRegistry *NameRegistry) *QueueSubspace {
+func NewQueueStore(nameRegistry *NameRegistry) *QueueSubspace {
 	return &QueueSubspace{
 		metadataSubspace{
 			SubspaceName: nameRegistry.QueueSubspaceName(),
@@ -28,7 +28,7 @@ func initQueueTest(t *testing.T) (*QueueSubspace, transaction.Tx, func()) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	s := newQueueStore(newTestNameRegistry(t))
+	s := NewQueueStore(newTestNameRegistry(t))
 
 	_ = kvStore.DropTable(ctx, s.SubspaceName)
 
@@ -15,18 +15,27 @@
 package metrics
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-api-client-go/api/v1/datadog"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/server/config"
 	ulog "github.com/tigrisdata/tigris/util/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 )
 
 var (
@@ -37,34 +46,199 @@ var (
 	rateLimitRemaining = "X-RateLimit-Remaining"
 	rateLimitReset     = "X-RateLimit-Reset"
 	rateLimitName      = "X-RateLimit-Name"
+
+	datadogProvider = "datadog"
 )
 
 type Datadog struct {
-	apiClient *datadog.APIClient
-	host      map[string]string
+	apiClient      *datadog.APIClient
+	httpClient     *http.Client
+	serverIndex    int
+	host           map[string]string
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	queryPool      *QueryPool
+	postThreshold  int
+	alwaysPost     bool
 }
 
 func InitDatadog(cfg *config.Config) *Datadog {
 	d := Datadog{}
 	c := datadog.NewConfiguration()
-	c.AddDefaultHeader(dDApiKey, cfg.Observability.ApiKey)
-	c.AddDefaultHeader(dDAppKey, cfg.Observability.AppKey)
+	headers, err := ProviderAuthHeaders(&cfg.Observability)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid observability provider auth headers")
+	}
+	for name, value := range headers {
+		c.AddDefaultHeader(name, value)
+	}
+	d.httpClient = NewHTTPClient(&cfg.Observability)
+	c.HTTPClient = d.httpClient
 
 	d.apiClient = datadog.NewAPIClient(c)
-	d.host = map[string]string{"site": cfg.Observability.ProviderUrl}
+	serverIndex, host, err := ParseProviderURL(cfg.Observability.ProviderUrl)
+	if err != nil {
+		log.Warn().Err(err).Str("url", cfg.Observability.ProviderUrl).Msg("Invalid observability provider url")
+		host = map[string]string{"site": cfg.Observability.ProviderUrl}
+	}
+	d.serverIndex, d.host = serverIndex, host
+	d.maxAttempts = cfg.Observability.QueryMaxAttempts
+	d.retryBaseDelay = cfg.Observability.QueryRetryBaseDelay
+	d.queryPool = NewQueryPool(cfg.Observability.MaxConcurrentQueries)
+	d.postThreshold = cfg.Observability.QueryPostThreshold
+	d.alwaysPost = cfg.Observability.QueryAlwaysPost
 
 	return &d
 }
 
+// ProviderAuthHeaders returns the headers authenticating the queries sent to the provider, with the environment
+// variables in their values expanded. Without configured headers, they are the api and application keys of Datadog.
+// Every header must have a value, a header that is empty, usually because its variable is not set, is returned as an
+// error along with the headers that have a value.
+func ProviderAuthHeaders(cfg *config.ObservabilityConfig) (map[string]string, error) {
+	if len(cfg.AuthHeaders) == 0 {
+		return map[string]string{dDApiKey: cfg.ApiKey, dDAppKey: cfg.AppKey}, nil
+	}
+
+	var missing []string
+	headers := make(map[string]string, len(cfg.AuthHeaders))
+	for name, template := range cfg.AuthHeaders {
+		value := os.ExpandEnv(template)
+		if len(strings.TrimSpace(value)) == 0 {
+			missing = append(missing, name)
+			continue
+		}
+		headers[name] = value
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return headers, fmt.Errorf("provider auth headers '%s' have no value", strings.Join(missing, ", "))
+	}
+
+	return headers, nil
+}
+
+// ParseProviderURL returns the server of the provider client and its variables for the configured provider url. The
+// url is either a site of the provider, like "us3.datadoghq.com", or a http or https url without a path, like
+// "https://api.us3.datadoghq.com" or the url of a proxy. The trailing slashes are ignored so that appending a path to
+// the url never produces a double slash.
+func ParseProviderURL(providerURL string) (int, map[string]string, error) {
+	trimmed := strings.TrimRight(strings.TrimSpace(providerURL), "/")
+	if len(trimmed) == 0 {
+		return 0, nil, fmt.Errorf("provider url is empty")
+	}
+
+	servers := datadog.NewConfiguration().Servers
+	if !strings.Contains(trimmed, "://") {
+		for _, site := range servers[0].Variables["site"].EnumValues {
+			if site == trimmed {
+				return 0, map[string]string{"site": trimmed}, nil
+			}
+		}
+		return 0, nil, fmt.Errorf("provider url '%s' is neither a known site nor a http or https url", providerURL)
+	}
+
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid provider url '%s': %w", providerURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return 0, nil, fmt.Errorf("provider url '%s' must be a http or https url", providerURL)
+	}
+	if len(u.Host) == 0 {
+		return 0, nil, fmt.Errorf("provider url '%s' has no host", providerURL)
+	}
+	if len(u.Path) > 0 || len(u.RawQuery) > 0 || len(u.Fragment) > 0 || u.User != nil {
+		return 0, nil, fmt.Errorf("provider url '%s' must not have a path, a query or credentials", providerURL)
+	}
+
+	// the second server of the client takes the protocol and the name of the host
+	return 1, map[string]string{"protocol": u.Scheme, "name": u.Host}, nil
+}
+
+// NewHTTPClient returns the client used to send the queries to the provider. It is created once per provider and is
+// safe for concurrent use, so that the queries reuse the pooled connections instead of opening a new connection, with
+// its TLS handshake, per query.
+func NewHTTPClient(cfg *config.ObservabilityConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+
+	return &http.Client{Transport: transport, Timeout: cfg.QueryTimeout}
+}
+
+// RunQueries runs the sub-queries of a single request with the bounded concurrency of the query pool.
+func (d *Datadog) RunQueries(ctx context.Context, queries ...SubQuery) error {
+	return d.queryPool.Run(ctx, queries...)
+}
+
+// CurrentMetricValueQuery returns the sub-query reading the current value of the metric into value, see
+// GetCurrentMetricValue.
+func (d *Datadog) CurrentMetricValueQuery(namespace string, metric string, tp api.TigrisOperation, avgLength time.Duration,
+	value *int64,
+) SubQuery {
+	return func(ctx context.Context) error {
+		v, err := d.GetCurrentMetricValue(ctx, namespace, metric, tp, avgLength)
+		if err != nil {
+			return err
+		}
+
+		*value = v
+		return nil
+	}
+}
+
 func (d *Datadog) Query(ctx context.Context, from int64, to int64, query string) (*datadog.MetricsQueryResponse, error) {
+	ctx = context.WithValue(ctx, datadog.ContextServerIndex, d.serverIndex)
 	ctx = context.WithValue(ctx, datadog.ContextServerVariables, d.host)
 
-	resp, hResp, err := d.apiClient.MetricsApi.QueryMetrics(ctx, from, to, query)
+	var (
+		resp  datadog.MetricsQueryResponse
+		hResp *http.Response
+		err   error
+	)
+	for attempt := 1; ; attempt++ {
+		if d.usePost(query) {
+			resp, hResp, err = d.queryMetricsPost(ctx, from, to, query)
+		} else {
+			resp, hResp, err = d.apiClient.MetricsApi.QueryMetrics(ctx, from, to, query)
+		}
+		if attempt >= d.maxAttempts || ctx.Err() != nil || !isRetriableQueryError(hResp, err) {
+			break
+		}
+
+		CountProviderError(datadogProvider, err, hResp)
+		if hResp != nil {
+			_ = hResp.Body.Close()
+		}
+
+		delay := d.retryDelay(attempt)
+		log.Debug().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("retrying Datadog query")
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.DeadlineExceeded("Failed to query metrics: reason = " + ctx.Err().Error())
+		case <-time.After(delay):
+		}
+	}
+
 	if ulog.E(err) {
+		CountProviderError(datadogProvider, err, hResp)
+		if isQueryTimeout(ctx, err) {
+			return nil, errors.DeadlineExceeded("Failed to query metrics: reason = " + err.Error())
+		}
+		if hResp != nil && hResp.StatusCode >= http.StatusMultipleChoices {
+			return nil, providerStatusError(hResp)
+		}
 		return nil, errors.Internal("Failed to query metrics: reason = " + err.Error())
 	}
 	defer func() { _ = hResp.Body.Close() }()
 
+	if hResp.StatusCode != http.StatusOK {
+		CountProviderError(datadogProvider, nil, hResp)
+	}
+
 	if hResp.StatusCode == http.StatusTooManyRequests {
 		log.Warn().Str(rateLimitLimit, hResp.Header.Get(rateLimitLimit)).
 			Str(rateLimitPeriod, hResp.Header.Get(rateLimitPeriod)).
@@ -83,11 +257,228 @@ func (d *Datadog) Query(ctx context.Context, from int64, to int64, query string)
 	return &resp, nil
 }
 
+// providerErrorResponse is the error envelope of the provider.
+type providerErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// providerStatusError converts a failed response of the provider. The errors of the provider are parsed from its
+// error envelope and attached to the error info as metadata, so that the callers don't have to parse the message. A
+// bad query is reported as an invalid argument. A rejected authorization is an internal error, as it is the
+// credentials of the server that are rejected and not the ones of the user. The raw body is used as the reason when
+// it isn't an error envelope.
+func providerStatusError(hResp *http.Response) error {
+	body, _ := io.ReadAll(hResp.Body)
+
+	var (
+		envelope providerErrorResponse
+		reasons  []string
+	)
+	if err := jsoniter.Unmarshal(body, &envelope); err == nil && len(envelope.Errors) > 0 {
+		reasons = envelope.Errors
+	} else {
+		reasons = []string{strings.TrimSpace(string(body))}
+	}
+
+	code := api.Code_INTERNAL
+	switch hResp.StatusCode {
+	case http.StatusBadRequest:
+		code = api.Code_INVALID_ARGUMENT
+	case http.StatusTooManyRequests:
+		code = api.Code_RESOURCE_EXHAUSTED
+	}
+
+	metadata := map[string]string{
+		"provider": datadogProvider,
+		"status":   hResp.Status,
+	}
+	for i, reason := range reasons {
+		metadata["reason_"+strconv.Itoa(i)] = reason
+	}
+
+	return api.Errorf(code, "Failed to query metrics: reason = %s", strings.Join(reasons, "; ")).
+		WithDetails(&errdetails.ErrorInfo{Reason: api.CodeToString(code), Metadata: metadata})
+}
+
+// usePost returns true if the query is sent in the body of a POST rather than in the URL of a GET.
+func (d *Datadog) usePost(query string) bool {
+	return d.alwaysPost || (d.postThreshold > 0 && len(query) > d.postThreshold)
+}
+
+// timeseriesQueryName is the name of the single query of a timeseries request, which its formula refers to.
+const timeseriesQueryName = "query1"
+
+// timeseriesResponse is the response of the timeseries endpoint of the provider. The values of a series are in the
+// order of the times, a missing value is null.
+type timeseriesResponse struct {
+	Data struct {
+		Attributes struct {
+			Series []struct {
+				GroupTags []string `json:"group_tags"`
+			} `json:"series"`
+			Times  []int64      `json:"times"`
+			Values [][]*float64 `json:"values"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Errors string `json:"errors"`
+}
+
+// queryMetricsPost runs the query with the timeseries endpoint of the provider, which takes the query in a JSON body
+// of a POST so that a long query doesn't exceed the URL length limit of the query endpoint, which only accepts a GET.
+// The response is converted to the response of the query endpoint, its series are told apart by their group tags.
+func (d *Datadog) queryMetricsPost(ctx context.Context, from int64, to int64, query string) (datadog.MetricsQueryResponse, *http.Response, error) {
+	var resp datadog.MetricsQueryResponse
+
+	basePath, err := d.apiClient.GetConfig().ServerURLWithContext(ctx, "MetricsApiService.QueryMetrics")
+	if err != nil {
+		return resp, nil, err
+	}
+
+	// the timeseries endpoint takes the window in milliseconds
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "timeseries_request",
+			"attributes": map[string]interface{}{
+				"from": from * 1000,
+				"to":   to * 1000,
+				"queries": []map[string]interface{}{
+					{"data_source": "metrics", "query": query, "name": timeseriesQueryName},
+				},
+				"formulas": []map[string]interface{}{{"formula": timeseriesQueryName}},
+			},
+		},
+	}
+	headers := map[string]string{"Content-Type": "application/json", "Accept": "application/json"}
+	req, err := d.apiClient.PrepareRequest(ctx, basePath+"/api/v2/query/timeseries", http.MethodPost, body, headers, url.Values{}, url.Values{}, nil)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	hResp, err := d.apiClient.CallAPI(req)
+	if err != nil || hResp == nil {
+		return resp, hResp, err
+	}
+
+	// the body is kept readable for the caller, like the client does
+	data, err := io.ReadAll(hResp.Body)
+	_ = hResp.Body.Close()
+	hResp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return resp, hResp, err
+	}
+
+	if hResp.StatusCode >= http.StatusMultipleChoices {
+		return resp, hResp, fmt.Errorf("%s", hResp.Status)
+	}
+
+	var tsResp timeseriesResponse
+	if err = jsoniter.Unmarshal(data, &tsResp); err != nil {
+		return resp, hResp, err
+	}
+
+	return toMetricsQueryResponse(from, to, query, &tsResp), hResp, nil
+}
+
+// toMetricsQueryResponse converts the response of the timeseries endpoint to the response of the query endpoint.
+func toMetricsQueryResponse(from int64, to int64, query string, tsResp *timeseriesResponse) datadog.MetricsQueryResponse {
+	resp := datadog.MetricsQueryResponse{}
+	resp.SetQuery(query)
+	resp.SetFromDate(from * 1000)
+	resp.SetToDate(to * 1000)
+	if len(tsResp.Errors) > 0 {
+		resp.SetStatus("error")
+		resp.SetError(tsResp.Errors)
+		return resp
+	}
+	resp.SetStatus("ok")
+
+	attrs := &tsResp.Data.Attributes
+	metric := queryMetricName(query)
+	for i, s := range attrs.Series {
+		series := datadog.MetricsQueryMetadata{TagSet: s.GroupTags}
+		series.SetMetric(metric)
+		series.SetExpression(query)
+
+		var values []*float64
+		if i < len(attrs.Values) {
+			values = attrs.Values[i]
+		}
+		series.Pointlist = make([][]*float64, 0, len(attrs.Times))
+		for j, t := range attrs.Times {
+			timestamp := float64(t)
+			var v *float64
+			if j < len(values) {
+				v = values[j]
+			}
+			series.Pointlist = append(series.Pointlist, []*float64{&timestamp, v})
+		}
+		if len(attrs.Times) > 0 {
+			series.SetStart(attrs.Times[0])
+			series.SetEnd(attrs.Times[len(attrs.Times)-1])
+		}
+
+		resp.Series = append(resp.Series, series)
+	}
+
+	return resp
+}
+
+// queryMetricName returns the name of the metric of a query formed by FormDatadogQuery, which is between the space
+// aggregation and the tags, like "tigris.requests_count_ok.count" in "sum:tigris.requests_count_ok.count{db:db1}".
+func queryMetricName(query string) string {
+	_, metric, _ := strings.Cut(query, ":")
+	metric, _, _ = strings.Cut(metric, "{")
+	return metric
+}
+
+// isRetriableQueryError returns true if the query failed because of rate-limiting, a server error or a network
+// error, the other client errors are not retried.
+func isRetriableQueryError(hResp *http.Response, err error) bool {
+	if hResp == nil {
+		return err != nil
+	}
+
+	return hResp.StatusCode == http.StatusTooManyRequests || hResp.StatusCode >= http.StatusInternalServerError
+}
+
+// isQueryTimeout returns true if the query failed because the request was canceled or its deadline passed, or
+// because the query timed out.
+func isQueryTimeout(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+
+	_, errorType := classifyProviderError(err, nil)
+	return errorType == ProviderErrorTimeout
+}
+
+// retryDelay returns the delay before the retry following the attempt. It grows exponentially from the base delay and
+// is jittered so that the retries of concurrent queries are spread out.
+func (d *Datadog) retryDelay(attempt int) time.Duration {
+	delay := d.retryBaseDelay << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+
+	//nolint:gosec
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
 func FormDatadogQuery(namespace string, req *api.QueryTimeSeriesMetricsRequest) (string, error) {
-	return FormDatadogQueryNoMeta(namespace, false, req)
+	return formDatadogQuery(namespace, "", false, req)
+}
+
+// FormDatadogProjectQuery forms the query of the request scoped to the project, it matches the metrics of all the
+// databases of the project. A db set in the request narrows the query down to a single database within the project.
+func FormDatadogProjectQuery(namespace string, project string, req *api.QueryTimeSeriesMetricsRequest) (string, error) {
+	return formDatadogQuery(namespace, project, false, req)
 }
 
 func FormDatadogQueryNoMeta(namespace string, noMeta bool, req *api.QueryTimeSeriesMetricsRequest) (string, error) {
+	return formDatadogQuery(namespace, "", noMeta, req)
+}
+
+func formDatadogQuery(namespace string, project string, noMeta bool, req *api.QueryTimeSeriesMetricsRequest) (string, error) {
 	// final version examples:
 	// sum:tigris.requests_count_ok.count{db:ycsb_tigris,collection:user_tables}.as_rate()
 	// sum:tigris.requests_count_ok.count{db:ycsb_tigris,tigris_tenant:default_namespace} by {db,collection}.as_rate()
@@ -115,6 +506,12 @@ func FormDatadogQueryNoMeta(namespace string, noMeta bool, req *api.QueryTimeSer
 		tags = append(tags, "env:"+config.GetEnvironment())
 	}
 
+	// project and db are combined with AND when both are set, so db narrows
+	// the query down to a single database within the project.
+	if project != "" {
+		tags = append(tags, "project:"+project)
+	}
+
 	if req.Db != "" {
 		tags = append(tags, "db:"+req.Db)
 	}
@@ -132,7 +529,7 @@ func FormDatadogQueryNoMeta(namespace string, noMeta bool, req *api.QueryTimeSer
 	}
 
 	if req.Quantile != 0 {
-		tags = append(tags, "quantile:"+fmt.Sprintf("%.3g", req.Quantile))
+		tags = append(tags, "quantile:"+strconv.FormatFloat(float64(req.Quantile), 'f', -1, 32))
 	}
 
 	if len(tags) == 0 {
@@ -165,14 +562,78 @@ func FormDatadogQueryNoMeta(namespace string, noMeta bool, req *api.QueryTimeSer
 	}
 
 	for _, additionalFunction := range req.AdditionalFunctions {
-		if additionalFunction.Rollup != nil {
-			ddQuery = fmt.Sprintf("%s.rollup(%s, %d)", ddQuery, convertToDDAggregatorFunc(additionalFunction.Rollup.Aggregator), additionalFunction.Rollup.Interval)
+		if rollup := additionalFunction.Rollup; rollup != nil {
+			// without an interval the provider picks one based on the queried window
+			if rollup.Interval == 0 {
+				ddQuery = fmt.Sprintf("%s.rollup(%s)", ddQuery, convertToDDAggregatorFunc(rollup.Aggregator))
+			} else {
+				ddQuery = fmt.Sprintf("%s.rollup(%s, %d)", ddQuery, convertToDDAggregatorFunc(rollup.Aggregator), rollup.Interval)
+			}
 		}
 	}
 
 	return ddQuery, nil
 }
 
+// timeAggregationMethods are the methods combining the points within each interval of a query, see TimeAggregation.
+var timeAggregationMethods = map[string]struct{}{
+	"avg":  {},
+	"max":  {},
+	"min":  {},
+	"last": {},
+	"sum":  {},
+}
+
+// TimeAggregation is how the points within each interval of a query are combined, for example the maximum value per
+// interval rather than the average the provider uses by default. The interval is in seconds, zero lets the provider
+// pick the interval.
+type TimeAggregation struct {
+	Method   string
+	Interval int64
+}
+
+// ParseTimeAggregation parses a time aggregation formatted as "<method>" or "<method>:<interval>", like "max:60". The
+// method must be one of avg, max, min, last or sum. An empty value returns a nil aggregation.
+func ParseTimeAggregation(value string) (*TimeAggregation, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	method, interval, hasInterval := strings.Cut(value, ":")
+	method = strings.ToLower(method)
+	if _, ok := timeAggregationMethods[method]; !ok {
+		return nil, errors.InvalidArgument("Failed to query metrics: reason = invalid time aggregation '%s', allowed methods are avg, max, min, last and sum", value)
+	}
+
+	ta := &TimeAggregation{Method: method}
+	if hasInterval {
+		var err error
+		if ta.Interval, err = strconv.ParseInt(interval, 10, 64); err != nil || ta.Interval <= 0 {
+			return nil, errors.InvalidArgument("Failed to query metrics: reason = invalid time aggregation interval '%s'", interval)
+		}
+	}
+
+	return ta, nil
+}
+
+// Apply returns the query with the rollup of the time aggregation appended. A nil aggregation returns the query as is.
+func (ta *TimeAggregation) Apply(query string) string {
+	if ta == nil {
+		return query
+	}
+	if ta.Interval == 0 {
+		return fmt.Sprintf("%s.rollup(%s)", query, ta.Method)
+	}
+
+	return fmt.Sprintf("%s.rollup(%s, %d)", query, ta.Method, ta.Interval)
+}
+
+// IsSupportedRollupAggregator returns true if the aggregator of a rollup function can be sent to the provider, one of
+// avg, sum, count, min or max.
+func IsSupportedRollupAggregator(aggregator api.RollupAggregator) bool {
+	return convertToDDAggregatorFunc(aggregator) != ""
+}
+
 func convertToDDAggregatorFunc(aggregator api.RollupAggregator) string {
 	switch aggregator {
 	case api.RollupAggregator_ROLLUP_AGGREGATOR_AVG:
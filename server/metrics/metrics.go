@@ -149,6 +149,7 @@ func InitializeMetrics() func() {
 		}
 
 		initializeQuotaScopes()
+		initializeObservabilityScopes()
 
 		SchemaMetrics = root.SubScope("schema")
 		GlobalSt = NewGlobalStatus()
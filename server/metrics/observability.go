@@ -0,0 +1,81 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	ProviderErrorHTTP      = "http"
+	ProviderErrorTimeout   = "timeout"
+	ProviderErrorDNS       = "dns"
+	ProviderErrorParse     = "parse"
+	ProviderErrorTransport = "transport"
+
+	noStatusCode = "none"
+)
+
+var ObservabilityMetrics tally.Scope
+
+func initializeObservabilityScopes() {
+	ObservabilityMetrics = root.SubScope("observability")
+}
+
+func getProviderErrorTags(provider string, statusCode string, errorType string) map[string]string {
+	return map[string]string{
+		"provider":    provider,
+		"status_code": statusCode,
+		"error_type":  errorType,
+	}
+}
+
+// classifyProviderError returns the status code and the classification of a failed call to the observability
+// provider. The status code is "none" if the call failed before a response was received.
+func classifyProviderError(err error, hResp *http.Response) (string, string) {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return noStatusCode, ProviderErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return noStatusCode, ProviderErrorTimeout
+	case hResp == nil:
+		return noStatusCode, ProviderErrorTransport
+	case hResp.StatusCode == http.StatusOK && err != nil:
+		// the response was received but couldn't be decoded
+		return strconv.Itoa(hResp.StatusCode), ProviderErrorParse
+	default:
+		return strconv.Itoa(hResp.StatusCode), ProviderErrorHTTP
+	}
+}
+
+// CountProviderError increments the error counter of the observability provider. It is called for every transport
+// error and for every response with a status code other than 200.
+func CountProviderError(provider string, err error, hResp *http.Response) {
+	if ObservabilityMetrics == nil {
+		return
+	}
+
+	statusCode, errorType := classifyProviderError(err, hResp)
+	ObservabilityMetrics.Tagged(getProviderErrorTags(provider, statusCode, errorType)).Counter("provider_errors").Inc(1)
+}
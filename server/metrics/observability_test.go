@@ -0,0 +1,72 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestClassifyProviderError(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		resp       *http.Response
+		statusCode string
+		errorType  string
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "api.datadoghq.com"}, nil, "none", ProviderErrorDNS},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), nil, "none", ProviderErrorTimeout},
+		{"net_timeout", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, nil, "none", ProviderErrorTimeout},
+		{"transport", fmt.Errorf("connection refused"), nil, "none", ProviderErrorTransport},
+		{"parse", fmt.Errorf("invalid character"), &http.Response{StatusCode: http.StatusOK}, "200", ProviderErrorParse},
+		{"http", fmt.Errorf("403 Forbidden"), &http.Response{StatusCode: http.StatusForbidden}, "403", ProviderErrorHTTP},
+		{"http_no_error", nil, &http.Response{StatusCode: http.StatusTooManyRequests}, "429", ProviderErrorHTTP},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			statusCode, errorType := classifyProviderError(c.err, c.resp)
+			require.Equal(t, c.statusCode, statusCode)
+			require.Equal(t, c.errorType, errorType)
+		})
+	}
+}
+
+func TestCountProviderError(t *testing.T) {
+	save := ObservabilityMetrics
+	t.Cleanup(func() { ObservabilityMetrics = save })
+
+	scope := tally.NewTestScope("", nil)
+	ObservabilityMetrics = scope
+
+	CountProviderError("datadog", nil, &http.Response{StatusCode: http.StatusBadGateway})
+	CountProviderError("datadog", nil, &http.Response{StatusCode: http.StatusBadGateway})
+	CountProviderError("datadog", context.DeadlineExceeded, nil)
+
+	counters := scope.Snapshot().Counters()
+	require.Equal(t, int64(2), counters["provider_errors+error_type=http,provider=datadog,status_code=502"].Value())
+	require.Equal(t, int64(1), counters["provider_errors+error_type=timeout,provider=datadog,status_code=none"].Value())
+
+	ObservabilityMetrics = nil
+	CountProviderError("datadog", nil, &http.Response{StatusCode: http.StatusBadGateway})
+}
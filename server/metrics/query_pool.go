@@ -0,0 +1,95 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// SubQuery is a single upstream query issued on behalf of a client request.
+type SubQuery func(ctx context.Context) error
+
+// QueryPool runs the sub-queries of a client request concurrently, with at most size of them in flight, so that a
+// single request can't open an unbounded number of connections to the provider.
+type QueryPool struct {
+	size int
+}
+
+// NewQueryPool returns a pool running at most size sub-queries at a time, at least one.
+func NewQueryPool(size int) *QueryPool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &QueryPool{size: size}
+}
+
+// Run runs the sub-queries and waits for them to finish. The sub-queries share a context which is canceled as soon
+// as one of them fails, the sub-queries not started yet are then skipped. The errors of the failed sub-queries are
+// aggregated, except the context.Canceled errors of the sub-queries canceled because of an earlier failure. If none
+// of the sub-queries failed but the parent context is done, its error is returned.
+func (p *QueryPool) Run(ctx context.Context, queries ...SubQuery) error {
+	poolCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs *multierror.Error
+		sem  = make(chan struct{}, p.size)
+	)
+
+launch:
+	for _, query := range queries {
+		select {
+		case <-poolCtx.Done():
+			break launch
+		case sem <- struct{}{}:
+		}
+		if poolCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(query SubQuery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := query(poolCtx); err != nil {
+				mu.Lock()
+				if poolCtx.Err() == nil || !errors.Is(err, context.Canceled) {
+					errs = multierror.Append(errs, err)
+				}
+				mu.Unlock()
+
+				cancel()
+			}
+		}(query)
+	}
+
+	wg.Wait()
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
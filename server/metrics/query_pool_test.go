@@ -0,0 +1,106 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPool(t *testing.T) {
+	t.Run("bounded", func(t *testing.T) {
+		var inFlight, maxInFlight, done int32
+		query := func(ctx context.Context) error {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			atomic.AddInt32(&done, 1)
+			return nil
+		}
+
+		queries := make([]SubQuery, 10)
+		for i := range queries {
+			queries[i] = query
+		}
+
+		require.NoError(t, NewQueryPool(3).Run(context.Background(), queries...))
+		require.Equal(t, int32(10), atomic.LoadInt32(&done))
+		require.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(3))
+	})
+
+	t.Run("failure_cancels_the_rest", func(t *testing.T) {
+		var started int32
+		failing := func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			return fmt.Errorf("query failed")
+		}
+		blocking := func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Minute):
+				return nil
+			}
+		}
+
+		err := NewQueryPool(2).Run(context.Background(), blocking, failing, blocking, blocking, blocking)
+
+		var merr *multierror.Error
+		require.ErrorAs(t, err, &merr)
+		require.Equal(t, []error{fmt.Errorf("query failed")}, merr.Errors)
+		require.Equal(t, int32(2), atomic.LoadInt32(&started))
+	})
+
+	t.Run("errors_aggregated", func(t *testing.T) {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		failing := func(msg string) SubQuery {
+			return func(ctx context.Context) error {
+				// both the sub-queries fail on their own, irrespective of which one fails first
+				wg.Done()
+				wg.Wait()
+				return fmt.Errorf("%s", msg)
+			}
+		}
+
+		err := NewQueryPool(2).Run(context.Background(), failing("first"), failing("second"))
+
+		var merr *multierror.Error
+		require.ErrorAs(t, err, &merr)
+		require.ElementsMatch(t, []error{fmt.Errorf("first"), fmt.Errorf("second")}, merr.Errors)
+	})
+
+	t.Run("parent_canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := NewQueryPool(2).Run(ctx, func(ctx context.Context) error { return ctx.Err() })
+		require.Equal(t, context.Canceled, err)
+	})
+}
@@ -69,7 +69,7 @@ func (c CustomClaim) Validate(_ context.Context) error {
 type TigrisClaims struct {
 	NamespaceCode        string `json:"nc"`
 	NamespaceDisplayName string `json:"nd"`
-	Project              string `json:"-"`
+	Project              string `json:"p"`
 	UserEmail            string `json:"ue"`
 }
 
@@ -238,6 +238,7 @@ func authFunction(ctx context.Context, jwtValidators []*validator.Validator, con
 			token := &types.AccessToken{
 				Namespace: namespaceCode,
 				Sub:       validatedClaims.RegisteredClaims.Subject,
+				Project:   customClaims.TigrisClaims.Project,
 			}
 			reqMetadata.SetAccessToken(token)
 			// update cache
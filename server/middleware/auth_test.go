@@ -16,6 +16,7 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"testing"
 	"time"
@@ -85,6 +86,13 @@ func TestAuth(t *testing.T) {
 		require.True(t, isAdminNamespace("tigris-admin", &enforcedAuthConfig))
 	})
 
+	t.Run("project claim", func(t *testing.T) {
+		var claims CustomClaim
+		require.NoError(t, json.Unmarshal([]byte(`{"https://tigris":{"nc":"test-namespace","p":"project-a"}}`), &claims))
+		require.Equal(t, "test-namespace", claims.TigrisClaims.NamespaceCode)
+		require.Equal(t, "project-a", claims.TigrisClaims.Project)
+	})
+
 	t.Run("bypassCache", func(t *testing.T) {
 		cache := gcache.New(2).Expiration(time.Duration(5) * time.Minute).Build()
 		_ = cache.Set("token1", "token-value-1")
@@ -31,19 +31,22 @@ type Datadog struct {
 }
 
 func (d *Datadog) CurRates(ctx context.Context, namespace string) (int64, int64, error) {
-	r, err := d.Datadog.GetCurrentMetricValue(ctx, namespace, "tigris.quota_usage_read_units.count", api.TigrisOperation_ALL, RunningAverageLength)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	w, err := d.Datadog.GetCurrentMetricValue(ctx, namespace, "tigris.quota_usage_write_units.count", api.TigrisOperation_ALL, RunningAverageLength)
-	if err != nil {
+	var r, w int64
+	if err := d.Datadog.RunQueries(ctx, d.CurRatesQueries(namespace, &r, &w)...); err != nil {
 		return 0, 0, err
 	}
 
 	return r, w, nil
 }
 
+// CurRatesQueries returns the sub-queries reading the current read and write rates of the namespace into r and w.
+func (d *Datadog) CurRatesQueries(namespace string, r *int64, w *int64) []metrics.SubQuery {
+	return []metrics.SubQuery{
+		d.Datadog.CurrentMetricValueQuery(namespace, "tigris.quota_usage_read_units.count", api.TigrisOperation_ALL, RunningAverageLength, r),
+		d.Datadog.CurrentMetricValueQuery(namespace, "tigris.quota_usage_write_units.count", api.TigrisOperation_ALL, RunningAverageLength, w),
+	}
+}
+
 func initDatadogMetrics(cfg *config.Config) *Datadog {
 	log.Debug().Msg("initializing Datadog coordinated quota backend")
 	return &Datadog{metrics.InitDatadog(cfg)}
@@ -258,7 +258,10 @@ type NamespaceExtractor interface {
 
 type AccessTokenNamespaceExtractor struct{}
 
-var ErrNamespaceNotFound = errors.NotFound("namespace not found")
+var (
+	ErrNamespaceNotFound = errors.NotFound("namespace not found")
+	ErrProjectNotFound   = errors.NotFound("project not found")
+)
 
 func GetRequestMetadataFromContext(ctx context.Context) (*Metadata, error) {
 	// read token
@@ -299,6 +302,22 @@ func GetNamespace(ctx context.Context) (string, error) {
 	return "", ErrNamespaceNotFound
 }
 
+// GetProject returns the project of the caller. The project bound to the access token takes precedence over
+// the project extracted from the request.
+func GetProject(ctx context.Context) (string, error) {
+	if value := ctx.Value(MetadataCtxKey{}); value != nil {
+		if requestMetadata, ok := value.(*Metadata); ok {
+			if requestMetadata.accessToken != nil && requestMetadata.accessToken.Project != "" {
+				return requestMetadata.accessToken.Project, nil
+			}
+			if requestMetadata.project != "" {
+				return requestMetadata.project, nil
+			}
+		}
+	}
+	return "", ErrProjectNotFound
+}
+
 func IsHumanUser(ctx context.Context) bool {
 	if value := ctx.Value(MetadataCtxKey{}); value != nil {
 		if requestMetadata, ok := value.(*Metadata); ok {
@@ -40,6 +40,32 @@ func TestRequestMetadata(t *testing.T) {
 		require.Equal(t, "test-namespace-1", namespaceName)
 	})
 
+	t.Run("extraction of project", func(t *testing.T) {
+		_, err := GetProject(context.TODO())
+		require.Equal(t, ErrProjectNotFound, err)
+
+		ctx := context.WithValue(context.TODO(), MetadataCtxKey{}, &Metadata{
+			accessToken: &types.AccessToken{
+				Namespace: "test-namespace-1",
+			},
+			project: "p1",
+		})
+		project, err := GetProject(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "p1", project)
+
+		ctx = context.WithValue(context.TODO(), MetadataCtxKey{}, &Metadata{
+			accessToken: &types.AccessToken{
+				Namespace: "test-namespace-1",
+				Project:   "p2",
+			},
+			project: "p1",
+		})
+		project, err = GetProject(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "p2", project)
+	})
+
 	t.Run("extraction of token", func(t *testing.T) {
 		ctx := context.TODO()
 		ctx = context.WithValue(ctx, MetadataCtxKey{}, &Metadata{
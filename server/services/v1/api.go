@@ -22,6 +22,7 @@ import (
 	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
@@ -38,6 +39,7 @@ import (
 	"github.com/tigrisdata/tigris/store/search"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
 )
 
 const (
@@ -108,6 +110,8 @@ func newApiService(kv kv.TxStore, searchStore search.Store, tenantMgr *metadata.
 	}
 	u.runnerFactory = database.NewQueryRunnerFactory(u.txMgr, u.cdcMgr, u.searchStore)
 
+	database.NewTTLExpirer(u.txMgr, u.tenantMgr, u.searchStore).Start(context.Background(), config.DefaultConfig.Schema.TTLInterval)
+
 	return u
 }
 
@@ -336,13 +340,20 @@ func (s *apiService) Read(r *api.ReadRequest, stream api.Tigris_ReadServer) erro
 func (s *apiService) Explain(ctx context.Context, r *api.ReadRequest) (*api.ExplainResponse, error) {
 	queryMetrics := metrics.WriteQueryMetrics{}
 	accessToken, _ := request.GetAccessToken(ctx)
-	resp, err := s.sessions.Execute(ctx, s.runnerFactory.GetExplainQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{
+	runner := s.runnerFactory.GetExplainQueryRunner(r, &queryMetrics, accessToken)
+	resp, err := s.sessions.Execute(ctx, runner, database.ReqOptions{
 		TxCtx: api.GetTransaction(ctx),
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if plan, err := jsoniter.Marshal(runner.Plan()); err == nil {
+		if err = grpc.SetHeader(ctx, grpcmd.Pairs(api.HeaderExplainPlan, string(plan))); err != nil {
+			log.Warn().Err(err).Msg("failed to set the explain plan header")
+		}
+	}
+
 	return resp.Response.(*api.ExplainResponse), nil
 }
 
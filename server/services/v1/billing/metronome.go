@@ -30,7 +30,10 @@ const (
 	TimeFormat = time.RFC3339
 )
 
-type MetronomeId = uuid.UUID
+// MetronomeId is kept for backward compatibility.
+//
+// Deprecated: use AccountID.
+type MetronomeId = AccountID
 
 type Metronome struct {
 	Config config.Metronome
@@ -49,7 +52,12 @@ func NewMetronomeProvider(config config.Metronome) (*Metronome, error) {
 	return &Metronome{Config: config, client: client}, nil
 }
 
-func (m *Metronome) CreateAccount(ctx context.Context, namespaceId string, name string) (MetronomeId, error) {
+// CreateAccount creates a customer in metronome. The metadata is stored as custom fields of the customer, the keys
+// need to be registered as custom field keys in metronome beforehand.
+//
+// The namespaceId is used as the idempotency key. It is registered as the ingest alias of the customer and metronome
+// rejects a second customer with the same alias, in which case the id of the existing customer is returned.
+func (m *Metronome) CreateAccount(ctx context.Context, namespaceId string, name string, metadata map[string]string) (AccountID, error) {
 	body := biller.CreateCustomerJSONRequestBody{
 		IngestAliases: &[]string{namespaceId},
 		Name:          name,
@@ -57,16 +65,162 @@ func (m *Metronome) CreateAccount(ctx context.Context, namespaceId string, name
 
 	resp, err := m.client.CreateCustomerWithResponse(ctx, body)
 	if err != nil {
-		return uuid.Nil, err
+		return "", err
+	}
+
+	var accountId AccountID
+	switch {
+	case resp.JSON200 != nil:
+		accountId = AccountID(resp.JSON200.Data.Id.String())
+	case resp.StatusCode() == http.StatusConflict:
+		existing, err := m.findCustomerByNamespace(ctx, namespaceId)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return "", errors.Internal("metronome failure: %s", resp.Body)
+		}
+		accountId = AccountID(existing.Id.String())
+	default:
+		return "", errors.Internal("metronome failure: %s", resp.Body)
+	}
+	if len(metadata) > 0 {
+		if _, err = m.UpdateAccountMetadata(ctx, accountId, metadata); err != nil {
+			return "", err
+		}
+	}
+
+	return accountId, nil
+}
+
+// findCustomerByNamespace looks up the customer that has namespaceId as its ingest alias.
+func (m *Metronome) findCustomerByNamespace(ctx context.Context, namespaceId string) (*biller.CustomerDetail, error) {
+	resp, err := m.client.ListCustomersWithResponse(ctx, &biller.ListCustomersParams{IngestAlias: &namespaceId})
+	if err != nil {
+		return nil, err
+	}
+	if resp.JSON200 == nil {
+		return nil, errors.Internal("metronome failure: %s", resp.Body)
+	}
+	if len(resp.JSON200.Data) == 0 {
+		return nil, nil
+	}
+
+	return &resp.JSON200.Data[0], nil
+}
+
+// getCustomer looks up the customer by its metronome id.
+func (m *Metronome) getCustomer(ctx context.Context, customerId uuid.UUID) (*biller.CustomerDetail, error) {
+	resp, err := m.client.GetCustomerWithResponse(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, nil
 	}
 	if resp.JSON200 == nil {
-		return uuid.Nil, errors.Internal("metronome failure: %s", resp.Body)
+		return nil, errors.Internal("metronome failure: %s", resp.Body)
+	}
+
+	return &resp.JSON200.Data, nil
+}
+
+// currentPlan returns the id of the plan the customer is on right now, or an empty string if there is none.
+func (m *Metronome) currentPlan(ctx context.Context, customerId uuid.UUID) (string, error) {
+	resp, err := m.client.ListCustomerPlansWithResponse(ctx, customerId, &biller.ListCustomerPlansParams{})
+	if err != nil {
+		return "", err
+	}
+	if resp.JSON200 == nil {
+		return "", errors.Internal("metronome failure: %s", resp.Body)
+	}
+
+	now := time.Now().UTC()
+	for _, plan := range resp.JSON200.Data {
+		if plan.StartingOn.After(now) {
+			continue
+		}
+		if plan.EndingBefore != nil && !plan.EndingBefore.After(now) {
+			continue
+		}
+		return plan.PlanId.String(), nil
+	}
+
+	return "", nil
+}
+
+// GetAccount looks up the customer either by its metronome id or by the namespace id that it was created with.
+func (m *Metronome) GetAccount(ctx context.Context, idOrExternalKey string) (*Account, error) {
+	var (
+		customer *biller.CustomerDetail
+		err      error
+	)
+	if customerId, parseErr := uuid.Parse(idOrExternalKey); parseErr == nil {
+		customer, err = m.getCustomer(ctx, customerId)
+	} else {
+		customer, err = m.findCustomerByNamespace(ctx, idOrExternalKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, errors.NotFound("billing account '%s' not found", idOrExternalKey)
 	}
 
-	return resp.JSON200.Data.Id, nil
+	plan, err := m.currentPlan(ctx, customer.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		Id:       AccountID(customer.Id.String()),
+		Plan:     plan,
+		Metadata: customer.CustomFields,
+	}, nil
+}
+
+func (m *Metronome) UpdateAccountMetadata(ctx context.Context, accountId AccountID, metadata map[string]string) (bool, error) {
+	if len(metadata) == 0 {
+		return true, nil
+	}
+
+	customerId, err := toCustomerId(accountId)
+	if err != nil {
+		return false, err
+	}
+
+	body := biller.SetCustomFieldsJSONRequestBody{
+		CustomFields: metadata,
+		Entity:       biller.ManagedEntityCustomer,
+		EntityId:     customerId,
+	}
+
+	resp, err := m.client.SetCustomFieldsWithResponse(ctx, body)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return false, errors.Internal("metronome failure: %s", resp.Body)
+	}
+
+	return true, nil
 }
 
-func (m *Metronome) AddDefaultPlan(ctx context.Context, accountId MetronomeId) (bool, error) {
+// CreateAccounts creates the accounts one by one as metronome doesn't support creating customers in bulk.
+func (m *Metronome) CreateAccounts(ctx context.Context, specs []AccountSpec) ([]AccountID, error) {
+	ids := make([]AccountID, 0, len(specs))
+	for _, spec := range specs {
+		id, err := m.CreateAccount(ctx, spec.NamespaceId, spec.Name, spec.Metadata)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (m *Metronome) AddDefaultPlan(ctx context.Context, accountId AccountID) (bool, error) {
 	planId, err := uuid.Parse(m.Config.DefaultPlan)
 	if err != nil {
 		return false, err
@@ -74,14 +228,19 @@ func (m *Metronome) AddDefaultPlan(ctx context.Context, accountId MetronomeId) (
 	return m.AddPlan(ctx, accountId, planId)
 }
 
-func (m *Metronome) AddPlan(ctx context.Context, accountId MetronomeId, planId uuid.UUID) (bool, error) {
+func (m *Metronome) AddPlan(ctx context.Context, accountId AccountID, planId uuid.UUID) (bool, error) {
+	customerId, err := toCustomerId(accountId)
+	if err != nil {
+		return false, err
+	}
+
 	body := biller.AddPlanToCustomerJSONRequestBody{
 		PlanId: planId,
 		// plans can only start at UTC midnight, so we either +1 or -1 from current day
 		StartingOn: pastMidnight(),
 	}
 
-	resp, err := m.client.AddPlanToCustomerWithResponse(ctx, accountId, body)
+	resp, err := m.client.AddPlanToCustomerWithResponse(ctx, customerId, body)
 	if err != nil {
 		return false, err
 	}
@@ -93,6 +252,20 @@ func (m *Metronome) AddPlan(ctx context.Context, accountId MetronomeId, planId u
 	return true, nil
 }
 
+// Ping lists a single customer, which is the cheapest call that requires a valid api key.
+func (m *Metronome) Ping(ctx context.Context) error {
+	limit := 1
+	resp, err := m.client.ListCustomersWithResponse(ctx, &biller.ListCustomersParams{Limit: &limit})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return errors.Unavailable("metronome failure: %s", resp.Body)
+	}
+
+	return nil
+}
+
 func (m *Metronome) PushUsageEvents(ctx context.Context, events []*UsageEvent) error {
 	var billingEvents []biller.Event
 	for _, se := range events {
@@ -132,6 +305,15 @@ func (m *Metronome) pushBillingEvents(ctx context.Context, events []biller.Event
 	return nil
 }
 
+// toCustomerId converts the account id to the uuid that metronome uses for customers.
+func toCustomerId(accountId AccountID) (uuid.UUID, error) {
+	customerId, err := uuid.Parse(string(accountId))
+	if err != nil {
+		return uuid.Nil, errors.InvalidArgument("invalid metronome account id '%s'", accountId)
+	}
+	return customerId, nil
+}
+
 func pastMidnight() time.Time {
 	now := time.Now().UTC()
 	yyyy, mm, dd := now.Date()
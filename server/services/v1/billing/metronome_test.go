@@ -52,7 +52,32 @@ func TestMetronome_CreateAccount(t *testing.T) {
 				},
 			})
 
-		createdId, err := metronome.CreateAccount(ctx, namespaceId, tenantName)
+		createdId, err := metronome.CreateAccount(ctx, namespaceId, tenantName, nil)
+		require.NoError(t, err)
+		require.Equal(t, "16d145ec-d18e-11ed-afa1-0242ac120002", createdId.String())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("creating account with metadata", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Post("/customers").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": map[string]string{
+					"id": "16d145ec-d18e-11ed-afa1-0242ac120002",
+				},
+			})
+		gock.New(cfg.URL).
+			Post("/customFields/setValues").
+			MatchType("json").
+			JSON(map[string]interface{}{
+				"custom_fields": map[string]string{"region": "us-west-2", "tier": "free"},
+				"entity":        "customer",
+				"entity_id":     "16d145ec-d18e-11ed-afa1-0242ac120002",
+			}).
+			Reply(200)
+
+		createdId, err := metronome.CreateAccount(ctx, "nsId_123", "foo tenant", map[string]string{"region": "us-west-2", "tier": "free"})
 		require.NoError(t, err)
 		require.Equal(t, "16d145ec-d18e-11ed-afa1-0242ac120002", createdId.String())
 		require.True(t, gock.IsDone())
@@ -65,7 +90,7 @@ func TestMetronome_CreateAccount(t *testing.T) {
 				"message": "Unauthorized",
 			})
 
-		createdId, err := metronome.CreateAccount(ctx, "nsId1", "foo_tenant")
+		createdId, err := metronome.CreateAccount(ctx, "nsId1", "foo_tenant", nil)
 		require.ErrorContains(t, err, "Unauthorized")
 		require.Empty(t, createdId)
 		require.True(t, gock.IsDone())
@@ -78,12 +103,268 @@ func TestMetronome_CreateAccount(t *testing.T) {
 			JSON(map[string]string{
 				"message": "ingest alias conflict",
 			})
+		gock.New(cfg.URL).
+			Get("/customers").
+			MatchParam("ingest_alias", "nsId1").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"id": "16d145ec-d18e-11ed-afa1-0242ac120002", "name": "foo_tenant", "ingest_aliases": []string{"nsId1"}},
+				},
+			})
 
-		createdId, err := metronome.CreateAccount(ctx, "nsId1", "foo_tenant")
+		createdId, err := metronome.CreateAccount(ctx, "nsId1", "foo_tenant", nil)
+		require.NoError(t, err)
+		require.Equal(t, "16d145ec-d18e-11ed-afa1-0242ac120002", createdId.String())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("conflict without an existing account", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Post("/customers").
+			Reply(409).
+			JSON(map[string]string{
+				"message": "ingest alias conflict",
+			})
+		gock.New(cfg.URL).
+			Get("/customers").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": []map[string]interface{}{},
+			})
+
+		createdId, err := metronome.CreateAccount(ctx, "nsId1", "foo_tenant", nil)
 		require.ErrorContains(t, err, "ingest alias conflict")
 		require.Empty(t, createdId)
 		require.True(t, gock.IsDone())
 	})
+
+	t.Run("retry with the same namespace returns the same account", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Post("/customers").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": map[string]string{
+					"id": "16d145ec-d18e-11ed-afa1-0242ac120002",
+				},
+			})
+		gock.New(cfg.URL).
+			Post("/customers").
+			Reply(409).
+			JSON(map[string]string{
+				"message": "ingest alias conflict",
+			})
+		gock.New(cfg.URL).
+			Get("/customers").
+			MatchParam("ingest_alias", "nsId_retry").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"id": "16d145ec-d18e-11ed-afa1-0242ac120002", "name": "foo_tenant", "ingest_aliases": []string{"nsId_retry"}},
+				},
+			})
+
+		firstId, err := metronome.CreateAccount(ctx, "nsId_retry", "foo_tenant", nil)
+		require.NoError(t, err)
+		secondId, err := metronome.CreateAccount(ctx, "nsId_retry", "foo_tenant", nil)
+		require.NoError(t, err)
+		require.Equal(t, firstId, secondId)
+		require.True(t, gock.IsDone())
+	})
+}
+
+func TestMetronome_CreateAccounts(t *testing.T) {
+	defer gock.Off()
+	cfg := config.DefaultConfig.Billing.Metronome
+	metronome, err := NewMetronomeProvider(cfg)
+	require.NoError(t, err)
+	ctx := context.TODO()
+
+	specs := []AccountSpec{
+		{NamespaceId: "nsId_1", Name: "tenant 1"},
+		{NamespaceId: "nsId_2", Name: "tenant 2"},
+	}
+
+	t.Run("all accounts are created in order", func(t *testing.T) {
+		expectedIds := []string{"16d145ec-d18e-11ed-afa1-0242ac120002", "26d145ec-d18e-11ed-afa1-0242ac120002"}
+		for i, spec := range specs {
+			gock.New(cfg.URL).
+				Post("/customers").
+				MatchType("json").
+				JSON(map[string]interface{}{
+					"name":           spec.Name,
+					"ingest_aliases": []string{spec.NamespaceId},
+				}).
+				Reply(200).
+				JSON(map[string]interface{}{
+					"data": map[string]string{
+						"id": expectedIds[i],
+					},
+				})
+		}
+
+		createdIds, err := metronome.CreateAccounts(ctx, specs)
+		require.NoError(t, err)
+		require.Len(t, createdIds, 2)
+		require.Equal(t, expectedIds[0], createdIds[0].String())
+		require.Equal(t, expectedIds[1], createdIds[1].String())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Post("/customers").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": map[string]string{
+					"id": "16d145ec-d18e-11ed-afa1-0242ac120002",
+				},
+			})
+		gock.New(cfg.URL).
+			Post("/customers").
+			Reply(409).
+			JSON(map[string]string{
+				"message": "ingest alias conflict",
+			})
+		gock.New(cfg.URL).
+			Get("/customers").
+			MatchParam("ingest_alias", "nsId_2").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": []map[string]interface{}{},
+			})
+
+		createdIds, err := metronome.CreateAccounts(ctx, specs)
+		require.ErrorContains(t, err, "ingest alias conflict")
+		require.Len(t, createdIds, 1)
+		require.Equal(t, "16d145ec-d18e-11ed-afa1-0242ac120002", createdIds[0].String())
+		require.True(t, gock.IsDone())
+	})
+}
+
+func TestMetronome_UpdateAccountMetadata(t *testing.T) {
+	defer gock.Off()
+	cfg := config.DefaultConfig.Billing.Metronome
+	metronome, err := NewMetronomeProvider(cfg)
+	require.NoError(t, err)
+	ctx := context.TODO()
+
+	t.Run("updates custom fields", func(t *testing.T) {
+		metronomeId := AccountID(uuid.NewString())
+		gock.New(cfg.URL).
+			Post("/customFields/setValues").
+			MatchHeader("Authorization", cfg.ApiKey).
+			MatchType("json").
+			JSON(map[string]interface{}{
+				"custom_fields": map[string]string{"tier": "paid"},
+				"entity":        "customer",
+				"entity_id":     metronomeId.String(),
+			}).
+			Reply(200)
+
+		updated, err := metronome.UpdateAccountMetadata(ctx, metronomeId, map[string]string{"tier": "paid"})
+		require.NoError(t, err)
+		require.True(t, updated)
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("unknown custom field key", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Post("/customFields/setValues").
+			Reply(400).
+			JSON(map[string]string{
+				"message": "Invalid custom field key",
+			})
+
+		updated, err := metronome.UpdateAccountMetadata(ctx, AccountID(uuid.NewString()), map[string]string{"foo": "bar"})
+		require.ErrorContains(t, err, "Invalid custom field key")
+		require.False(t, updated)
+		require.True(t, gock.IsDone())
+	})
+}
+
+func TestMetronome_GetAccount(t *testing.T) {
+	defer gock.Off()
+	cfg := config.DefaultConfig.Billing.Metronome
+	metronome, err := NewMetronomeProvider(cfg)
+	require.NoError(t, err)
+	ctx := context.TODO()
+
+	customerId := "16d145ec-d18e-11ed-afa1-0242ac120002"
+	planId := "47eda90f-d2e8-4184-8955-cb3a64677821"
+	customer := map[string]interface{}{
+		"id":             customerId,
+		"name":           "foo_tenant",
+		"ingest_aliases": []string{"nsId1"},
+		"custom_fields":  map[string]string{"tier": "free"},
+	}
+	mockPlans := func() {
+		gock.New(cfg.URL).
+			Get(fmt.Sprintf("/customers/%s/plans", customerId)).
+			Reply(200).
+			JSON(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{
+						"id":            "5a5f9c0e-d18e-11ed-afa1-0242ac120002",
+						"plan_id":       "00000000-0000-0000-0000-000000000001",
+						"plan_name":     "expired",
+						"starting_on":   "2022-01-01T00:00:00Z",
+						"ending_before": "2022-02-01T00:00:00Z",
+					},
+					{
+						"id":          "6a5f9c0e-d18e-11ed-afa1-0242ac120002",
+						"plan_id":     planId,
+						"plan_name":   "default",
+						"starting_on": "2023-01-01T00:00:00Z",
+					},
+				},
+			})
+	}
+
+	t.Run("by account id", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Get(fmt.Sprintf("/customers/%s", customerId)).
+			Reply(200).
+			JSON(map[string]interface{}{"data": customer})
+		mockPlans()
+
+		account, err := metronome.GetAccount(ctx, customerId)
+		require.NoError(t, err)
+		require.Equal(t, &Account{
+			Id:       AccountID(customerId),
+			Plan:     planId,
+			Metadata: map[string]string{"tier": "free"},
+		}, account)
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("by namespace id", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Get("/customers").
+			MatchParam("ingest_alias", "nsId1").
+			Reply(200).
+			JSON(map[string]interface{}{"data": []interface{}{customer}})
+		mockPlans()
+
+		account, err := metronome.GetAccount(ctx, "nsId1")
+		require.NoError(t, err)
+		require.Equal(t, AccountID(customerId), account.Id)
+		require.Equal(t, planId, account.Plan)
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("account does not exist", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Get("/customers").
+			MatchParam("ingest_alias", "nsId2").
+			Reply(200).
+			JSON(map[string]interface{}{"data": []interface{}{}})
+
+		account, err := metronome.GetAccount(ctx, "nsId2")
+		require.ErrorContains(t, err, "billing account 'nsId2' not found")
+		require.Nil(t, account)
+		require.True(t, gock.IsDone())
+	})
 }
 
 func TestMetronome_AddDefaultPlan(t *testing.T) {
@@ -94,7 +375,7 @@ func TestMetronome_AddDefaultPlan(t *testing.T) {
 	ctx := context.TODO()
 
 	t.Run("create new time", func(t *testing.T) {
-		metronomeId := uuid.New()
+		metronomeId := AccountID(uuid.NewString())
 		yyyy, mm, dd := time.Now().UTC().Date()
 		expectedDate := time.Date(yyyy, mm, dd, 0, 0, 0, 0, time.UTC).Format(TimeFormat)
 
@@ -121,7 +402,7 @@ func TestMetronome_AddDefaultPlan(t *testing.T) {
 	})
 
 	t.Run("bad request when no plan exists", func(t *testing.T) {
-		metronomeId := uuid.New()
+		metronomeId := AccountID(uuid.NewString())
 
 		gock.New(cfg.URL).
 			Post(fmt.Sprintf("/customers/%s/plans/add", metronomeId)).
@@ -135,6 +416,44 @@ func TestMetronome_AddDefaultPlan(t *testing.T) {
 		require.False(t, added)
 		require.True(t, gock.IsDone())
 	})
+
+	t.Run("account id is not a metronome id", func(t *testing.T) {
+		added, err := metronome.AddDefaultPlan(ctx, "cus_NffrFeUfNV2Hib")
+		require.ErrorContains(t, err, "invalid metronome account id")
+		require.False(t, added)
+	})
+}
+
+func TestMetronome_Ping(t *testing.T) {
+	defer gock.Off()
+	cfg := config.DefaultConfig.Billing.Metronome
+	metronome, err := NewMetronomeProvider(cfg)
+	require.NoError(t, err)
+	ctx := context.TODO()
+
+	t.Run("reachable", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Get("/customers").
+			MatchHeader("Authorization", cfg.ApiKey).
+			MatchParam("limit", "1").
+			Reply(200).
+			JSON(map[string]interface{}{"data": []interface{}{}})
+
+		require.NoError(t, metronome.Ping(ctx))
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("invalid api key", func(t *testing.T) {
+		gock.New(cfg.URL).
+			Get("/customers").
+			Reply(401).
+			JSON(map[string]string{
+				"message": "Unauthorized",
+			})
+
+		require.ErrorContains(t, metronome.Ping(ctx), "Unauthorized")
+		require.True(t, gock.IsDone())
+	})
 }
 
 func TestMetronome_PushStorageEvents(t *testing.T) {
@@ -21,16 +21,35 @@ import (
 	"github.com/tigrisdata/tigris/errors"
 )
 
+// ErrBillingNotEnabled is returned by all the calls when billing is not enabled on the server.
+var ErrBillingNotEnabled = errors.Unimplemented("billing not enabled on this server")
+
 type noop struct{}
 
-func (n *noop) CreateAccount(_ context.Context, _ string, _ string) (MetronomeId, error) {
-	return uuid.Nil, errors.Unimplemented("billing not enabled on this server")
+func (n *noop) CreateAccount(_ context.Context, _ string, _ string, _ map[string]string) (AccountID, error) {
+	return "", ErrBillingNotEnabled
+}
+
+func (*noop) CreateAccounts(_ context.Context, _ []AccountSpec) ([]AccountID, error) {
+	return nil, ErrBillingNotEnabled
+}
+
+func (*noop) UpdateAccountMetadata(_ context.Context, _ AccountID, _ map[string]string) (bool, error) {
+	return false, ErrBillingNotEnabled
 }
 
-func (n *noop) AddDefaultPlan(ctx context.Context, accountId MetronomeId) (bool, error) {
+func (*noop) GetAccount(_ context.Context, _ string) (*Account, error) {
+	return nil, ErrBillingNotEnabled
+}
+
+func (n *noop) AddDefaultPlan(ctx context.Context, accountId AccountID) (bool, error) {
 	return n.AddPlan(ctx, accountId, uuid.New())
 }
 
-func (*noop) AddPlan(_ context.Context, _ MetronomeId, _ uuid.UUID) (bool, error) {
-	return false, errors.Unimplemented("billing not enabled on this server")
+func (*noop) AddPlan(_ context.Context, _ AccountID, _ uuid.UUID) (bool, error) {
+	return false, ErrBillingNotEnabled
+}
+
+func (*noop) Ping(_ context.Context) error {
+	return ErrBillingNotEnabled
 }
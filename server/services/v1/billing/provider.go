@@ -22,10 +22,49 @@ import (
 	ulog "github.com/tigrisdata/tigris/util/log"
 )
 
+// AccountID identifies an account in the billing provider. It is kept as an opaque string so that providers with
+// non-uuid customer ids can implement the Provider interface.
+type AccountID string
+
+func (id AccountID) String() string {
+	return string(id)
+}
+
+// Account is a billing account as seen by the billing provider.
+type Account struct {
+	Id AccountID
+	// Plan is the id of the plan the account is currently on, empty if the account is not on any plan
+	Plan     string
+	Metadata map[string]string
+}
+
+// AccountSpec describes a billing account to be created.
+type AccountSpec struct {
+	NamespaceId string
+	Name        string
+	// Metadata is optional tenant context like region or plan tier that is attached to the account
+	Metadata map[string]string
+}
+
 type Provider interface {
-	CreateAccount(ctx context.Context, namespaceId string, name string) (MetronomeId, error)
-	AddDefaultPlan(ctx context.Context, accountId MetronomeId) (bool, error)
-	AddPlan(ctx context.Context, accountId MetronomeId, planId uuid.UUID) (bool, error)
+	// CreateAccount creates a billing account for the namespace. The metadata is optional and is attached to the
+	// account when it is not empty. The namespaceId is the idempotency key, retrying the call for a namespace returns
+	// the account that was created by the previous call instead of creating a new one.
+	CreateAccount(ctx context.Context, namespaceId string, name string, metadata map[string]string) (AccountID, error)
+	// CreateAccounts creates an account for each of the specs and returns the ids in the same order as the specs. The
+	// accounts are created in order and the creation stops at the first failure, the returned ids are then only for the
+	// specs that succeeded i.e. specs[:len(ids)] were created and specs[len(ids):] were not.
+	CreateAccounts(ctx context.Context, specs []AccountSpec) ([]AccountID, error)
+	// UpdateAccountMetadata sets the metadata on an existing account, keys that are not part of metadata are left
+	// untouched.
+	UpdateAccountMetadata(ctx context.Context, accountId AccountID, metadata map[string]string) (bool, error)
+	// GetAccount looks up an account either by its id or by the namespace id it was created for. It returns a
+	// NotFound error if there is no such account.
+	GetAccount(ctx context.Context, idOrExternalKey string) (*Account, error)
+	AddDefaultPlan(ctx context.Context, accountId AccountID) (bool, error)
+	AddPlan(ctx context.Context, accountId AccountID, planId uuid.UUID) (bool, error)
+	// Ping checks that the billing backend is reachable and the server is authorized to use it.
+	Ping(ctx context.Context) error
 }
 
 func NewProvider() Provider {
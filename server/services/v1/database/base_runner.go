@@ -132,7 +132,8 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 			return nil, nil, err
 		}
 
-		keyGen := newKeyGenerator(doc, tenant.TableKeyGenerator, coll.GetPrimaryKey())
+		keyGen := newKeyGenerator(doc, tenant.TableKeyGenerator, coll, nil)
+		keyGen.strict = strictAutoGenerate(coll, insert)
 		key, err := keyGen.generate(ctx, runner.txMgr, runner.encoder, coll.EncodedName)
 		if err != nil {
 			return nil, nil, err
@@ -221,6 +222,36 @@ func (runner *BaseQueryRunner) buildKeysUsingFilter(coll *schema.DefaultCollecti
 func (runner *BaseQueryRunner) buildSecondaryIndexKeysUsingFilter(coll *schema.DefaultCollection,
 	reqFilter []byte, collation *value.Collation,
 ) (*filter.QueryPlan, error) {
+	filters, err := runner.secondaryIndexFilters(coll, reqFilter, collation)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSecondaryIndexKeys(coll, filters)
+}
+
+// buildSecondaryIndexPlansUsingFilter is like buildSecondaryIndexKeysUsingFilter, except that a filter with an $or
+// is served by a plan per branch of the $or, which are read as a union.
+func (runner *BaseQueryRunner) buildSecondaryIndexPlansUsingFilter(coll *schema.DefaultCollection,
+	reqFilter []byte, collation *value.Collation,
+) ([]*filter.QueryPlan, error) {
+	filters, err := runner.secondaryIndexFilters(coll, reqFilter, collation)
+	if err != nil {
+		return nil, err
+	}
+	if hasOrFilter(filters) {
+		return BuildSecondaryIndexUnionKeys(coll, filters)
+	}
+
+	queryPlan, err := BuildSecondaryIndexKeys(coll, filters)
+	if err != nil {
+		return nil, err
+	}
+	return []*filter.QueryPlan{queryPlan}, nil
+}
+
+func (runner *BaseQueryRunner) secondaryIndexFilters(coll *schema.DefaultCollection, reqFilter []byte,
+	collation *value.Collation,
+) ([]filter.Filter, error) {
 	if filter.None(reqFilter) {
 		return nil, errors.InvalidArgument("cannot query on an empty filter")
 	}
@@ -230,11 +261,7 @@ func (runner *BaseQueryRunner) buildSecondaryIndexKeysUsingFilter(coll *schema.D
 	}
 
 	filterFactory := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields())
-	filters, err := filterFactory.Factorize(reqFilter)
-	if err != nil {
-		return nil, err
-	}
-	return BuildSecondaryIndexKeys(coll, filters)
+	return filterFactory.Factorize(reqFilter)
 }
 
 func (runner *BaseQueryRunner) mustBeDocumentsCollection(collection *schema.DefaultCollection, method string) error {
@@ -274,10 +301,14 @@ func (runner *BaseQueryRunner) getWriteIterator(ctx context.Context, tx transact
 	reader := NewDatabaseReader(ctx, tx)
 
 	if config.DefaultConfig.SecondaryIndex.MutateEnabled {
-		if skIter, err := runner.getSecondaryWriterIterator(ctx, tx, collection, reqFilter, collation); err == nil {
+		skIter, err := runner.getSecondaryWriterIterator(ctx, tx, collection, reqFilter, collation)
+		if err == nil {
 			metrics.SetWriteType("secondary")
 			return skIter, nil
 		}
+		if err = invalidFilterError(err); err != nil {
+			return nil, err
+		}
 	}
 	if iKeys, err := runner.buildKeysUsingFilter(collection, reqFilter, collation); err == nil {
 		if iterator, err := reader.KeyIterator(iKeys); err == nil {
@@ -319,7 +350,7 @@ func (runner *BaseQueryRunner) getSecondaryWriterIterator(ctx context.Context, t
 	if err != nil {
 		return nil, err
 	}
-	return NewSecondaryIndexReader(ctx, tx, coll, filter.NewWrappedFilter(filters), queryPlan)
+	return NewSecondaryIndexReader(ctx, tx, nil, coll, filter.NewWrappedFilter(filters), queryPlan)
 }
 
 func (runner *BaseQueryRunner) indexToCollectionIndex(all []*schema.Index) []*api.CollectionIndex {
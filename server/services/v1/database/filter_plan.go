@@ -0,0 +1,84 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// FilterPlan describes how the filter of a read is served. It is derived from the options the read is built with,
+// without reading the index or the documents.
+type FilterPlan struct {
+	// ReadType is how the documents are read, one of secondary, pkey, search or full_scan.
+	ReadType string `json:"read_type"`
+	// Field is the indexed field serving the filter, set for a secondary index read.
+	Field string `json:"field,omitempty"`
+	// QueryType is the kind of lookup on the index, one of EQUAL, RANGE, FULLRANGE or UNION.
+	QueryType string `json:"query_type,omitempty"`
+	// DataType is the type of the indexed values being looked up.
+	DataType string `json:"data_type,omitempty"`
+	// FullScan is true if the collection is scanned.
+	FullScan bool `json:"full_scan"`
+	// Reason is why the secondary index doesn't serve the filter.
+	Reason string `json:"reason,omitempty"`
+	// Branches are the plans of the branches of an $or filter, set for a secondary index union read.
+	Branches []*FilterPlan `json:"branches,omitempty"`
+}
+
+const (
+	readTypeSecondary = "secondary"
+	readTypePrimary   = "pkey"
+	readTypeSearch    = "search"
+	readTypeFullScan  = "full_scan"
+
+	// queryTypeUnion is the query type of a read of the union of the plans of its branches.
+	queryTypeUnion = "UNION"
+)
+
+// explainFilterPlan returns the plan of the read built with the options.
+func explainFilterPlan(options readerOptions) *FilterPlan {
+	switch {
+	case options.plan != nil:
+		return newFilterPlan(options.plan)
+	case len(options.orPlans) > 0:
+		plan := &FilterPlan{ReadType: readTypeSecondary, QueryType: queryTypeUnion}
+		for _, branch := range options.orPlans {
+			plan.Branches = append(plan.Branches, newFilterPlan(branch))
+		}
+		return plan
+	case options.inMemoryStore:
+		return &FilterPlan{ReadType: readTypeSearch, Reason: options.indexReason}
+	case len(options.ikeys) > 0:
+		return &FilterPlan{ReadType: readTypePrimary, Reason: options.indexReason}
+	default:
+		return &FilterPlan{ReadType: readTypeFullScan, FullScan: true, Reason: options.indexReason}
+	}
+}
+
+func newFilterPlan(queryPlan *filter.QueryPlan) *FilterPlan {
+	plan := &FilterPlan{
+		ReadType:  readTypeSecondary,
+		QueryType: queryPlan.QueryType.String(),
+		DataType:  schema.FieldNames[queryPlan.DataType],
+	}
+	if len(queryPlan.Keys) > 0 {
+		if parts := queryPlan.Keys[0].IndexParts(); len(parts) > secondaryIndexFieldPos {
+			plan.Field, _ = parts[secondaryIndexFieldPos].(string)
+		}
+	}
+
+	return plan
+}
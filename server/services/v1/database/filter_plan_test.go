@@ -0,0 +1,83 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+)
+
+func TestExplainFilterPlan(t *testing.T) {
+	defer func(index config.SecondaryIndexConfig, search config.SearchConfig) {
+		config.DefaultConfig.SecondaryIndex, config.DefaultConfig.Search = index, search
+	}(config.DefaultConfig.SecondaryIndex, config.DefaultConfig.Search)
+	config.DefaultConfig.SecondaryIndex.ReadEnabled = true
+	config.DefaultConfig.Search.ReadEnabled = false
+
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer", "index": true },
+			"name": { "type": "string", "index": true }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	runner := &BaseQueryRunner{encoder: metadata.NewEncoder()}
+
+	explain := func(reqFilter string, options *api.ReadRequestOptions) *FilterPlan {
+		opts, err := runner.buildReaderOptions(&api.ReadRequest{Filter: []byte(reqFilter), Options: options}, coll)
+		require.NoError(t, err)
+		return explainFilterPlan(opts)
+	}
+
+	require.Equal(t, &FilterPlan{ReadType: "secondary", Field: "name", QueryType: "EQUAL", DataType: "string"},
+		explain(`{"name": "a"}`, nil))
+	require.Equal(t, &FilterPlan{ReadType: "secondary", Field: "id", QueryType: "RANGE", DataType: "int64"},
+		explain(`{"$and": [{"id": {"$gt": 1}}, {"id": {"$lt": 10}}]}`, nil))
+	require.Equal(t, &FilterPlan{ReadType: "secondary", Field: "id", QueryType: "FULLRANGE", DataType: "int64"},
+		explain(`{"id": {"$gte": 1}}`, nil))
+
+	require.Equal(t, &FilterPlan{ReadType: "full_scan", FullScan: true, Reason: "cannot query on an empty filter"},
+		explain(`{}`, nil))
+
+	// every branch of an $or is served by the index
+	require.Equal(t, &FilterPlan{ReadType: "secondary", QueryType: "UNION", Branches: []*FilterPlan{
+		{ReadType: "secondary", Field: "name", QueryType: "EQUAL", DataType: "string"},
+		{ReadType: "secondary", Field: "id", QueryType: "FULLRANGE", DataType: "int64"},
+	}}, explain(`{"$or": [{"name": "a"}, {"id": {"$gt": 5}}]}`, nil))
+
+	// a case-insensitive collation can't use the index, the collection is scanned
+	plan := explain(`{"name": "a"}`, &api.ReadRequestOptions{Collation: &api.Collation{Case: "ci"}})
+	require.True(t, plan.FullScan)
+	require.Equal(t, "secondary indexes do not support case insensitive collation", plan.Reason)
+
+	// the primary key serves the filter when the secondary index can't
+	config.DefaultConfig.SecondaryIndex.ReadEnabled = false
+	require.Equal(t, &FilterPlan{ReadType: "pkey", Reason: "secondary index reads are disabled"},
+		explain(`{"id": 1}`, nil))
+	require.Equal(t, &FilterPlan{ReadType: "full_scan", FullScan: true, Reason: "secondary index reads are disabled"},
+		explain(`{"name": "a"}`, nil))
+
+	// or the search index, if it is enabled
+	config.DefaultConfig.Search.ReadEnabled, config.DefaultConfig.Search.WriteEnabled = true, true
+	require.Equal(t, &FilterPlan{ReadType: "search", Reason: "secondary index reads are disabled"},
+		explain(`{"name": "a"}`, nil))
+}
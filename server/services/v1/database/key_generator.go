@@ -19,73 +19,125 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/buger/jsonparser"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/lib/snowflake"
 	"github.com/tigrisdata/tigris/lib/uuid"
 	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/value"
 )
 
+// snowflakeGenerator generates the int64 values of the snowflake strategy, it is created on first use as the worker
+// id is only known once the configuration is loaded.
+var (
+	snowflakeOnce      sync.Once
+	snowflakeGenerator *snowflake.Generator
+	snowflakeErr       error
+)
+
 var (
 	zeroIntStringSlice  = []byte("0")
 	zeroUUIDStringSlice = []byte(uuid.NullUUID.String())
-	zeroTimeStringSlice = []byte(time.Time{}.Format(time.RFC3339Nano))
 )
 
 // keyGenerator is used to extract the keys from document and return keys.Key which will be used by Insert/Replace API.
 // keyGenerator may need to modify the document in case autoGenerate is set for primary key fields. The keyGenerator
 // makes the copy of the original document in case it needs to modify the document.
 type keyGenerator struct {
-	generator   *metadata.TableKeyGenerator
-	document    []byte
-	keysForResp []byte
-	index       *schema.Index
-	forceInsert bool
+	generator     *metadata.TableKeyGenerator
+	autoGenerator AutoGenerator
+	document      []byte
+	keysForResp   []byte
+	index         *schema.Index
+	forceInsert   bool
+	strategies    schema.AutoGenerateStrategies
+	// ownsDocument is set once the document has been copied, so that it is copied at most once however many fields
+	// are set in it.
+	ownsDocument bool
+	// strict rejects the documents that set a value for an auto-generated field, instead of generating a value if it
+	// is the zero value of its type.
+	strict bool
+}
+
+// AutoGenerator generates the values of the auto-generated primary key fields. Generate returns the unquoted JSON
+// value to set in the document and the value to build the key with.
+type AutoGenerator interface {
+	Generate(ctx context.Context, field *schema.Field) ([]byte, value.Value, error)
 }
 
-func newKeyGenerator(document []byte, generator *metadata.TableKeyGenerator, index *schema.Index) *keyGenerator {
+// newKeyGenerator returns the key generator of the document. The values of the auto-generated fields are generated by
+// autoGenerator, if it is nil they are generated from the strategies configured in the collection.
+func newKeyGenerator(document []byte, generator *metadata.TableKeyGenerator, coll *schema.DefaultCollection, autoGenerator AutoGenerator) *keyGenerator {
 	return &keyGenerator{
-		document:  document,
-		generator: generator,
-		index:     index,
+		document:      document,
+		generator:     generator,
+		autoGenerator: autoGenerator,
+		index:         coll.GetPrimaryKey(),
+		strategies:    coll.AutoGenerateStrategies,
 	}
 }
 
+// strictAutoGenerate returns whether the values set for the auto-generated fields are rejected. Only the inserts are
+// strict, a replace has to set the primary key of the document it replaces, auto-generated fields included.
+func strictAutoGenerate(coll *schema.DefaultCollection, insert bool) bool {
+	return insert && coll.StrictAutoGenerate
+}
+
 func (k *keyGenerator) getKeysForResp() []byte {
 	return []byte(fmt.Sprintf(`{%s}`, k.keysForResp))
 }
 
-// generate method also modifies the JSON document in case of autoGenerate primary key.
+// generate method also modifies the JSON document in case of autoGenerate primary key. In a composite primary key, only
+// the auto-generated fields that are missing or set to the zero value of their type are generated, the other fields
+// are used as they are and must be set.
 func (k *keyGenerator) generate(ctx context.Context, txMgr *transaction.Manager, encoder metadata.Encoder, table []byte) (keys.Key, error) {
+	autoGenerator := k.autoGenerator
+	if autoGenerator == nil {
+		autoGenerator = &strategyAutoGenerator{
+			generator:  k.generator,
+			txMgr:      txMgr,
+			table:      table,
+			strategies: k.strategies,
+		}
+	}
+
 	indexParts := make([]interface{}, 0, len(k.index.Fields))
 	for _, field := range k.index.Fields {
 		jsonVal, dtp, _, err := jsonparser.Get(k.document, field.FieldName)
-		autoGenerate := field.IsAutoGenerated() && (dtp == jsonparser.NotExist ||
-			err == nil && (isNull(field.Type(), jsonVal) || dtp == jsonparser.Null))
-
-		if !autoGenerate && err != nil {
-			return nil, errors.InvalidArgument(fmt.Errorf("missing index key column(s) '%s': %w", field.FieldName, err).Error())
+		if err != nil && dtp != jsonparser.NotExist {
+			return nil, errors.InvalidArgument(fmt.Errorf("invalid index key column '%s': %w", field.FieldName, err).Error())
+		}
+		if k.strict && field.IsAutoGenerated() && dtp != jsonparser.NotExist {
+			return nil, errors.InvalidArgument("auto-generated field '%s' must not be set", field.FieldName)
 		}
+		missing := dtp == jsonparser.NotExist || dtp == jsonparser.Null
 
 		var v value.Value
-		if autoGenerate {
-			if jsonVal, v, err = k.get(ctx, txMgr, table, field); err != nil {
+		switch {
+		case field.IsAutoGenerated() && (missing || isNull(field.Type(), jsonVal)):
+			if jsonVal, v, err = autoGenerator.Generate(ctx, field); err != nil {
 				return nil, err
 			}
 			if err = k.setKeyInDoc(field, jsonVal); err != nil {
 				return nil, err
 			}
-			if field.Type() == schema.Int64Type || field.Type() == schema.DateTimeType {
+			if k.conflictProne(field) {
 				// if we have autogenerated pkey and if it is prone to conflict then force to use Insert API
 				k.forceInsert = true
 			}
-		} else if v, err = value.NewValue(field.Type(), jsonVal); err != nil {
-			return nil, err
+		case missing:
+			return nil, errors.InvalidArgument("missing index key column(s) '%s'", field.FieldName)
+		default:
+			if v, err = value.NewValue(field.Type(), jsonVal); err != nil {
+				return nil, err
+			}
 		}
 
 		k.addKeyToResp(field, jsonVal)
@@ -95,13 +147,29 @@ func (k *keyGenerator) generate(ctx context.Context, txMgr *transaction.Manager,
 	return encoder.EncodeKey(table, k.index, indexParts)
 }
 
+// conflictProne returns true if the values generated for the field may be generated again by another worker, which
+// is the case of the timestamps. The snowflake ids are only unique if every server has a distinct worker id, which
+// is not enforced, so they are conflict prone as well. Only the counters are unique.
+func (k *keyGenerator) conflictProne(field *schema.Field) bool {
+	switch field.Type() {
+	case schema.DateTimeType:
+		return true
+	case schema.Int64Type:
+		return k.strategies.For(field) != schema.AutoGenerateSequence
+	}
+	return false
+}
+
 func (k *keyGenerator) setKeyInDoc(field *schema.Field, jsonVal []byte) error {
 	jsonVal = k.getJsonQuotedValue(field.Type(), jsonVal)
 
 	// as we are mutating the document, do not change original document.
-	tmp := make([]byte, len(k.document))
-	copy(tmp, k.document)
-	k.document = tmp
+	if !k.ownsDocument {
+		tmp := make([]byte, len(k.document))
+		copy(tmp, k.document)
+		k.document = tmp
+		k.ownsDocument = true
+	}
 
 	var err error
 	k.document, err = jsonparser.Set(k.document, jsonVal, field.FieldName)
@@ -139,35 +207,76 @@ func isNull(tp schema.FieldType, val []byte) bool {
 	case schema.UUIDType:
 		return bytes.Equal(val, zeroUUIDStringSlice)
 	case schema.DateTimeType:
-		return bytes.Equal(val, zeroTimeStringSlice)
+		// the zero time is formatted differently depending on the precision, like "0001-01-01T00:00:00.000Z"
+		t, err := time.Parse(time.RFC3339Nano, string(val))
+		return err == nil && t.IsZero()
 	case schema.StringType, schema.ByteType:
 		return len(val) == 0
 	}
 	return false
 }
 
-// get returns generated id for the supported primary key fields. This method returns unquoted JSON values. This is to
-// align with the json library that we are using as that returns unquoted strings as well. It is returning internal
-// value as well so that we don't need to recalculate it from jsonVal.
-func (k *keyGenerator) get(ctx context.Context, txMgr *transaction.Manager, table []byte, field *schema.Field) ([]byte, value.Value, error) {
+// strategyAutoGenerator is the default AutoGenerator, it generates the values using the strategy set on the field or
+// configured in the collection for the type of the field, the default strategy of the type is used otherwise.
+type strategyAutoGenerator struct {
+	generator  *metadata.TableKeyGenerator
+	txMgr      *transaction.Manager
+	table      []byte
+	strategies schema.AutoGenerateStrategies
+}
+
+// Generate returns generated id for the supported primary key fields. This method returns unquoted JSON values. This
+// is to align with the json library that we are using as that returns unquoted strings as well. It is returning
+// internal value as well so that we don't need to recalculate it from jsonVal.
+func (g *strategyAutoGenerator) Generate(ctx context.Context, field *schema.Field) ([]byte, value.Value, error) {
+	strategy := g.strategies.For(field)
+
 	switch field.Type() {
 	case schema.StringType, schema.UUIDType:
-		val := value.NewStringValue(uuid.NewUUIDAsString(), nil)
+		var id string
+		switch strategy {
+		case schema.AutoGenerateUUIDv7:
+			id = uuid.NewV7AsString()
+		case schema.AutoGenerateULID:
+			id = uuid.NewULIDAsString()
+		default:
+			id = uuid.NewUUIDAsString()
+		}
+		val := value.NewStringValue(id, nil)
 		return []byte(val.Value), val, nil
 	case schema.ByteType:
 		val := value.NewBytesValue([]byte(uuid.NewUUIDAsString()))
 		b64 := base64.StdEncoding.EncodeToString(*val)
 		return []byte(b64), val, nil
 	case schema.DateTimeType:
-		// use timestamp nano to reduce the contention if multiple workers end up generating same timestamp.
-		val := value.NewStringValue(time.Now().UTC().Format(time.RFC3339Nano), nil)
+		// use timestamp nano by default to reduce the contention if multiple workers end up generating same timestamp.
+		val := value.NewStringValue(field.DateTimePrecision.Format(time.Now()), nil)
 		return []byte(val.Value), val, nil
 	case schema.Int64Type:
+		if strategy == schema.AutoGenerateSnowflake {
+			id, err := nextSnowflake()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			val := value.NewIntValue(id)
+			return []byte(fmt.Sprintf(`%d`, *val)), val, nil
+		}
+		if strategy == schema.AutoGenerateSequence {
+			valueI32, err := g.generator.GenerateCounter(ctx, g.txMgr, g.table, field.CounterStart(), field.CounterStep())
+			if err != nil {
+				return nil, nil, err
+			}
+
+			val := value.NewIntValue(int64(valueI32))
+			return []byte(fmt.Sprintf(`%d`, *val)), val, nil
+		}
+
 		// use timestamp nano to reduce the contention if multiple workers end up generating same timestamp.
 		val := value.NewIntValue(time.Now().UTC().UnixNano())
 		return []byte(fmt.Sprintf(`%d`, *val)), val, nil
 	case schema.Int32Type:
-		valueI32, err := k.generator.GenerateCounter(ctx, txMgr, table)
+		valueI32, err := g.generator.GenerateCounter(ctx, g.txMgr, g.table, field.CounterStart(), field.CounterStep())
 		if err != nil {
 			return nil, nil, err
 		}
@@ -177,3 +286,14 @@ func (k *keyGenerator) get(ctx context.Context, txMgr *transaction.Manager, tabl
 	}
 	return nil, nil, errors.InvalidArgument("unsupported type found in auto-generator")
 }
+
+func nextSnowflake() (int64, error) {
+	snowflakeOnce.Do(func() {
+		snowflakeGenerator, snowflakeErr = snowflake.NewGenerator(config.DefaultConfig.Schema.AutoGenerateWorkerID)
+	})
+	if snowflakeErr != nil {
+		return 0, errors.Internal("snowflake generator is not configured: %s", snowflakeErr.Error())
+	}
+
+	return snowflakeGenerator.Next(), nil
+}
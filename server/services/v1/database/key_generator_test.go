@@ -0,0 +1,263 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestKeyGeneratorCompositeKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	newCollection := func(reqSchema string) *schema.DefaultCollection {
+		schFactory, err := schema.NewFactoryBuilder(true).Build("t1", []byte(reqSchema))
+		require.NoError(t, err)
+		coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+		require.NoError(t, err)
+		coll.EncodedName = []byte("key_generator_composite")
+		return coll
+	}
+
+	coll := newCollection(`{"title":"t1","properties":{"userId":{"type":"string"},"seq":{"type":"integer","format":"int32","autoGenerate":true}},"primary_key":["userId","seq"]}`)
+	_ = kvStore.DropTable(ctx, []byte("generator"))
+
+	tm := transaction.NewManager(kvStore)
+	generator := metadata.NewTableKeyGenerator()
+	encoder := metadata.NewEncoder()
+
+	t.Run("generate_missing_part", func(t *testing.T) {
+		k := newKeyGenerator([]byte(`{"userId":"u1","name":"a"}`), generator, coll, nil)
+		key, err := k.generate(ctx, tm, encoder, coll.EncodedName)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"u1", int64(1)}, key.IndexParts()[1:])
+		require.JSONEq(t, `{"userId":"u1","name":"a","seq":1}`, string(k.document))
+		require.JSONEq(t, `{"userId":"u1","seq":1}`, string(k.getKeysForResp()))
+		// the counter is unique, the document doesn't need to be inserted instead of replaced
+		require.False(t, k.forceInsert)
+
+		k = newKeyGenerator([]byte(`{"userId":"u2","seq":0}`), generator, coll, nil)
+		key, err = k.generate(ctx, tm, encoder, coll.EncodedName)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"u2", int64(2)}, key.IndexParts()[1:])
+	})
+
+	t.Run("keep_supplied_parts", func(t *testing.T) {
+		doc := []byte(`{"userId":"u1","seq":10}`)
+		k := newKeyGenerator(doc, generator, coll, nil)
+		key, err := k.generate(ctx, tm, encoder, coll.EncodedName)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"u1", int64(10)}, key.IndexParts()[1:])
+		require.Equal(t, doc, k.document)
+		require.False(t, k.forceInsert)
+	})
+
+	t.Run("missing_user_part", func(t *testing.T) {
+		for _, doc := range []string{`{"seq":10}`, `{"userId":null}`} {
+			k := newKeyGenerator([]byte(doc), generator, coll, nil)
+			_, err := k.generate(ctx, tm, encoder, coll.EncodedName)
+			require.Equal(t, errors.InvalidArgument("missing index key column(s) '%s'", "userId"), err)
+		}
+	})
+
+	t.Run("conflict_prone_part", func(t *testing.T) {
+		coll := newCollection(`{"title":"t1","properties":{"userId":{"type":"string"},"created":{"type":"integer","autoGenerate":true}},"primary_key":["userId","created"]}`)
+
+		k := newKeyGenerator([]byte(`{"userId":"u1"}`), generator, coll, nil)
+		_, err := k.generate(ctx, tm, encoder, coll.EncodedName)
+		require.NoError(t, err)
+		require.True(t, k.forceInsert)
+
+		// the supplied timestamp is not generated, so it doesn't force the insert
+		k = newKeyGenerator([]byte(`{"userId":"u1","created":5}`), generator, coll, nil)
+		_, err = k.generate(ctx, tm, encoder, coll.EncodedName)
+		require.NoError(t, err)
+		require.False(t, k.forceInsert)
+	})
+
+	t.Run("snowflake_part", func(t *testing.T) {
+		coll := newCollection(`{"title":"t1","properties":{"userId":{"type":"string"},"id":{"type":"integer","autoGenerate":true,"autoGenerateStrategy":"snowflake"}},"primary_key":["userId","id"]}`)
+
+		// two servers sharing a worker id generate the same ids, a duplicate must not replace the other document
+		k := newKeyGenerator([]byte(`{"userId":"u1"}`), generator, coll, nil)
+		_, err := k.generate(ctx, tm, encoder, coll.EncodedName)
+		require.NoError(t, err)
+		require.True(t, k.forceInsert)
+	})
+}
+
+type sequenceAutoGenerator struct {
+	next int64
+}
+
+func (g *sequenceAutoGenerator) Generate(_ context.Context, field *schema.Field) ([]byte, value.Value, error) {
+	g.next++
+	val := value.NewStringValue(fmt.Sprintf("%s-%d", field.FieldName, g.next), nil)
+	return []byte(val.Value), val, nil
+}
+
+func TestKeyGeneratorCustomAutoGenerator(t *testing.T) {
+	schFactory, err := schema.NewFactoryBuilder(true).Build("t1", []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true}},"primary_key":["id"]}`))
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	autoGenerator := &sequenceAutoGenerator{}
+	for _, expected := range []string{"id-1", "id-2"} {
+		// neither the transaction manager nor the counters are needed by a custom generator
+		k := newKeyGenerator([]byte(`{"name":"a"}`), nil, coll, autoGenerator)
+		key, err := k.generate(context.Background(), nil, metadata.NewEncoder(), []byte("t1"))
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{expected}, key.IndexParts()[1:])
+		require.JSONEq(t, `{"name":"a","id":"`+expected+`"}`, string(k.document))
+		require.JSONEq(t, `{"id":"`+expected+`"}`, string(k.getKeysForResp()))
+	}
+}
+
+func TestKeyGeneratorStrict(t *testing.T) {
+	schFactory, err := schema.NewFactoryBuilder(true).Build("t1", []byte(`{"title":"t1","properties":{"id":{"type":"string","autoGenerate":true}},"primary_key":["id"],"strict_auto_generate":true}`))
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+	require.True(t, coll.StrictAutoGenerate)
+
+	generate := func(doc string, strict bool) (string, error) {
+		k := newKeyGenerator([]byte(doc), nil, coll, &sequenceAutoGenerator{})
+		k.strict = strict
+		if _, err := k.generate(context.Background(), nil, metadata.NewEncoder(), []byte("t1")); err != nil {
+			return "", err
+		}
+		return string(k.getKeysForResp()), nil
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		for doc, expected := range map[string]string{
+			`{"name":"a"}`:              `{"id":"id-1"}`,
+			`{"name":"a","id":""}`:      `{"id":"id-1"}`,
+			`{"name":"a","id":null}`:    `{"id":"id-1"}`,
+			`{"name":"a","id":"given"}`: `{"id":"given"}`,
+		} {
+			keys, err := generate(doc, false)
+			require.NoError(t, err)
+			require.JSONEq(t, expected, keys)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		keys, err := generate(`{"name":"a"}`, strictAutoGenerate(coll, true))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":"id-1"}`, keys)
+
+		for _, doc := range []string{`{"name":"a","id":""}`, `{"name":"a","id":null}`, `{"name":"a","id":"given"}`} {
+			_, err = generate(doc, strictAutoGenerate(coll, true))
+			require.Equal(t, errors.InvalidArgument("auto-generated field '%s' must not be set", "id"), err)
+		}
+	})
+
+	// a replace is not strict, it sets the key of the replaced document which can be any value
+	t.Run("replace", func(t *testing.T) {
+		require.False(t, strictAutoGenerate(coll, false))
+
+		keys, err := generate(`{"name":"a","id":"given"}`, strictAutoGenerate(coll, false))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":"given"}`, keys)
+	})
+}
+
+func TestKeyGeneratorKeepsOriginalDocument(t *testing.T) {
+	schFactory, err := schema.NewFactoryBuilder(true).Build("t1", []byte(`{"title":"t1","properties":{"a":{"type":"string","autoGenerate":true},"b":{"type":"string","autoGenerate":true}},"primary_key":["a","b"]}`))
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	doc := []byte(`{"name":"x","a":"","b":""}`)
+	k := newKeyGenerator(doc, nil, coll, &sequenceAutoGenerator{})
+	_, err = k.generate(context.Background(), nil, metadata.NewEncoder(), []byte("t1"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"x","a":"a-1","b":"b-2"}`, string(k.document))
+	require.Equal(t, `{"name":"x","a":"","b":""}`, string(doc))
+}
+
+func BenchmarkKeyGeneratorCompositeKey(b *testing.B) {
+	schFactory, err := schema.NewFactoryBuilder(true).Build("t1", []byte(`{"title":"t1","properties":{"a":{"type":"string","autoGenerate":true},"b":{"type":"string","autoGenerate":true},"data":{"type":"string"}},"primary_key":["a","b"]}`))
+	require.NoError(b, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(b, err)
+
+	doc := []byte(`{"data":"` + strings.Repeat("x", 1024*1024) + `"}`)
+	encoder := metadata.NewEncoder()
+	autoGenerator := &sequenceAutoGenerator{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := newKeyGenerator(doc, nil, coll, autoGenerator)
+		if _, err = k.generate(context.Background(), nil, encoder, []byte("t1")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestKeyGeneratorDateTimePrecision(t *testing.T) {
+	for precision, fraction := range map[string]int{"seconds": 0, "millis": 3, "micros": 6} {
+		schFactory, err := schema.NewFactoryBuilder(true).Build("t1", []byte(`{"title":"t1","properties":{"created":{"type":"string","format":"date-time","autoGenerate":true,"autoGeneratePrecision":"`+precision+`"}},"primary_key":["created"]}`))
+		require.NoError(t, err)
+		coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+		require.NoError(t, err)
+
+		k := newKeyGenerator([]byte(`{}`), nil, coll, nil)
+		key, err := k.generate(context.Background(), nil, metadata.NewEncoder(), []byte("t1"))
+		require.NoError(t, err)
+
+		generated, ok := key.IndexParts()[1].(string)
+		require.True(t, ok)
+		// "2006-01-02T15:04:05" and "Z", with the fractional seconds of the precision
+		expectedLen := 20
+		if fraction > 0 {
+			expectedLen += 1 + fraction
+		}
+		require.Len(t, generated, expectedLen, precision)
+
+		v, err := value.NewValue(schema.DateTimeType, []byte(generated))
+		require.NoError(t, err)
+		require.Equal(t, generated, v.AsInterface())
+		_, err = time.Parse(time.RFC3339Nano, generated)
+		require.NoError(t, err)
+	}
+
+	_, err := schema.NewFactoryBuilder(true).Build("t1", []byte(`{"title":"t1","properties":{"created":{"type":"string","format":"date-time","autoGenerate":true,"autoGeneratePrecision":"days"}},"primary_key":["created"]}`))
+	require.Error(t, err)
+}
+
+func TestIsNullDateTime(t *testing.T) {
+	for _, p := range []schema.DateTimePrecision{
+		schema.DateTimePrecisionDefault, schema.DateTimePrecisionSeconds, schema.DateTimePrecisionMillis,
+		schema.DateTimePrecisionMicros, schema.DateTimePrecisionNanos,
+	} {
+		require.True(t, isNull(schema.DateTimeType, []byte(p.Format(time.Time{}))), p)
+		require.False(t, isNull(schema.DateTimeType, []byte(p.Format(time.Now()))), p)
+	}
+}
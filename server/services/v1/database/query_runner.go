@@ -264,7 +264,7 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		newKey := key
 		if primaryKeyMutation {
 			// we need to deleteReq old key and build new key from new data
-			keyGen := newKeyGenerator(newData.RawData, tenant.TableKeyGenerator, coll.GetPrimaryKey())
+			keyGen := newKeyGenerator(newData.RawData, tenant.TableKeyGenerator, coll, nil)
 			if newKey, err = keyGen.generate(ctx, runner.txMgr, runner.encoder, coll.EncodedName); err != nil {
 				return Response{}, nil, err
 			}
@@ -412,6 +412,15 @@ type readerOptions struct {
 	sorting      *sort.Ordering
 	filter       *filter.WrappedFilter
 	fieldFactory *read.FieldFactory
+	// orPlans are the plans of the branches of an $or filter served by the secondary index, they are read as a union.
+	orPlans []*filter.QueryPlan
+	// indexReason is why the secondary index doesn't serve the read, if it doesn't.
+	indexReason string
+}
+
+// secondaryIndexRead returns true if the read is served by the secondary index.
+func (options readerOptions) secondaryIndexRead() bool {
+	return options.plan != nil || len(options.orPlans) > 0
 }
 
 func (runner *BaseQueryRunner) buildReaderOptions(req *api.ReadRequest, collection *schema.DefaultCollection) (readerOptions, error) {
@@ -438,11 +447,22 @@ func (runner *BaseQueryRunner) buildReaderOptions(req *api.ReadRequest, collecti
 		}
 	}
 
+	options.indexReason = "secondary index reads are disabled"
 	if config.DefaultConfig.SecondaryIndex.ReadEnabled {
-		if queryPlan, err := runner.buildSecondaryIndexKeysUsingFilter(collection, req.Filter, collation); err == nil {
-			options.plan = queryPlan
+		queryPlans, err := runner.buildSecondaryIndexPlansUsingFilter(collection, req.Filter, collation)
+		if err == nil {
+			if len(queryPlans) == 1 {
+				options.plan = queryPlans[0]
+			} else {
+				options.orPlans = queryPlans
+			}
+			options.indexReason = ""
 			return options, nil
 		}
+		if invalidErr := invalidFilterError(err); invalidErr != nil {
+			return options, invalidErr
+		}
+		options.indexReason = err.Error()
 	}
 
 	if options.filter.None() || !options.filter.IsSearchIndexed() {
@@ -469,7 +489,7 @@ func (runner *BaseQueryRunner) buildReaderOptions(req *api.ReadRequest, collecti
 func (runner *StreamingQueryRunner) instrumentRunner(ctx context.Context, options readerOptions) context.Context {
 	// Set read type
 	//nolint:gocritic
-	if options.plan != nil {
+	if options.secondaryIndexRead() {
 		runner.queryMetrics.SetReadType("secondary")
 	} else if options.ikeys == nil {
 		runner.queryMetrics.SetReadType("non-pkey")
@@ -522,8 +542,8 @@ func (runner *StreamingQueryRunner) ReadOnly(ctx context.Context, tenant *metada
 		}
 
 		var last []byte
-		if options.plan != nil {
-			last, err = runner.iterateOnSecondaryIndexStore(ctx, tx, collection, options)
+		if options.secondaryIndexRead() {
+			last, err = runner.iterateOnSecondaryIndexStore(ctx, tx, runner.txMgr, collection, options)
 		} else {
 			last, err = runner.iterateOnKvStore(ctx, tx, collection, options)
 		}
@@ -571,8 +591,8 @@ func (runner *StreamingQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 		}
 		return Response{}, ctx, nil
 	} else {
-		if options.plan != nil {
-			if _, err = runner.iterateOnSecondaryIndexStore(ctx, tx, coll, options); err != nil {
+		if options.secondaryIndexRead() {
+			if _, err = runner.iterateOnSecondaryIndexStore(ctx, tx, nil, coll, options); err != nil {
 				return Response{}, ctx, createApiError(err)
 			}
 			return Response{}, ctx, nil
@@ -606,13 +626,33 @@ func (runner *StreamingQueryRunner) iterateOnKvStore(ctx context.Context, tx tra
 	return runner.iterate(ctx, coll, iter, options.fieldFactory)
 }
 
-func (runner *StreamingQueryRunner) iterateOnSecondaryIndexStore(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, options readerOptions) ([]byte, error) {
-	iter, err := NewSecondaryIndexReader(ctx, tx, coll, options.filter, options.plan)
+func (runner *StreamingQueryRunner) iterateOnSecondaryIndexStore(ctx context.Context, tx transaction.Tx, txMgr *transaction.Manager, coll *schema.DefaultCollection, options readerOptions) ([]byte, error) {
+	iter, err := newSecondaryIndexIterator(ctx, tx, txMgr, coll, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.iterate(ctx, coll, iter, options.fieldFactory)
+}
+
+// newSecondaryIndexIterator returns the documents of the secondary index read matching the filter of the read. With a
+// txMgr, the scan of a single plan is restarted on a new transaction after a transient error. The plans of a union
+// are always read in the same transaction, as they share its read version.
+func newSecondaryIndexIterator(ctx context.Context, tx transaction.Tx, txMgr *transaction.Manager, coll *schema.DefaultCollection, options readerOptions) (Iterator, error) {
+	var (
+		iter Iterator
+		err  error
+	)
+	if len(options.orPlans) > 0 {
+		iter, err = newSecondaryIndexUnionReader(ctx, tx, coll, options.filter, options.orPlans)
+	} else {
+		iter, err = NewSecondaryIndexReader(ctx, tx, txMgr, coll, options.filter, options.plan)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return runner.iterate(ctx, coll, NewFilterIterator(iter, options.filter), options.fieldFactory)
+	return NewFilterIterator(iter, options.filter), nil
 }
 
 func (runner *StreamingQueryRunner) iterateOnSearchStore(ctx context.Context, coll *schema.DefaultCollection, options readerOptions) error {
@@ -761,7 +801,13 @@ func (runner *StreamingQueryRunner) writeTimestamp(buf *bytes.Buffer, key string
 type ExplainQueryRunner struct {
 	*BaseQueryRunner
 
-	req *api.ReadRequest
+	req  *api.ReadRequest
+	plan *FilterPlan
+}
+
+// Plan returns how the secondary index would serve the filter of the request, once the runner has been executed.
+func (runner *ExplainQueryRunner) Plan() *FilterPlan {
+	return runner.plan
 }
 
 func (runner *ExplainQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
@@ -782,6 +828,8 @@ func (runner *ExplainQueryRunner) Run(ctx context.Context, tx transaction.Tx, te
 		return Response{}, ctx, err
 	}
 
+	runner.plan = explainFilterPlan(options)
+
 	return Response{
 		Response: buildExplainResp(options, collection, runner.req.Filter),
 	}, ctx, nil
@@ -797,7 +845,7 @@ func buildExplainResp(options readerOptions, coll *schema.DefaultCollection, fil
 		Collection: coll.Name,
 		Filter:     string(filter),
 	}
-	if options.plan != nil {
+	if options.secondaryIndexRead() {
 		explain.ReadType = SECONDARY
 		return explain
 	}
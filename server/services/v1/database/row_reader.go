@@ -38,44 +38,58 @@ type Iterator interface {
 	Interrupted() error
 }
 
-type ScanIterator struct {
-	ctx context.Context
+// RowIterator adapts the key-value iterator returned by the kv layer to the Iterator of the database layer. The
+// rows are keyed by the FDB key and hold the decoded table data.
+type RowIterator struct {
 	it  kv.Iterator
 	err error
 }
 
-func NewScanIterator(ctx context.Context, tx transaction.Tx, from keys.Key, to keys.Key) (*ScanIterator, error) {
-	it, err := tx.ReadRange(ctx, from, to, false)
-	if ulog.E(err) {
-		return nil, err
-	}
-
-	return &ScanIterator{
-		ctx: ctx,
-		it:  it,
-	}, nil
+func NewRowIterator(it kv.Iterator) *RowIterator {
+	return &RowIterator{it: it}
 }
 
-func (s *ScanIterator) Next(row *Row) bool {
-	if s.err != nil {
+func (r *RowIterator) Next(row *Row) bool {
+	if r.err != nil {
 		return false
 	}
 
 	var keyValue kv.KeyValue
-	if s.it.Next(&keyValue) {
+	if r.it.Next(&keyValue) {
 		row.Key = keyValue.FDBKey
 		row.Data = keyValue.Data
 		return true
 	}
-	s.err = s.it.Err()
+	r.err = r.it.Err()
 
 	return false
 }
 
-func (s *ScanIterator) Interrupted() error { return s.err }
+func (r *RowIterator) Interrupted() error { return r.err }
+
+type ScanIterator struct {
+	ctx context.Context
+	it  *RowIterator
+}
+
+func NewScanIterator(ctx context.Context, tx transaction.Tx, from keys.Key, to keys.Key) (*ScanIterator, error) {
+	it, err := tx.ReadRange(ctx, from, to, false)
+	if ulog.E(err) {
+		return nil, err
+	}
+
+	return &ScanIterator{
+		ctx: ctx,
+		it:  NewRowIterator(it),
+	}, nil
+}
+
+func (s *ScanIterator) Next(row *Row) bool { return s.it.Next(row) }
+
+func (s *ScanIterator) Interrupted() error { return s.it.Interrupted() }
 
 type KeyIterator struct {
-	it    kv.Iterator
+	it    *RowIterator
 	tx    transaction.Tx
 	ctx   context.Context
 	keys  []keys.Key
@@ -92,7 +106,7 @@ func NewKeyIterator(ctx context.Context, tx transaction.Tx, keys []keys.Key) (*K
 
 	return &KeyIterator{
 		tx:    tx,
-		it:    it,
+		it:    NewRowIterator(it),
 		ctx:   ctx,
 		keys:  keys,
 		keyId: keyId,
@@ -105,14 +119,11 @@ func (k *KeyIterator) Next(row *Row) bool {
 	}
 
 	for {
-		var keyValue kv.KeyValue
-		if k.it.Next(&keyValue) {
-			row.Key = keyValue.FDBKey
-			row.Data = keyValue.Data
+		if k.it.Next(row) {
 			return true
 		}
 
-		if k.err = k.it.Err(); k.err != nil {
+		if k.err = k.it.Interrupted(); k.err != nil {
 			return false
 		}
 
@@ -121,7 +132,11 @@ func (k *KeyIterator) Next(row *Row) bool {
 			return false
 		}
 
-		k.it, k.err = k.tx.Read(k.ctx, k.keys[k.keyId])
+		var it kv.Iterator
+		if it, k.err = k.tx.Read(k.ctx, k.keys[k.keyId]); k.err != nil {
+			return false
+		}
+		k.it = NewRowIterator(it)
 	}
 }
 
@@ -0,0 +1,72 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// sliceKVIterator is an in-memory kv.Iterator that fails with err once the values are exhausted.
+type sliceKVIterator struct {
+	values []kv.KeyValue
+	err    error
+}
+
+func (s *sliceKVIterator) Next(value *kv.KeyValue) bool {
+	if len(s.values) == 0 {
+		return false
+	}
+
+	*value = s.values[0]
+	s.values = s.values[1:]
+
+	return true
+}
+
+func (s *sliceKVIterator) Err() error { return s.err }
+
+func TestRowIterator(t *testing.T) {
+	t.Run("rows", func(t *testing.T) {
+		data1 := internal.NewTableData([]byte(`{"a":1}`))
+		data2 := internal.NewTableData([]byte(`{"a":2}`))
+		it := NewRowIterator(&sliceKVIterator{values: []kv.KeyValue{
+			{FDBKey: []byte("k1"), Data: data1},
+			{FDBKey: []byte("k2"), Data: data2},
+		}})
+
+		var row Row
+		require.True(t, it.Next(&row))
+		require.Equal(t, Row{Key: []byte("k1"), Data: data1}, row)
+		require.True(t, it.Next(&row))
+		require.Equal(t, Row{Key: []byte("k2"), Data: data2}, row)
+		require.False(t, it.Next(&row))
+		require.NoError(t, it.Interrupted())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		it := NewRowIterator(&sliceKVIterator{err: fmt.Errorf("read failed")})
+
+		var row Row
+		require.False(t, it.Next(&row))
+		require.Equal(t, fmt.Errorf("read failed"), it.Interrupted())
+		require.False(t, it.Next(&row))
+		require.Equal(t, Row{}, row)
+	})
+}
@@ -172,7 +172,9 @@ func PackSearchFields(ctx context.Context, data *internal.TableData, collection
 		}
 	}
 
-	decData = util.FlatMap(decData, doNotFlatten)
+	if decData, err = util.FlatMap(decData, doNotFlatten); err != nil {
+		return nil, errors.InvalidArgument("%s", err.Error())
+	}
 
 	keysToRemove := ctx.Value(TentativeSearchKeysToRemove{})
 	if keysToRemove != nil {
@@ -334,7 +336,10 @@ func UnpackSearchFields(doc map[string]interface{}, collection *schema.DefaultCo
 	}
 
 	// unFlatten the map now
-	doc = util.UnFlatMap(doc)
+	doc, err := util.UnFlatMap(doc)
+	if err != nil {
+		return "", nil, nil, errors.InvalidArgument("%s", err.Error())
+	}
 
 	searchKey := doc[schema.SearchId].(string)
 	if value, ok := doc[schema.ReservedFields[schema.IdToSearchKey]]; ok {
@@ -37,12 +37,15 @@ func TestFlattenObj(t *testing.T) {
 	var UnFlattenMap map[string]any
 	require.NoError(t, jsoniter.Unmarshal(rawData, &UnFlattenMap))
 
-	flattened := util.FlatMap(UnFlattenMap, container.NewHashSet())
+	flattened, err := util.FlatMap(UnFlattenMap, container.NewHashSet())
+	require.NoError(t, err)
 	require.Equal(t, "foo", flattened["b.c.d"])
 	require.Equal(t, float64(3), flattened["b.e"])
 	require.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, flattened["b.f"])
 
-	require.True(t, reflect.DeepEqual(UnFlattenMap, util.UnFlatMap(flattened)))
+	unFlattened, err := util.UnFlatMap(flattened)
+	require.NoError(t, err)
+	require.True(t, reflect.DeepEqual(UnFlattenMap, unFlattened))
 }
 
 func TestPackSearchFields(t *testing.T) {
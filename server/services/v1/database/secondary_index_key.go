@@ -0,0 +1,141 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// The positions of the parts of a secondary index entry, after the table prefix:
+//
+//	<keyword> "kvs" <field> <type order> <value> <array position> <primary key>...
+const (
+	secondaryIndexKeywordPos = iota
+	secondaryIndexSubspacePos
+	secondaryIndexFieldPos
+	secondaryIndexTypeOrderPos
+	secondaryIndexValuePos
+	secondaryIndexArrayPos
+	secondaryIndexPrimaryKeyPos
+)
+
+// SecondaryIndexEntry is a decoded key of the secondary index of a collection.
+type SecondaryIndexEntry struct {
+	Field string
+	// TypeOrder groups the entries of the field by the type of their values, a field holding values of different
+	// types across the documents has the values of every type ordered separately.
+	TypeOrder int
+	Value     interface{}
+	// ArrayPos is the position of the value in an array field, zero for a field that is not an array.
+	ArrayPos   int
+	PrimaryKey []interface{}
+}
+
+// secondaryIndexLayout builds and decodes the keys of the secondary index of a collection. It is the only place
+// that knows the order of the parts of an index key, so the indexer and the reader can't disagree on it.
+type secondaryIndexLayout struct {
+	table   []byte
+	keyword string
+}
+
+func newSecondaryIndexLayout(coll *schema.DefaultCollection) secondaryIndexLayout {
+	return secondaryIndexLayout{
+		table:   coll.EncodedTableIndexName,
+		keyword: coll.SecondaryIndexKeyword(),
+	}
+}
+
+// Prefix returns the key of the index entries starting with the parts, which are in the order of the layout starting
+// at the field. Without parts, it is the start of the index.
+func (l secondaryIndexLayout) Prefix(parts ...interface{}) keys.Key {
+	indexParts := make([]interface{}, 0, secondaryIndexFieldPos+len(parts))
+	indexParts = append(indexParts, l.keyword, KVSubspace)
+	indexParts = append(indexParts, parts...)
+
+	return keys.NewKey(l.table, indexParts...)
+}
+
+// prefixRangeEnd is the part appended to a prefix to build the end of its range. The tuple layer orders the parts by
+// their type before their value and a UUID comes after all the types an index key is made of, so a UUID with every
+// bit set comes after any part that can follow the prefix, whatever the position of the prefix in the layout.
+var prefixRangeEnd = tuple.UUID{
+	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+}
+
+// PrefixRange returns the range [start, end) of the index entries starting with the parts, which are in the order of
+// the layout starting at the field. For example, the parts field, type order and value match all the entries of a
+// value, whatever their array position and primary key, which can itself be made of several fields.
+func (l secondaryIndexLayout) PrefixRange(parts ...interface{}) (keys.Key, keys.Key) {
+	endParts := make([]interface{}, 0, len(parts)+1)
+	endParts = append(endParts, parts...)
+	endParts = append(endParts, prefixRangeEnd)
+
+	return l.Prefix(parts...), l.Prefix(endParts...)
+}
+
+// End returns the key just after the last entry of the index.
+func (l secondaryIndexLayout) End() keys.Key {
+	return l.Prefix(0xFF)
+}
+
+// Encode returns the key of the index entry.
+func (l secondaryIndexLayout) Encode(entry *SecondaryIndexEntry) keys.Key {
+	parts := make([]interface{}, 0, secondaryIndexPrimaryKeyPos-secondaryIndexFieldPos+len(entry.PrimaryKey))
+	parts = append(parts, entry.Field, entry.TypeOrder, entry.Value, entry.ArrayPos)
+	parts = append(parts, entry.PrimaryKey...)
+
+	return l.Prefix(parts...)
+}
+
+// Decode returns the index entry stored under the binary key.
+func (l secondaryIndexLayout) Decode(fdbKey []byte) (*SecondaryIndexEntry, error) {
+	key, err := keys.FromBinary(l.table, fdbKey)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := key.IndexParts()
+	if len(parts) <= secondaryIndexPrimaryKeyPos || parts[secondaryIndexKeywordPos] != l.keyword ||
+		parts[secondaryIndexSubspacePos] != KVSubspace {
+		return nil, errors.Internal("unexpected secondary index key layout '%v'", parts)
+	}
+
+	field, ok := parts[secondaryIndexFieldPos].(string)
+	if !ok {
+		return nil, errors.Internal("unexpected secondary index field '%v'", parts[secondaryIndexFieldPos])
+	}
+
+	return &SecondaryIndexEntry{
+		Field:      field,
+		TypeOrder:  indexPartToInt(parts[secondaryIndexTypeOrderPos]),
+		Value:      parts[secondaryIndexValuePos],
+		ArrayPos:   indexPartToInt(parts[secondaryIndexArrayPos]),
+		PrimaryKey: parts[secondaryIndexPrimaryKeyPos:],
+	}, nil
+}
+
+// indexPartToInt converts an integer part of a key, which is an int when the key is built and an int64 once decoded.
+func indexPartToInt(part interface{}) int {
+	switch v := part.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
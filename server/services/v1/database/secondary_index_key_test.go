@@ -0,0 +1,98 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestSecondaryIndexLayout(t *testing.T) {
+	layout := secondaryIndexLayout{table: []byte("t1"), keyword: "skey"}
+
+	entry := &SecondaryIndexEntry{
+		Field:      "address.city",
+		TypeOrder:  value.ToSecondaryOrder(schema.StringType, nil),
+		Value:      "NYC",
+		ArrayPos:   2,
+		PrimaryKey: []interface{}{int64(10), "a"},
+	}
+
+	key := layout.Encode(entry)
+	require.Equal(t, []interface{}{"skey", KVSubspace, "address.city", entry.TypeOrder, "NYC", 2, int64(10), "a"}, key.IndexParts())
+
+	decoded, err := layout.Decode(key.SerializeToBytes())
+	require.NoError(t, err)
+	require.Equal(t, entry, decoded)
+
+	require.Equal(t, []interface{}{"skey", KVSubspace, "address.city"}, layout.Prefix("address.city").IndexParts())
+	require.Equal(t, []interface{}{"skey", KVSubspace, 0xFF}, layout.End().IndexParts())
+
+	_, err = layout.Decode(layout.Prefix("address.city", entry.TypeOrder, "NYC").SerializeToBytes())
+	require.Error(t, err)
+
+	_, err = layout.Decode(keys.NewKey([]byte("t1"), "other", KVSubspace, "f", 1, "v", 0, 1).SerializeToBytes())
+	require.Error(t, err)
+}
+
+func TestSecondaryIndexPrefixRange(t *testing.T) {
+	layout := secondaryIndexLayout{table: []byte("t1"), keyword: "skey"}
+	typeOrder := value.ToSecondaryOrder(schema.StringType, nil)
+
+	inRange := func(start keys.Key, end keys.Key, key keys.Key) bool {
+		return start.CompareBytes(key.SerializeToBytes()) <= 0 && end.CompareBytes(key.SerializeToBytes()) > 0
+	}
+
+	// the collection has a composite primary key (id, created_at), the query is only on the status
+	start, end := layout.PrefixRange("status", typeOrder, "active")
+	require.Equal(t, []interface{}{"skey", KVSubspace, "status", typeOrder, "active"}, start.IndexParts())
+	require.Equal(t, []interface{}{"skey", KVSubspace, "status", typeOrder, "active", prefixRangeEnd}, end.IndexParts())
+
+	for _, entry := range []*SecondaryIndexEntry{
+		{Field: "status", TypeOrder: typeOrder, Value: "active", PrimaryKey: []interface{}{int64(1), "2023-01-01T00:00:00Z"}},
+		// a position that doesn't fit in a byte is encoded on more bytes
+		{Field: "status", TypeOrder: typeOrder, Value: "active", ArrayPos: 300, PrimaryKey: []interface{}{int64(2), "2023-01-02T00:00:00Z"}},
+	} {
+		key := layout.Encode(entry)
+		require.True(t, inRange(start, end, key))
+
+		decoded, err := layout.Decode(key.SerializeToBytes())
+		require.NoError(t, err)
+		require.Equal(t, entry, decoded)
+	}
+
+	for _, entry := range []*SecondaryIndexEntry{
+		// a value that the queried one is a prefix of is not part of the range
+		{Field: "status", TypeOrder: typeOrder, Value: "activated", PrimaryKey: []interface{}{int64(3), "2023-01-03T00:00:00Z"}},
+		{Field: "status", TypeOrder: typeOrder, Value: "inactive", PrimaryKey: []interface{}{int64(4), "2023-01-04T00:00:00Z"}},
+	} {
+		require.False(t, inRange(start, end, layout.Encode(entry)))
+	}
+
+	// the part following a shorter prefix, the value, can be of any type
+	start, end = layout.PrefixRange("status", typeOrder)
+	for _, v := range []interface{}{nil, []byte("a"), "a", int64(-1), int64(1 << 40), 1.5, true} {
+		require.True(t, inRange(start, end, layout.Encode(&SecondaryIndexEntry{Field: "status", TypeOrder: typeOrder, Value: v})), "%v", v)
+	}
+
+	// the end can be serialized, compared and printed like any other key
+	require.True(t, bytes.HasPrefix(end.SerializeToBytes(), start.SerializeToBytes()))
+	require.NotEmpty(t, end.String())
+}
@@ -71,6 +71,14 @@ func (m *secondaryIndexerWithMetrics) BuildCollection(ctx context.Context, txMgr
 	return
 }
 
+func (m *secondaryIndexerWithMetrics) Reindex(ctx context.Context, txMgr *transaction.Manager, opts ReindexOptions) (progress ReindexProgress, err error) {
+	m.measure(ctx, "Reindex", func(ctx context.Context) error {
+		progress, err = m.q.Reindex(ctx, txMgr, opts)
+		return err
+	})
+	return
+}
+
 func (m *secondaryIndexerWithMetrics) ReadDocAndDelete(ctx context.Context, tx transaction.Tx, key keys.Key) (err error) {
 	m.measure(ctx, "ReadDocAndDelete", func(ctx context.Context) error {
 		err = m.q.ReadDocAndDelete(ctx, tx, key)
@@ -107,23 +115,42 @@ type secondaryIndexReaderWithMetrics struct {
 	reader *SecondaryIndexReaderImpl
 }
 
-func NewSecondaryIndexReader(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (Iterator, error) {
+// NewSecondaryIndexReader returns the documents matching the query plan. With a txMgr, a scan failing with a transient
+// error is restarted on a new transaction started by the reader. A read that has to stay in its transaction, like a
+// read of an explicit transaction, passes a nil txMgr.
+func NewSecondaryIndexReader(ctx context.Context, tx transaction.Tx, txMgr *transaction.Manager, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (Iterator, error) {
+	reader, err := newSecondaryIndexReaderImpl(ctx, tx, coll, filter, queryPlan)
+	if err != nil {
+		return nil, err
+	}
+	if txMgr != nil {
+		reader.txMgr = txMgr
+	}
+
 	if config.DefaultConfig.Metrics.SecondaryIndex.Enabled {
-		return newSecondaryIndexReaderWithMetrics(ctx, tx, coll, filter, queryPlan)
+		return &secondaryIndexReaderWithMetrics{
+			reader: reader,
+		}, nil
 	}
 
-	return newSecondaryIndexReaderImpl(ctx, tx, coll, filter, queryPlan)
+	return reader, nil
 }
 
-func newSecondaryIndexReaderWithMetrics(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (Iterator, error) {
-	reader, err := newSecondaryIndexReaderImpl(ctx, tx, coll, filter, queryPlan)
+// NewSecondaryIndexKeysReader is like NewSecondaryIndexReader but only returns the primary keys of the matching
+// documents, the Data of the rows is always nil.
+func NewSecondaryIndexKeysReader(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (Iterator, error) {
+	reader, err := newSecondaryIndexKeysReaderImpl(ctx, tx, coll, filter, queryPlan)
 	if err != nil {
 		return nil, err
 	}
 
-	return &secondaryIndexReaderWithMetrics{
-		reader: reader,
-	}, nil
+	if config.DefaultConfig.Metrics.SecondaryIndex.Enabled {
+		return &secondaryIndexReaderWithMetrics{
+			reader: reader,
+		}, nil
+	}
+
+	return reader, nil
 }
 
 func (m *secondaryIndexReaderWithMetrics) measure(ctx context.Context, name string, f func(ctx context.Context) error) {
@@ -15,6 +15,7 @@
 package database
 
 import (
+	"bytes"
 	"context"
 
 	"github.com/rs/zerolog/log"
@@ -27,7 +28,30 @@ import (
 	"github.com/tigrisdata/tigris/value"
 )
 
-var PrimaryKeyPos = 6
+// maxScanRestarts is the number of times a scan is restarted on a new transaction without returning a row in between
+// before it is aborted.
+const maxScanRestarts = 3
+
+// transientReadErrors are the errors of a read that can succeed on a new transaction, like the transaction of a long
+// scan getting too old to read.
+var transientReadErrors = []error{
+	kv.ErrTransactionMaxDurationReached,
+	kv.ErrTransactionTimedOut,
+}
+
+func isTransientReadError(err error) bool {
+	for _, kvErr := range transientReadErrors {
+		if kvErr == err {
+			return true
+		}
+	}
+	return false
+}
+
+// txStarter starts the transactions a scan is restarted on, it is implemented by transaction.Manager.
+type txStarter interface {
+	StartTx(ctx context.Context) (transaction.Tx, error)
+}
 
 type SecondaryIndexReaderImpl struct {
 	ctx       context.Context
@@ -37,6 +61,21 @@ type SecondaryIndexReaderImpl struct {
 	err       error
 	queryPlan *filter.QueryPlan
 	kvIter    Iterator
+	// keysOnly skips reading the documents, the rows only carry the primary key.
+	keysOnly bool
+	// pointLookup reads the single index entry of the plan directly instead of through an iterator, see
+	// isPointLookupPlan.
+	pointLookup bool
+	done        bool
+	// txMgr starts the transaction that a scan failing with a transient error is restarted on. It is nil when the
+	// scan can't leave the transaction it was created with, like the reads of an explicit transaction.
+	txMgr txStarter
+	// ownTx is set once the scan runs on a transaction started by the reader, which rolls it back at the end.
+	ownTx bool
+	// restarts is the number of restarts since the last returned row.
+	restarts int
+	// lastIndexKey is the key of the index entry of the last returned row, a restarted scan resumes right after it.
+	lastIndexKey []byte
 }
 
 func newSecondaryIndexReaderImpl(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (*SecondaryIndexReaderImpl, error) {
@@ -52,18 +91,38 @@ func newSecondaryIndexReaderImpl(ctx context.Context, tx transaction.Tx, coll *s
 	return reader.createIter()
 }
 
+// newSecondaryIndexKeysReaderImpl creates a reader that only yields the primary keys of the matching documents
+// without reading the documents. The Key of the returned Row is the serialized primary key, the same as the key of
+// the row that the regular reader returns, and the Data is always nil.
+func newSecondaryIndexKeysReaderImpl(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (*SecondaryIndexReaderImpl, error) {
+	reader, err := newSecondaryIndexReaderImpl(ctx, tx, coll, filter, queryPlan)
+	if err != nil {
+		return nil, err
+	}
+	reader.keysOnly = true
+
+	return reader, nil
+}
+
 func (reader *SecondaryIndexReaderImpl) createIter() (*SecondaryIndexReaderImpl, error) {
 	var err error
 
 	log.Debug().Msgf("Query Plan Keys %v", reader.queryPlan.GetKeyInterfaceParts())
 
-	switch reader.queryPlan.QueryType {
-	case filter.FULLRANGE, filter.RANGE:
-		reader.kvIter, err = NewScanIterator(reader.ctx, reader.tx, reader.queryPlan.Keys[0], reader.queryPlan.Keys[1])
+	if start, end, ok := reader.scanKeys(); ok {
+		reader.kvIter, err = NewScanIterator(reader.ctx, reader.tx, start, end)
 		if err != nil {
 			return nil, err
 		}
+		return reader, nil
+	}
+
+	switch reader.queryPlan.QueryType {
 	case filter.EQUAL:
+		if isPointLookupPlan(reader.coll, reader.queryPlan) {
+			reader.pointLookup = true
+			return reader, nil
+		}
 		reader.kvIter, err = NewKeyIterator(reader.ctx, reader.tx, reader.queryPlan.Keys)
 		if err != nil {
 			return nil, err
@@ -75,6 +134,133 @@ func (reader *SecondaryIndexReaderImpl) createIter() (*SecondaryIndexReaderImpl,
 	return reader, nil
 }
 
+// scanKeys returns the keys of the range of the index scanned by the plan, if the plan is read with a single scan.
+func (reader *SecondaryIndexReaderImpl) scanKeys() (keys.Key, keys.Key, bool) {
+	switch {
+	case reader.queryPlan.QueryType == filter.FULLRANGE || reader.queryPlan.QueryType == filter.RANGE:
+		return reader.queryPlan.Keys[0], reader.queryPlan.Keys[1], true
+	case reader.queryPlan.QueryType == filter.EQUAL && reader.queryPlan.Range != nil &&
+		!isPointLookupPlan(reader.coll, reader.queryPlan):
+		start, end := reader.queryPlan.Range.ScanKeys()
+		return start, end, true
+	}
+
+	return nil, nil, false
+}
+
+// SecondaryIndexEntryReader yields the decoded entries of the index matching a query plan, the indexed value of the
+// field along with the primary key of the document, and never reads the documents. It is the cheapest scan of a
+// secondary index, for the callers that only need the (value, primary key) pairs. Unlike the documents read by the
+// other readers, the value is the one stored in the index, so it is lowercased for a case-insensitive index.
+type SecondaryIndexEntryReader struct {
+	reader *SecondaryIndexReaderImpl
+	layout secondaryIndexLayout
+}
+
+func NewSecondaryIndexEntryReader(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlan *filter.QueryPlan) (*SecondaryIndexEntryReader, error) {
+	reader, err := newSecondaryIndexReaderImpl(ctx, tx, coll, filter, queryPlan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecondaryIndexEntryReader{
+		reader: reader,
+		layout: newSecondaryIndexLayout(coll),
+	}, nil
+}
+
+// Next fills the next entry, it returns false once there are no more entries or the scan failed.
+func (it *SecondaryIndexEntryReader) Next(entry *SecondaryIndexEntry) bool {
+	var indexRow Row
+	if !it.reader.nextIndexRow(&indexRow) {
+		return false
+	}
+
+	decoded, err := it.layout.Decode(indexRow.Key)
+	if err != nil {
+		it.reader.err = err
+		return false
+	}
+	*entry = *decoded
+
+	return true
+}
+
+func (it *SecondaryIndexEntryReader) Interrupted() error { return it.reader.Interrupted() }
+
+// SecondaryIndexUnionReader returns the union of the documents matching several secondary index query plans, for
+// example one plan per branch of an $or filter. All the sub-readers are created up front on the same transaction so
+// that they share a single read version, which makes the union a consistent point-in-time view even if the documents
+// are modified while the scan is in progress. A document matching more than one plan is returned only once.
+type SecondaryIndexUnionReader struct {
+	readers []*SecondaryIndexReaderImpl
+	current int
+	seen    map[string]struct{}
+	err     error
+}
+
+func newSecondaryIndexUnionReader(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, filter *filter.WrappedFilter, queryPlans []*filter.QueryPlan) (*SecondaryIndexUnionReader, error) {
+	if len(queryPlans) == 0 {
+		return nil, errors.InvalidArgument("Cannot create a union reader without a query plan")
+	}
+
+	queryPlans = coalesceQueryPlans(queryPlans)
+	readers := make([]*SecondaryIndexReaderImpl, 0, len(queryPlans))
+	for _, plan := range queryPlans {
+		reader, err := newSecondaryIndexReaderImpl(ctx, tx, coll, filter, plan)
+		if err != nil {
+			return nil, err
+		}
+		readers = append(readers, reader)
+	}
+
+	return &SecondaryIndexUnionReader{
+		readers: readers,
+		seen:    make(map[string]struct{}),
+	}, nil
+}
+
+// coalesceQueryPlans orders the plans and merges the adjacent ranges, so that each merged range is read with a single
+// scan instead of one scan per plan.
+func coalesceQueryPlans(queryPlans []*filter.QueryPlan) []*filter.QueryPlan {
+	plans := make([]filter.QueryPlan, 0, len(queryPlans))
+	for _, plan := range queryPlans {
+		plans = append(plans, *plan)
+	}
+	plans = filter.MergeAdjacentRanges(filter.SortQueryPlans(plans))
+
+	coalesced := make([]*filter.QueryPlan, 0, len(plans))
+	for i := range plans {
+		coalesced = append(coalesced, &plans[i])
+	}
+
+	return coalesced
+}
+
+func (it *SecondaryIndexUnionReader) Next(row *Row) bool {
+	for it.err == nil && it.current < len(it.readers) {
+		reader := it.readers[it.current]
+		if !reader.Next(row) {
+			if it.err = reader.Interrupted(); it.err != nil {
+				return false
+			}
+			it.current++
+			continue
+		}
+
+		if _, ok := it.seen[string(row.Key)]; ok {
+			continue
+		}
+		it.seen[string(row.Key)] = struct{}{}
+
+		return true
+	}
+
+	return false
+}
+
+func (it *SecondaryIndexUnionReader) Interrupted() error { return it.err }
+
 func BuildSecondaryIndexKeys(coll *schema.DefaultCollection, queryFilters []filter.Filter) (*filter.QueryPlan, error) {
 	if len(queryFilters) == 0 {
 		return nil, errors.InvalidArgument("Cannot index with an empty filter")
@@ -85,11 +271,24 @@ func BuildSecondaryIndexKeys(coll *schema.DefaultCollection, queryFilters []filt
 		return nil, errors.InvalidArgument("No indexable fields")
 	}
 
+	if err := validateRangeTypeOrders(queryFilters); err != nil {
+		return nil, err
+	}
+
+	layout := newSecondaryIndexLayout(coll)
 	encoder := func(indexParts ...interface{}) (keys.Key, error) {
-		return newKeyWithPrimaryKey(indexParts, coll.EncodedTableIndexName, coll.SecondaryIndexKeyword(), "kvs"), nil
+		return layout.Prefix(indexParts...), nil
+	}
+
+	fields := make(map[string]*schema.QueryableField, len(indexeableFields))
+	for _, f := range indexeableFields {
+		fields[f.Name()] = f
 	}
 
 	buildIndexParts := func(fieldName string, val value.Value) []interface{} {
+		if f, ok := fields[fieldName]; ok {
+			val = caseInsensitiveValue(f, val)
+		}
 		typeOrder := value.ToSecondaryOrder(val.DataType(), val)
 		return []interface{}{fieldName, typeOrder, val.AsInterface()}
 	}
@@ -99,7 +298,7 @@ func BuildSecondaryIndexKeys(coll *schema.DefaultCollection, queryFilters []filt
 	if err == nil {
 		for _, plan := range eqPlan {
 			if indexedDataType(plan) {
-				return &plan, nil
+				return prefixRangePlan(coll, layout, &plan), nil
 			}
 		}
 	}
@@ -123,6 +322,175 @@ func BuildSecondaryIndexKeys(coll *schema.DefaultCollection, queryFilters []filt
 	return nil, errors.InvalidArgument("Could not find a useuable query plan")
 }
 
+// BuildSecondaryIndexUnionKeys builds the plans of a filter with an $or, one plan per branch of the $or. The filters
+// around the $or apply to every branch, so they are part of the filters of each branch. The secondary index only
+// serves the filter if it serves every branch, the documents matching any of the plans are then read with a
+// SecondaryIndexUnionReader and filtered with the whole filter.
+func BuildSecondaryIndexUnionKeys(coll *schema.DefaultCollection, queryFilters []filter.Filter) ([]*filter.QueryPlan, error) {
+	var branches, conjunction []filter.Filter
+	for _, f := range queryFilters {
+		if logical, ok := f.(filter.LogicalFilter); ok && logical.Type() == filter.OrOP {
+			if branches != nil {
+				return nil, errors.InvalidArgument("a single $or filter is supported for secondary index")
+			}
+			branches = logical.GetFilters()
+			continue
+		}
+		conjunction = append(conjunction, f)
+	}
+	if len(branches) == 0 {
+		return nil, errors.InvalidArgument("Cannot build a union without an $or filter")
+	}
+
+	plans := make([]*filter.QueryPlan, 0, len(branches))
+	for _, branch := range branches {
+		branchFilters := append([]filter.Filter(nil), conjunction...)
+		if logical, ok := branch.(filter.LogicalFilter); ok && logical.Type() == filter.AndOP {
+			branchFilters = append(branchFilters, logical.GetFilters()...)
+		} else {
+			branchFilters = append(branchFilters, branch)
+		}
+
+		plan, err := BuildSecondaryIndexKeys(coll, branchFilters)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+// hasOrFilter returns true if one of the top level filters is an $or.
+func hasOrFilter(queryFilters []filter.Filter) bool {
+	for _, f := range queryFilters {
+		if logical, ok := f.(filter.LogicalFilter); ok && logical.Type() == filter.OrOP {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeTypeOrdersError is the error of a filter with a range that can't match anything. Unlike the other errors of
+// BuildSecondaryIndexKeys, which only mean that the secondary index can't serve the filter and the collection is
+// scanned instead, it is returned to the client.
+type rangeTypeOrdersError struct {
+	err error
+}
+
+func (e *rangeTypeOrdersError) Error() string { return e.err.Error() }
+
+// invalidFilterError returns the error of BuildSecondaryIndexKeys to return to the client, nil if the collection can
+// be scanned instead.
+func invalidFilterError(err error) error {
+	if e, ok := err.(*rangeTypeOrdersError); ok {
+		return e.err
+	}
+	return nil
+}
+
+// validateRangeTypeOrders rejects the filters with a range whose bounds on a field are values of different types, for
+// example {"$gt": 10, "$lt": "a"}. The entries of the index of a field are grouped by the type order of their values
+// before the values themselves, so that a field holding an int in a document and a string in another has its ints
+// and strings in two separate groups. A range spanning types would scan the groups of all the types ordered in
+// between, while a value is never greater or less than a value of another type and the range can't match anything.
+// A range with a single bound only matches the values of the type of its bound.
+//
+// The bounds of the top level filters and of an $and apply together, while each branch of an $or only applies with
+// the bounds of the filters around the $or, so the bounds of two branches are never compared.
+func validateRangeTypeOrders(queryFilters []filter.Filter) error {
+	return validateConjunctionTypeOrders(queryFilters, make(map[string]value.Value))
+}
+
+// validateConjunctionTypeOrders validates the filters that apply together, the bounds are those of the filters around
+// them.
+func validateConjunctionTypeOrders(conjunction []filter.Filter, bounds map[string]value.Value) error {
+	var disjunctions [][]filter.Filter
+	level := append([]filter.Filter(nil), conjunction...)
+	for i := 0; i < len(level); i++ {
+		if logical, ok := level[i].(filter.LogicalFilter); ok {
+			if logical.Type() == filter.AndOP {
+				level = append(level, logical.GetFilters()...)
+			} else {
+				disjunctions = append(disjunctions, logical.GetFilters())
+			}
+			continue
+		}
+
+		sel, ok := level[i].(*filter.Selector)
+		if !ok {
+			continue
+		}
+
+		switch sel.Matcher.Type() {
+		case filter.GT, filter.GTE, filter.LT, filter.LTE:
+		default:
+			continue
+		}
+
+		name, bound := sel.Field.Name(), sel.Matcher.GetValue()
+		if other, found := bounds[name]; found &&
+			value.ToSecondaryOrder(other.DataType(), nil) != value.ToSecondaryOrder(bound.DataType(), nil) {
+			return &rangeTypeOrdersError{
+				err: errors.InvalidArgument("range on field '%s' can't span values of different types '%s' and '%s'",
+					name, schema.FieldNames[other.DataType()], schema.FieldNames[bound.DataType()]),
+			}
+		}
+		bounds[name] = bound
+	}
+
+	for _, branches := range disjunctions {
+		for _, branch := range branches {
+			branchBounds := make(map[string]value.Value, len(bounds))
+			for name, bound := range bounds {
+				branchBounds[name] = bound
+			}
+			if err := validateConjunctionTypeOrders([]filter.Filter{branch}, branchBounds); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isPointLookupPlan returns true if the plan can match at most one index entry, which is the case of an equality on
+// the field that is the whole primary key of the collection, as its values are unique. Such a plan is the common
+// lookup of a document by its id. A case-insensitive index stores the values lowercased, the unique values that only
+// differ by their case then share an index value, so an equality on them can match several entries.
+func isPointLookupPlan(coll *schema.DefaultCollection, queryPlan *filter.QueryPlan) bool {
+	if queryPlan.QueryType != filter.EQUAL || len(queryPlan.Keys) != 1 {
+		return false
+	}
+
+	pk := coll.GetPrimaryKey()
+	if pk == nil || len(pk.Fields) != 1 {
+		return false
+	}
+
+	parts := queryPlan.Keys[0].IndexParts()
+	if len(parts) <= secondaryIndexFieldPos || parts[secondaryIndexFieldPos] != pk.Fields[0].Name() {
+		return false
+	}
+
+	field, err := coll.GetQueryableField(pk.Fields[0].Name())
+	return err == nil && !field.IndexCaseInsensitive
+}
+
+// prefixRangePlan sets the range of an equality plan to the index entries prefixed by its key, that is the entries of
+// the value whatever their array position and primary key, which can itself be made of several fields. The plan is
+// still reported as an equality. A point lookup plan is kept as is, its single entry is read directly.
+func prefixRangePlan(coll *schema.DefaultCollection, layout secondaryIndexLayout, queryPlan *filter.QueryPlan) *filter.QueryPlan {
+	if len(queryPlan.Keys) != 1 || isPointLookupPlan(coll, queryPlan) {
+		return queryPlan
+	}
+
+	start, end := layout.PrefixRange(queryPlan.Keys[0].IndexParts()[secondaryIndexFieldPos:]...)
+	queryPlan.Range = keys.NewRange(keys.ClosedBound(start), keys.OpenBound(end))
+
+	return queryPlan
+}
+
 func indexedDataType(queryPlan filter.QueryPlan) bool {
 	switch queryPlan.DataType {
 	case schema.ByteType, schema.UnknownType, schema.ArrayType:
@@ -133,42 +501,161 @@ func indexedDataType(queryPlan filter.QueryPlan) bool {
 }
 
 func (it *SecondaryIndexReaderImpl) Next(row *Row) bool {
-	if it.err != nil {
-		return false
+	for {
+		var indexRow Row
+		if it.nextIndexRow(&indexRow) && it.readIndexEntry(&indexRow, row) {
+			it.lastIndexKey = indexRow.Key
+			it.restarts = 0
+			return true
+		}
+
+		if !it.restart() {
+			if it.ownTx {
+				_ = it.tx.Rollback(it.ctx)
+				it.ownTx = false
+			}
+			return false
+		}
 	}
+}
 
-	if it.kvIter.Interrupted() != nil {
-		it.err = it.kvIter.Interrupted()
+// restart moves a scan that failed with a transient error, like its transaction getting too old during a long scan,
+// to a new transaction. The scan resumes right after the index entry of the last returned row, so the rows are
+// neither skipped nor returned twice. It returns false if the scan can't be restarted, the scan is then over and its
+// error, if any, is returned by Interrupted.
+func (it *SecondaryIndexReaderImpl) restart() bool {
+	if it.txMgr == nil || !isTransientReadError(it.err) || it.restarts >= maxScanRestarts {
 		return false
 	}
 
-	var indexRow Row
-	if it.kvIter.Next(&indexRow) {
-		indexKey, err := keys.FromBinary(it.coll.EncodedTableIndexName, indexRow.Key)
+	start, end, ok := it.scanKeys()
+	if !ok && !it.pointLookup {
+		return false
+	}
+	if ok && it.lastIndexKey != nil {
+		from, err := keys.FromBinary(it.coll.EncodedTableIndexName, it.lastIndexKey)
 		if err != nil {
 			it.err = err
 			return false
 		}
+		start = from
+	}
 
-		pks := indexKey.IndexParts()[PrimaryKeyPos:]
-		pkIndexParts := keys.NewKey(it.coll.EncodedName, pks...)
+	tx, err := it.txMgr.StartTx(it.ctx)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if it.ownTx {
+		_ = it.tx.Rollback(it.ctx)
+	}
+	log.Debug().Err(it.err).Int("restarts", it.restarts+1).Msg("restarting secondary index scan on a new transaction")
+	it.tx, it.ownTx, it.err = tx, true, nil
+	it.restarts++
 
-		docIter, err := it.tx.Read(it.ctx, pkIndexParts)
-		if err != nil {
-			it.err = err
-			return false
-		}
+	if it.pointLookup {
+		// the single entry is read again, unless it was already returned
+		it.done = it.lastIndexKey != nil
+		return true
+	}
 
-		var keyValue kv.KeyValue
-		if docIter.Next(&keyValue) {
-			row.Data = keyValue.Data
-			row.Key = keyValue.FDBKey
-			return true
+	if it.kvIter, err = NewScanIterator(it.ctx, it.tx, start, end); err != nil {
+		it.err = err
+		return false
+	}
+
+	return true
+}
+
+// nextIndexRow fills the next index entry of the plan, without reading its document.
+func (it *SecondaryIndexReaderImpl) nextIndexRow(indexRow *Row) bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.pointLookup {
+		return it.nextPoint(indexRow)
+	}
+
+	if it.kvIter.Interrupted() != nil {
+		it.err = it.kvIter.Interrupted()
+		return false
+	}
+
+	for it.kvIter.Next(indexRow) {
+		// a restarted scan starts at the entry of the last returned row
+		if it.lastIndexKey != nil && bytes.Equal(indexRow.Key, it.lastIndexKey) {
+			continue
 		}
+		return true
 	}
+	it.err = it.kvIter.Interrupted()
+
 	return false
 }
 
+// nextPoint returns the single index entry of a point lookup plan.
+func (it *SecondaryIndexReaderImpl) nextPoint(indexRow *Row) bool {
+	if it.done {
+		return false
+	}
+	it.done = true
+
+	indexIter, err := it.tx.Read(it.ctx, it.queryPlan.Keys[0])
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	indexRows := NewRowIterator(indexIter)
+	if !indexRows.Next(indexRow) {
+		it.err = indexRows.Interrupted()
+		return false
+	}
+
+	return true
+}
+
+// readIndexEntry fills the row with the document, or only the primary key when keysOnly is set, of the index entry.
+func (it *SecondaryIndexReaderImpl) readIndexEntry(indexRow *Row, row *Row) bool {
+	entry, err := newSecondaryIndexLayout(it.coll).Decode(indexRow.Key)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	pkIndexParts := keys.NewKey(it.coll.EncodedName, entry.PrimaryKey...)
+
+	if it.keysOnly {
+		row.Key = pkIndexParts.SerializeToBytes()
+		row.Data = nil
+		return true
+	}
+
+	found, err := it.readDocument(pkIndexParts, row)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	return found
+}
+
+// readDocument reads the document for the primary key. A transient read error restarts the scan, see restart.
+func (it *SecondaryIndexReaderImpl) readDocument(pkIndexParts keys.Key, row *Row) (bool, error) {
+	docIter, err := it.tx.Read(it.ctx, pkIndexParts)
+	if err != nil {
+		return false, err
+	}
+
+	docRows := NewRowIterator(docIter)
+	if docRows.Next(row) {
+		return true, nil
+	}
+
+	return false, docRows.Interrupted()
+}
+
 func (it *SecondaryIndexReaderImpl) Interrupted() error { return it.err }
 
 // For local debugging and testing.
@@ -0,0 +1,782 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestBuildSecondaryIndexKeysNestedField(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"address": {
+				"type": "object",
+				"properties": {
+					"city": { "type": "string", "index": true },
+					"zip": { "type": "integer" }
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+
+	var indexed []string
+	for _, f := range coll.GetActiveIndexedFields() {
+		indexed = append(indexed, f.Name())
+	}
+	require.Contains(t, indexed, "address.city")
+	require.NotContains(t, indexed, "address.zip")
+
+	filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(`{"address.city": "NYC"}`))
+	require.NoError(t, err)
+
+	plan, err := BuildSecondaryIndexKeys(coll, filters)
+	require.NoError(t, err)
+	require.Equal(t, filter.EQUAL, plan.QueryType)
+	require.Equal(t, schema.StringType, plan.DataType)
+	require.Len(t, plan.Keys, 1)
+
+	parts := plan.Keys[0].IndexParts()
+	require.Equal(t, []interface{}{"skey", KVSubspace, "address.city", value.ToSecondaryOrder(schema.StringType, nil)}, parts[:4])
+
+	_, err = filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(`{"address.zip": 1}`))
+	require.Error(t, err)
+}
+
+func TestBuildSecondaryIndexKeysPrefixRange(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"created_at": { "type": "string", "format": "date-time" },
+			"status": { "type": "string", "index": true }
+		},
+		"primary_key": ["id", "created_at"]
+	}`)
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	layout := newSecondaryIndexLayout(coll)
+
+	plan := func(filterJSON string) *filter.QueryPlan {
+		filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(filterJSON))
+		require.NoError(t, err)
+		plan, err := BuildSecondaryIndexKeys(coll, filters)
+		require.NoError(t, err)
+		return plan
+	}
+
+	active := plan(`{"status": "active"}`)
+	require.Equal(t, filter.EQUAL, active.QueryType)
+	require.NotNil(t, active.Range)
+
+	start, end := active.Range.ScanKeys()
+	require.Equal(t, active.Keys[0].IndexParts(), start.IndexParts())
+
+	// the entries of the value are in the range whatever the two fields of their primary key
+	typeOrder := value.ToSecondaryOrder(schema.StringType, nil)
+	encoded := start.IndexParts()[secondaryIndexValuePos]
+	for _, entry := range []*SecondaryIndexEntry{
+		{Field: "status", TypeOrder: typeOrder, Value: encoded, PrimaryKey: []interface{}{int64(1), "2023-01-01T00:00:00Z"}},
+		{Field: "status", TypeOrder: typeOrder, Value: encoded, PrimaryKey: []interface{}{int64(1), "2023-01-02T00:00:00Z"}},
+		{Field: "status", TypeOrder: typeOrder, Value: encoded, ArrayPos: 300, PrimaryKey: []interface{}{int64(2), "2022-12-31T00:00:00Z"}},
+	} {
+		key := layout.Encode(entry).SerializeToBytes()
+		require.True(t, start.CompareBytes(key) <= 0 && end.CompareBytes(key) > 0)
+
+		decoded, err := layout.Decode(key)
+		require.NoError(t, err)
+		require.Equal(t, entry.PrimaryKey, decoded.PrimaryKey)
+	}
+
+	// the entries of a value that the queried one is a prefix of are not in the range
+	activated := plan(`{"status": "activated"}`).Keys[0].IndexParts()[secondaryIndexValuePos]
+	key := layout.Encode(&SecondaryIndexEntry{Field: "status", TypeOrder: typeOrder, Value: activated, PrimaryKey: []interface{}{int64(3), "2023-01-03T00:00:00Z"}})
+	require.False(t, start.CompareBytes(key.SerializeToBytes()) <= 0 && end.CompareBytes(key.SerializeToBytes()) > 0)
+
+	// a point lookup on a single field primary key is read directly
+	coll = setupActiveIndexCollection(t, []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer", "index": true }
+		},
+		"primary_key": ["id"]
+	}`))
+	require.Nil(t, plan(`{"id": 1}`).Range)
+}
+
+func TestIsPointLookupPlan(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer", "index": true },
+			"name": { "type": "string", "index": true }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+
+	plan := func(filterJSON string) *filter.QueryPlan {
+		filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(filterJSON))
+		require.NoError(t, err)
+		plan, err := BuildSecondaryIndexKeys(coll, filters)
+		require.NoError(t, err)
+		return plan
+	}
+
+	require.True(t, isPointLookupPlan(coll, plan(`{"id": 1}`)))
+	// the other fields can have the same value in several documents, they fall back to the iterator
+	require.False(t, isPointLookupPlan(coll, plan(`{"name": "a"}`)))
+	require.False(t, isPointLookupPlan(coll, plan(`{"id": {"$gt": 1}}`)))
+
+	// "A" and "a" are distinct ids sharing the same entry value in a case-insensitive index
+	coll = setupActiveIndexCollection(t, []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "string", "index": true, "indexCaseInsensitive": true }
+		},
+		"primary_key": ["id"]
+	}`))
+	require.False(t, isPointLookupPlan(coll, plan(`{"id": "a"}`)))
+}
+
+func TestBuildSecondaryIndexKeysCaseInsensitive(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"email": { "type": "string", "index": true, "indexCaseInsensitive": true },
+			"name": { "type": "string", "index": true }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	indexer := newSecondaryIndexerImpl(coll)
+
+	indexKeyParts := func(doc string, field string) []interface{} {
+		td, pk := createDoc(doc, 1)
+		updateSet, err := indexer.buildAddAndRemoveKVs(td, nil, pk)
+		require.NoError(t, err)
+		for _, k := range updateSet.addKeys {
+			if parts := k.IndexParts(); parts[2] == field {
+				// skip the position and the primary key
+				return parts[:5]
+			}
+		}
+		require.Fail(t, "index key not found", field)
+		return nil
+	}
+	planKeyParts := func(reqFilter string) []interface{} {
+		filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(reqFilter))
+		require.NoError(t, err)
+		plan, err := BuildSecondaryIndexKeys(coll, filters)
+		require.NoError(t, err)
+		require.Len(t, plan.Keys, 1)
+		return plan.Keys[0].IndexParts()
+	}
+
+	lower := indexKeyParts(`{"id":1, "email":"foo@example.com", "name":"foo"}`, "email")
+	mixed := indexKeyParts(`{"id":1, "email":"Foo@Example.com", "name":"Foo"}`, "email")
+	require.Equal(t, lower, mixed)
+	require.Equal(t, lower, planKeyParts(`{"email": "FOO@example.COM"}`))
+
+	// the fields without case-insensitive index are not normalized
+	require.NotEqual(t,
+		indexKeyParts(`{"id":1, "email":"foo@example.com", "name":"foo"}`, "name"),
+		indexKeyParts(`{"id":1, "email":"foo@example.com", "name":"Foo"}`, "name"))
+	require.NotEqual(t, indexKeyParts(`{"id":1, "name":"foo"}`, "name"), planKeyParts(`{"name": "Foo"}`))
+}
+
+func TestValidateRangeTypeOrders(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"score": { "type": "integer", "index": true },
+			"active": { "type": "boolean", "index": true }
+		},
+		"primary_key": ["id"]
+	}`)
+	coll := setupActiveIndexCollection(t, reqSchema)
+
+	fields := make(map[string]*schema.QueryableField)
+	for _, f := range coll.GetActiveIndexedFields() {
+		fields[f.Name()] = f
+	}
+	selector := func(field string, op string, v value.Value) filter.Filter {
+		matcher, err := filter.NewMatcher(op, v)
+		require.NoError(t, err)
+		return filter.NewSelector(fields[field], matcher, nil)
+	}
+
+	// the field held an int in some documents and a string in others, a range from one to the other is rejected
+	err := validateRangeTypeOrders([]filter.Filter{
+		selector("score", filter.GT, value.NewIntValue(10)),
+		selector("score", filter.LT, value.NewStringValue("a", nil)),
+	})
+	require.Equal(t, errors.InvalidArgument("range on field 'score' can't span values of different types 'int64' and 'string'"),
+		invalidFilterError(err))
+
+	and, err := filter.NewAndFilter([]filter.Filter{
+		selector("score", filter.GTE, value.NewStringValue("a", nil)),
+		selector("score", filter.LTE, value.NewIntValue(10)),
+	})
+	require.NoError(t, err)
+	require.Error(t, validateRangeTypeOrders([]filter.Filter{and}))
+
+	// bounds of the same type, ints and doubles are both numbers, and a single bound are accepted
+	require.NoError(t, validateRangeTypeOrders([]filter.Filter{
+		selector("score", filter.GT, value.NewIntValue(10)),
+		selector("score", filter.LT, value.NewDoubleUsingFloat(20.5)),
+	}))
+	require.NoError(t, validateRangeTypeOrders([]filter.Filter{
+		selector("active", filter.GTE, value.NewBoolValue(false)),
+		selector("active", filter.LTE, value.NewBoolValue(true)),
+	}))
+	require.NoError(t, validateRangeTypeOrders([]filter.Filter{
+		selector("score", filter.GT, value.NewIntValue(10)),
+		selector("active", filter.LT, value.NewBoolValue(true)),
+	}))
+
+	// the branches of an $or don't apply together, each only applies with the bounds around the $or
+	or, err := filter.NewOrFilter([]filter.Filter{
+		selector("score", filter.GT, value.NewIntValue(10)),
+		selector("score", filter.LT, value.NewStringValue("a", nil)),
+	})
+	require.NoError(t, err)
+	require.NoError(t, validateRangeTypeOrders([]filter.Filter{or}))
+	require.Error(t, validateRangeTypeOrders([]filter.Filter{selector("score", filter.GTE, value.NewStringValue("b", nil)), or}))
+
+	nested, err := filter.NewAndFilter([]filter.Filter{
+		selector("active", filter.LT, value.NewBoolValue(true)),
+		selector("score", filter.LTE, value.NewStringValue("b", nil)),
+	})
+	require.NoError(t, err)
+	require.Error(t, validateRangeTypeOrders([]filter.Filter{selector("score", filter.GT, value.NewIntValue(10)), nested}))
+
+	_, err = BuildSecondaryIndexKeys(coll, []filter.Filter{
+		selector("score", filter.GT, value.NewIntValue(10)),
+		selector("score", filter.LT, value.NewStringValue("a", nil)),
+	})
+	require.Error(t, invalidFilterError(err))
+
+	// the other errors only mean the index can't serve the filter
+	_, err = BuildSecondaryIndexKeys(coll, nil)
+	require.Error(t, err)
+	require.NoError(t, invalidFilterError(err))
+}
+
+func TestSecondaryIndexKeysOnlyReader(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"name": {
+				"type": "string",
+				"index": true
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedName))
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedTableIndexName))
+
+	tm := transaction.NewManager(kvStore)
+	indexer := newSecondaryIndexerImpl(coll)
+
+	// only the index entries are written, the keys only reader must not need the documents
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	for i, name := range []string{"a", "b", "a"} {
+		td, pk := createDoc(fmt.Sprintf(`{"id":%d, "name":"%s"}`, i+1, name), i+1)
+		require.NoError(t, indexer.Index(ctx, tx, td, pk))
+	}
+	require.NoError(t, tx.Commit(ctx))
+
+	filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(`{"name": "a"}`))
+	require.NoError(t, err)
+	plan, err := BuildSecondaryIndexKeys(coll, filters)
+	require.NoError(t, err)
+
+	tx, err = tm.StartTx(ctx)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	reader, err := newSecondaryIndexKeysReaderImpl(ctx, tx, coll, filter.NewWrappedFilter(filters), plan)
+	require.NoError(t, err)
+
+	var (
+		row     Row
+		results [][]byte
+	)
+	for reader.Next(&row) {
+		require.Nil(t, row.Data)
+		results = append(results, row.Key)
+	}
+	require.NoError(t, reader.Interrupted())
+	require.Equal(t, [][]byte{
+		keys.NewKey(coll.EncodedName, int64(1)).SerializeToBytes(),
+		keys.NewKey(coll.EncodedName, int64(3)).SerializeToBytes(),
+	}, results)
+}
+
+func TestSecondaryIndexEntryReader(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"name": {
+				"type": "string",
+				"index": true
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedName))
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedTableIndexName))
+
+	tm := transaction.NewManager(kvStore)
+	indexer := newSecondaryIndexerImpl(coll)
+
+	// only the index entries are written, the entry reader must not need the documents
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	for i, name := range []string{"c", "a", "b"} {
+		td, pk := createDoc(fmt.Sprintf(`{"id":%d, "name":"%s"}`, i+1, name), i+1)
+		require.NoError(t, indexer.Index(ctx, tx, td, pk))
+	}
+	require.NoError(t, tx.Commit(ctx))
+
+	filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(`{"name": {"$gte": "b"}}`))
+	require.NoError(t, err)
+	plan, err := BuildSecondaryIndexKeys(coll, filters)
+	require.NoError(t, err)
+
+	tx, err = tm.StartTx(ctx)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	reader, err := NewSecondaryIndexEntryReader(ctx, tx, coll, filter.NewWrappedFilter(filters), plan)
+	require.NoError(t, err)
+
+	var (
+		entry   SecondaryIndexEntry
+		results []SecondaryIndexEntry
+	)
+	for reader.Next(&entry) {
+		results = append(results, entry)
+	}
+	require.NoError(t, reader.Interrupted())
+
+	typeOrder := value.ToSecondaryOrder(schema.StringType, nil)
+	require.Equal(t, []SecondaryIndexEntry{
+		{Field: "name", TypeOrder: typeOrder, Value: "b", PrimaryKey: []interface{}{int64(3)}},
+		{Field: "name", TypeOrder: typeOrder, Value: "c", PrimaryKey: []interface{}{int64(1)}},
+	}, results)
+}
+
+func TestSecondaryIndexUnionReaderSnapshot(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"name": {
+				"type": "string",
+				"index": true
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedName))
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedTableIndexName))
+
+	tm := transaction.NewManager(kvStore)
+	indexer := newSecondaryIndexerImpl(coll)
+
+	insert := func(id int, name string) {
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		td, pk := createDoc(fmt.Sprintf(`{"id":%d, "name":"%s"}`, id, name), id)
+		require.NoError(t, tx.Insert(ctx, keys.NewKey(coll.EncodedName, int64(id)), td))
+		require.NoError(t, indexer.Index(ctx, tx, td, pk))
+		require.NoError(t, tx.Commit(ctx))
+	}
+	insert(1, "a")
+	insert(2, "b")
+	insert(3, "a")
+
+	var plans []*filter.QueryPlan
+	for _, f := range []string{`{"name": "a"}`, `{"name": "b"}`} {
+		filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(f))
+		require.NoError(t, err)
+		plan, err := BuildSecondaryIndexKeys(coll, filters)
+		require.NoError(t, err)
+		plans = append(plans, plan)
+	}
+
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	reader, err := newSecondaryIndexUnionReader(ctx, tx, coll, filter.NewWrappedFilter(nil), plans)
+	require.NoError(t, err)
+
+	var (
+		row Row
+		ids []int64
+	)
+	readID := func() {
+		var doc struct {
+			ID int64 `json:"id"`
+		}
+		require.NoError(t, jsoniter.Unmarshal(row.Data.RawData, &doc))
+		ids = append(ids, doc.ID)
+	}
+
+	require.True(t, reader.Next(&row))
+	readID()
+
+	// documents matching both plans written while the scan is in progress must not be visible to any sub-reader
+	insert(4, "a")
+	insert(5, "b")
+
+	for reader.Next(&row) {
+		readID()
+	}
+	require.NoError(t, reader.Interrupted())
+	require.Equal(t, []int64{1, 3, 2}, ids)
+}
+
+func TestSecondaryIndexOrRead(t *testing.T) {
+	defer func(index config.SecondaryIndexConfig) {
+		config.DefaultConfig.SecondaryIndex = index
+	}(config.DefaultConfig.SecondaryIndex)
+	config.DefaultConfig.SecondaryIndex.ReadEnabled = true
+
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"age": {
+				"type": "integer",
+				"index": true
+			},
+			"name": {
+				"type": "string",
+				"index": true
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedName))
+	assert.NoError(t, kvStore.DropTable(ctx, coll.EncodedTableIndexName))
+
+	tm := transaction.NewManager(kvStore)
+	indexer := newSecondaryIndexerImpl(coll)
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+	for id := 1; id <= 6; id++ {
+		td, pk := createDoc(fmt.Sprintf(`{"id":%d, "age":%d, "name":"n%d"}`, id, id*10, id), id)
+		require.NoError(t, tx.Insert(ctx, keys.NewKey(coll.EncodedName, int64(id)), td))
+		require.NoError(t, indexer.Index(ctx, tx, td, pk))
+	}
+	require.NoError(t, tx.Commit(ctx))
+
+	runner := &BaseQueryRunner{encoder: metadata.NewEncoder()}
+	read := func(reqFilter string) (readerOptions, []int64) {
+		options, err := runner.buildReaderOptions(&api.ReadRequest{Filter: []byte(reqFilter)}, coll)
+		require.NoError(t, err)
+
+		tx, err := tm.StartTx(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		iter, err := newSecondaryIndexIterator(ctx, tx, nil, coll, options)
+		require.NoError(t, err)
+
+		var (
+			row Row
+			ids []int64
+		)
+		for iter.Next(&row) {
+			var doc struct {
+				ID int64 `json:"id"`
+			}
+			require.NoError(t, jsoniter.Unmarshal(row.Data.RawData, &doc))
+			ids = append(ids, doc.ID)
+		}
+		require.NoError(t, iter.Interrupted())
+
+		return options, ids
+	}
+
+	// the overlapping branches are read with a single scan and a document matching both is returned once
+	options, ids := read(`{"$or": [{"age": {"$gte": 20, "$lte": 40}}, {"age": {"$gte": 30, "$lte": 50}}]}`)
+	require.Len(t, options.orPlans, 2)
+	require.Len(t, coalesceQueryPlans(options.orPlans), 1)
+	require.Equal(t, []int64{2, 3, 4, 5}, ids)
+
+	// the branches that don't overlap are read one after the other
+	options, ids = read(`{"$or": [{"age": 60}, {"age": {"$lt": 20}}, {"age": 10}]}`)
+	require.Len(t, options.orPlans, 3)
+	require.ElementsMatch(t, []int64{1, 6}, ids)
+
+	// the filters around the $or apply to every branch
+	options, ids = read(`{"name": "n3", "$or": [{"age": {"$gte": 20, "$lte": 40}}, {"age": {"$gte": 30, "$lte": 50}}]}`)
+	require.Len(t, options.orPlans, 2)
+	require.Equal(t, []int64{3}, ids)
+
+	// a branch the index can't serve scans the collection
+	options, err = runner.buildReaderOptions(&api.ReadRequest{Filter: []byte(`{"$or": [{"age": 10}, {"id": 2}]}`)}, coll)
+	require.NoError(t, err)
+	require.False(t, options.secondaryIndexRead())
+}
+
+func TestSecondaryIndexReaderRestart(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"age": { "type": "integer", "index": true }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	coll := setupActiveIndexCollection(t, reqSchema)
+	indexer := newSecondaryIndexerImpl(coll)
+
+	var rows []kv.KeyValue
+	for id := 1; id <= 5; id++ {
+		td, pk := createDoc(fmt.Sprintf(`{"id":%d, "age":%d}`, id, id*10), int64(id))
+		rows = append(rows, kv.KeyValue{FDBKey: keys.NewKey(coll.EncodedName, int64(id)).SerializeToBytes(), Data: td})
+
+		updateSet, err := indexer.buildAddAndRemoveKVs(td, nil, pk)
+		require.NoError(t, err)
+		for _, indexKey := range updateSet.addKeys {
+			rows = append(rows, kv.KeyValue{FDBKey: indexKey.SerializeToBytes()})
+		}
+	}
+
+	filters, err := filter.NewFactoryForSecondaryIndex(coll.GetActiveIndexedFields()).Factorize([]byte(`{"age": {"$gte": 0}}`))
+	require.NoError(t, err)
+	plan, err := BuildSecondaryIndexKeys(coll, filters)
+	require.NoError(t, err)
+
+	// read scans the index with a transaction failing the reads chosen by fail, the first read is the one of the index
+	read := func(fail func(read int) error, restartable bool) ([]int64, int, error) {
+		reads, started := 0, 0
+		tx := &memReadTx{rows: rows, reads: &reads, fail: fail}
+		reader, err := newSecondaryIndexReaderImpl(context.Background(), tx, coll, nil, plan)
+		require.NoError(t, err)
+		if restartable {
+			reader.txMgr = txStarterFunc(func(context.Context) (transaction.Tx, error) {
+				started++
+				return &memReadTx{rows: rows, reads: &reads, fail: fail}, nil
+			})
+		}
+
+		var (
+			row Row
+			ids []int64
+		)
+		for reader.Next(&row) {
+			var doc struct {
+				ID int64 `json:"id"`
+			}
+			require.NoError(t, jsoniter.Unmarshal(row.Data.RawData, &doc))
+			ids = append(ids, doc.ID)
+		}
+
+		return ids, started, reader.Interrupted()
+	}
+
+	failRead := func(failed ...int) func(int) error {
+		return func(read int) error {
+			for _, f := range failed {
+				if f == read {
+					return kv.ErrTransactionMaxDurationReached
+				}
+			}
+			return nil
+		}
+	}
+
+	// the read of the third document fails, the scan resumes after the second one on a new transaction
+	ids, started, err := read(failRead(4), true)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+	require.Equal(t, 1, started)
+
+	// the scan of the index fails too, right after the restart
+	ids, started, err = read(failRead(4, 5), true)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, ids)
+	require.Equal(t, 2, started)
+
+	// the restarts are bounded, the scan gives up if it doesn't make progress
+	ids, started, err = read(func(read int) error {
+		if read > 2 {
+			return kv.ErrTransactionMaxDurationReached
+		}
+		return nil
+	}, true)
+	require.Equal(t, kv.ErrTransactionMaxDurationReached, err)
+	require.Equal(t, []int64{1}, ids)
+	require.Equal(t, maxScanRestarts, started)
+
+	// the other errors abort the scan
+	ids, started, err = read(func(read int) error {
+		if read == 3 {
+			return kv.ErrConflictingTransaction
+		}
+		return nil
+	}, true)
+	require.Equal(t, kv.ErrConflictingTransaction, err)
+	require.Equal(t, []int64{1}, ids)
+	require.Zero(t, started)
+
+	// a scan without a transaction manager stays in its transaction
+	ids, started, err = read(failRead(4), false)
+	require.Equal(t, kv.ErrTransactionMaxDurationReached, err)
+	require.Equal(t, []int64{1, 2}, ids)
+	require.Zero(t, started)
+}
+
+type txStarterFunc func(ctx context.Context) (transaction.Tx, error)
+
+func (f txStarterFunc) StartTx(ctx context.Context) (transaction.Tx, error) { return f(ctx) }
+
+// memReadTx serves the reads from rows held in memory, the reads for which fail returns an error fail with it. The
+// reads are numbered across the transactions sharing the counter.
+type memReadTx struct {
+	transaction.Tx
+
+	rows  []kv.KeyValue
+	reads *int
+	fail  func(read int) error
+}
+
+func (tx *memReadTx) Read(_ context.Context, key keys.Key) (kv.Iterator, error) {
+	prefix := key.SerializeToBytes()
+	return tx.read(prefix, append(append([]byte{}, prefix...), 0xFF)), nil
+}
+
+func (tx *memReadTx) ReadRange(_ context.Context, lKey keys.Key, rKey keys.Key, _ bool, _ ...kv.ReadOption) (kv.Iterator, error) {
+	return tx.read(lKey.SerializeToBytes(), rKey.SerializeToBytes()), nil
+}
+
+func (tx *memReadTx) Rollback(context.Context) error { return nil }
+
+func (tx *memReadTx) read(from []byte, to []byte) kv.Iterator {
+	*tx.reads++
+	it := &memIterator{err: tx.fail(*tx.reads)}
+	if it.err != nil {
+		return it
+	}
+
+	for _, row := range tx.rows {
+		if bytes.Compare(row.FDBKey, from) >= 0 && bytes.Compare(row.FDBKey, to) < 0 {
+			it.rows = append(it.rows, row)
+		}
+	}
+	sort.Slice(it.rows, func(i, j int) bool { return bytes.Compare(it.rows[i].FDBKey, it.rows[j].FDBKey) < 0 })
+
+	return it
+}
+
+type memIterator struct {
+	rows []kv.KeyValue
+	err  error
+}
+
+func (it *memIterator) Next(value *kv.KeyValue) bool {
+	if it.err != nil || len(it.rows) == 0 {
+		return false
+	}
+	*value, it.rows = it.rows[0], it.rows[1:]
+
+	return true
+}
+
+func (it *memIterator) Err() error { return it.err }
+
+func setupActiveIndexCollection(t *testing.T, reqSchema []byte) *schema.DefaultCollection {
+	schFactory, err := schema.NewFactoryBuilder(true).Build("t1", reqSchema)
+	require.NoError(t, err)
+	for _, idx := range schFactory.Indexes.All {
+		idx.State = schema.INDEX_ACTIVE
+	}
+
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+	coll.EncodedName = []byte("t1")
+	coll.EncodedTableIndexName = []byte("sidx1")
+
+	return coll
+}
@@ -15,6 +15,7 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -22,9 +23,11 @@ import (
 	"github.com/buger/jsonparser"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/keys"
 	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metrics"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
@@ -42,6 +45,8 @@ var (
 type SecondaryIndexer interface {
 	// Bulk build the indexes in the collection
 	BuildCollection(ctx context.Context, txMgr *transaction.Manager) error
+	// Reindex rebuilds the indexes in batches and can be resumed from a checkpoint
+	Reindex(ctx context.Context, txMgr *transaction.Manager, opts ReindexOptions) (ReindexProgress, error)
 	// Read the document from the primary store and delete it from secondary indexes
 	ReadDocAndDelete(ctx context.Context, tx transaction.Tx, key keys.Key) error
 	// Delete document from the secondary index
@@ -136,14 +141,17 @@ type SecondaryIndexerImpl struct {
 	indexAll bool
 	// Spare indexes do not index missing fields
 	sparse bool
+	// maxEntries is the maximum number of index entries a single document can generate, zero means no limit
+	maxEntries int
 }
 
 func newSecondaryIndexerImpl(coll *schema.DefaultCollection) *SecondaryIndexerImpl {
 	return &SecondaryIndexerImpl{
-		collation: value.NewCollationFrom(&api.Collation{Case: "csk"}),
-		coll:      coll,
-		indexAll:  false,
-		sparse:    false, // For now indexes are only non-sparse
+		collation:  value.NewCollationFrom(&api.Collation{Case: "csk"}),
+		coll:       coll,
+		indexAll:   false,
+		sparse:     false, // For now indexes are only non-sparse
+		maxEntries: config.DefaultConfig.SecondaryIndex.MaxEntriesPerDocument,
 	}
 }
 
@@ -208,6 +216,195 @@ func (q *SecondaryIndexerImpl) BuildCollection(ctx context.Context, txMgr *trans
 	}
 }
 
+// ReindexOptions controls a re-index of a collection.
+type ReindexOptions struct {
+	// BatchSize is the number of documents indexed in a single transaction, defaults to defaultReindexBatchSize.
+	BatchSize int
+	// Checkpoint is the key of the last indexed document of a previous run, the re-index resumes right after it.
+	Checkpoint []byte
+	// OnProgress is called after every committed batch.
+	OnProgress func(ReindexProgress)
+	// DetectDuplicates tracks the primary keys of the scanned documents to find documents whose keys are different
+	// but decode to the same primary key, which means the data is corrupted. Such a document is not indexed, as it
+	// would overwrite the index entries of the other one, and is reported in ReindexProgress.Duplicates instead. The
+	// primary keys of the whole run are kept in memory.
+	DetectDuplicates bool
+}
+
+// ReindexDuplicate is a document skipped by a re-index because its primary key collides with the one of a document
+// that was already indexed.
+type ReindexDuplicate struct {
+	// Key is the key of the skipped document
+	Key []byte
+	// Existing is the key of the indexed document with the same primary key
+	Existing []byte
+	// IndexKeys are the secondary index keys that both documents map to
+	IndexKeys [][]byte
+}
+
+// ReindexProgress is the progress of a re-index.
+type ReindexProgress struct {
+	// Documents is the number of documents indexed by this run
+	Documents int64
+	// Batches is the number of batches committed by this run
+	Batches int64
+	// Checkpoint is the key of the last indexed document, it is only moved forward once a batch is committed
+	Checkpoint []byte
+	// Duplicates are the documents skipped because of a primary key collision, only with DetectDuplicates
+	Duplicates []ReindexDuplicate
+}
+
+// reindexKeyTracker remembers the key of every scanned document by its decoded primary key to detect collisions.
+type reindexKeyTracker struct {
+	seen map[string][]byte
+}
+
+func newReindexKeyTracker() *reindexKeyTracker {
+	return &reindexKeyTracker{seen: make(map[string][]byte)}
+}
+
+// Track records the document key under its decoded primary key and returns the key of another document already
+// recorded under the same primary key, if any. Tracking the same document again, as a retried batch does, is not a
+// collision.
+func (t *reindexKeyTracker) Track(table []byte, key []byte, primaryKey []interface{}) ([]byte, bool) {
+	decoded := string(keys.NewKey(table, primaryKey...).SerializeToBytes())
+	if existing, ok := t.seen[decoded]; ok {
+		return existing, !bytes.Equal(existing, key)
+	}
+
+	t.seen[decoded] = append([]byte(nil), key...)
+	return nil, false
+}
+
+const defaultReindexBatchSize = 500
+
+// Reindex walks the collection in primary key order and writes the secondary index keys of every document, a batch
+// of documents per transaction. The key of the last document of a committed batch is reported as the checkpoint so
+// that an interrupted re-index can resume after it instead of starting over. A batch failing with a retriable error
+// is retried with half the batch size.
+func (q *SecondaryIndexerImpl) Reindex(ctx context.Context, txMgr *transaction.Manager, opts ReindexOptions) (ReindexProgress, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReindexBatchSize
+	}
+
+	var tracker *reindexKeyTracker
+	if opts.DetectDuplicates {
+		tracker = newReindexKeyTracker()
+	}
+
+	progress := ReindexProgress{Checkpoint: opts.Checkpoint}
+	for {
+		count, last, duplicates, err := q.reindexBatch(ctx, txMgr, progress.Checkpoint, batchSize, tracker)
+		if err != nil {
+			if !shouldRetryBulkIndex(err) || batchSize == 1 {
+				return progress, err
+			}
+
+			batchSize /= 2
+			continue
+		}
+
+		if count > 0 {
+			progress.Documents += int64(count - len(duplicates))
+			progress.Batches++
+			progress.Checkpoint = last
+			progress.Duplicates = append(progress.Duplicates, duplicates...)
+			if opts.OnProgress != nil {
+				opts.OnProgress(progress)
+			}
+		}
+
+		if count < batchSize {
+			log.Info().Msgf("Collection '%s' re-indexed %d docs in %d batches", q.coll.Name, progress.Documents, progress.Batches)
+			return progress, nil
+		}
+	}
+}
+
+// reindexBatch indexes up to batchSize documents after the checkpoint in a single transaction and returns the number
+// of scanned documents along with the key of the last one. With a tracker, the documents colliding with an already
+// scanned one are skipped and returned as duplicates.
+func (q *SecondaryIndexerImpl) reindexBatch(ctx context.Context, txMgr *transaction.Manager, checkpoint []byte, batchSize int, tracker *reindexKeyTracker) (int, []byte, []ReindexDuplicate, error) {
+	tx, err := txMgr.StartTx(ctx)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	iter, err := createBulkDocsReader(ctx, tx, q.coll.EncodedName, nil, checkpoint)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var (
+		row        Row
+		last       []byte
+		count      int
+		duplicates []ReindexDuplicate
+	)
+	for count < batchSize && iter.Next(&row) {
+		// the scan starts at the checkpoint which was already indexed by the previous batch
+		if checkpoint != nil && bytes.Equal(row.Key, checkpoint) {
+			continue
+		}
+
+		pk, err := keys.FromBinary(q.coll.EncodedName, row.Key)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		last = row.Key
+		count++
+
+		if tracker != nil {
+			if existing, collides := tracker.Track(q.coll.EncodedName, row.Key, pk.IndexParts()); collides {
+				duplicate, err := q.newReindexDuplicate(row, existing, pk.IndexParts())
+				if err != nil {
+					return 0, nil, nil, err
+				}
+				log.Warn().Msgf("Collection '%s' has documents with colliding primary key '%v', skipping the document",
+					q.coll.Name, pk.IndexParts())
+				duplicates = append(duplicates, duplicate)
+				continue
+			}
+		}
+
+		if err = q.Index(ctx, tx, row.Data, pk.IndexParts()); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	if err = iter.Interrupted(); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, nil, nil, err
+	}
+
+	return count, last, duplicates, nil
+}
+
+func (q *SecondaryIndexerImpl) newReindexDuplicate(row Row, existing []byte, primaryKey []interface{}) (ReindexDuplicate, error) {
+	updateSet, err := q.buildAddAndRemoveKVs(row.Data, nil, primaryKey)
+	if err != nil {
+		return ReindexDuplicate{}, err
+	}
+
+	indexKeys := make([][]byte, 0, len(updateSet.addKeys))
+	for _, indexKey := range updateSet.addKeys {
+		indexKeys = append(indexKeys, indexKey.SerializeToBytes())
+	}
+
+	return ReindexDuplicate{
+		Key:       append([]byte(nil), row.Key...),
+		Existing:  existing,
+		IndexKeys: indexKeys,
+	}, nil
+}
+
+// createBulkDocsReader scans the documents of the table. The key of a row is the key as stored in FDB, not the one
+// re-encoded from its decoded primary key, which is what allows a re-index to detect colliding primary keys.
 func createBulkDocsReader(ctx context.Context, tx transaction.Tx, table []byte, first []byte, last []byte) (Iterator, error) {
 	reader := NewDatabaseReader(ctx, tx)
 	if first != nil {
@@ -246,15 +443,13 @@ func shouldRetryBulkIndex(err error) bool {
 }
 
 func (q *SecondaryIndexerImpl) scanIndex(ctx context.Context, tx transaction.Tx) (kv.Iterator, error) {
-	start := keys.NewKey(q.coll.EncodedTableIndexName, q.coll.SecondaryIndexKeyword(), KVSubspace)
-	end := keys.NewKey(q.coll.EncodedTableIndexName, q.coll.SecondaryIndexKeyword(), KVSubspace, 0xFF)
-	return tx.ReadRange(ctx, start, end, false)
+	layout := newSecondaryIndexLayout(q.coll)
+	return tx.ReadRange(ctx, layout.Prefix(), layout.End(), false)
 }
 
 func (q *SecondaryIndexerImpl) IndexSize(ctx context.Context, tx transaction.Tx) (int64, error) {
-	lKey := keys.NewKey(q.coll.EncodedTableIndexName, q.coll.SecondaryIndexKeyword(), KVSubspace)
-	rKey := keys.NewKey(q.coll.EncodedTableIndexName, q.coll.SecondaryIndexKeyword(), KVSubspace, 0xFF)
-	return tx.RangeSize(ctx, q.coll.EncodedTableIndexName, lKey, rKey)
+	layout := newSecondaryIndexLayout(q.coll)
+	return tx.RangeSize(ctx, q.coll.EncodedTableIndexName, layout.Prefix(), layout.End())
 }
 
 // The count of the number of rows in the index is not efficient
@@ -363,6 +558,10 @@ func (q *SecondaryIndexerImpl) buildAddAndRemoveKVs(newTableData *internal.Table
 	if err != nil {
 		return nil, err
 	}
+	if q.maxEntries > 0 && len(newRows) > q.maxEntries {
+		return nil, errors.InvalidArgument("document generates %d secondary index entries, maximum allowed is %d",
+			len(newRows), q.maxEntries)
+	}
 	oldRows, err := q.buildTableRows(oldTableData)
 	if err != nil {
 		return nil, err
@@ -371,8 +570,14 @@ func (q *SecondaryIndexerImpl) buildAddAndRemoveKVs(newTableData *internal.Table
 	rowsToRemove := removeDuplicateRows(newRows, oldRows)
 	rowsToAdd := removeDuplicateRows(oldRows, newRows)
 
-	addKeys, addSizes, addCounts := q.createKeysAndIndexInfo(primaryKey, rowsToAdd)
-	removeKeys, removeSizes, removeCounts := q.createKeysAndIndexInfo(primaryKey, rowsToRemove)
+	addKeys, addSizes, addCounts, err := q.createKeysAndIndexInfo(primaryKey, rowsToAdd)
+	if err != nil {
+		return nil, err
+	}
+	removeKeys, removeSizes, removeCounts, err := q.createKeysAndIndexInfo(primaryKey, rowsToRemove)
+	if err != nil {
+		return nil, err
+	}
 
 	mergeDuplicates(addSizes, removeSizes)
 	mergeDuplicates(addCounts, removeCounts)
@@ -410,6 +615,9 @@ func (q *SecondaryIndexerImpl) buildTableRows(tableData *internal.TableData) ([]
 					return nil, err
 				}
 			}
+			for i := range newRows {
+				newRows[i].value = caseInsensitiveValue(field, newRows[i].value)
+			}
 			rows = append(rows, newRows...)
 		} else {
 			row, err := q.indexField(tableData.RawData, field.FieldName, field.DataType, 0, field.KeyPath()...)
@@ -421,6 +629,7 @@ func (q *SecondaryIndexerImpl) buildTableRows(tableData *internal.TableData) ([]
 					return nil, err
 				}
 			}
+			row.value = caseInsensitiveValue(field, row.value)
 			rows = append(rows, *row)
 		}
 	}
@@ -470,15 +679,21 @@ func (q *SecondaryIndexerImpl) buildTSRows(tableData *internal.TableData) ([]Ind
 }
 
 func (q *SecondaryIndexerImpl) buildIndexKey(row IndexRow, primaryKey []interface{}) keys.Key {
-	if row.null {
-		return newKeyWithPrimaryKey(primaryKey, q.coll.EncodedTableIndexName, q.coll.SecondaryIndexKeyword(), KVSubspace, row.Name(), value.SecondaryNullOrder(), row.value.AsInterface(), row.pos)
+	entry := &SecondaryIndexEntry{
+		Field:      row.Name(),
+		TypeOrder:  value.SecondaryNullOrder(),
+		Value:      row.value.AsInterface(),
+		ArrayPos:   row.pos,
+		PrimaryKey: primaryKey,
+	}
+	if !row.null {
+		entry.TypeOrder = value.ToSecondaryOrder(row.dataType, row.value)
 	}
 
-	dataTypeOrder := value.ToSecondaryOrder(row.dataType, row.value)
-	return newKeyWithPrimaryKey(primaryKey, q.coll.EncodedTableIndexName, q.coll.SecondaryIndexKeyword(), KVSubspace, row.Name(), dataTypeOrder, row.value.AsInterface(), row.pos)
+	return newSecondaryIndexLayout(q.coll).Encode(entry)
 }
 
-func (q *SecondaryIndexerImpl) createKeysAndIndexInfo(primaryKey []interface{}, rows []IndexRow) ([]keys.Key, map[string]int64, map[string]int64) {
+func (q *SecondaryIndexerImpl) createKeysAndIndexInfo(primaryKey []interface{}, rows []IndexRow) ([]keys.Key, map[string]int64, map[string]int64, error) {
 	indexKeys := make([]keys.Key, 0, len(rows))
 	sizeIncrease := int64(0)
 	stubs := map[string]bool{}
@@ -501,14 +716,18 @@ func (q *SecondaryIndexerImpl) createKeysAndIndexInfo(primaryKey []interface{},
 			rowCounts[row.Name()] = 1
 		}
 
-		sizeIncrease += int64(len(indexKey.SerializeToBytes()))
+		serialized, err := indexKey.Serialize()
+		if err != nil {
+			return nil, nil, nil, errors.Internal("failed to build index key for field '%s': %s", row.Name(), err.Error())
+		}
+		sizeIncrease += int64(len(serialized))
 		if val, ok := rowSizes[row.name]; ok {
 			rowSizes[row.Name()] = val + sizeIncrease
 		} else {
 			rowSizes[row.Name()] = sizeIncrease
 		}
 	}
-	return indexKeys, rowSizes, rowCounts
+	return indexKeys, rowSizes, rowCounts, nil
 }
 
 func (q *SecondaryIndexerImpl) indexField(doc []byte, fieldName string, dataType schema.FieldType, pos int, keyPath ...string) (*IndexRow, error) {
@@ -626,6 +845,21 @@ func (q *SecondaryIndexerImpl) indexArray(doc []byte, field *schema.QueryableFie
 	return rows, nil
 }
 
+// caseInsensitiveValue lowercases the string value if the field has a case-insensitive index. The lowercased string
+// is then encoded with the same sort key collation (English, case-sensitive) as any other string in the index. This is
+// used both when building the index keys and the query plan, only the index key is changed and not the document.
+func caseInsensitiveValue(field *schema.QueryableField, v value.Value) value.Value {
+	if !field.IndexCaseInsensitive {
+		return v
+	}
+
+	if s, ok := v.(*value.StringValue); ok {
+		return value.NewStringValue(strings.ToLower(s.Value), s.Collation)
+	}
+
+	return v
+}
+
 func (q *SecondaryIndexerImpl) getIndexedFields() []*schema.QueryableField {
 	if q.indexAll {
 		return q.coll.QueryableFields
@@ -634,12 +868,6 @@ func (q *SecondaryIndexerImpl) getIndexedFields() []*schema.QueryableField {
 	return q.coll.GetIndexedFields()
 }
 
-// This is used to append the Primary key to the end of the key.
-func newKeyWithPrimaryKey(id []interface{}, table []byte, indexParts ...interface{}) keys.Key {
-	indexParts = append(indexParts, id...)
-	return keys.NewKey(table, indexParts...)
-}
-
 func containsIndexRow(rows []IndexRow, field IndexRow) bool {
 	for _, row := range rows {
 		if row.IsEqual(field) {
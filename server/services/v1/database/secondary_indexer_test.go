@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/keys"
 	"github.com/tigrisdata/tigris/schema"
@@ -415,6 +416,44 @@ func TestIndexingObjectArrayKVGen(t *testing.T) {
 	assertKVs(t, expected, updateSet.addKeys, updateSet.addCounts)
 }
 
+func TestIndexingMaxEntriesPerDocument(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"arr": {
+				"type": "array",
+				"items": { "type": "integer" }
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	indexStore := setupTest(t, reqSchema)
+	indexStore.maxEntries = 5
+
+	// created_at, updated_at, id and two array items
+	td, primaryKey := createDoc(`{"id":1, "arr": [1, 2]}`)
+	updateSet, err := indexStore.buildAddAndRemoveKVs(td, nil, primaryKey)
+	assert.NoError(t, err)
+	assert.Len(t, updateSet.addKeys, 5)
+
+	td, primaryKey = createDoc(`{"id":1, "arr": [1, 2, 3]}`)
+	_, err = indexStore.buildAddAndRemoveKVs(td, nil, primaryKey)
+	assert.Equal(t, errors.InvalidArgument("document generates 6 secondary index entries, maximum allowed is 5"), err)
+
+	// the limit applies to the new version of the document on updates
+	oldTd, _ := createDoc(`{"id":1, "arr": [1, 2, 3]}`)
+	td, primaryKey = createDoc(`{"id":1, "arr": [1]}`)
+	_, err = indexStore.buildAddAndRemoveKVs(td, oldTd, primaryKey)
+	assert.NoError(t, err)
+
+	indexStore.maxEntries = 0
+	td, primaryKey = createDoc(`{"id":1, "arr": [1, 2, 3, 4, 5, 6, 7, 8]}`)
+	_, err = indexStore.buildAddAndRemoveKVs(td, nil, primaryKey)
+	assert.NoError(t, err)
+}
+
 // Add stubs for nested arrays.
 func TestIndexingArrayWithObjectAndNestedArrayKeyGen(t *testing.T) {
 	reqSchema := []byte(`{
@@ -774,6 +813,109 @@ func TestBulkIndexing(t *testing.T) {
 	assert.Equal(t, count, totalDocs*5)
 }
 
+func TestReindex(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"my_string": {
+				"index": true,
+				"type": "string"
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(t, kvStore.DropTable(ctx, []byte("t1")))
+	assert.NoError(t, kvStore.CreateTable(ctx, []byte("t1")))
+	assert.NoError(t, kvStore.DropTable(ctx, []byte("sidx1")))
+	assert.NoError(t, kvStore.CreateTable(ctx, []byte("sidx1")))
+	indexStore := setupTest(t, reqSchema)
+	tm := transaction.NewManager(kvStore)
+	coll := indexStore.coll
+	indexStore.indexAll = false
+
+	totalDocs := 7
+	tx, err := tm.StartTx(ctx)
+	assert.NoError(t, err)
+	for i := 0; i < totalDocs; i++ {
+		td, pk := createDoc(fmt.Sprintf(`{"id":%d, "my_string":"a string"}`, i), i)
+		assert.NoError(t, tx.Insert(ctx, keys.NewKey(coll.EncodedName, pk...), td))
+	}
+	assert.NoError(t, tx.Commit(ctx))
+
+	countIndexed := func() int {
+		tx, err := tm.StartTx(ctx)
+		assert.NoError(t, err)
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		iter, err := indexStore.scanIndex(ctx, tx)
+		assert.NoError(t, err)
+
+		count := 0
+		var row kv.KeyValue
+		for iter.Next(&row) {
+			count++
+		}
+		return count
+	}
+
+	// stop after the first two batches to simulate an interrupted re-index
+	var progresses []ReindexProgress
+	rctx, rcancel := context.WithCancel(ctx)
+	_, err = indexStore.Reindex(rctx, tm, ReindexOptions{
+		BatchSize: 2,
+		OnProgress: func(p ReindexProgress) {
+			progresses = append(progresses, p)
+			if p.Batches == 2 {
+				rcancel()
+			}
+		},
+	})
+	assert.Error(t, err)
+	assert.Len(t, progresses, 2)
+	assert.Equal(t, int64(4), progresses[1].Documents)
+	assert.Equal(t, keys.NewKey(coll.EncodedName, int64(3)).SerializeToBytes(), progresses[1].Checkpoint)
+	// every document adds an entry for the indexed field and the two reserved timestamp fields
+	assert.Equal(t, 4*3, countIndexed())
+
+	progress, err := indexStore.Reindex(ctx, tm, ReindexOptions{
+		BatchSize:  2,
+		Checkpoint: progresses[1].Checkpoint,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), progress.Documents)
+	assert.Equal(t, int64(2), progress.Batches)
+	assert.Equal(t, keys.NewKey(coll.EncodedName, int64(6)).SerializeToBytes(), progress.Checkpoint)
+	assert.Equal(t, totalDocs*3, countIndexed())
+}
+
+func TestReindexKeyTracker(t *testing.T) {
+	tracker := newReindexKeyTracker()
+	table := []byte("t1")
+	canonical := keys.NewKey(table, int64(1)).SerializeToBytes()
+	// a key with a wider integer encoding than needed, which decodes to the same primary key
+	corrupted := append(append([]byte{}, table...), 0x16, 0x00, 0x01)
+
+	_, collides := tracker.Track(table, canonical, []interface{}{int64(1)})
+	assert.False(t, collides)
+	_, collides = tracker.Track(table, keys.NewKey(table, int64(2)).SerializeToBytes(), []interface{}{int64(2)})
+	assert.False(t, collides)
+
+	// tracking the same document again is not a collision
+	_, collides = tracker.Track(table, canonical, []interface{}{int64(1)})
+	assert.False(t, collides)
+
+	existing, collides := tracker.Track(table, corrupted, []interface{}{int64(1)})
+	assert.True(t, collides)
+	assert.Equal(t, canonical, existing)
+}
+
 func setupTest(t *testing.T, reqSchema []byte) *SecondaryIndexerImpl {
 	schFactory, err := schema.NewFactoryBuilder(true).Build("t1", reqSchema)
 	assert.NoError(t, err)
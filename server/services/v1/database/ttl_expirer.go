@@ -0,0 +1,380 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/lib/uuid"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/store/search"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+const (
+	// ttlLeaseTask is the name of the cluster lease held by the server expiring the documents.
+	ttlLeaseTask = "ttl_expiry"
+	// ttlSearchDeleteKind is the kind of the queue items holding the search documents of the expired documents.
+	ttlSearchDeleteKind = "ttl_search_delete"
+)
+
+var (
+	// ttlBatchSize is the maximum number of documents expired in a single transaction.
+	ttlBatchSize = 500
+	// ttlSearchDeleteLease is how long a queued search delete is leased while it is processed, it is retried once the
+	// lease expires if it wasn't completed.
+	ttlSearchDeleteLease = time.Minute
+)
+
+// ttlSearchDelete is the queue item of the search documents of a batch of expired documents. It is queued in the
+// transaction deleting the documents, so that the search documents are deleted even if the server stops or the search
+// store fails after the transaction is committed.
+type ttlSearchDelete struct {
+	Kind  string   `json:"kind"`
+	Index string   `json:"index"`
+	Keys  []string `json:"keys"`
+}
+
+// TTLExpirer deletes the documents of the collections with a ttl once they are older than it. The age of a document
+// is its first primary key field, an auto-generated date-time, so the keys are ordered by age and the expired
+// documents are always a range at the start of the collection which is cleared with a single range delete. The
+// expired documents are still read to remove their secondary index entries and their search documents, which are not
+// ordered by age.
+//
+// The documents are expired by a single server of the cluster at a time, the one holding the cluster lease.
+type TTLExpirer struct {
+	txMgr       *transaction.Manager
+	tenantMgr   *metadata.TenantManager
+	searchStore search.Store
+	encoder     metadata.Encoder
+	cluster     *metadata.ClusterSubspace
+	queue       *metadata.QueueSubspace
+	// owner identifies the expirer in the cluster lease.
+	owner string
+}
+
+func NewTTLExpirer(txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, searchStore search.Store) *TTLExpirer {
+	return &TTLExpirer{
+		txMgr:       txMgr,
+		tenantMgr:   tenantMgr,
+		searchStore: searchStore,
+		encoder:     metadata.NewEncoder(),
+		cluster:     metadata.NewClusterStore(metadata.DefaultNameRegistry),
+		queue:       metadata.NewQueueStore(metadata.DefaultNameRegistry),
+		owner:       uuid.NewUUIDAsString(),
+	}
+}
+
+// Start expires the documents every interval in the background until the context is done. The documents are only
+// expired while the expirer holds the cluster lease, which it extends every interval. The lease outlives two intervals
+// so that another server takes over once the server holding it has stopped.
+func (e *TTLExpirer) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				if e.obtainLease(ctx, 2*interval) {
+					e.expireAll(ctx, time.Now())
+				}
+			case <-ctx.Done():
+				log.Debug().Msg("TTL expiry loop exited")
+				return
+			}
+		}
+	}()
+}
+
+// obtainLease returns true if the expirer holds the cluster lease for the duration.
+func (e *TTLExpirer) obtainLease(ctx context.Context, duration time.Duration) bool {
+	tx, err := e.txMgr.StartTx(ctx)
+	if ulog.E(err) {
+		return false
+	}
+
+	obtained, err := e.cluster.ObtainLease(ctx, tx, ttlLeaseTask, e.owner, duration)
+	if ulog.E(err) || !obtained {
+		_ = tx.Rollback(ctx)
+		return false
+	}
+
+	return !ulog.E(tx.Commit(ctx))
+}
+
+func (e *TTLExpirer) expireAll(ctx context.Context, now time.Time) {
+	// the search deletes left by a previous run are retried first
+	if err := e.deleteSearchDocuments(ctx); err != nil {
+		log.Err(err).Msg("deleting expired search documents failed")
+	}
+
+	for _, namespace := range e.tenantMgr.GetNamespaceNames() {
+		tenant, err := e.tenantMgr.GetTenant(ctx, namespace)
+		if ulog.E(err) {
+			continue
+		}
+
+		for _, projName := range tenant.ListProjects(ctx) {
+			project, err := tenant.GetProject(projName)
+			if err != nil {
+				// the project may have been dropped since it was listed
+				continue
+			}
+
+			for _, db := range project.GetDatabaseWithBranches() {
+				for _, coll := range db.ListCollection() {
+					if coll.TTL == 0 {
+						continue
+					}
+
+					count, err := e.ExpireCollection(ctx, coll, now)
+					if err != nil {
+						log.Err(err).Str("ns", namespace).Str("db", db.Name()).Str("collection", coll.Name).
+							Msg("expiring documents failed")
+						continue
+					}
+					if count > 0 {
+						log.Debug().Str("ns", namespace).Str("db", db.Name()).Str("collection", coll.Name).
+							Int("count", count).Msg("expired documents")
+					}
+				}
+			}
+		}
+	}
+
+	if err := e.deleteSearchDocuments(ctx); err != nil {
+		log.Err(err).Msg("deleting expired search documents failed")
+	}
+}
+
+// ExpireCollection deletes the documents of the collection that are older than its ttl at the time now, in batches
+// of ttlBatchSize documents per transaction. It returns the number of deleted documents. The search documents of the
+// deleted documents are queued, to be deleted by deleteSearchDocuments.
+func (e *TTLExpirer) ExpireCollection(ctx context.Context, coll *schema.DefaultCollection, now time.Time) (int, error) {
+	start, err := e.encoder.EncodeKey(coll.EncodedName, coll.GetPrimaryKey(), nil)
+	if err != nil {
+		return 0, err
+	}
+	end, err := e.encoder.EncodeKey(coll.EncodedName, coll.GetPrimaryKey(), []interface{}{ttlCutoff(now, coll.TTL)})
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		count, done, err := e.expireBatch(ctx, coll, start, end)
+		if err != nil {
+			return total, err
+		}
+		total += count
+
+		if done {
+			return total, nil
+		}
+	}
+}
+
+// expireBatch deletes at most ttlBatchSize documents in the range [start, end) and queues the deletion of their
+// search documents in the same transaction. It returns true once the range is empty.
+func (e *TTLExpirer) expireBatch(ctx context.Context, coll *schema.DefaultCollection, start keys.Key, end keys.Key) (int, bool, error) {
+	tx, err := e.txMgr.StartTx(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	iter, err := NewScanIterator(ctx, tx, start, end)
+	if err != nil {
+		return 0, false, err
+	}
+
+	indexer := NewSecondaryIndexer(coll)
+
+	var (
+		row        Row
+		last       keys.Key
+		count      int
+		searchKeys []string
+	)
+	for count < ttlBatchSize && iter.Next(&row) {
+		pk, err := keys.FromBinary(coll.EncodedName, row.Key)
+		if err != nil {
+			return 0, false, err
+		}
+		if err = indexer.Delete(ctx, tx, row.Data, pk.IndexParts()); err != nil {
+			return 0, false, err
+		}
+
+		searchKey, err := CreateSearchKey(kv.BuildKey(pk.IndexParts()...))
+		if err != nil {
+			return 0, false, err
+		}
+		searchKeys = append(searchKeys, searchKey)
+
+		last = pk
+		count++
+	}
+	if err = iter.Interrupted(); err != nil {
+		return 0, false, err
+	}
+
+	// a full batch may be followed by more expired documents, only the documents read so far are deleted
+	done := count < ttlBatchSize
+	rangeEnd := end
+	if !done {
+		rangeEnd = last
+		if err = tx.Delete(ctx, last); err != nil {
+			return 0, false, err
+		}
+	}
+	if err = tx.DeleteRange(ctx, start, rangeEnd); err != nil {
+		return 0, false, err
+	}
+
+	if err = e.queueSearchDelete(ctx, tx, coll, searchKeys); err != nil {
+		return 0, false, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+
+	return count, done, nil
+}
+
+// queueSearchDelete queues the deletion of the search documents of the expired documents, the range delete doesn't
+// emit the delete events the search indexer relies on.
+func (e *TTLExpirer) queueSearchDelete(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection,
+	searchKeys []string,
+) error {
+	if !config.DefaultConfig.Search.WriteEnabled || len(searchKeys) == 0 {
+		return nil
+	}
+
+	searchIndex := coll.GetImplicitSearchIndex()
+	if searchIndex == nil {
+		return nil
+	}
+
+	data, err := jsoniter.Marshal(&ttlSearchDelete{
+		Kind:  ttlSearchDeleteKind,
+		Index: searchIndex.StoreIndexName(),
+		Keys:  searchKeys,
+	})
+	if err != nil {
+		return err
+	}
+
+	return e.queue.Enqueue(ctx, tx, metadata.NewQueueItem(0, data), 0)
+}
+
+// deleteSearchDocuments deletes the queued search documents of the expired documents. A queued delete is leased while
+// its documents are deleted and is only removed from the queue once they are, a delete that fails is retried once its
+// lease expires.
+func (e *TTLExpirer) deleteSearchDocuments(ctx context.Context) error {
+	for {
+		items, deletes, err := e.leaseSearchDeletes(ctx)
+		if err != nil || len(items) == 0 {
+			return err
+		}
+
+		for i := range items {
+			for _, key := range deletes[i].Keys {
+				if err = e.searchStore.DeleteDocument(ctx, deletes[i].Index, key); err != nil && !search.IsErrNotFound(err) {
+					return err
+				}
+			}
+
+			if err = e.completeSearchDelete(ctx, &items[i]); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// leaseSearchDeletes leases the queued search deletes that are ready to be processed.
+func (e *TTLExpirer) leaseSearchDeletes(ctx context.Context) ([]metadata.QueueItem, []ttlSearchDelete, error) {
+	tx, err := e.txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ready, err := e.queue.Peek(ctx, tx, ttlBatchSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		items   []metadata.QueueItem
+		deletes []ttlSearchDelete
+	)
+	for i := range ready {
+		var d ttlSearchDelete
+		if err = jsoniter.Unmarshal(ready[i].Data, &d); err != nil || d.Kind != ttlSearchDeleteKind {
+			// queued by another worker
+			continue
+		}
+
+		item, err := e.queue.ObtainLease(ctx, tx, &ready[i], ttlSearchDeleteLease)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, *item)
+		deletes = append(deletes, d)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return items, deletes, nil
+}
+
+func (e *TTLExpirer) completeSearchDelete(ctx context.Context, item *metadata.QueueItem) error {
+	tx, err := e.txMgr.StartTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = e.queue.Complete(ctx, tx, item); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ttlCutoff returns the key value before which the documents are expired. The auto-generated date-time keys are UTC
+// RFC 3339 strings with a variable number of fractional digits, which are not ordered within a second as "05Z" sorts
+// after "05.1Z". The cutoff is therefore truncated to the second and has no suffix, so that it sorts before all the
+// values of its second and after all the values of the previous seconds. A document is expired at most a second late.
+func ttlCutoff(now time.Time, ttl time.Duration) string {
+	return now.Add(-ttl).UTC().Truncate(time.Second).Format("2006-01-02T15:04:05")
+}
@@ -0,0 +1,104 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/search"
+)
+
+func TestTTLCutoff(t *testing.T) {
+	now := time.Date(2023, 1, 2, 10, 0, 5, 500000000, time.UTC)
+	cutoff := ttlCutoff(now, time.Hour)
+	require.Equal(t, "2023-01-02T09:00:05", cutoff)
+
+	encode := func(v string) []byte {
+		return keys.NewKey([]byte("t"), v).SerializeToBytes()
+	}
+
+	// the keys are compared the way they are stored, as tuple encoded strings
+	for _, expired := range []string{"2023-01-02T09:00:04Z", "2023-01-02T09:00:04.999999999Z", "2022-12-31T23:59:59.1Z"} {
+		require.Equal(t, -1, bytes.Compare(encode(expired), encode(cutoff)), expired)
+	}
+	for _, alive := range []string{"2023-01-02T09:00:05Z", "2023-01-02T09:00:05.000000001Z", "2023-01-02T09:30:00.5Z"} {
+		require.Equal(t, 1, bytes.Compare(encode(alive), encode(cutoff)), alive)
+	}
+}
+
+type failingDeleteStore struct {
+	search.NoopStore
+	fail    bool
+	deleted []string
+}
+
+func (s *failingDeleteStore) DeleteDocument(_ context.Context, index string, key string) error {
+	if s.fail {
+		return fmt.Errorf("search store unavailable")
+	}
+	s.deleted = append(s.deleted, index+"/"+key)
+	return nil
+}
+
+func TestTTLSearchDeleteRetried(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	defer func(lease time.Duration) { ttlSearchDeleteLease = lease }(ttlSearchDeleteLease)
+	ttlSearchDeleteLease = 100 * time.Millisecond
+
+	store := &failingDeleteStore{fail: true}
+	e := &TTLExpirer{
+		txMgr:       transaction.NewManager(kvStore),
+		searchStore: store,
+		queue:       metadata.NewQueueStore(&metadata.NameRegistry{QueueSB: "test_queue_" + t.Name()}),
+	}
+	_ = kvStore.DropTable(ctx, []byte("test_queue_"+t.Name()))
+
+	data, err := jsoniter.Marshal(&ttlSearchDelete{Kind: ttlSearchDeleteKind, Index: "idx", Keys: []string{"k1", "k2"}})
+	require.NoError(t, err)
+
+	tx, err := e.txMgr.StartTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, e.queue.Enqueue(ctx, tx, metadata.NewQueueItem(0, data), 0))
+	require.NoError(t, tx.Commit(ctx))
+
+	// the delete stays queued when the search store fails
+	require.Error(t, e.deleteSearchDocuments(ctx))
+	require.Empty(t, store.deleted)
+
+	// and is retried once its lease has expired
+	store.fail = false
+	require.NoError(t, e.deleteSearchDocuments(ctx))
+	require.Empty(t, store.deleted)
+
+	time.Sleep(2 * ttlSearchDeleteLease)
+	require.NoError(t, e.deleteSearchDocuments(ctx))
+	require.Equal(t, []string{"idx/k1", "idx/k2"}, store.deleted)
+
+	// and is removed from the queue once completed
+	time.Sleep(2 * ttlSearchDeleteLease)
+	require.NoError(t, e.deleteSearchDocuments(ctx))
+	require.Len(t, store.deleted, 2)
+}
@@ -118,8 +118,8 @@ func (m *managementService) CreateNamespace(ctx context.Context, req *api.Create
 
 	// Create a Billing account, if it fails metrics reporter will retry in a separate flow
 	// does not block namespace creation
-	billingId, err := m.BillingProvider.CreateAccount(ctx, id, req.GetName())
-	if !ulog.E(err) && billingId != uuid2.NullUUID {
+	billingId, err := m.BillingProvider.CreateAccount(ctx, id, req.GetName(), nil)
+	if !ulog.E(err) && billingId != "" {
 		// account creation succeeds, update namespace metadata
 		meta.Accounts.AddMetronome(billingId.String())
 		// add tenant to default plan
@@ -18,8 +18,11 @@ import (
 	"context"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/DataDog/datadog-api-client-go/api/v1/datadog"
 	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -27,6 +30,7 @@ import (
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/metrics"
 	"github.com/tigrisdata/tigris/server/quota"
@@ -55,67 +59,153 @@ type Datadog struct {
 }
 
 func (dd *Datadog) QueryTimeSeriesMetrics(ctx context.Context, req *api.QueryTimeSeriesMetricsRequest) (*api.QueryTimeSeriesMetricsResponse, error) {
-	if err := validateQueryTimeSeriesMetricsRequest(req); err != nil {
+	project, err := metricsProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateQueryTimeSeriesMetricsRequest(req); err != nil {
+		return nil, err
+	}
+	if err := normalizeQueryWindow(req, time.Now()); err != nil {
+		return nil, err
+	}
+	timeAggregation, err := metrics.ParseTimeAggregation(api.GetHeader(ctx, api.HeaderMetricsTimeAggregation))
+	if err != nil {
+		return nil, err
+	}
+	if err = validateTimeAggregation(timeAggregation, req); err != nil {
 		return nil, err
 	}
 
-	namespace, _ := request.GetNamespace(ctx)
-	ddQuery, err := metrics.FormDatadogQuery(namespace, req)
+	ddQuery, err := formTenantQuery(ctx, project, req)
 	if err != nil {
-		return nil, errors.Internal("Failed to query metrics: reason = " + err.Error())
+		return nil, err
 	}
+	ddQuery = timeAggregation.Apply(ddQuery)
 
 	ddResp, err := dd.Datadog.Query(ctx, req.From, req.To, ddQuery)
 	if err != nil {
-		return nil, errors.Internal("Failed to query metrics: reason = " + err.Error())
+		// the errors are already reported with the code and the reason of the failure
+		return nil, err
 	}
+	if err = validateSeriesCount(len(ddResp.Series)); err != nil {
+		return nil, err
+	}
+
+	return toQueryTimeSeriesMetricsResponse(ddResp), nil
+}
 
-	result := api.QueryTimeSeriesMetricsResponse{
-		From:  ddResp.GetFromDate(),
-		To:    ddResp.GetToDate(),
-		Query: ddResp.GetQuery(),
+// validateTimeAggregation rejects a time aggregation set along with a rollup in the additional functions of the
+// request, both are a rollup of the query and the provider rejects a query with two of them.
+func validateTimeAggregation(ta *metrics.TimeAggregation, req *api.QueryTimeSeriesMetricsRequest) error {
+	if ta == nil {
+		return nil
 	}
-	result.Series = []*api.MetricSeries{}
-	if err != nil {
-		return nil, errors.Internal("Failed to unmarshal remote response: reason = " + err.Error())
+
+	for _, f := range req.AdditionalFunctions {
+		if f.GetRollup() != nil {
+			return errors.InvalidArgument("Failed to query metrics: reason = time aggregation can't be combined with a rollup function")
+		}
 	}
 
-	if len(ddResp.Series) > 0 {
-		for _, series := range ddResp.Series {
-			thisSeries := &api.MetricSeries{
-				From:   series.GetStart(),
-				To:     series.GetEnd(),
-				Metric: series.GetMetric(),
-				Scope:  series.GetScope(),
-			}
-			thisSeries.DataPoints = make([]*api.DataPoint, len(series.GetPointlist()))
-			for i, v := range series.GetPointlist() {
-				thisSeries.DataPoints[i] = &api.DataPoint{}
-				if len(v) < 2 || v[0] == nil || v[1] == nil {
-					log.Debug().Msg("Malformed data point returned")
-				} else {
-					thisSeries.DataPoints[i].Timestamp = int64(*v[0])
-					thisSeries.DataPoints[i].Value = *v[1]
-				}
-			}
-			result.Series = append(result.Series, thisSeries)
+	return nil
+}
+
+// metricsProject returns the project a metrics query is scoped to, set with the Tigris-Metrics-Project header. A
+// caller with a project scoped token can only query the metrics of its project, which is used if the header isn't set.
+func metricsProject(ctx context.Context) (string, error) {
+	project := api.GetHeader(ctx, api.HeaderMetricsProject)
+	if scoped, err := request.GetProject(ctx); err == nil {
+		if len(project) > 0 && project != scoped {
+			return "", errors.PermissionDenied("Failed to query metrics: reason = project '%s' doesn't match the project of the request", project)
 		}
-		return &result, nil
+		project = scoped
 	}
 
-	log.Debug().Msg("Unexpected remote response: reason = 0 series returned")
-	return &result, nil
+	if !isAllowedMetricQueryInput(project) {
+		return "", errors.PermissionDenied("Failed to query metrics: reason = invalid character detected in the input")
+	}
+
+	return project, nil
 }
 
-func (dd *Datadog) QueryQuotaUsage(ctx context.Context, _ *api.QuotaUsageRequest) (*api.QuotaUsageResponse, error) {
-	ns, _ := request.GetNamespace(ctx)
+// formTenantQuery forms the query of the request scoped to the namespace of the caller, and to the project if one is
+// set. The query is never formed without the tenant tag, it would return the metrics of all the tenants, so a
+// namespace that can't be resolved is rejected.
+func formTenantQuery(ctx context.Context, project string, req *api.QueryTimeSeriesMetricsRequest) (string, error) {
+	namespace, err := request.GetNamespace(ctx)
+	if err != nil || len(namespace) == 0 || namespace == defaults.UnknownValue {
+		return "", errors.PermissionDenied("Failed to query metrics: reason = namespace of the request is unknown")
+	}
 
-	q := quota.Datadog{Datadog: dd.Datadog}
-	ru, wu, err := q.CurRates(ctx, ns)
+	ddQuery, err := metrics.FormDatadogProjectQuery(namespace, project, req)
 	if err != nil {
-		return nil, errors.Internal("error reading quota usage")
+		return "", errors.Internal("Failed to query metrics: reason = " + err.Error())
 	}
 
+	return ddQuery, nil
+}
+
+// toQueryTimeSeriesMetricsResponse converts the response of the provider. A query grouped by some tags, through the
+// SpaceAggregatedBy of the request, returns one series per group, all of them are returned.
+func toQueryTimeSeriesMetricsResponse(ddResp *datadog.MetricsQueryResponse) *api.QueryTimeSeriesMetricsResponse {
+	result := &api.QueryTimeSeriesMetricsResponse{
+		From:   ddResp.GetFromDate(),
+		To:     ddResp.GetToDate(),
+		Query:  ddResp.GetQuery(),
+		Series: make([]*api.MetricSeries, 0, len(ddResp.Series)),
+	}
+
+	if len(ddResp.Series) == 0 {
+		log.Debug().Msg("Unexpected remote response: reason = 0 series returned")
+	}
+	for i := range ddResp.Series {
+		result.Series = append(result.Series, toMetricSeries(&ddResp.Series[i]))
+	}
+
+	return result
+}
+
+// toMetricSeries converts a series of the provider. The scope of the series holds the values of the tags the query is
+// grouped by, which is how the series of a grouped query are told apart.
+func toMetricSeries(series *datadog.MetricsQueryMetadata) *api.MetricSeries {
+	thisSeries := &api.MetricSeries{
+		From:   series.GetStart(),
+		To:     series.GetEnd(),
+		Metric: series.GetMetric(),
+		Scope:  seriesScope(series),
+	}
+
+	thisSeries.DataPoints = make([]*api.DataPoint, len(series.GetPointlist()))
+	for i, v := range series.GetPointlist() {
+		thisSeries.DataPoints[i] = &api.DataPoint{}
+		if len(v) < 2 || v[0] == nil || v[1] == nil {
+			log.Debug().Msg("Malformed data point returned")
+		} else {
+			thisSeries.DataPoints[i].Timestamp = int64(*v[0])
+			thisSeries.DataPoints[i].Value = *v[1]
+		}
+	}
+
+	return thisSeries
+}
+
+// seriesScope returns the scope of the series, falling back to its sorted group by tags, formatted as a scope, when
+// the provider doesn't return the scope.
+func seriesScope(series *datadog.MetricsQueryMetadata) string {
+	if scope := series.GetScope(); len(scope) > 0 || len(series.TagSet) == 0 {
+		return scope
+	}
+
+	tags := append([]string(nil), series.TagSet...)
+	sort.Strings(tags)
+
+	return strings.Join(tags, ",")
+}
+
+func (dd *Datadog) QueryQuotaUsage(ctx context.Context, _ *api.QuotaUsageRequest) (*api.QuotaUsageResponse, error) {
+	ns, _ := request.GetNamespace(ctx)
+
 	tenant, err := dd.Tenants.GetTenant(ctx, ns)
 	if err != nil {
 		return nil, errors.Internal("error reading storage quota usage")
@@ -126,18 +216,15 @@ func (dd *Datadog) QueryQuotaUsage(ctx context.Context, _ *api.QuotaUsageRequest
 		return nil, errors.Internal("error reading storage quota usage")
 	}
 
-	rt, err := dd.Datadog.GetCurrentMetricValue(ctx, ns, "tigris.quota_throttled_read_units.count", api.TigrisOperation_ALL, quota.RunningAverageLength)
-	if err != nil {
-		return nil, errors.Internal("error reading quota usage")
-	}
-
-	wt, err := dd.Datadog.GetCurrentMetricValue(ctx, ns, "tigris.quota_throttled_write_units.count", api.TigrisOperation_ALL, quota.RunningAverageLength)
-	if err != nil {
-		return nil, errors.Internal("error reading quota usage")
-	}
+	q := quota.Datadog{Datadog: dd.Datadog}
 
-	st, err := dd.Datadog.GetCurrentMetricValue(ctx, ns, "tigris.quota_throttled_storage.count", api.TigrisOperation_ALL, quota.RunningAverageLength)
-	if err != nil {
+	var ru, wu, rt, wt, st int64
+	queries := append(q.CurRatesQueries(ns, &ru, &wu),
+		dd.Datadog.CurrentMetricValueQuery(ns, "tigris.quota_throttled_read_units.count", api.TigrisOperation_ALL, quota.RunningAverageLength, &rt),
+		dd.Datadog.CurrentMetricValueQuery(ns, "tigris.quota_throttled_write_units.count", api.TigrisOperation_ALL, quota.RunningAverageLength, &wt),
+		dd.Datadog.CurrentMetricValueQuery(ns, "tigris.quota_throttled_storage.count", api.TigrisOperation_ALL, quota.RunningAverageLength, &st),
+	)
+	if err = dd.Datadog.RunQueries(ctx, queries...); err != nil {
 		return nil, errors.Internal("error reading quota usage")
 	}
 
@@ -152,28 +239,82 @@ func (dd *Datadog) QueryQuotaUsage(ctx context.Context, _ *api.QuotaUsageRequest
 }
 
 func newObservabilityService(tenants *metadata.TenantManager) *observabilityService {
+	return &observabilityService{
+		UnimplementedObservabilityServer: api.UnimplementedObservabilityServer{},
+		Provider:                         newObservableProvider(tenants),
+	}
+}
+
+// newObservableProvider returns the configured provider, or nil if there is none or its configuration is invalid. The
+// server still starts without a provider, only the endpoints that need it are failing.
+func newObservableProvider(tenants *metadata.TenantManager) observableProvider {
 	cfg := config.DefaultConfig.Observability
 
 	log.Debug().Str("provider", cfg.Provider).Bool("enabled", cfg.Enabled).Str("url", cfg.ProviderUrl).Msg("Initializing observability service")
 
-	if cfg.Provider == "datadog" {
-		return &observabilityService{
-			UnimplementedObservabilityServer: api.UnimplementedObservabilityServer{},
-			Provider: &Datadog{
-				Tenants: tenants,
-				Datadog: metrics.InitDatadog(&config.DefaultConfig),
-			},
+	if cfg.Provider != "datadog" {
+		if cfg.Enabled {
+			log.Error().Str("observabilityProvider", cfg.Provider).Msg("Unable to configure external observability provider, disabling it")
 		}
+		return nil
+	}
+
+	if _, _, err := metrics.ParseProviderURL(cfg.ProviderUrl); err != nil && cfg.Enabled {
+		log.Error().Err(err).Str("url", cfg.ProviderUrl).Msg("Invalid observability provider url, disabling it")
+		return nil
 	}
-	if cfg.Enabled {
-		log.Error().Str("observabilityProvider", cfg.Provider).Msg("Unable to configure external observability provider")
-		panic("Unable to configure external observability provider")
+	if _, err := metrics.ProviderAuthHeaders(&cfg); err != nil && cfg.Enabled {
+		log.Error().Err(err).Msg("Invalid observability provider auth headers, disabling it")
+		return nil
 	}
-	return nil
+
+	var provider observableProvider = &Datadog{
+		Tenants: tenants,
+		Datadog: metrics.InitDatadog(&config.DefaultConfig),
+	}
+	if cfg.CacheTTL > 0 {
+		provider = newCachedProvider(provider, cfg.CacheTTL, cfg.CacheMaxEntries)
+	}
+
+	return provider
 }
 
 func (o *observabilityService) QueryTimeSeriesMetrics(ctx context.Context, req *api.QueryTimeSeriesMetricsRequest) (*api.QueryTimeSeriesMetricsResponse, error) {
-	return o.Provider.QueryTimeSeriesMetrics(ctx, req)
+	if o.Provider == nil {
+		return nil, errors.Unimplemented("Failed to query metrics: reason = observability provider is not configured")
+	}
+
+	format, err := parseTimestampFormat(api.GetHeader(ctx, api.HeaderMetricsTimestampFormat))
+	if err != nil {
+		return nil, err
+	}
+	if window := api.GetHeader(ctx, api.HeaderMetricsWindow); len(window) > 0 {
+		if err = resolveRelativeWindow(req, window, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	header := api.GetHeader(ctx, api.HeaderMetricsPercentile)
+	if len(header) == 0 {
+		resp, err := o.Provider.QueryTimeSeriesMetrics(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return timestampResponse(resp, format), nil
+	}
+
+	p, err := parsePercentile(header)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.Provider.QueryTimeSeriesMetrics(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return timestampResponse(percentileResponse(resp, p), format), nil
 }
 
 func (o *observabilityService) QuotaLimits(ctx context.Context, _ *api.QuotaLimitsRequest) (*api.QuotaLimitsResponse, error) {
@@ -190,6 +331,10 @@ func (o *observabilityService) QuotaLimits(ctx context.Context, _ *api.QuotaLimi
 }
 
 func (o *observabilityService) QuotaUsage(ctx context.Context, request *api.QuotaUsageRequest) (*api.QuotaUsageResponse, error) {
+	if o.Provider == nil {
+		return nil, errors.Unimplemented("Failed to read quota usage: reason = observability provider is not configured")
+	}
+
 	return o.Provider.QueryQuotaUsage(ctx, request)
 }
 
@@ -208,8 +353,9 @@ func (o *observabilityService) RegisterHTTP(router chi.Router, inproc *inprocgrp
 	}
 
 	api.RegisterObservabilityServer(inproc, o)
+	router.Post(observabilityTailPattern, tailMetricsHandler(mux, api.NewObservabilityClient(inproc)))
 	router.HandleFunc(observabilityPattern, func(w http.ResponseWriter, r *http.Request) {
-		mux.ServeHTTP(w, r)
+		serveWithTimestampFormat(mux, w, r)
 	})
 	return nil
 }
@@ -219,11 +365,68 @@ func (o *observabilityService) RegisterGRPC(grpc *grpc.Server) error {
 	return nil
 }
 
+func isAllowedMetricName(name string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == a {
+			return true
+		}
+	}
+
+	return false
+}
+
 func isAllowedMetricQueryInput(tagValue string) bool {
 	allowedPattern := regexp.MustCompile("^[a-zA-Z0-9_.]*$")
 	return allowedPattern.MatchString(tagValue)
 }
 
+// validateSeriesCount rejects a response with more series than the configured maximum.
+func validateSeriesCount(count int) error {
+	if limit := config.DefaultConfig.Observability.MaxSeries; limit > 0 && count > limit {
+		return errors.InvalidArgument("Failed to query metrics: reason = query returned %d series, maximum allowed is %d", count, limit)
+	}
+	return nil
+}
+
+// msTimestampThreshold is the smallest timestamp treated as unix milliseconds. As unix seconds it is in the year 5138,
+// while as unix milliseconds it is in March 1973, before any metric could have been recorded.
+const msTimestampThreshold = 100_000_000_000
+
+// maxQueryWindowFutureSkew is how far in the future the end of a queried window can be.
+const maxQueryWindowFutureSkew = 24 * time.Hour
+
+// normalizeQueryWindow converts the from and to of the request to unix seconds, as expected by the provider, if they
+// are in unix milliseconds, which is detected from their magnitude. It then checks that the window is sane, a window
+// that is empty or ends far in the future is a client mistake that would otherwise silently return no data.
+func normalizeQueryWindow(req *api.QueryTimeSeriesMetricsRequest, now time.Time) error {
+	if req.From >= msTimestampThreshold {
+		req.From /= 1000
+	}
+	if req.To >= msTimestampThreshold {
+		req.To /= 1000
+	}
+
+	if req.From <= 0 || req.To <= 0 {
+		return errors.InvalidArgument("Failed to query metrics: reason = from and to must be positive unix timestamps")
+	}
+	if req.From >= req.To {
+		return errors.InvalidArgument("Failed to query metrics: reason = from '%d' must be before to '%d'", req.From, req.To)
+	}
+	if req.To > now.Add(maxQueryWindowFutureSkew).Unix() {
+		return errors.InvalidArgument("Failed to query metrics: reason = to '%d' is too far in the future", req.To)
+	}
+
+	return nil
+}
+
 func validateQueryTimeSeriesMetricsRequest(req *api.QueryTimeSeriesMetricsRequest) error {
 	if !isAllowedMetricQueryInput(req.MetricName) || !isAllowedMetricQueryInput(req.Db) || !isAllowedMetricQueryInput(req.Collection) {
 		return errors.PermissionDenied("Failed to query metrics: reason = invalid character detected in the input")
@@ -236,8 +439,23 @@ func validateQueryTimeSeriesMetricsRequest(req *api.QueryTimeSeriesMetricsReques
 	if strings.Contains(req.MetricName, ":") {
 		return errors.InvalidArgument("Failed to query metrics: reason = Metric name cannot contain :")
 	}
-	if !(req.Quantile == 0 || req.Quantile == 0.5 || req.Quantile == 0.75 || req.Quantile == 0.95 || req.Quantile == 0.99 || req.Quantile == 0.999) {
-		return errors.InvalidArgument("Failed to query metrics: reason = allowed quantile values are [0.5, 0.75, 0.95, 0.99, 0.999]")
+	if !isAllowedMetricName(req.MetricName, config.DefaultConfig.Observability.AllowedMetrics) {
+		return errors.PermissionDenied("Failed to query metrics: reason = metric '%s' is not allowed", req.MetricName)
+	}
+	// zero is the unset quantile, any other value must be within (0, 1)
+	if req.Quantile != 0 && !(req.Quantile > 0 && req.Quantile < 1) {
+		return errors.InvalidArgument("Failed to query metrics: reason = quantile must be within (0, 1), received %v", req.Quantile)
+	}
+	for _, f := range req.AdditionalFunctions {
+		if f.GetRollup() == nil {
+			continue
+		}
+		if !metrics.IsSupportedRollupAggregator(f.Rollup.Aggregator) {
+			return errors.InvalidArgument("Failed to query metrics: reason = unsupported rollup aggregator '%s', allowed aggregators are avg, sum, min, max and count", f.Rollup.Aggregator)
+		}
+		if f.Rollup.Interval < 0 {
+			return errors.InvalidArgument("Failed to query metrics: reason = rollup interval must not be negative, received %d", f.Rollup.Interval)
+		}
 	}
 	return nil
 }
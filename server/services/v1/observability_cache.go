@@ -0,0 +1,134 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/request"
+)
+
+type cachedMetrics struct {
+	resp      *api.QueryTimeSeriesMetricsResponse
+	fetchedAt time.Time
+}
+
+// cachedProvider caches the time series query results of the wrapped provider for up to ttl. A request can ask for
+// fresher data by setting the maximum staleness it tolerates, in which case an older cached result is fetched anew.
+// At most maxEntries results are cached, zero leaves the cache unbounded.
+type cachedProvider struct {
+	sync.Mutex
+
+	provider   observableProvider
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cachedMetrics
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+func newCachedProvider(provider observableProvider, ttl time.Duration, maxEntries int) *cachedProvider {
+	return &cachedProvider{
+		provider:   provider,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedMetrics),
+		lastSweep:  time.Now(),
+		now:        time.Now,
+	}
+}
+
+func (c *cachedProvider) QueryTimeSeriesMetrics(ctx context.Context, req *api.QueryTimeSeriesMetricsRequest) (*api.QueryTimeSeriesMetricsResponse, error) {
+	maxStaleness := c.ttl
+	if header := api.GetHeader(ctx, api.HeaderMetricsMaxStaleness); len(header) > 0 {
+		staleness, err := time.ParseDuration(header)
+		if err != nil || staleness < 0 {
+			return nil, errors.InvalidArgument("Failed to query metrics: reason = invalid max staleness '%s'", header)
+		}
+		maxStaleness = staleness
+	}
+
+	return c.query(ctx, req, maxStaleness)
+}
+
+// query returns the cached result if it was fetched within both the ttl and the maxStaleness, otherwise it fetches
+// the result from the provider and caches it. A zero maxStaleness always fetches the result.
+func (c *cachedProvider) query(ctx context.Context, req *api.QueryTimeSeriesMetricsRequest, maxStaleness time.Duration) (*api.QueryTimeSeriesMetricsResponse, error) {
+	if maxStaleness > c.ttl {
+		maxStaleness = c.ttl
+	}
+
+	// the results are scoped to the caller, the namespace and the project are part of the key, and so is the time
+	// aggregation which changes the query without being part of the request
+	namespace, _ := request.GetNamespace(ctx)
+	project, _ := metricsProject(ctx)
+	timeAggregation := api.GetHeader(ctx, api.HeaderMetricsTimeAggregation)
+	key := namespace + "/" + project + "/" + timeAggregation + "/" + req.String()
+
+	c.Lock()
+	entry, ok := c.entries[key]
+	c.Unlock()
+	if ok && maxStaleness > 0 && c.now().Sub(entry.fetchedAt) <= maxStaleness {
+		return entry.resp, nil
+	}
+
+	resp, err := c.provider.QueryTimeSeriesMetrics(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.insert(key, cachedMetrics{resp: resp, fetchedAt: c.now()})
+
+	return resp, nil
+}
+
+// insert caches the entry. The expired entries are swept at most once per ttl, or when the cache is full, in which
+// case the oldest entry is evicted if none has expired. The caller must hold the lock.
+func (c *cachedProvider) insert(key string, entry cachedMetrics) {
+	_, replace := c.entries[key]
+	full := !replace && c.maxEntries > 0 && len(c.entries) >= c.maxEntries
+
+	if full || entry.fetchedAt.Sub(c.lastSweep) > c.ttl {
+		for k, e := range c.entries {
+			if entry.fetchedAt.Sub(e.fetchedAt) > c.ttl {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = entry.fetchedAt
+	}
+
+	if full && len(c.entries) >= c.maxEntries {
+		var oldest string
+		for k, e := range c.entries {
+			if oldest == "" || e.fetchedAt.Before(c.entries[oldest].fetchedAt) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+
+	c.entries[key] = entry
+}
+
+func (c *cachedProvider) QueryQuotaUsage(ctx context.Context, req *api.QuotaUsageRequest) (*api.QuotaUsageResponse, error) {
+	return c.provider.QueryQuotaUsage(ctx, req)
+}
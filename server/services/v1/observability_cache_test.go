@@ -0,0 +1,110 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) QueryTimeSeriesMetrics(_ context.Context, _ *api.QueryTimeSeriesMetricsRequest) (*api.QueryTimeSeriesMetricsResponse, error) {
+	p.calls++
+	return &api.QueryTimeSeriesMetricsResponse{From: int64(p.calls)}, nil
+}
+
+func (*countingProvider) QueryQuotaUsage(_ context.Context, _ *api.QuotaUsageRequest) (*api.QuotaUsageResponse, error) {
+	return &api.QuotaUsageResponse{}, nil
+}
+
+func TestCachedProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingProvider{}
+	cached := newCachedProvider(provider, time.Minute, 0)
+
+	now := time.Now()
+	cached.now = func() time.Time { return now }
+
+	req := &api.QueryTimeSeriesMetricsRequest{MetricName: "tigris.requests_count_ok.count", From: 1, To: 2}
+	query := func(maxStaleness time.Duration) int64 {
+		resp, err := cached.query(ctx, req, maxStaleness)
+		require.NoError(t, err)
+		return resp.From
+	}
+
+	require.Equal(t, int64(1), query(time.Minute))
+	now = now.Add(30 * time.Second)
+
+	// a panel tolerating minutes old data gets the cached result
+	require.Equal(t, int64(1), query(5*time.Minute))
+	// a panel needing fresher data than the cached result forces a fetch
+	require.Equal(t, int64(2), query(10*time.Second))
+	require.Equal(t, int64(3), query(0))
+	require.Equal(t, int64(3), query(time.Minute))
+
+	// the ttl bounds the staleness of any request
+	now = now.Add(2 * time.Minute)
+	require.Equal(t, int64(4), query(time.Hour))
+
+	// different requests are cached separately
+	resp, err := cached.query(ctx, &api.QueryTimeSeriesMetricsRequest{MetricName: "tigris.requests_count_ok.count", From: 1, To: 3}, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), resp.From)
+	require.Equal(t, 5, provider.calls)
+}
+
+func TestCachedProviderBounded(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingProvider{}
+	cached := newCachedProvider(provider, time.Minute, 2)
+
+	now := time.Now()
+	cached.now = func() time.Time { return now }
+
+	query := func(to int64, maxStaleness time.Duration) int64 {
+		req := &api.QueryTimeSeriesMetricsRequest{MetricName: "tigris.requests_count_ok.count", From: 1, To: to}
+		resp, err := cached.query(ctx, req, maxStaleness)
+		require.NoError(t, err)
+		return resp.From
+	}
+
+	require.Equal(t, int64(1), query(2, time.Minute))
+	now = now.Add(time.Second)
+	require.Equal(t, int64(2), query(3, time.Minute))
+	now = now.Add(time.Second)
+
+	// refreshing a cached result doesn't evict another one
+	require.Equal(t, int64(3), query(3, 0))
+	require.Equal(t, int64(1), query(2, time.Minute))
+
+	// a new result evicts the oldest one once the cache is full
+	require.Equal(t, int64(4), query(4, time.Minute))
+	require.Len(t, cached.entries, 2)
+	require.Equal(t, int64(3), query(3, time.Minute))
+	require.Equal(t, int64(5), query(2, time.Minute))
+
+	// the expired results are swept once the ttl has passed, even if the cache isn't full
+	cached.maxEntries = 0
+	now = now.Add(2 * time.Minute)
+	require.Equal(t, int64(6), query(5, time.Minute))
+	require.Len(t, cached.entries, 1)
+}
@@ -0,0 +1,95 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// parsePercentile parses the percentile requested through HeaderMetricsPercentile, it must be within [0, 100].
+func parsePercentile(header string) (float64, error) {
+	p, err := strconv.ParseFloat(header, 64)
+	if err != nil || math.IsNaN(p) || p < 0 || p > 100 {
+		return 0, errors.InvalidArgument("Failed to query metrics: reason = invalid percentile '%s', allowed values are [0, 100]", header)
+	}
+
+	return p, nil
+}
+
+// percentileResponse returns a copy of the response where the data points of each series are replaced by a single
+// data point holding the percentile p of the values of the series, timestamped with the last point of the series.
+// A series without any data point is returned without data points. The response itself is not modified as it may be
+// shared through the metrics cache.
+//
+// The percentile is exact over the returned points, it interpolates linearly between the two closest ranks. However,
+// the points are the rollups of the provider over the returned window, for example per interval averages, so the
+// result only approximates the percentile of the raw samples and gets coarser as the window grows. It differs from
+// the quantiles computed by the provider, set through the Quantile of the request, which are computed from the raw
+// samples of the metrics pre-aggregated into distributions.
+func percentileResponse(resp *api.QueryTimeSeriesMetricsResponse, p float64) *api.QueryTimeSeriesMetricsResponse {
+	result := &api.QueryTimeSeriesMetricsResponse{
+		From:   resp.From,
+		To:     resp.To,
+		Query:  resp.Query,
+		Series: make([]*api.MetricSeries, 0, len(resp.Series)),
+	}
+
+	for _, series := range resp.Series {
+		thisSeries := &api.MetricSeries{
+			From:   series.From,
+			To:     series.To,
+			Metric: series.Metric,
+			Scope:  series.Scope,
+		}
+
+		var (
+			values    []float64
+			timestamp int64
+		)
+		for _, dp := range series.DataPoints {
+			// malformed points returned by the provider are zeroed, they don't carry a value
+			if dp == nil || dp.Timestamp == 0 {
+				continue
+			}
+			values = append(values, dp.Value)
+			if dp.Timestamp > timestamp {
+				timestamp = dp.Timestamp
+			}
+		}
+
+		if len(values) > 0 {
+			thisSeries.DataPoints = []*api.DataPoint{{Timestamp: timestamp, Value: percentile(values, p)}}
+		}
+		result.Series = append(result.Series, thisSeries)
+	}
+
+	return result
+}
+
+// percentile returns the percentile p, within [0, 100], of the non-empty values. The values are sorted in place.
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
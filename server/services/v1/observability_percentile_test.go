@@ -0,0 +1,79 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestPercentile(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		values := func() []float64 { return []float64{5, 1, 4, 2, 3} }
+
+		require.Equal(t, 1.0, percentile(values(), 0))
+		require.Equal(t, 3.0, percentile(values(), 50))
+		require.Equal(t, 5.0, percentile(values(), 100))
+		require.InDelta(t, 4.8, percentile(values(), 95), 1e-9)
+		require.Equal(t, 7.0, percentile([]float64{7}, 99))
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		p, err := parsePercentile("99.9")
+		require.NoError(t, err)
+		require.Equal(t, 99.9, p)
+
+		for _, header := range []string{"-1", "101", "p95", "NaN"} {
+			_, err = parsePercentile(header)
+			require.Equal(t, errors.InvalidArgument(
+				"Failed to query metrics: reason = invalid percentile '%s', allowed values are [0, 100]", header), err)
+		}
+	})
+
+	t.Run("response", func(t *testing.T) {
+		resp := &api.QueryTimeSeriesMetricsResponse{
+			From: 1,
+			To:   5,
+			Series: []*api.MetricSeries{
+				{
+					Metric: "m1",
+					DataPoints: []*api.DataPoint{
+						{Timestamp: 1, Value: 10},
+						{Timestamp: 2, Value: 30},
+						{}, // malformed
+						{Timestamp: 4, Value: 20},
+					},
+				},
+				{Metric: "m2"},
+				{Metric: "m3", DataPoints: []*api.DataPoint{{}}},
+			},
+		}
+
+		result := percentileResponse(resp, 50)
+		require.Equal(t, int64(1), result.From)
+		require.Equal(t, int64(5), result.To)
+		require.Len(t, result.Series, 3)
+		require.Equal(t, "m1", result.Series[0].Metric)
+		require.Equal(t, []*api.DataPoint{{Timestamp: 4, Value: 20}}, result.Series[0].DataPoints)
+		require.Empty(t, result.Series[1].DataPoints)
+		require.Empty(t, result.Series[2].DataPoints)
+
+		// the response is not modified
+		require.Len(t, resp.Series[0].DataPoints, 4)
+	})
+}
@@ -0,0 +1,261 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	observabilityTailPattern = "/" + version + "/observability/metrics/timeseries/tail"
+
+	defaultMetricsTailInterval = 10 * time.Second
+	minMetricsTailInterval     = time.Second
+	metricsTailKeepAlive       = 15 * time.Second
+)
+
+// metricsTail tracks the last data point sent for every series of a tailed query, so that every poll of the
+// provider only emits the points that arrived since the previous one.
+type metricsTail struct {
+	lastSeen map[string]int64
+}
+
+func newMetricsTail() *metricsTail {
+	return &metricsTail{
+		lastSeen: make(map[string]int64),
+	}
+}
+
+// next returns a copy of the response holding only the points of each series that are newer than the last point
+// already returned for it, and the series that have new points. It returns nil if there is nothing new.
+func (t *metricsTail) next(resp *api.QueryTimeSeriesMetricsResponse) *api.QueryTimeSeriesMetricsResponse {
+	var result *api.QueryTimeSeriesMetricsResponse
+	for _, series := range resp.Series {
+		key := series.Metric + "|" + series.Scope
+		last := t.lastSeen[key]
+
+		var points []*api.DataPoint
+		for _, dp := range series.DataPoints {
+			if dp == nil || dp.Timestamp <= last {
+				continue
+			}
+			points = append(points, dp)
+			if dp.Timestamp > t.lastSeen[key] {
+				t.lastSeen[key] = dp.Timestamp
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		if result == nil {
+			result = &api.QueryTimeSeriesMetricsResponse{
+				From:  resp.From,
+				To:    resp.To,
+				Query: resp.Query,
+			}
+		}
+		result.Series = append(result.Series, &api.MetricSeries{
+			From:       series.From,
+			To:         series.To,
+			Metric:     series.Metric,
+			Scope:      series.Scope,
+			DataPoints: points,
+		})
+	}
+
+	return result
+}
+
+// parseMetricsTailInterval parses the interval at which a tailed query polls the provider, passed in the interval
+// query parameter as a Go duration. The interval is bounded below so that a dashboard can't hammer the provider.
+func parseMetricsTailInterval(value string) (time.Duration, error) {
+	if len(value) == 0 {
+		return defaultMetricsTailInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < minMetricsTailInterval {
+		return 0, errors.InvalidArgument("Failed to tail metrics: reason = invalid interval '%s', minimum is '%s'",
+			value, minMetricsTailInterval)
+	}
+
+	return interval, nil
+}
+
+// writeSSEEvent writes the data as a single server-sent event, named event if it is not empty, and flushes it to the
+// client.
+func writeSSEEvent(w io.Writer, flusher http.Flusher, event string, data []byte) error {
+	if len(event) > 0 {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	return nil
+}
+
+// writeHTTPError responds with the error in the same JSON format as the other HTTP endpoints, it is used by the
+// handlers served outside of the gRPC gateway, before a stream has started if the handler streams.
+func writeHTTPError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	body, mErr := api.MarshalStatus(st.Proto())
+	if mErr != nil {
+		body = []byte(st.Message())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+	_, _ = w.Write(body)
+}
+
+// tailMetricsHandler streams the new points of a time series query as server-sent events. The body of the request is
+// the same as the one of the query endpoint, its window sets the look back of every poll, which is then moved to end
+// at the time of the poll. The query goes through the in-process gRPC client so that it is authenticated, namespaced
+// and validated the same way as a regular query. Keep-alive comments are sent in between the polls so that proxies
+// don't close an idle stream, which ends when the client disconnects or a query fails.
+func tailMetricsHandler(mux *runtime.ServeMux, client api.ObservabilityClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeHTTPError(w, errors.Unimplemented("Failed to tail metrics: reason = streaming is not supported"))
+			return
+		}
+
+		interval, err := parseMetricsTailInterval(r.URL.Query().Get("interval"))
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+
+		var req api.QueryTimeSeriesMetricsRequest
+		if err = jsoniter.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeHTTPError(w, errors.InvalidArgument("Failed to tail metrics: reason = %s", err.Error()))
+			return
+		}
+		if header := r.Header.Get(api.HeaderMetricsWindow); len(header) > 0 {
+			if err = resolveRelativeWindow(&req, header, time.Now()); err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+		}
+		if req.From >= msTimestampThreshold {
+			req.From /= 1000
+		}
+		if req.To >= msTimestampThreshold {
+			req.To /= 1000
+		}
+		window := req.To - req.From
+		if window <= 0 {
+			writeHTTPError(w, errors.InvalidArgument("Failed to tail metrics: reason = from '%d' must be before to '%d'",
+				req.From, req.To))
+			return
+		}
+
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, api.ObservabilityMethodPrefix+"QueryTimeSeriesMetrics")
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		poll := time.NewTicker(interval)
+		defer poll.Stop()
+		keepAlive := time.NewTicker(metricsTailKeepAlive)
+		defer keepAlive.Stop()
+
+		tail := newMetricsTail()
+		rfc3339 := r.Header.Get(api.HeaderMetricsTimestampFormat) == timestampFormatRFC3339
+		if err = pollMetricsTail(ctx, w, flusher, client, &req, window, tail, rfc3339); err != nil {
+			log.Debug().Err(err).Msg("stopping metrics tail")
+			return
+		}
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err = io.WriteString(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-poll.C:
+				if err = pollMetricsTail(ctx, w, flusher, client, &req, window, tail, rfc3339); err != nil {
+					log.Debug().Err(err).Msg("stopping metrics tail")
+					return
+				}
+			}
+		}
+	}
+}
+
+// pollMetricsTail queries the window ending now and sends the new points, if any, with their timestamps formatted in
+// RFC 3339 if rfc3339 is set. A failed query is sent as an error event, in the same format as the body of an HTTP
+// error, and ends the stream.
+func pollMetricsTail(ctx context.Context, w io.Writer, flusher http.Flusher, client api.ObservabilityClient,
+	req *api.QueryTimeSeriesMetricsRequest, window int64, tail *metricsTail, rfc3339 bool,
+) error {
+	now := time.Now().Unix()
+	req.From, req.To = now-window, now
+
+	resp, err := client.QueryTimeSeriesMetrics(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if data, mErr := api.MarshalStatus(status.Convert(err).Proto()); mErr == nil {
+			_ = writeSSEEvent(w, flusher, "error", data)
+		}
+		return err
+	}
+
+	update := tail.next(resp)
+	if update == nil {
+		return nil
+	}
+
+	data, err := jsoniter.Marshal(update)
+	if err != nil {
+		return err
+	}
+	if rfc3339 {
+		if data, err = rfc3339Timestamps(data); err != nil {
+			return err
+		}
+	}
+
+	return writeSSEEvent(w, flusher, "", data)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+func TestMetricsTail(t *testing.T) {
+	t.Run("next", func(t *testing.T) {
+		tail := newMetricsTail()
+
+		resp := &api.QueryTimeSeriesMetricsResponse{
+			Series: []*api.MetricSeries{
+				{Metric: "m1", Scope: "db:d1", DataPoints: []*api.DataPoint{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}}},
+				{Metric: "m1", Scope: "db:d2", DataPoints: []*api.DataPoint{{Timestamp: 1, Value: 3}}},
+			},
+		}
+		require.Equal(t, resp.Series, tail.next(resp).Series)
+
+		// nothing new
+		require.Nil(t, tail.next(resp))
+
+		resp.Series[0].DataPoints = append(resp.Series[0].DataPoints, nil, &api.DataPoint{Timestamp: 3, Value: 4})
+		update := tail.next(resp)
+		require.Len(t, update.Series, 1)
+		require.Equal(t, "db:d1", update.Series[0].Scope)
+		require.Equal(t, []*api.DataPoint{{Timestamp: 3, Value: 4}}, update.Series[0].DataPoints)
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		interval, err := parseMetricsTailInterval("")
+		require.NoError(t, err)
+		require.Equal(t, defaultMetricsTailInterval, interval)
+
+		interval, err = parseMetricsTailInterval("30s")
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Second, interval)
+
+		for _, value := range []string{"100ms", "10", "-1s"} {
+			_, err = parseMetricsTailInterval(value)
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("event", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, writeSSEEvent(w, w, "", []byte(`{"a":1}`)))
+		require.NoError(t, writeSSEEvent(w, w, "error", []byte(`{"b":2}`)))
+		require.Equal(t, "data: {\"a\":1}\n\nevent: error\ndata: {\"b\":2}\n\n", w.Body.String())
+		require.True(t, w.Flushed)
+	})
+}
@@ -15,9 +15,21 @@
 package v1
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DataDog/datadog-api-client-go/api/v1/datadog"
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/types"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestDatadogQueryValidation(t *testing.T) {
@@ -29,3 +41,249 @@ func TestDatadogQueryValidation(t *testing.T) {
 	require.False(t, isAllowedMetricQueryInput("users "))
 	require.False(t, isAllowedMetricQueryInput("users,foo:bar"))
 }
+
+func TestDatadogQueryAllowedMetrics(t *testing.T) {
+	allowed := []string{"tigris.*", "requests_count_ok.count"}
+	require.True(t, isAllowedMetricName("tigris.requests_count_ok.count", allowed))
+	require.True(t, isAllowedMetricName("requests_count_ok.count", allowed))
+	require.False(t, isAllowedMetricName("requests_count_ok.count.rate", allowed))
+	require.False(t, isAllowedMetricName("internal.fdb.latency", allowed))
+	require.False(t, isAllowedMetricName("tigrisx.requests", allowed))
+	require.True(t, isAllowedMetricName("internal.fdb.latency", nil))
+
+	save := config.DefaultConfig.Observability.AllowedMetrics
+	t.Cleanup(func() { config.DefaultConfig.Observability.AllowedMetrics = save })
+	config.DefaultConfig.Observability.AllowedMetrics = []string{"tigris.*"}
+
+	require.NoError(t, validateQueryTimeSeriesMetricsRequest(&api.QueryTimeSeriesMetricsRequest{
+		MetricName: "tigris.requests_count_ok.count",
+	}))
+	require.Equal(t, errors.PermissionDenied("Failed to query metrics: reason = metric '%s' is not allowed", "fdb.latency"),
+		validateQueryTimeSeriesMetricsRequest(&api.QueryTimeSeriesMetricsRequest{MetricName: "fdb.latency"}))
+}
+
+func TestDatadogQueryQuantile(t *testing.T) {
+	save := config.DefaultConfig.Observability.AllowedMetrics
+	t.Cleanup(func() { config.DefaultConfig.Observability.AllowedMetrics = save })
+	config.DefaultConfig.Observability.AllowedMetrics = nil
+
+	validate := func(quantile float32) error {
+		return validateQueryTimeSeriesMetricsRequest(&api.QueryTimeSeriesMetricsRequest{
+			MetricName: "tigris.requests_response_time.quantile",
+			Quantile:   quantile,
+		})
+	}
+
+	for _, q := range []float32{0, 0.5, 0.9, 0.909, 0.999} {
+		require.NoError(t, validate(q), q)
+	}
+
+	for _, q := range []float32{1.5, 1, -0.5} {
+		err := validate(q)
+		require.Equal(t, errors.InvalidArgument("Failed to query metrics: reason = quantile must be within (0, 1), received %v", q), err)
+		require.Equal(t, api.Code_INVALID_ARGUMENT, err.(*api.TigrisError).Code)
+	}
+}
+
+func TestDatadogQueryRollup(t *testing.T) {
+	save := config.DefaultConfig.Observability.AllowedMetrics
+	t.Cleanup(func() { config.DefaultConfig.Observability.AllowedMetrics = save })
+	config.DefaultConfig.Observability.AllowedMetrics = nil
+
+	validate := func(rollup *api.RollupFunction) error {
+		return validateQueryTimeSeriesMetricsRequest(&api.QueryTimeSeriesMetricsRequest{
+			MetricName:          "tigris.requests_count_ok.count",
+			AdditionalFunctions: []*api.AdditionalFunction{{Rollup: rollup}},
+		})
+	}
+
+	require.NoError(t, validate(&api.RollupFunction{Aggregator: api.RollupAggregator_ROLLUP_AGGREGATOR_AVG, Interval: 3600}))
+	require.NoError(t, validate(&api.RollupFunction{Aggregator: api.RollupAggregator_ROLLUP_AGGREGATOR_MAX}))
+
+	err := validate(&api.RollupFunction{Aggregator: api.RollupAggregator(100), Interval: 3600})
+	require.Error(t, err)
+	require.Equal(t, api.Code_INVALID_ARGUMENT, err.(*api.TigrisError).Code)
+
+	err = validate(&api.RollupFunction{Aggregator: api.RollupAggregator_ROLLUP_AGGREGATOR_SUM, Interval: -1})
+	require.Equal(t, errors.InvalidArgument("Failed to query metrics: reason = rollup interval must not be negative, received -1"), err)
+}
+
+func TestValidateTimeAggregation(t *testing.T) {
+	ta := &metrics.TimeAggregation{Method: "max", Interval: 60}
+	rollup := &api.QueryTimeSeriesMetricsRequest{
+		AdditionalFunctions: []*api.AdditionalFunction{{Rollup: &api.RollupFunction{Aggregator: api.RollupAggregator_ROLLUP_AGGREGATOR_AVG}}},
+	}
+
+	require.NoError(t, validateTimeAggregation(nil, rollup))
+	require.NoError(t, validateTimeAggregation(ta, &api.QueryTimeSeriesMetricsRequest{}))
+	require.Equal(t, errors.InvalidArgument("Failed to query metrics: reason = time aggregation can't be combined with a rollup function"),
+		validateTimeAggregation(ta, rollup))
+}
+
+func TestFormTenantQuery(t *testing.T) {
+	req := &api.QueryTimeSeriesMetricsRequest{
+		MetricName:       "tigris.requests_count_ok.count",
+		SpaceAggregation: api.MetricQuerySpaceAggregation_SUM,
+		Function:         api.MetricQueryFunction_NONE,
+	}
+
+	withNamespace := func(namespace string) context.Context {
+		md := &request.Metadata{}
+		md.SetNamespace(context.Background(), namespace)
+		return md.SaveToContext(context.Background())
+	}
+
+	for _, ctx := range []context.Context{context.Background(), withNamespace(""), withNamespace(defaults.UnknownValue)} {
+		query, err := formTenantQuery(ctx, "", req)
+		require.Equal(t, errors.PermissionDenied("Failed to query metrics: reason = namespace of the request is unknown"), err)
+		require.Empty(t, query)
+	}
+
+	query, err := formTenantQuery(withNamespace("ns1"), "", req)
+	require.NoError(t, err)
+	require.Equal(t, "sum:tigris.requests_count_ok.count{tigris_tenant:ns1}", query)
+	require.False(t, strings.Contains(query, "{*}"))
+
+	query, err = formTenantQuery(withNamespace("ns1"), "p1", req)
+	require.NoError(t, err)
+	require.Equal(t, "sum:tigris.requests_count_ok.count{project:p1 AND tigris_tenant:ns1}", query)
+}
+
+func TestValidateSeriesCount(t *testing.T) {
+	save := config.DefaultConfig.Observability.MaxSeries
+	t.Cleanup(func() { config.DefaultConfig.Observability.MaxSeries = save })
+
+	config.DefaultConfig.Observability.MaxSeries = 0
+	require.NoError(t, validateSeriesCount(1000))
+
+	config.DefaultConfig.Observability.MaxSeries = 10
+	require.NoError(t, validateSeriesCount(10))
+	require.Equal(t, errors.InvalidArgument("Failed to query metrics: reason = query returned 11 series, maximum allowed is 10"),
+		validateSeriesCount(11))
+}
+
+func TestNormalizeQueryWindow(t *testing.T) {
+	now := time.Unix(1_680_000_000, 0)
+
+	for _, c := range []struct {
+		name     string
+		from, to int64
+		expFrom  int64
+		expTo    int64
+		err      error
+	}{
+		{"seconds", 1_679_990_000, 1_680_000_000, 1_679_990_000, 1_680_000_000, nil},
+		{"milliseconds", 1_679_990_000_000, 1_680_000_000_000, 1_679_990_000, 1_680_000_000, nil},
+		{"mixed", 1_679_990_000, 1_680_000_000_123, 1_679_990_000, 1_680_000_000, nil},
+		{"empty", 1_680_000_000, 1_680_000_000_000, 0, 0, errors.InvalidArgument(
+			"Failed to query metrics: reason = from '1680000000' must be before to '1680000000'")},
+		{"reversed", 1_680_000_000, 1_679_990_000, 0, 0, errors.InvalidArgument(
+			"Failed to query metrics: reason = from '1680000000' must be before to '1679990000'")},
+		{"unset", 0, 1_680_000_000, 0, 0, errors.InvalidArgument(
+			"Failed to query metrics: reason = from and to must be positive unix timestamps")},
+		{"future", 1_680_000_000, 1_690_000_000, 0, 0, errors.InvalidArgument(
+			"Failed to query metrics: reason = to '1690000000' is too far in the future")},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := &api.QueryTimeSeriesMetricsRequest{From: c.from, To: c.to}
+			err := normalizeQueryWindow(req, now)
+			require.Equal(t, c.err, err)
+			if err == nil {
+				require.Equal(t, c.expFrom, req.From)
+				require.Equal(t, c.expTo, req.To)
+			}
+		})
+	}
+}
+
+func TestToQueryTimeSeriesMetricsResponse(t *testing.T) {
+	point := func(ts float64, v float64) []*float64 { return []*float64{&ts, &v} }
+
+	ddResp := datadog.NewMetricsQueryResponse()
+	ddResp.SetFromDate(1000)
+	ddResp.SetToDate(2000)
+	ddResp.SetQuery("sum:tigris.requests_count_ok.count{*} by {collection}")
+
+	users := datadog.NewMetricsQueryMetadata()
+	users.SetMetric("tigris.requests_count_ok.count")
+	users.SetScope("collection:users")
+	users.SetPointlist([][]*float64{point(1000, 1), point(1500, 2)})
+
+	orders := datadog.NewMetricsQueryMetadata()
+	orders.SetMetric("tigris.requests_count_ok.count")
+	orders.SetTagSet([]string{"db:shop", "collection:orders"})
+	orders.SetPointlist([][]*float64{point(1000, 3), {nil}})
+
+	ddResp.SetSeries([]datadog.MetricsQueryMetadata{*users, *orders})
+
+	resp := toQueryTimeSeriesMetricsResponse(ddResp)
+	require.Equal(t, int64(1000), resp.From)
+	require.Equal(t, int64(2000), resp.To)
+	require.Equal(t, "sum:tigris.requests_count_ok.count{*} by {collection}", resp.Query)
+	require.Len(t, resp.Series, 2)
+
+	require.Equal(t, "collection:users", resp.Series[0].Scope)
+	require.Equal(t, []*api.DataPoint{{Timestamp: 1000, Value: 1}, {Timestamp: 1500, Value: 2}}, resp.Series[0].DataPoints)
+
+	// the group by tags are used when the scope is missing
+	require.Equal(t, "collection:orders,db:shop", resp.Series[1].Scope)
+	require.Equal(t, []*api.DataPoint{{Timestamp: 1000, Value: 3}, {}}, resp.Series[1].DataPoints)
+
+	require.Empty(t, toQueryTimeSeriesMetricsResponse(datadog.NewMetricsQueryResponse()).Series)
+}
+
+func TestObservabilityProviderDisabled(t *testing.T) {
+	save := config.DefaultConfig.Observability
+	t.Cleanup(func() { config.DefaultConfig.Observability = save })
+	config.DefaultConfig.Observability.Enabled = true
+	config.DefaultConfig.Observability.Provider = "unknown"
+
+	o := newObservabilityService(nil)
+	require.Nil(t, o.Provider)
+
+	_, err := o.QueryTimeSeriesMetrics(context.Background(), &api.QueryTimeSeriesMetricsRequest{})
+	require.Equal(t, errors.Unimplemented("Failed to query metrics: reason = observability provider is not configured"), err)
+
+	_, err = o.QuotaUsage(context.Background(), &api.QuotaUsageRequest{})
+	require.Equal(t, errors.Unimplemented("Failed to read quota usage: reason = observability provider is not configured"), err)
+
+	resp, err := o.GetInfo(context.Background(), &api.GetInfoRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+}
+
+func TestMetricsProject(t *testing.T) {
+	withHeader := func(ctx context.Context, project string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(api.HeaderMetricsProject, project))
+	}
+
+	project, err := metricsProject(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, project)
+
+	project, err = metricsProject(withHeader(context.Background(), "project_b"))
+	require.NoError(t, err)
+	require.Equal(t, "project_b", project)
+
+	_, err = metricsProject(withHeader(context.Background(), "project b"))
+	require.Equal(t, errors.PermissionDenied("Failed to query metrics: reason = invalid character detected in the input"), err)
+
+	// a project scoped token can only query its project
+	md := request.NewRequestMetadata(context.Background())
+	md.SetAccessToken(&types.AccessToken{Namespace: "ns1", Project: "project_a"})
+	ctx := context.WithValue(context.Background(), request.MetadataCtxKey{}, &md)
+
+	project, err = metricsProject(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "project_a", project)
+
+	project, err = metricsProject(withHeader(ctx, "project_a"))
+	require.NoError(t, err)
+	require.Equal(t, "project_a", project)
+
+	dd := &Datadog{}
+	_, err = dd.QueryTimeSeriesMetrics(withHeader(ctx, "project_b"), &api.QueryTimeSeriesMetricsRequest{
+		MetricName: "tigris.requests_count_ok.count",
+	})
+	require.Equal(t, errors.PermissionDenied("Failed to query metrics: reason = project '%s' doesn't match the project of the request", "project_b"), err)
+}
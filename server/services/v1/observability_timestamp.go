@@ -0,0 +1,191 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// The formats of the timestamps of the data points, set through HeaderMetricsTimestampFormat. The provider returns
+// the timestamps in unix milliseconds, which is the default.
+const (
+	timestampFormatSeconds = "s"
+	timestampFormatMillis  = "ms"
+	timestampFormatRFC3339 = "rfc3339"
+)
+
+// parseTimestampFormat parses the format of the timestamps requested through HeaderMetricsTimestampFormat.
+func parseTimestampFormat(header string) (string, error) {
+	switch header {
+	case "":
+		return timestampFormatMillis, nil
+	case timestampFormatSeconds, timestampFormatMillis, timestampFormatRFC3339:
+		return header, nil
+	}
+
+	return "", errors.InvalidArgument("Failed to query metrics: reason = invalid timestamp format '%s', allowed values are [%s, %s, %s]",
+		header, timestampFormatSeconds, timestampFormatMillis, timestampFormatRFC3339)
+}
+
+// timestampResponse returns a copy of the response with the timestamps of the data points in unix seconds if that
+// is the format, otherwise the response is returned as is. The timestamps of a response in RFC 3339 stay in unix
+// milliseconds, as the data points of the gRPC response can only carry an integer timestamp, they are only formatted
+// by the HTTP endpoint, see rfc3339Timestamps. The response itself is not modified as it may be shared through the
+// metrics cache.
+func timestampResponse(resp *api.QueryTimeSeriesMetricsResponse, format string) *api.QueryTimeSeriesMetricsResponse {
+	if format != timestampFormatSeconds {
+		return resp
+	}
+
+	result := &api.QueryTimeSeriesMetricsResponse{
+		From:   resp.From,
+		To:     resp.To,
+		Query:  resp.Query,
+		Series: make([]*api.MetricSeries, 0, len(resp.Series)),
+	}
+	for _, series := range resp.Series {
+		thisSeries := &api.MetricSeries{
+			From:       series.From,
+			To:         series.To,
+			Metric:     series.Metric,
+			Scope:      series.Scope,
+			DataPoints: make([]*api.DataPoint, 0, len(series.DataPoints)),
+		}
+		for _, dp := range series.DataPoints {
+			if dp == nil {
+				thisSeries.DataPoints = append(thisSeries.DataPoints, dp)
+				continue
+			}
+			thisSeries.DataPoints = append(thisSeries.DataPoints, &api.DataPoint{
+				Timestamp: time.UnixMilli(dp.Timestamp).Unix(),
+				Value:     dp.Value,
+			})
+		}
+		result.Series = append(result.Series, thisSeries)
+	}
+
+	return result
+}
+
+// rfc3339Timestamps rewrites the timestamps of the data points of a JSON encoded QueryTimeSeriesMetricsResponse from
+// unix milliseconds to UTC RFC 3339 strings with the milliseconds. A body that isn't a metrics response is returned
+// as is.
+func rfc3339Timestamps(body []byte) ([]byte, error) {
+	var resp map[string]interface{}
+	decoder := jsoniter.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&resp); err != nil {
+		return body, nil
+	}
+
+	series, ok := resp["series"].([]interface{})
+	if !ok {
+		return body, nil
+	}
+	for _, s := range series {
+		thisSeries, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// the field is named after the proto field when marshaled through its struct tags
+		dataPoints, ok := thisSeries["data_points"].([]interface{})
+		if !ok {
+			dataPoints, _ = thisSeries["dataPoints"].([]interface{})
+		}
+		for _, p := range dataPoints {
+			dp, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ms, err := timestampMillis(dp["timestamp"])
+			if err != nil {
+				return nil, err
+			}
+			dp["timestamp"] = time.UnixMilli(ms).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+		}
+	}
+
+	return jsoniter.Marshal(resp)
+}
+
+// timestampMillis returns the JSON timestamp of a data point, encoded either as a number or as a string of an int64,
+// a missing timestamp is zero.
+func timestampMillis(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		// the decoder of jsoniter returns the numbers of the standard library with UseNumber
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	return 0, errors.Internal("unexpected data point timestamp '%v'", value)
+}
+
+// rfc3339Writer buffers the response of the HTTP endpoint so that the timestamps of a successful metrics query are
+// formatted once the response is complete.
+type rfc3339Writer struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+}
+
+func (w *rfc3339Writer) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *rfc3339Writer) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// flush writes the buffered response, with the timestamps formatted if it is successful.
+func (w *rfc3339Writer) flush() {
+	body := w.body.Bytes()
+	if w.status == 0 || w.status == http.StatusOK {
+		if formatted, err := rfc3339Timestamps(body); err == nil {
+			body = formatted
+		}
+	}
+
+	w.ResponseWriter.Header().Del("Content-Length")
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// serveWithTimestampFormat serves the request, formatting the timestamps of the data points in RFC 3339 if the
+// request asks for it.
+func serveWithTimestampFormat(handler http.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(api.HeaderMetricsTimestampFormat) != timestampFormatRFC3339 {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	rw := &rfc3339Writer{ResponseWriter: w}
+	handler.ServeHTTP(rw, r)
+	rw.flush()
+}
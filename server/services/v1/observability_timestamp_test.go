@@ -0,0 +1,112 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestTimestampFormat(t *testing.T) {
+	// 2023-03-01T10:20:30.450Z
+	const ms = int64(1677666030450)
+
+	newResponse := func() *api.QueryTimeSeriesMetricsResponse {
+		return &api.QueryTimeSeriesMetricsResponse{
+			From: 1,
+			To:   5,
+			Series: []*api.MetricSeries{
+				{Metric: "m1", DataPoints: []*api.DataPoint{{Timestamp: ms, Value: 10}, {Timestamp: ms + 1000, Value: 20}}},
+			},
+		}
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		for header, expected := range map[string]string{
+			"":        timestampFormatMillis,
+			"s":       timestampFormatSeconds,
+			"ms":      timestampFormatMillis,
+			"rfc3339": timestampFormatRFC3339,
+		} {
+			format, err := parseTimestampFormat(header)
+			require.NoError(t, err)
+			require.Equal(t, expected, format)
+		}
+
+		_, err := parseTimestampFormat("ns")
+		require.Equal(t, errors.InvalidArgument(
+			"Failed to query metrics: reason = invalid timestamp format 'ns', allowed values are [s, ms, rfc3339]"), err)
+	})
+
+	t.Run("millis", func(t *testing.T) {
+		resp := newResponse()
+		require.Equal(t, resp, timestampResponse(resp, timestampFormatMillis))
+	})
+
+	t.Run("seconds", func(t *testing.T) {
+		resp := newResponse()
+		result := timestampResponse(resp, timestampFormatSeconds)
+		require.Equal(t, []*api.DataPoint{{Timestamp: ms / 1000, Value: 10}, {Timestamp: ms/1000 + 1, Value: 20}},
+			result.Series[0].DataPoints)
+
+		// the response is not modified
+		require.Equal(t, newResponse(), resp)
+	})
+
+	t.Run("rfc3339", func(t *testing.T) {
+		resp := newResponse()
+		// the gRPC response stays in milliseconds
+		require.Equal(t, resp, timestampResponse(resp, timestampFormatRFC3339))
+
+		for _, body := range []string{
+			`{"from":1,"to":5,"series":[{"metric":"m1","data_points":[{"timestamp":1677666030450,"value":10},{"timestamp":"1677667030450","value":20}]}]}`,
+			`{"from":1,"to":5,"series":[{"metric":"m1","dataPoints":[{"timestamp":1677666030450,"value":10},{"timestamp":"1677667030450","value":20}]}]}`,
+		} {
+			formatted, err := rfc3339Timestamps([]byte(body))
+			require.NoError(t, err)
+			require.Contains(t, string(formatted), `"timestamp":"2023-03-01T10:20:30.450Z"`)
+			require.Contains(t, string(formatted), `"timestamp":"2023-03-01T10:37:10.450Z"`)
+		}
+
+		// the other bodies are left as is
+		for _, body := range []string{`{"code":3,"message":"bad"}`, `not json`} {
+			formatted, err := rfc3339Timestamps([]byte(body))
+			require.NoError(t, err)
+			require.Equal(t, body, string(formatted))
+		}
+	})
+
+	t.Run("http", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"series":[{"data_points":[{"timestamp":1677666030450}]}]}`))
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/observability/metrics/timeseries/query", nil)
+		w := httptest.NewRecorder()
+		serveWithTimestampFormat(handler, w, r)
+		require.Equal(t, `{"series":[{"data_points":[{"timestamp":1677666030450}]}]}`, w.Body.String())
+
+		r.Header.Set(api.HeaderMetricsTimestampFormat, timestampFormatRFC3339)
+		w = httptest.NewRecorder()
+		serveWithTimestampFormat(handler, w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `{"series":[{"data_points":[{"timestamp":"2023-03-01T10:20:30.450Z"}]}]}`, w.Body.String())
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+// maxRelativeWindow bounds the number of a relative window so that it can't overflow a duration.
+const maxRelativeWindow = time.Duration(1<<63 - 1)
+
+// relativeWindowUnits are the units of a relative window, on top of the ones of a Go duration days and weeks are
+// accepted as they are the usual units of a metrics dashboard.
+var relativeWindowUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseRelativeWindow parses the relative window requested through HeaderMetricsWindow, a positive integer followed
+// by a unit like "1h" or "7d", optionally prefixed by "last" as in "last 1h". The window is bounded by the configured
+// maximum lookback.
+func parseRelativeWindow(header string) (time.Duration, error) {
+	expr := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.ToLower(header)), "last"))
+
+	invalid := errors.InvalidArgument("Failed to query metrics: reason = invalid window '%s', expected a positive number followed by one of s, m, h, d or w like '1h'", header)
+	if len(expr) < 2 {
+		return 0, invalid
+	}
+
+	unit, ok := relativeWindowUnits[expr[len(expr)-1:]]
+	if !ok {
+		return 0, invalid
+	}
+	n, err := strconv.ParseInt(expr[:len(expr)-1], 10, 64)
+	if err != nil || n <= 0 || n > int64(maxRelativeWindow/unit) {
+		return 0, invalid
+	}
+	window := time.Duration(n) * unit
+
+	if maxLookback := config.DefaultConfig.Observability.MaxQueryLookback; maxLookback > 0 && window > maxLookback {
+		return 0, errors.InvalidArgument("Failed to query metrics: reason = window '%s' is longer than the maximum lookback '%s'",
+			header, maxLookback)
+	}
+
+	return window, nil
+}
+
+// resolveRelativeWindow sets the from and to of the request to the window ending now, in unix seconds. The relative
+// window takes precedence over the from and to of the request, which are ignored when the window is set, so that a
+// client with a skewed clock still queries the latest data.
+func resolveRelativeWindow(req *api.QueryTimeSeriesMetricsRequest, header string, now time.Time) error {
+	window, err := parseRelativeWindow(header)
+	if err != nil {
+		return err
+	}
+
+	req.To = now.Unix()
+	req.From = now.Add(-window).Unix()
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+func TestRelativeWindow(t *testing.T) {
+	maxLookback := config.DefaultConfig.Observability.MaxQueryLookback
+	defer func() { config.DefaultConfig.Observability.MaxQueryLookback = maxLookback }()
+	config.DefaultConfig.Observability.MaxQueryLookback = 30 * 24 * time.Hour
+
+	t.Run("parse", func(t *testing.T) {
+		for header, expected := range map[string]time.Duration{
+			"90s":     90 * time.Second,
+			"30m":     30 * time.Minute,
+			"1h":      time.Hour,
+			"7d":      7 * 24 * time.Hour,
+			"2w":      14 * 24 * time.Hour,
+			"last 1h": time.Hour,
+			"Last 7D": 7 * 24 * time.Hour,
+		} {
+			window, err := parseRelativeWindow(header)
+			require.NoError(t, err, header)
+			require.Equal(t, expected, window, header)
+		}
+
+		for _, header := range []string{"", "last", "h", "0h", "-1h", "1.5h", "1y", "1h30m", "99999999999999999999w"} {
+			_, err := parseRelativeWindow(header)
+			require.Equal(t, errors.InvalidArgument("Failed to query metrics: reason = invalid window '%s', expected a positive number followed by one of s, m, h, d or w like '1h'", header), err)
+		}
+	})
+
+	t.Run("max_lookback", func(t *testing.T) {
+		_, err := parseRelativeWindow("31d")
+		require.Equal(t, errors.InvalidArgument(
+			"Failed to query metrics: reason = window '31d' is longer than the maximum lookback '720h0m0s'"), err)
+
+		config.DefaultConfig.Observability.MaxQueryLookback = 0
+		defer func() { config.DefaultConfig.Observability.MaxQueryLookback = 30 * 24 * time.Hour }()
+
+		window, err := parseRelativeWindow("52w")
+		require.NoError(t, err)
+		require.Equal(t, 52*7*24*time.Hour, window)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		now := time.Unix(1700000000, 0)
+
+		// the window takes precedence over the from and to of the request
+		req := &api.QueryTimeSeriesMetricsRequest{From: 1, To: 2}
+		require.NoError(t, resolveRelativeWindow(req, "1h", now))
+		require.Equal(t, int64(1700000000-3600), req.From)
+		require.Equal(t, int64(1700000000), req.To)
+		require.NoError(t, normalizeQueryWindow(req, now))
+
+		req = &api.QueryTimeSeriesMetricsRequest{From: 1, To: 2}
+		require.Error(t, resolveRelativeWindow(req, "1y", now))
+		require.Equal(t, int64(1), req.From)
+	})
+}
@@ -18,11 +18,15 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
@@ -34,10 +38,17 @@ import (
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
 	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	realtimePathPattern = fullProjectPath + "/realtime/*"
+	realtimePathPattern       = fullProjectPath + "/realtime/*"
+	realtimeSeekConsumerPath  = fullProjectPath + "/realtime/channels/{channel}/consumers/{consumer}/seek"
+	realtimeStatsPath         = fullProjectPath + "/realtime/stats"
+	realtimeMessagesPath      = fullProjectPath + "/realtime/messages"
+	realtimeChannelPath       = fullProjectPath + "/realtime/channels/{channel}"
+	realtimeChannelConfigPath = fullProjectPath + "/realtime/channels/{channel}/config"
 )
 
 type realtimeService struct {
@@ -54,9 +65,22 @@ func newRealtimeService(_ kv.TxStore, _ search.Store, tenantMgr *metadata.Tenant
 	heartbeatF := realtime.NewHeartbeatFactory(cacheS, encoder)
 	channelFactory := realtime.NewChannelFactory(cacheS, encoder, heartbeatF)
 
+	rtmRunner := realtime.NewRTMRunnerFactory(cacheS, channelFactory)
+	if nodes := config.DefaultConfig.Realtime.Nodes; len(nodes) > 0 {
+		local := config.DefaultConfig.Realtime.NodeID
+		found := false
+		for _, node := range nodes {
+			found = found || node == local
+		}
+		if !found {
+			log.Error().Str("node_id", local).Strs("nodes", nodes).Msg("the node is not one of the realtime nodes")
+		}
+		rtmRunner.SetRoutingTable(realtime.NewStaticRoutingTable(nodes, local))
+	}
+
 	return &realtimeService{
 		cache:     cacheS,
-		rtmRunner: realtime.NewRTMRunnerFactory(cacheS, channelFactory),
+		rtmRunner: rtmRunner,
 		devices:   realtime.NewSessionMgr(cacheS, tenantMgr, txMgr, heartbeatF, channelFactory),
 	}
 }
@@ -75,6 +99,13 @@ func (s *realtimeService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Cha
 	api.RegisterRealtimeServer(inproc, s)
 
 	router.HandleFunc(apiPathPrefix+"/projects/{project}/realtime", s.DeviceConnectionHandler)
+	router.Post(apiPathPrefix+realtimeSeekConsumerPath, s.SeekConsumerHandler)
+	router.Get(apiPathPrefix+realtimeStatsPath, s.ChannelStatsHandler)
+	router.Post(apiPathPrefix+realtimeMessagesPath, s.MultiChannelMessagesHandler)
+	router.Get(apiPathPrefix+realtimeMessagesPath, s.MultiChannelReadHandler)
+	router.Delete(apiPathPrefix+realtimeChannelPath, s.DeleteChannelHandler)
+	router.Get(apiPathPrefix+realtimeChannelConfigPath, s.ChannelConfigHandler)
+	router.Put(apiPathPrefix+realtimeChannelConfigPath, s.ChannelConfigHandler)
 	router.HandleFunc(apiPathPrefix+realtimePathPattern, func(w http.ResponseWriter, r *http.Request) {
 		mux.ServeHTTP(w, r)
 	})
@@ -138,6 +169,282 @@ func (s *realtimeService) DeviceConnectionHandler(w http.ResponseWriter, r *http
 	_ = session.Start(ctx)
 }
 
+// seekConsumerBody is the body of a seek request, the position is either a message id or a time, see
+// realtime.DecodePosition.
+type seekConsumerBody struct {
+	Position string `json:"position"`
+}
+
+// SeekConsumerHandler moves the offset of a consumer of a channel so that its next read resumes from the position in
+// the body of the request.
+func (s *realtimeService) SeekConsumerHandler(w http.ResponseWriter, r *http.Request) {
+	var body seekConsumerBody
+	if err := jsoniter.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeHTTPError(w, errors.InvalidArgument("failed to decode the seek request: %s", err.Error()))
+		return
+	}
+
+	runner := s.rtmRunner.GetSeekConsumerRunner(&realtime.SeekConsumerRequest{
+		Project:  chi.URLParam(r, "project"),
+		Channel:  chi.URLParam(r, "channel"),
+		Consumer: chi.URLParam(r, "consumer"),
+		Position: body.Position,
+	})
+	if _, err := s.devices.ExecuteRunner(r.Context(), runner); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	writeHTTPResponse(w, struct{}{})
+}
+
+// ChannelStatsHandler responds with the number of channels of the project, and the number of messages and bytes
+// they buffer.
+func (s *realtimeService) ChannelStatsHandler(w http.ResponseWriter, r *http.Request) {
+	runner := s.rtmRunner.GetChannelStatsRunner(chi.URLParam(r, "project"))
+	if _, err := s.devices.ExecuteRunner(r.Context(), runner); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	writeHTTPResponse(w, runner.Stats())
+}
+
+type deleteChannelResponse struct {
+	Status string `json:"status"`
+}
+
+// DeleteChannelHandler deletes a channel of the project along with its messages. The other methods of the channel
+// path are served by the gRPC gateway.
+func (s *realtimeService) DeleteChannelHandler(w http.ResponseWriter, r *http.Request) {
+	runner := s.rtmRunner.GetDeleteChannelRunner(&realtime.DeleteChannelRequest{
+		Project: chi.URLParam(r, "project"),
+		Channel: chi.URLParam(r, "channel"),
+	})
+	resp, err := s.devices.ExecuteRunner(r.Context(), runner)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	writeHTTPResponse(w, &deleteChannelResponse{Status: resp.Status})
+}
+
+// ChannelConfigHandler responds with the configuration of a channel, a PUT replaces it with the one in the body of the
+// request first. The configuration can be set before anything is published to the channel.
+func (s *realtimeService) ChannelConfigHandler(w http.ResponseWriter, r *http.Request) {
+	req := &realtime.ChannelConfigRequest{
+		Project: chi.URLParam(r, "project"),
+		Channel: chi.URLParam(r, "channel"),
+	}
+	if r.Method == http.MethodPut {
+		req.Config = &realtime.ChannelConfig{}
+		if err := jsoniter.NewDecoder(r.Body).Decode(req.Config); err != nil {
+			writeHTTPError(w, errors.InvalidArgument("failed to decode the channel configuration: %s", err.Error()))
+			return
+		}
+	}
+
+	runner := s.rtmRunner.GetChannelConfigRunner(req)
+	if _, err := s.devices.ExecuteRunner(r.Context(), runner); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	writeHTTPResponse(w, runner.Config())
+}
+
+// channelMessagesBody are the messages of a single channel of a multi-channel publish. The event times are in unix
+// milliseconds, in the same order as the messages.
+type channelMessagesBody struct {
+	Channel    string         `json:"channel"`
+	Messages   []*api.Message `json:"messages"`
+	EventTimes []int64        `json:"event_times,omitempty"`
+}
+
+type multiChannelMessagesBody struct {
+	Channels []*channelMessagesBody `json:"channels"`
+}
+
+// channelMessagesResult is the outcome of the publish of a single channel, the error is set if the publish to that
+// channel failed.
+type channelMessagesResult struct {
+	Channel string            `json:"channel"`
+	Ids     []string          `json:"ids,omitempty"`
+	Error   *api.ErrorDetails `json:"error,omitempty"`
+}
+
+type multiChannelMessagesResponse struct {
+	Results []channelMessagesResult `json:"results"`
+}
+
+// MultiChannelMessagesHandler publishes messages to multiple channels of the project in a single request. The
+// response holds the outcome of every channel, in the order of the channels of the request.
+func (s *realtimeService) MultiChannelMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	var body multiChannelMessagesBody
+	if err := jsoniter.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeHTTPError(w, errors.InvalidArgument("failed to decode the messages request: %s", err.Error()))
+		return
+	}
+
+	req := &realtime.MultiChannelMessagesRequest{
+		Project:  chi.URLParam(r, "project"),
+		Channels: make([]*realtime.ChannelMessages, 0, len(body.Channels)),
+	}
+	for _, c := range body.Channels {
+		var eventTimes []time.Time
+		for _, ms := range c.EventTimes {
+			var eventTime time.Time
+			if ms > 0 {
+				eventTime = time.UnixMilli(ms)
+			}
+			eventTimes = append(eventTimes, eventTime)
+		}
+
+		req.Channels = append(req.Channels, &realtime.ChannelMessages{
+			Channel:    c.Channel,
+			Messages:   c.Messages,
+			EventTimes: eventTimes,
+		})
+	}
+
+	runner := s.rtmRunner.GetMultiChannelMessagesRunner(req)
+	if _, err := s.devices.ExecuteRunner(r.Context(), runner); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	var resp multiChannelMessagesResponse
+	for _, result := range runner.Results() {
+		resp.Results = append(resp.Results, channelMessagesResult{
+			Channel: result.Channel,
+			Ids:     result.Ids,
+			Error:   toErrorDetails(result.Err),
+		})
+	}
+
+	writeHTTPResponse(w, &resp)
+}
+
+// toErrorDetails converts the error to the details of the body of an HTTP error, nil if there is no error.
+func toErrorDetails(err error) *api.ErrorDetails {
+	if err == nil {
+		return nil
+	}
+
+	st := status.Convert(err)
+	details := &api.ErrorDetails{Message: st.Message()}
+	if body, mErr := api.MarshalStatus(st.Proto()); mErr == nil {
+		resp := struct {
+			Error *api.ErrorDetails `json:"error"`
+		}{Error: details}
+		_ = jsoniter.Unmarshal(body, &resp)
+	}
+
+	return details
+}
+
+// multiChannelMessage is the data of the event of a message of a multi-channel read. The times are in unix
+// milliseconds, the event time is the ingestion time if the publisher didn't supply one.
+type multiChannelMessage struct {
+	Channel       string       `json:"channel"`
+	Message       *api.Message `json:"message"`
+	EventTime     int64        `json:"event_time"`
+	IngestionTime int64        `json:"ingestion_time"`
+}
+
+// sseMultiChannelStreaming sends the messages of a multi-channel read as server-sent events. The stream starts with the
+// first message, so that an error returned before it is written as a regular HTTP error.
+type sseMultiChannelStreaming struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+func (s *sseMultiChannelStreaming) Send(channel string, resp *api.ReadMessagesResponse, times realtime.MessageTimes) error {
+	data, err := jsoniter.Marshal(&multiChannelMessage{
+		Channel:       channel,
+		Message:       resp.Message,
+		EventTime:     times.EventTime.UnixMilli(),
+		IngestionTime: times.IngestionTime.UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("Connection", "keep-alive")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	return writeSSEEvent(s.w, s.flusher, "", data)
+}
+
+// MultiChannelReadHandler streams the messages of several channels of the project as server-sent events, ordered by
+// their ingestion time. The channels are passed in the channel query parameters and the i-th start query parameter,
+// if any, is the position the i-th channel is read from. A channel without a start is read from its new messages, the
+// stream then goes on until the client disconnects or the limit query parameter is reached. An error that happens
+// once the stream has started is sent as an error event and ends the stream.
+func (s *realtimeService) MultiChannelReadHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, errors.Unimplemented("streaming is not supported"))
+		return
+	}
+
+	query := r.URL.Query()
+	req := &realtime.MultiChannelReadRequest{
+		Project: chi.URLParam(r, "project"),
+	}
+	starts := query["start"]
+	for i, channel := range query["channel"] {
+		position := &realtime.ChannelPosition{Channel: channel}
+		if i < len(starts) {
+			position.Start = starts[i]
+		}
+		req.Channels = append(req.Channels, position)
+	}
+	if limit := query.Get("limit"); len(limit) > 0 {
+		var err error
+		if req.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || req.Limit < 0 {
+			writeHTTPError(w, errors.InvalidArgument("invalid limit '%s'", limit))
+			return
+		}
+	}
+
+	streaming := &sseMultiChannelStreaming{w: w, flusher: flusher}
+	runner := s.rtmRunner.GetMultiChannelReadRunner(req, streaming)
+	if _, err := s.devices.ExecuteRunner(r.Context(), runner); err != nil {
+		if !streaming.started {
+			writeHTTPError(w, err)
+			return
+		}
+		if r.Context().Err() != nil {
+			return
+		}
+		if data, mErr := api.MarshalStatus(status.Convert(err).Proto()); mErr == nil {
+			_ = writeSSEEvent(w, flusher, "error", data)
+		}
+		log.Debug().Err(err).Msg("stopping multi-channel read")
+	}
+}
+
+// writeHTTPResponse responds with the JSON encoding of the value.
+func writeHTTPResponse(w http.ResponseWriter, v any) {
+	body, err := jsoniter.Marshal(v)
+	if err != nil {
+		writeHTTPError(w, errors.Internal("failed to encode the response: %s", err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
 func (s *realtimeService) Ping(_ context.Context, _ *api.HeartbeatEvent) (*api.HeartbeatEvent, error) {
 	return &api.HeartbeatEvent{}, nil
 }
@@ -156,16 +463,44 @@ func (s *realtimeService) GetRTChannel(ctx context.Context, req *api.GetRTChanne
 func (s *realtimeService) GetRTChannels(ctx context.Context, req *api.GetRTChannelsRequest) (*api.GetRTChannelsResponse, error) {
 	runner := s.rtmRunner.GetChannelRunner()
 	runner.SetChannelsReq(req)
+	runner.SetChannelsPage(api.GetHeader(ctx, api.HeaderChannelsPageSize), api.GetHeader(ctx, api.HeaderChannelsPageToken))
+	runner.SetChannelsPattern(api.GetHeader(ctx, api.HeaderChannelsPattern))
 
 	resp, err := s.devices.ExecuteRunner(ctx, runner)
 	if err != nil {
 		return nil, err
 	}
+	if next := runner.NextPageToken(); len(next) > 0 {
+		if err = grpc.SetHeader(ctx, grpcmd.Pairs(api.HeaderChannelsNextPageToken, next)); err != nil {
+			return nil, err
+		}
+	}
+	if infos := runner.ChannelsInfo(); len(infos) > 0 {
+		watchers := make([]string, 0, len(infos))
+		lastActivity := make([]string, 0, len(infos))
+		for _, info := range infos {
+			watchers = append(watchers, strconv.FormatInt(info.Watchers, 10))
+
+			var ms int64
+			if !info.LastActivity.IsZero() {
+				ms = info.LastActivity.UnixMilli()
+			}
+			lastActivity = append(lastActivity, strconv.FormatInt(ms, 10))
+		}
+		if err = grpc.SetHeader(ctx, grpcmd.Pairs(
+			api.HeaderChannelsWatchers, strings.Join(watchers, ","),
+			api.HeaderChannelsLastActivity, strings.Join(lastActivity, ","),
+		)); err != nil {
+			return nil, err
+		}
+	}
 	return resp.Response.(*api.GetRTChannelsResponse), nil
 }
 
 func (s *realtimeService) ReadMessages(req *api.ReadMessagesRequest, stream api.Realtime_ReadMessagesServer) error {
 	runner := s.rtmRunner.GetReadMessagesRunner(req, stream)
+	runner.SetEnd(api.GetHeader(stream.Context(), api.HeaderReadMessagesEnd))
+	runner.SetReverse(api.GetHeader(stream.Context(), api.HeaderReadMessagesReverse) == "true")
 
 	_, err := s.devices.ExecuteRunner(stream.Context(), runner)
 	if err != nil {
@@ -176,10 +511,21 @@ func (s *realtimeService) ReadMessages(req *api.ReadMessagesRequest, stream api.
 
 func (s *realtimeService) Messages(ctx context.Context, req *api.MessagesRequest) (*api.MessagesResponse, error) {
 	runner := s.rtmRunner.GetMessagesRunner(req)
+	runner.SetIdempotencyKeys(api.GetHeader(ctx, api.HeaderIdempotencyKeys))
+	runner.SetEventTimes(api.GetHeader(ctx, api.HeaderMessageEventTimes))
+	runner.SetAtomic(api.GetHeader(ctx, api.HeaderAtomicPublish) == "true")
 	resp, err := s.devices.ExecuteRunner(ctx, runner)
 	if err != nil {
+		if ids := runner.PublishedIds(); len(ids) > 0 {
+			_ = grpc.SetHeader(ctx, grpcmd.Pairs(api.HeaderPublishedIds, strings.Join(ids, ",")))
+		}
 		return nil, err
 	}
+	if timestamps := runner.PublishedTimestamps(); len(timestamps) > 0 {
+		if err = grpc.SetHeader(ctx, grpcmd.Pairs(api.HeaderPublishedTimestamps, strings.Join(timestamps, ","))); err != nil {
+			return nil, err
+		}
+	}
 	return resp.Response.(*api.MessagesResponse), nil
 }
 
@@ -16,9 +16,11 @@ package realtime
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/store/cache"
 )
@@ -49,6 +51,11 @@ func (ch *Channel) Read(ctx context.Context, pos string) (*cache.StreamMessages,
 	return ch.stream.Read(ctx, pos)
 }
 
+// ReadReverse reads at most count messages at or before the position, newest first.
+func (ch *Channel) ReadReverse(ctx context.Context, pos string, count int64) (*cache.StreamMessages, bool, error) {
+	return ch.stream.ReadReverse(ctx, pos, count)
+}
+
 func (ch *Channel) PublishPresence(ctx context.Context, data *internal.StreamData) (string, error) {
 	return ch.stream.Add(ctx, data)
 }
@@ -57,6 +64,72 @@ func (ch *Channel) PublishMessage(ctx context.Context, data *internal.StreamData
 	return ch.stream.Add(ctx, data)
 }
 
+// PublishMessages publishes the messages together, either all of them are published or none is.
+func (ch *Channel) PublishMessages(ctx context.Context, data []*internal.StreamData) ([]string, error) {
+	return ch.stream.AddAll(ctx, data)
+}
+
+// SeekConsumer moves the stored offset of the named consumer so that its next read resumes from the position. The
+// position is either a message id, in which case that message is the next one read, or a time in unix milliseconds,
+// in which case the next message read is the first one published at or after that time. The position must be
+// within the messages the channel still holds.
+func (ch *Channel) SeekConsumer(ctx context.Context, consumerName string, toPosition string) error {
+	target, err := parseStreamPosition(toPosition)
+	if err != nil {
+		return errors.InvalidArgument("invalid position '%s'", toPosition)
+	}
+
+	_, exists, err := ch.stream.GetConsumerGroup(ctx, consumerName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NotFound("consumer '%s' not found", consumerName)
+	}
+
+	first, last, exists, err := ch.stream.Bounds(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.InvalidArgument("channel has no messages to seek to")
+	}
+	if err = validateSeekPosition(toPosition, target, first, last); err != nil {
+		return err
+	}
+
+	// the offset of a consumer is the id of the last message delivered to it, so it is set right before the target
+	lastDelivered, ok := prevStreamID(fmt.Sprintf("%d-%d", target.ms, target.seq))
+	if !ok {
+		lastDelivered = "0-0"
+	}
+
+	return ch.stream.SetID(ctx, consumerName, lastDelivered)
+}
+
+// validateSeekPosition checks that the target is neither past the newest message nor before the oldest message
+// retained by the channel. A time target covers all the messages published in that millisecond.
+func validateSeekPosition(pos string, target streamPosition, first string, last string) error {
+	firstPos, err := parseStreamPosition(first)
+	if err != nil {
+		return err
+	}
+	lastPos, err := parseStreamPosition(last)
+	if err != nil {
+		return err
+	}
+
+	if target.after(lastPos) {
+		return errors.InvalidArgument("position '%s' is beyond the tail '%s' of the channel", pos, last)
+	}
+
+	if target.ms < firstPos.ms || (target.hasSeq && target.ms == firstPos.ms && target.seq < firstPos.seq) {
+		return errors.InvalidArgument("position '%s' is before the oldest message '%s' retained by the channel", pos, first)
+	}
+
+	return nil
+}
+
 func (ch *Channel) getWatcher(watcher string) *ChannelWatcher {
 	ch.RLock()
 	defer ch.RUnlock()
@@ -150,6 +223,13 @@ func (ch *Channel) StopWatcher(watcher string) {
 }
 
 func (ch *Channel) Close(ctx context.Context) {
+	if err := ch.Delete(ctx); err != nil {
+		log.Err(err).Str("channel", ch.encName).Msg("deleting stream failed")
+	}
+}
+
+// Delete disconnects the watchers of the channel and deletes its stream.
+func (ch *Channel) Delete(ctx context.Context) error {
 	ch.Lock()
 	defer ch.Unlock()
 
@@ -158,8 +238,5 @@ func (ch *Channel) Close(ctx context.Context) {
 		delete(ch.watchers, w.name)
 	}
 
-	if err := ch.stream.Delete(ctx); err != nil {
-		log.Err(err).Str("channel", ch.encName).Msg("deleting stream failed")
-		return
-	}
+	return ch.stream.Delete(ctx)
 }
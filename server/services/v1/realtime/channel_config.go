@@ -0,0 +1,101 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/store/cache"
+)
+
+const channelConfigTable = "channel_config"
+
+// ChannelConfig is the configuration of a channel. It is stored apart from the stream of the channel, so it can be set
+// before the first message is published and it outlives the channel being closed.
+type ChannelConfig struct {
+	// DeadLetterChannel is the channel the messages rejected by the channel are published to instead of failing the
+	// publish. The rejected messages fail the publish if it is empty.
+	DeadLetterChannel string `json:"dead_letter_channel,omitempty"`
+}
+
+// Validate rejects a configuration that can't be applied to the channel.
+func (c *ChannelConfig) Validate(channel string) error {
+	if c.DeadLetterChannel == channel {
+		return errors.InvalidArgument("channel '%s' can't be its own dead-letter channel", channel)
+	}
+
+	return nil
+}
+
+func (factory *ChannelFactory) channelConfigTable(tenantId uint32, projId uint32) (string, error) {
+	return factory.encoder.EncodeCacheTableName(tenantId, projId, channelConfigTable)
+}
+
+// GetChannelConfig returns the configuration of the channel, the default configuration if none was set.
+func (factory *ChannelFactory) GetChannelConfig(ctx context.Context, tenantId uint32, projId uint32, channelName string) (*ChannelConfig, error) {
+	table, err := factory.channelConfigTable(tenantId, projId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := factory.cache.Get(ctx, table, channelName, nil)
+	if err == cache.ErrKeyNotFound {
+		return &ChannelConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config ChannelConfig
+	if err = jsoniter.Unmarshal(data.RawData, &config); err != nil {
+		return nil, errors.Internal("failed to decode the configuration of channel '%s'", channelName)
+	}
+
+	return &config, nil
+}
+
+// SetChannelConfig replaces the configuration of the channel.
+func (factory *ChannelFactory) SetChannelConfig(ctx context.Context, tenantId uint32, projId uint32, channelName string,
+	config *ChannelConfig,
+) error {
+	if err := config.Validate(channelName); err != nil {
+		return err
+	}
+
+	table, err := factory.channelConfigTable(tenantId, projId)
+	if err != nil {
+		return err
+	}
+
+	data, err := jsoniter.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return factory.cache.Set(ctx, table, channelName, internal.NewCacheData(data), nil)
+}
+
+func (factory *ChannelFactory) deleteChannelConfig(ctx context.Context, tenantId uint32, projId uint32, channelName string) error {
+	table, err := factory.channelConfigTable(tenantId, projId)
+	if err != nil {
+		return err
+	}
+
+	_, err = factory.cache.Delete(ctx, table, channelName)
+	return err
+}
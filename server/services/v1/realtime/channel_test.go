@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/store/cache"
@@ -208,3 +210,100 @@ func (dummy *dummyWatch) watch(events *cache.StreamMessages, err error) ([]strin
 	}
 	return ids, nil
 }
+
+func TestStreamMessageTimes(t *testing.T) {
+	eventTime := time.UnixMilli(1577836800000)
+
+	data, err := NewEventDataFromMessageAt(internal.MsgpackEncoding, "", "", "ev", &api.Message{Data: []byte(`{"a": 1}`)}, eventTime)
+	require.NoError(t, err)
+	md, err := DecodeStreamMD(data.Md)
+	require.NoError(t, err)
+	require.Equal(t, "ev", md.EventName)
+
+	times, err := md.Times("1672531200000-3")
+	require.NoError(t, err)
+	require.Equal(t, time.UnixMilli(1672531200000), times.IngestionTime)
+	require.Equal(t, eventTime, times.EventTime)
+
+	// the event time defaults to the ingestion time when the client doesn't supply it
+	data, err = NewEventDataFromMessage(internal.MsgpackEncoding, "", "", "ev", &api.Message{Data: []byte(`{"a": 1}`)})
+	require.NoError(t, err)
+	md, err = DecodeStreamMD(data.Md)
+	require.NoError(t, err)
+	require.Zero(t, md.EventTime)
+
+	times, err = md.Times("1672531200000-3")
+	require.NoError(t, err)
+	require.Equal(t, times.IngestionTime, times.EventTime)
+
+	_, err = md.Times("invalid")
+	require.Error(t, err)
+}
+
+func TestSeekConsumer(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+	_ = cacheS.DeleteStream(ctx, "ch_seek")
+
+	stream, err := cacheS.CreateStream(ctx, "ch_seek")
+	require.NoError(t, err)
+	channel := NewChannel("ch_seek", stream)
+	defer channel.Close(ctx)
+
+	require.NoError(t, stream.CreateConsumerGroup(ctx, "consumer", "$"))
+	require.Equal(t, errors.InvalidArgument("channel has no messages to seek to"), channel.SeekConsumer(ctx, "consumer", "0"))
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := channel.PublishMessage(ctx, internal.NewStreamData(internal.MsgpackEncoding, nil, []byte(fmt.Sprintf("%d", i))))
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	readNext := func() string {
+		resp, hasData, err := stream.ReadGroup(ctx, "consumer", cache.ReadGroupPosCurrent)
+		require.NoError(t, err)
+		require.True(t, hasData)
+		return resp.Messages[0].ID
+	}
+
+	t.Run("to_id", func(t *testing.T) {
+		require.NoError(t, channel.SeekConsumer(ctx, "consumer", ids[1]))
+		require.Equal(t, ids[1], readNext())
+	})
+
+	t.Run("to_time", func(t *testing.T) {
+		ingestion, err := IngestionTime(ids[0])
+		require.NoError(t, err)
+		require.NoError(t, channel.SeekConsumer(ctx, "consumer", fmt.Sprintf("%d", ingestion.UnixMilli())))
+		require.Equal(t, ids[0], readNext())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		require.Equal(t, errors.NotFound("consumer 'missing' not found"), channel.SeekConsumer(ctx, "missing", ids[0]))
+		require.Equal(t, errors.InvalidArgument("invalid position 'abc'"), channel.SeekConsumer(ctx, "consumer", "abc"))
+	})
+}
+
+func TestValidateSeekPosition(t *testing.T) {
+	cases := []struct {
+		pos string
+		err error
+	}{
+		{"100-2", nil},
+		{"150", nil},
+		{"200-5", nil},
+		{"100", nil},
+		{"200", nil},
+		{"100-1", errors.InvalidArgument("position '100-1' is before the oldest message '100-2' retained by the channel")},
+		{"99", errors.InvalidArgument("position '99' is before the oldest message '100-2' retained by the channel")},
+		{"200-6", errors.InvalidArgument("position '200-6' is beyond the tail '200-5' of the channel")},
+		{"201", errors.InvalidArgument("position '201' is beyond the tail '200-5' of the channel")},
+	}
+
+	for _, c := range cases {
+		target, err := parseStreamPosition(c.pos)
+		require.NoError(t, err)
+		require.Equal(t, c.err, validateSeekPosition(c.pos, target, "100-2", "200-5"), c.pos)
+	}
+}
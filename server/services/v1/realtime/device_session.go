@@ -197,6 +197,10 @@ func (session *Session) handleMessage(ctx context.Context, req *api.RealTimeMess
 			return errors.InternalWS("expecting 'attach' event")
 		}
 
+		if errEvent := session.routeChannel(event.Channel); errEvent != nil {
+			return errEvent
+		}
+
 		// create a channel if it doesn't exist
 		_, err := session.chFactory.GetOrCreateChannel(ctx, session.tenant.GetNamespace().Id(), session.project.Id(), event.Channel)
 		if err != nil {
@@ -231,6 +235,10 @@ func (session *Session) handleMessage(ctx context.Context, req *api.RealTimeMess
 			return errors.InternalWS("expecting 'subscribe' event")
 		}
 
+		if errEvent := session.routeChannel(event.Channel); errEvent != nil {
+			return errEvent
+		}
+
 		channel, err := session.chFactory.GetChannel(ctx, session.tenant.GetNamespace().Id(), session.project.Id(), event.Channel)
 		if err != nil {
 			return errors.InternalWS(err.Error())
@@ -258,6 +266,10 @@ func (session *Session) handleMessage(ctx context.Context, req *api.RealTimeMess
 			return errors.InternalWS("expecting message event")
 		}
 
+		if errEvent := session.routeChannel(event.Channel); errEvent != nil {
+			return errEvent
+		}
+
 		ch, err := session.chFactory.GetChannel(ctx, session.tenant.GetNamespace().Id(), session.project.Id(), event.Channel)
 		if err != nil {
 			return errors.InternalWS(err.Error())
@@ -277,6 +289,10 @@ func (session *Session) handleMessage(ctx context.Context, req *api.RealTimeMess
 			return errors.InternalWS("expecting presence event")
 		}
 
+		if errEvent := session.routeChannel(event.Channel); errEvent != nil {
+			return errEvent
+		}
+
 		ch, err := session.chFactory.GetChannel(ctx, session.tenant.GetNamespace().Id(), session.project.Id(), event.Channel)
 		if err != nil {
 			return errors.InternalWS(err.Error())
@@ -295,6 +311,15 @@ func (session *Session) handleMessage(ctx context.Context, req *api.RealTimeMess
 	return nil
 }
 
+// routeChannel rejects the event for a channel owned by another node, the error names the owner so that the device
+// can connect to it.
+func (session *Session) routeChannel(channel string) *api.ErrorEvent {
+	if err := session.chFactory.RouteChannel(session.tenant.GetNamespace().Id(), session.project.Id(), channel); err != nil {
+		return errors.Errorf(errors.ClosePolicyViolation, "%s", err.Error())
+	}
+	return nil
+}
+
 func SendReply(conn *websocket.Conn, encType internal.UserDataEncType, eventType api.EventType, event proto.Message) error {
 	encEvent, err := EncodeEvent(encType, event)
 	if err != nil {
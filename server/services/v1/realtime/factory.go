@@ -16,16 +16,43 @@ package realtime
 
 import (
 	"context"
+	"encoding/base64"
+	"sort"
 	"sync"
 	"time"
+	"unicode"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/store/cache"
 )
 
 const monitorChannelDuration = 2 * time.Minute
 
+// ChannelStats is the aggregated view of all the channels of a project.
+type ChannelStats struct {
+	// Channels is the number of channels in the project
+	Channels int64 `json:"channels"`
+	// Messages is the total number of messages buffered across all the channels
+	Messages int64 `json:"messages"`
+	// Bytes is the total memory footprint of all the channels
+	Bytes int64 `json:"bytes"`
+}
+
+// ChannelInfo is the activity of a channel.
+type ChannelInfo struct {
+	Channel string
+	// Watchers is the number of subscribers of the channel across all the nodes. It counts the consumer groups of
+	// the channel at the time it is read, so a subscriber that went away is counted until its group is removed, on
+	// disconnect or once its heartbeat expires.
+	Watchers int64
+	// LastActivity is the time of the newest message of the channel, zero if the channel has no messages.
+	LastActivity time.Time
+}
+
 type ChannelFactory struct {
 	sync.RWMutex
 
@@ -33,6 +60,7 @@ type ChannelFactory struct {
 	encoder    metadata.CacheEncoder
 	heartbeatF *HeartbeatFactory
 	channels   map[string]*Channel
+	router     *ChannelRouter
 }
 
 func NewChannelFactory(cache cache.Cache, encoder metadata.CacheEncoder, heartbeatF *HeartbeatFactory) *ChannelFactory {
@@ -41,6 +69,7 @@ func NewChannelFactory(cache cache.Cache, encoder metadata.CacheEncoder, heartbe
 		encoder:    encoder,
 		heartbeatF: heartbeatF,
 		channels:   make(map[string]*Channel),
+		router:     NewChannelRouter(LocalRoutingTable{}),
 	}
 
 	go factory.monitorStreams()
@@ -77,6 +106,46 @@ func (factory *ChannelFactory) deleteChannelIfInactive(c *Channel) error {
 	return nil
 }
 
+// SetRoutingTable sets the membership of the cluster the channels are spread over. This node owns all the channels
+// until it is set.
+func (factory *ChannelFactory) SetRoutingTable(table RoutingTable) {
+	factory.router.SetTable(table)
+}
+
+// RouteChannel rejects the request for the channel if it is owned by another node, the error names the owner so that
+// the request can be sent to it.
+func (factory *ChannelFactory) RouteChannel(tenantId uint32, projId uint32, channel string) error {
+	encName, err := factory.encodeChannelName(tenantId, projId, channel)
+	if err != nil {
+		return err
+	}
+
+	if owner, local := factory.router.Owner(keys.NewKey([]byte(encName))); !local {
+		return notOwnerError(channel, owner)
+	}
+
+	return nil
+}
+
+// encodeChannelName returns the cache key of a channel or a pattern of channels. The channels of all the tenants share
+// the same cache so the key must be scoped to the namespace and the project. The key is decoded back to guard against
+// an encoder that drops them, which would leak messages across tenants.
+func (factory *ChannelFactory) encodeChannelName(tenantId uint32, projId uint32, name string) (string, error) {
+	encName, err := factory.encoder.EncodeCacheTableName(tenantId, projId, name)
+	if err != nil {
+		return "", err
+	}
+
+	decTenantId, decProjId, _, ok := factory.encoder.DecodeCacheTableName(encName)
+	if !ok || decTenantId != tenantId || decProjId != projId {
+		log.Error().Str("key", encName).Uint32("tenant", tenantId).Uint32("project", projId).
+			Msg("channel key is not scoped to the tenant")
+		return "", errors.Internal("channel key is not scoped to the tenant")
+	}
+
+	return encName, nil
+}
+
 func (factory *ChannelFactory) getChannel(encStream string) (*Channel, bool) {
 	factory.RLock()
 	defer factory.RUnlock()
@@ -94,8 +163,10 @@ func (factory *ChannelFactory) getOrCreateChannelFromCache(ctx context.Context,
 	return NewChannel(encStream, stream), nil
 }
 
+// ListChannels returns the names of the channels matching the prefix, sorted lexicographically so that the result
+// is stable across calls.
 func (factory *ChannelFactory) ListChannels(ctx context.Context, tenantId uint32, projId uint32, prefix string) ([]string, error) {
-	encProj, err := factory.encoder.EncodeCacheTableName(tenantId, projId, prefix)
+	encProj, err := factory.encodeChannelName(tenantId, projId, prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -105,19 +176,246 @@ func (factory *ChannelFactory) ListChannels(ctx context.Context, tenantId uint32
 		return nil, err
 	}
 
-	channelNames := make([]string, len(streams))
-	for i, s := range streams {
+	channelNames := make([]string, 0, len(streams))
+	for _, s := range streams {
 		_, _, ch, cacheStream := factory.encoder.DecodeCacheTableName(s)
 		if cacheStream {
-			channelNames[i] = ch
+			channelNames = append(channelNames, ch)
 		}
 	}
 
+	sort.Strings(channelNames)
+
 	return channelNames, nil
 }
 
+// channelsScanCount is the number of keys the cache is asked to scan per round trip when listing a page of channels
+// or computing the stats of a project, and maxChannelsScanRounds bounds the round trips of a page, so that a page costs the same whatever the number of
+// channels, even when few of the scanned keys match the pattern.
+const (
+	channelsScanCount     = 100
+	maxChannelsScanRounds = 16
+)
+
+// ListChannelsPage returns at most limit channels of the project matching the pattern, starting where the page token
+// points to, along with the token of the next page. The token is empty on the last page. An empty page token starts
+// from the first channel and a zero limit returns all the remaining channels. The pattern is matched by the cache
+// when the channels are scanned, see validateChannelsPattern.
+//
+// The channels are paged with the cursor of a scan of the cache, so a page only reads a bounded number of keys. The
+// channels of a page are sorted by name but the pages follow the order of the scan, and a page can have fewer than
+// limit channels, even none, while there are more pages. Like the scan, a channel created or deleted while the
+// channels are listed may or may not be returned.
+func (factory *ChannelFactory) ListChannelsPage(ctx context.Context, tenantId uint32, projId uint32, pattern string,
+	pageToken string, limit int,
+) ([]string, string, error) {
+	if err := validateChannelsPattern(pattern); err != nil {
+		return nil, "", err
+	}
+
+	token, err := decodeChannelsPageToken(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	encPattern, err := factory.encodeChannelName(tenantId, projId, pattern)
+	if err != nil {
+		return nil, "", err
+	}
+
+	count := int64(channelsScanCount)
+	if limit > channelsScanCount {
+		count = int64(limit)
+	}
+
+	var channelNames []string
+	cursor, skip := token.Cursor, token.Skip
+	for round := 0; limit <= 0 || round < maxChannelsScanRounds; round++ {
+		streams, next, err := factory.cache.ScanStreams(ctx, encPattern, cursor, count)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// the batch of a cursor is sorted, so that the channels of the batch already returned can be skipped
+		batch := make([]string, 0, len(streams))
+		for _, s := range streams {
+			if _, _, ch, cacheStream := factory.encoder.DecodeCacheTableName(s); cacheStream {
+				batch = append(batch, ch)
+			}
+		}
+		sort.Strings(batch)
+		if skip > len(batch) {
+			skip = len(batch)
+		}
+		batch = batch[skip:]
+
+		if room := limit - len(channelNames); limit > 0 && len(batch) > room {
+			channelNames = append(channelNames, batch[:room]...)
+			sort.Strings(channelNames)
+			return channelNames, encodeChannelsPageToken(channelsPageToken{Cursor: cursor, Skip: skip + room}), nil
+		}
+
+		channelNames = append(channelNames, batch...)
+		cursor, skip = next, 0
+		if cursor == 0 {
+			sort.Strings(channelNames)
+			return channelNames, "", nil
+		}
+		if limit > 0 && len(channelNames) == limit {
+			break
+		}
+	}
+
+	sort.Strings(channelNames)
+	return channelNames, encodeChannelsPageToken(channelsPageToken{Cursor: cursor}), nil
+}
+
+// validateChannelsPattern checks the glob pattern of the channels to list, where "*" matches any sequence of
+// characters and "?" a single character, like "chat-*" for the channels starting with "chat-". The other special
+// characters of the cache patterns and the separator of the parts of the cache keys are rejected, as they would
+// change what the scan matches beyond the names of the channels of the project.
+func validateChannelsPattern(pattern string) error {
+	if len(pattern) == 0 {
+		return errors.InvalidArgument("empty channels pattern")
+	}
+
+	for _, r := range pattern {
+		switch {
+		case r == '[' || r == ']' || r == '\\' || r == ':':
+			return errors.InvalidArgument("invalid channels pattern '%s', it can't contain '%c'", pattern, r)
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			return errors.InvalidArgument("invalid channels pattern '%s', it can't contain whitespace", pattern)
+		}
+	}
+
+	return nil
+}
+
+const channelsPageTokenV2 = 2
+
+// channelsPageToken is the opaque token of the next page of channels handed out to the clients. It holds the cursor
+// of the scan of the cache and the number of channels of the batch of the cursor already returned, when a page ends
+// in the middle of a batch.
+type channelsPageToken struct {
+	Version int    `json:"v"`
+	Cursor  uint64 `json:"cursor"`
+	Skip    int    `json:"skip,omitempty"`
+}
+
+func encodeChannelsPageToken(t channelsPageToken) string {
+	t.Version = channelsPageTokenV2
+	enc, _ := jsoniter.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(enc)
+}
+
+// decodeChannelsPageToken returns the position the page starts at, the start of the scan for an empty token.
+func decodeChannelsPageToken(token string) (channelsPageToken, error) {
+	var t channelsPageToken
+	if len(token) == 0 {
+		return t, nil
+	}
+
+	enc, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return t, errors.InvalidArgument("invalid page token '%s'", token)
+	}
+
+	if err = jsoniter.Unmarshal(enc, &t); err != nil || (t.Cursor == 0 && t.Skip == 0) || t.Skip < 0 {
+		return t, errors.InvalidArgument("invalid page token '%s'", token)
+	}
+	if t.Version != channelsPageTokenV2 {
+		return t, errors.InvalidArgument("unsupported page token version '%d'", t.Version)
+	}
+
+	return t, nil
+}
+
+// Stats returns the channel count, buffered messages and memory footprint of a project. It only reads the stream
+// metadata from the cache and never iterates over the messages. The streams are scanned in batches, so that the cache
+// isn't blocked by a project with many channels.
+func (factory *ChannelFactory) Stats(ctx context.Context, tenantId uint32, projId uint32) (ChannelStats, error) {
+	encProj, err := factory.encodeChannelName(tenantId, projId, "*")
+	if err != nil {
+		return ChannelStats{}, err
+	}
+
+	var (
+		stats  ChannelStats
+		cursor uint64
+		// a scan can return a stream more than once
+		seen = make(map[string]struct{})
+	)
+	for {
+		streams, next, err := factory.cache.ScanStreams(ctx, encProj, cursor, channelsScanCount)
+		if err != nil {
+			return ChannelStats{}, err
+		}
+
+		batch := make([]string, 0, len(streams))
+		for _, s := range streams {
+			if _, ok := seen[s]; ok {
+				continue
+			}
+			seen[s] = struct{}{}
+
+			if _, _, _, cacheStream := factory.encoder.DecodeCacheTableName(s); cacheStream {
+				batch = append(batch, s)
+			}
+		}
+
+		streamStats, err := factory.cache.GetStreamStats(ctx, batch...)
+		if err != nil {
+			return ChannelStats{}, err
+		}
+		for _, s := range streamStats {
+			stats.Channels++
+			stats.Messages += s.Length
+			stats.Bytes += s.Bytes
+		}
+
+		if cursor = next; cursor == 0 {
+			return stats, nil
+		}
+	}
+}
+
+// ChannelsInfo returns the activity of the channels of the project, in the order of the names. The activity of all
+// the channels is read in a single round trip to the cache without opening the channels, it is not a consistent
+// snapshot across the channels though, as they can change while they are read. A channel that doesn't exist has no
+// activity.
+func (factory *ChannelFactory) ChannelsInfo(ctx context.Context, tenantId uint32, projId uint32, names []string) ([]ChannelInfo, error) {
+	streams := make([]string, 0, len(names))
+	for _, name := range names {
+		encName, err := factory.encodeChannelName(tenantId, projId, name)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, encName)
+	}
+
+	streamStats, err := factory.cache.GetStreamStats(ctx, streams...)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ChannelInfo, 0, len(names))
+	for i, s := range streamStats {
+		info := ChannelInfo{Channel: names[i]}
+		// every channel has the default group, which is not a subscriber
+		if s.Groups > 1 {
+			info.Watchers = s.Groups - 1
+		}
+		if pos, err := parseStreamPosition(s.LastID); err == nil {
+			info.LastActivity = time.UnixMilli(pos.ms)
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
 func (factory *ChannelFactory) GetChannel(ctx context.Context, tenantId uint32, projId uint32, channelName string) (*Channel, error) {
-	encStream, err := factory.encoder.EncodeCacheTableName(tenantId, projId, channelName)
+	encStream, err := factory.encodeChannelName(tenantId, projId, channelName)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +438,7 @@ func (factory *ChannelFactory) GetChannel(ctx context.Context, tenantId uint32,
 }
 
 func (factory *ChannelFactory) GetOrCreateChannel(ctx context.Context, tenantId uint32, projId uint32, channelName string) (*Channel, error) {
-	encStream, err := factory.encoder.EncodeCacheTableName(tenantId, projId, channelName)
+	encStream, err := factory.encodeChannelName(tenantId, projId, channelName)
 	if err != nil {
 		return nil, err
 	}
@@ -161,9 +459,28 @@ func (factory *ChannelFactory) GetOrCreateChannel(ctx context.Context, tenantId
 	return ch, nil
 }
 
+// GetOrCreateChannels resolves all the channels at once, the channels are returned keyed by their name. Either all
+// the channels are returned or none if any of them couldn't be resolved.
+func (factory *ChannelFactory) GetOrCreateChannels(ctx context.Context, tenantId uint32, projId uint32, channelNames []string) (map[string]*Channel, error) {
+	channels := make(map[string]*Channel, len(channelNames))
+	for _, name := range channelNames {
+		if _, ok := channels[name]; ok {
+			continue
+		}
+
+		ch, err := factory.GetOrCreateChannel(ctx, tenantId, projId, name)
+		if err != nil {
+			return nil, err
+		}
+		channels[name] = ch
+	}
+
+	return channels, nil
+}
+
 // CreateChannel will throw an error if stream already exists. Use CreateOrGet to create if not exists primitive.
 func (factory *ChannelFactory) CreateChannel(ctx context.Context, tenantId uint32, projId uint32, channelName string) (*Channel, error) {
-	encStream, err := factory.encoder.EncodeCacheTableName(tenantId, projId, channelName)
+	encStream, err := factory.encodeChannelName(tenantId, projId, channelName)
 	if err != nil {
 		return nil, err
 	}
@@ -184,6 +501,7 @@ func (factory *ChannelFactory) CreateChannel(ctx context.Context, tenantId uint3
 	return ch, nil
 }
 
+// DeleteChannel closes the channel and forgets about it, a failure to delete its stream is only logged.
 func (factory *ChannelFactory) DeleteChannel(ctx context.Context, ch *Channel) {
 	factory.Lock()
 	defer factory.Unlock()
@@ -191,3 +509,25 @@ func (factory *ChannelFactory) DeleteChannel(ctx context.Context, ch *Channel) {
 	ch.Close(ctx)
 	delete(factory.channels, ch.encName)
 }
+
+// DropChannel deletes the channel, its stream and its configuration, which reclaims the messages buffered in the
+// cache, and disconnects its watchers. It returns NotFound if the channel doesn't exist.
+func (factory *ChannelFactory) DropChannel(ctx context.Context, tenantId uint32, projId uint32, channelName string) error {
+	ch, err := factory.GetChannel(ctx, tenantId, projId, channelName)
+	if err == cache.ErrStreamNotFound {
+		return errors.NotFound("channel '%s' not present ", channelName)
+	}
+	if err != nil {
+		return err
+	}
+
+	factory.Lock()
+	defer factory.Unlock()
+
+	if err = ch.Delete(ctx); err != nil {
+		return err
+	}
+	delete(factory.channels, ch.encName)
+
+	return factory.deleteChannelConfig(ctx, tenantId, projId, channelName)
+}
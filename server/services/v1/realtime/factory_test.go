@@ -15,10 +15,15 @@
 package realtime
 
 import (
+	"bytes"
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/store/cache"
@@ -54,6 +59,302 @@ func TestFactory(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, channel1, channel3)
 	})
+	t.Run("get_or_create_channels", func(t *testing.T) {
+		existing, err := factory.GetOrCreateChannel(ctx, 1, 1, "existing")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, existing)
+
+		channels, err := factory.GetOrCreateChannels(ctx, 1, 1, []string{"existing", "new", "existing"})
+		require.NoError(t, err)
+		require.Len(t, channels, 2)
+		require.Equal(t, existing, channels["existing"])
+		defer factory.DeleteChannel(ctx, channels["new"])
+
+		_, err = channels["new"].PublishMessage(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(`{"a": 1}`)))
+		require.NoError(t, err)
+
+		names, err := factory.ListChannels(ctx, 1, 1, "*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"existing", "new"}, names)
+	})
+	t.Run("list_channels_sorted", func(t *testing.T) {
+		for _, name := range []string{"c", "a", "b"} {
+			channel, err := factory.GetOrCreateChannel(ctx, 1, 1, name)
+			require.NoError(t, err)
+			defer factory.DeleteChannel(ctx, channel)
+		}
+
+		channels, err := factory.ListChannels(ctx, 1, 1, "*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c"}, channels)
+	})
+	t.Run("list_channels_page", func(t *testing.T) {
+		for _, name := range []string{"e", "c", "a", "d", "b"} {
+			channel, err := factory.GetOrCreateChannel(ctx, 1, 1, name)
+			require.NoError(t, err)
+			defer factory.DeleteChannel(ctx, channel)
+		}
+
+		// the pages follow the order of the scan, every channel is returned once
+		var all []string
+		token := ""
+		for {
+			channels, next, err := factory.ListChannelsPage(ctx, 1, 1, "*", token, 2)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(channels), 2)
+			require.IsIncreasing(t, channels)
+			all = append(all, channels...)
+			if len(next) == 0 {
+				break
+			}
+			token = next
+		}
+		require.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, all)
+
+		channels, next, err := factory.ListChannelsPage(ctx, 1, 1, "*", "", 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c", "d", "e"}, channels)
+		require.Empty(t, next)
+
+		// a page can end in the middle of a batch of the scan
+		channels, next, err = factory.ListChannelsPage(ctx, 1, 1, "*", encodeChannelsPageToken(channelsPageToken{Skip: 3}), 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"d", "e"}, channels)
+		require.Empty(t, next)
+
+		_, _, err = factory.ListChannelsPage(ctx, 1, 1, "*", "invalid!", 2)
+		require.Equal(t, errors.InvalidArgument("invalid page token 'invalid!'"), err)
+	})
+	t.Run("list_channels_pattern", func(t *testing.T) {
+		for _, name := range []string{"room-1", "room-2", "room-10", "lobby"} {
+			channel, err := factory.GetOrCreateChannel(ctx, 1, 1, name)
+			require.NoError(t, err)
+			defer factory.DeleteChannel(ctx, channel)
+		}
+
+		list := func(pattern string) []string {
+			channels, _, err := factory.ListChannelsPage(ctx, 1, 1, pattern, "", 0)
+			require.NoError(t, err)
+			return channels
+		}
+
+		// prefix
+		require.Equal(t, []string{"room-1", "room-10", "room-2"}, list("room-*"))
+		// exact
+		require.Equal(t, []string{"room-1"}, list("room-1"))
+		require.Empty(t, list("room"))
+		// wildcards
+		require.Equal(t, []string{"room-1", "room-2"}, list("room-?"))
+		require.Equal(t, []string{"lobby", "room-1", "room-10", "room-2"}, list("*"))
+
+		channels, next, err := factory.ListChannelsPage(ctx, 1, 1, "room-*", "", 2)
+		require.NoError(t, err)
+		require.Len(t, channels, 2)
+		more, _, err := factory.ListChannelsPage(ctx, 1, 1, "room-*", next, 2)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"room-1", "room-10", "room-2"}, append(channels, more...))
+
+		for _, c := range []rune{'[', ']', '\\', ':'} {
+			pattern := "room" + string(c) + "*"
+			_, _, err = factory.ListChannelsPage(ctx, 1, 1, pattern, "", 0)
+			require.Equal(t, errors.InvalidArgument("invalid channels pattern '%s', it can't contain '%c'", pattern, c), err)
+		}
+		_, _, err = factory.ListChannelsPage(ctx, 1, 1, "room *", "", 0)
+		require.Equal(t, errors.InvalidArgument("invalid channels pattern 'room *', it can't contain whitespace"), err)
+		_, _, err = factory.ListChannelsPage(ctx, 1, 1, "", "", 0)
+		require.Equal(t, errors.InvalidArgument("empty channels pattern"), err)
+	})
+	t.Run("stats", func(t *testing.T) {
+		channel1, err := factory.GetOrCreateChannel(ctx, 1, 1, "test1")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, channel1)
+
+		channel2, err := factory.GetOrCreateChannel(ctx, 1, 1, "test2")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, channel2)
+
+		for i := 0; i < 3; i++ {
+			_, err = channel1.PublishMessage(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(`{"a": 1}`)))
+			require.NoError(t, err)
+		}
+		_, err = channel2.PublishMessage(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(`{"a": 1}`)))
+		require.NoError(t, err)
+
+		stats, err := factory.Stats(ctx, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), stats.Channels)
+		require.Equal(t, int64(4), stats.Messages)
+		require.Greater(t, stats.Bytes, int64(0))
+
+		stats, err = factory.Stats(ctx, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, ChannelStats{}, stats)
+	})
+	t.Run("channels_info", func(t *testing.T) {
+		active, err := factory.GetOrCreateChannel(ctx, 1, 1, "active")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, active)
+
+		idle, err := factory.GetOrCreateChannel(ctx, 1, 1, "idle")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, idle)
+
+		id, err := active.PublishMessage(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(`{"a": 1}`)))
+		require.NoError(t, err)
+		require.NoError(t, active.stream.CreateConsumerGroup(ctx, "w1", "$"))
+		require.NoError(t, active.stream.CreateConsumerGroup(ctx, "w2", "$"))
+
+		pos, err := parseStreamPosition(id)
+		require.NoError(t, err)
+
+		infos, err := factory.ChannelsInfo(ctx, 1, 1, []string{"active", "idle", "missing"})
+		require.NoError(t, err)
+		require.Equal(t, []ChannelInfo{
+			{Channel: "active", Watchers: 2, LastActivity: time.UnixMilli(pos.ms)},
+			{Channel: "idle"},
+			{Channel: "missing"},
+		}, infos)
+	})
+	t.Run("snapshot_restore", func(t *testing.T) {
+		channel, err := factory.GetOrCreateChannel(ctx, 1, 3, "snap")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, channel)
+
+		id, err := channel.PublishMessage(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(`{"a": 1}`)))
+		require.NoError(t, err)
+		require.NoError(t, channel.stream.CreateConsumerGroup(ctx, "consumer", id))
+
+		var buf bytes.Buffer
+		n, err := factory.Snapshot(ctx, 1, 3, &buf)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		n, err = factory.Restore(ctx, 1, 4, &buf)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		restored, err := factory.GetChannel(ctx, 1, 4, "snap")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, restored)
+
+		group, exists, err := restored.stream.GetConsumerGroup(ctx, "consumer")
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, id, group.LastDeliveredID)
+
+		// the messages are not part of the snapshot
+		_, exists, err = restored.ReadReverse(ctx, "+", 1)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		_, err = factory.Restore(ctx, 1, 4, strings.NewReader(`{"v": 2, "name": "snap"}`))
+		require.Equal(t, errors.InvalidArgument("unsupported channel snapshot version '2'"), err)
+	})
+}
+
+func TestFactoryTenantIsolation(t *testing.T) {
+	ctx := context.TODO()
+	factory := newFactory(t)
+
+	t.Run("same_channel_name", func(t *testing.T) {
+		channel1, err := factory.GetOrCreateChannel(ctx, 1, 1, "shared")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, channel1)
+
+		channel2, err := factory.GetOrCreateChannel(ctx, 2, 1, "shared")
+		require.NoError(t, err)
+		defer factory.DeleteChannel(ctx, channel2)
+
+		require.NotEqual(t, channel1.Name(), channel2.Name())
+
+		_, err = channel1.PublishMessage(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(`{"a": 1}`)))
+		require.NoError(t, err)
+
+		_, exists, err := channel2.ReadReverse(ctx, "+", 1)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		channels, err := factory.ListChannels(ctx, 2, 1, "*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"shared"}, channels)
+
+		stats, err := factory.Stats(ctx, 2, 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), stats.Messages)
+	})
+	t.Run("unscoped_key", func(t *testing.T) {
+		cacheS := cache.NewCache(config.GetTestCacheConfig())
+		factory := NewChannelFactory(cacheS, &unscopedEncoder{CacheEncoder: metadata.NewCacheEncoder()}, nil)
+
+		_, err := factory.GetOrCreateChannel(ctx, 1, 1, "shared")
+		require.Equal(t, errors.Internal("channel key is not scoped to the tenant"), err)
+
+		_, err = factory.ListChannels(ctx, 1, 1, "*")
+		require.Equal(t, errors.Internal("channel key is not scoped to the tenant"), err)
+	})
+}
+
+func TestFactoryDropChannel(t *testing.T) {
+	ctx := context.TODO()
+	factory := newFactory(t)
+
+	channel, err := factory.GetOrCreateChannel(ctx, 1, 1, "to_delete")
+	require.NoError(t, err)
+	_, err = channel.PublishMessage(ctx, internal.NewStreamData(internal.MsgpackEncoding, nil, []byte(`{"a": 1}`)))
+	require.NoError(t, err)
+
+	require.NoError(t, factory.DropChannel(ctx, 1, 1, "to_delete"))
+
+	channels, err := factory.ListChannels(ctx, 1, 1, "to_delete")
+	require.NoError(t, err)
+	require.Empty(t, channels)
+
+	_, err = factory.GetChannel(ctx, 1, 1, "to_delete")
+	require.Equal(t, cache.ErrStreamNotFound, err)
+
+	err = factory.DropChannel(ctx, 1, 1, "to_delete")
+	require.Equal(t, errors.NotFound("channel 'to_delete' not present "), err)
+}
+
+func TestFactoryChannelConfig(t *testing.T) {
+	ctx := context.TODO()
+	factory := newFactory(t)
+	_ = factory.deleteChannelConfig(ctx, 1, 1, "configured")
+
+	config, err := factory.GetChannelConfig(ctx, 1, 1, "configured")
+	require.NoError(t, err)
+	require.Equal(t, &ChannelConfig{}, config)
+
+	err = factory.SetChannelConfig(ctx, 1, 1, "configured", &ChannelConfig{DeadLetterChannel: "configured"})
+	require.Equal(t, errors.InvalidArgument("channel 'configured' can't be its own dead-letter channel"), err)
+
+	// the configuration can be set before the channel exists
+	require.NoError(t, factory.SetChannelConfig(ctx, 1, 1, "configured", &ChannelConfig{DeadLetterChannel: "configured_dlq"}))
+	config, err = factory.GetChannelConfig(ctx, 1, 1, "configured")
+	require.NoError(t, err)
+	require.Equal(t, &ChannelConfig{DeadLetterChannel: "configured_dlq"}, config)
+
+	// the configuration is scoped to the project
+	config, err = factory.GetChannelConfig(ctx, 1, 2, "configured")
+	require.NoError(t, err)
+	require.Equal(t, &ChannelConfig{}, config)
+
+	// and dropped along with the channel
+	_, err = factory.GetOrCreateChannel(ctx, 1, 1, "configured")
+	require.NoError(t, err)
+	require.NoError(t, factory.DropChannel(ctx, 1, 1, "configured"))
+	config, err = factory.GetChannelConfig(ctx, 1, 1, "configured")
+	require.NoError(t, err)
+	require.Equal(t, &ChannelConfig{}, config)
+}
+
+// unscopedEncoder drops the namespace from the cache key.
+type unscopedEncoder struct {
+	metadata.CacheEncoder
+}
+
+func (e *unscopedEncoder) EncodeCacheTableName(_ uint32, projId uint32, name string) (string, error) {
+	return e.CacheEncoder.EncodeCacheTableName(0, projId, name)
 }
 
 func newFactory(_ *testing.T) *ChannelFactory {
@@ -0,0 +1,144 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/store/cache"
+)
+
+// idempotencyTable is the cache table holding the ids of the messages published with an idempotency key.
+const idempotencyTable = "rt_idempotency"
+
+// publishDedupe remembers the id assigned to a message published with an idempotency key, so that publishing a
+// message with the same key again within the window returns that id instead of publishing a duplicate, which is what
+// a client retrying a publish after a network error does.
+type publishDedupe struct {
+	cache   cache.Cache
+	channel string
+	window  time.Duration
+	// keys are the idempotency keys of the messages, in the order of the messages. An empty key, or a message after
+	// the last key, is always published.
+	keys []string
+}
+
+// newPublishDedupe returns the dedupe of the messages published to the channel, nil if there are no keys or the
+// window is zero.
+func newPublishDedupe(c cache.Cache, channel string, window time.Duration, keys []string) *publishDedupe {
+	if window <= 0 || len(keys) == 0 {
+		return nil
+	}
+
+	return &publishDedupe{
+		cache:   c,
+		channel: channel,
+		window:  window,
+		keys:    keys,
+	}
+}
+
+// parseIdempotencyKeys parses the comma separated idempotency keys of the messages of a publish request. There can't
+// be more keys than messages and two messages can't have the same key.
+func parseIdempotencyKeys(value string, messages int) ([]string, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	keys := strings.Split(value, ",")
+	if len(keys) > messages {
+		return nil, errors.InvalidArgument("%d idempotency keys for %d messages", len(keys), messages)
+	}
+	seen := make(map[string]struct{}, len(keys))
+	for i := range keys {
+		keys[i] = strings.TrimSpace(keys[i])
+		if len(keys[i]) == 0 {
+			continue
+		}
+		if _, ok := seen[keys[i]]; ok {
+			return nil, errors.InvalidArgument("duplicate idempotency key '%s'", keys[i])
+		}
+		seen[keys[i]] = struct{}{}
+	}
+
+	return keys, nil
+}
+
+// key returns the idempotency key of the i-th message, empty if it has none. It is safe to call on a nil dedupe.
+func (d *publishDedupe) key(i int) string {
+	if d == nil || i >= len(d.keys) {
+		return ""
+	}
+	return d.keys[i]
+}
+
+func (d *publishDedupe) cacheKey(key string) string {
+	return d.channel + ":" + key
+}
+
+// pendingPublish is the value of an idempotency key reserved by a publish that hasn't stored the id of its message
+// yet. It can't be mistaken for an id, which is made of two numbers.
+const pendingPublish = "pending"
+
+// Reserve reserves the idempotency key before its message is published, so that concurrent publishes of the same
+// message can't both publish it. It returns the id of the message if it was already published with the key within
+// the window, in which case the message must not be published again. A key reserved by a publish that hasn't
+// completed is rejected as aborted, the client can retry once that publish has completed.
+//
+// The reservation is held for the window, like the id, so that a publish that doesn't complete, for example because
+// the server stopped after publishing the message, can't be followed by a duplicate within the window.
+func (d *publishDedupe) Reserve(ctx context.Context, key string) (string, bool, error) {
+	err := d.cache.Set(ctx, idempotencyTable, d.cacheKey(key), internal.NewCacheData([]byte(pendingPublish)),
+		&cache.SetOptions{NX: true, PX: uint64(d.window.Milliseconds())})
+	if err == nil {
+		return "", false, nil
+	}
+	if err != cache.ErrKeyAlreadyExists {
+		return "", false, err
+	}
+
+	data, err := d.cache.Get(ctx, idempotencyTable, d.cacheKey(key), nil)
+	if err == cache.ErrKeyNotFound || (err == nil && string(data.RawData) == pendingPublish) {
+		// expired in between, it is left to the retry of the client to reserve it again
+		return "", false, errors.Aborted("message with idempotency key '%s' is being published", key)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(data.RawData), true, nil
+}
+
+// Release frees the reservation of the idempotency key of a message that wasn't published, so that it can be
+// published again. A failure is only logged, the reservation then expires with the window.
+func (d *publishDedupe) Release(ctx context.Context, key string) {
+	if _, err := d.cache.Delete(ctx, idempotencyTable, d.cacheKey(key)); err != nil {
+		log.Warn().Err(err).Str("channel", d.channel).Str("key", key).Msg("failed to release idempotency key")
+	}
+}
+
+// Remember records the id of the message published with the reserved idempotency key for the window. The message is
+// published by then, so a failure is only logged, the key stays reserved which still prevents a duplicate.
+func (d *publishDedupe) Remember(ctx context.Context, key string, id string) {
+	if err := d.cache.Set(ctx, idempotencyTable, d.cacheKey(key), internal.NewCacheData([]byte(id)),
+		&cache.SetOptions{PX: uint64(d.window.Milliseconds())}); err != nil {
+		log.Warn().Err(err).Str("channel", d.channel).Str("key", key).Msg("failed to remember idempotency key")
+	}
+}
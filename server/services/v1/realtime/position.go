@@ -0,0 +1,68 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"encoding/base64"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+const positionTokenV1 = 1
+
+// positionToken is the opaque position of a message in a channel handed out to the clients. It wraps the id of the
+// message in the stream so that the clients don't depend on the format of the stream ids, the version allows changing
+// the wrapped position without breaking the tokens the clients already hold.
+type positionToken struct {
+	Version int    `json:"v"`
+	ID      string `json:"id"`
+}
+
+// EncodePosition returns the opaque position token of the stream id.
+func EncodePosition(id string) string {
+	enc, _ := jsoniter.Marshal(positionToken{Version: positionTokenV1, ID: id})
+	return base64.RawURLEncoding.EncodeToString(enc)
+}
+
+// DecodePosition returns the stream id of a position token. For backward compatibility a raw stream id, a time in unix
+// milliseconds or one of the special stream ids "$", "-" and "+" is accepted as is.
+func DecodePosition(pos string) (string, error) {
+	switch pos {
+	case "$", "-", "+":
+		return pos, nil
+	}
+	if _, err := parseStreamPosition(pos); err == nil {
+		return pos, nil
+	}
+
+	enc, err := base64.RawURLEncoding.DecodeString(pos)
+	if err != nil {
+		return "", errors.InvalidArgument("invalid position '%s'", pos)
+	}
+
+	var token positionToken
+	if err = jsoniter.Unmarshal(enc, &token); err != nil {
+		return "", errors.InvalidArgument("invalid position '%s'", pos)
+	}
+	if token.Version != positionTokenV1 {
+		return "", errors.InvalidArgument("unsupported position version '%d'", token.Version)
+	}
+	if _, err = parseStreamPosition(token.ID); err != nil {
+		return "", errors.InvalidArgument("invalid position '%s'", pos)
+	}
+
+	return token.ID, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+// heartbeatStreaming sends an empty ReadMessagesResponse whenever nothing was sent for the interval, so that the
+// proxies and the clients don't time out a read of an idle channel. The clients skip the responses without a message.
+// The sends are serialized as a stream can't be sent to concurrently.
+type heartbeatStreaming struct {
+	Streaming
+
+	sync.Mutex
+	interval time.Duration
+	lastSent time.Time
+}
+
+func newHeartbeatStreaming(streaming Streaming, interval time.Duration) *heartbeatStreaming {
+	return &heartbeatStreaming{
+		Streaming: streaming,
+		interval:  interval,
+		lastSent:  time.Now(),
+	}
+}
+
+func (h *heartbeatStreaming) Send(resp *api.ReadMessagesResponse) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.lastSent = time.Now()
+	return h.Streaming.Send(resp)
+}
+
+// beat sends a heartbeat if nothing was sent for the interval.
+func (h *heartbeatStreaming) beat(now time.Time) error {
+	h.Lock()
+	defer h.Unlock()
+
+	if now.Sub(h.lastSent) < h.interval {
+		return nil
+	}
+
+	h.lastSent = now
+	return h.Streaming.Send(&api.ReadMessagesResponse{})
+}
+
+// start sends the heartbeats in the background until the context is done or the returned stop is called. The stop
+// waits for the background sender to exit, so that nothing is sent once the read has returned.
+func (h *heartbeatStreaming) start(ctx context.Context) func() {
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+
+	// checking at half the interval sends a heartbeat at most half an interval late
+	check := h.interval / 2
+	if check <= 0 {
+		check = h.interval
+	}
+	t := time.NewTicker(check)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer t.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-t.C:
+				if err := h.beat(now); err != nil {
+					log.Debug().Err(err).Msg("stopping read heartbeats")
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+)
+
+type countStreaming struct {
+	api.Realtime_ReadMessagesServer
+
+	sync.Mutex
+	heartbeats int
+	messages   int
+}
+
+func (c *countStreaming) Send(resp *api.ReadMessagesResponse) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if resp.Message == nil {
+		c.heartbeats++
+	} else {
+		c.messages++
+	}
+	return nil
+}
+
+func (c *countStreaming) counts() (int, int) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.heartbeats, c.messages
+}
+
+func TestReadHeartbeat(t *testing.T) {
+	t.Run("beat", func(t *testing.T) {
+		streaming := &countStreaming{}
+		h := newHeartbeatStreaming(streaming, time.Minute)
+
+		now := time.Now()
+		require.NoError(t, h.beat(now))
+		require.NoError(t, h.Send(&api.ReadMessagesResponse{Message: &api.Message{Name: "ev"}}))
+		// a message was just sent
+		require.NoError(t, h.beat(now.Add(59*time.Second)))
+		heartbeats, messages := streaming.counts()
+		require.Equal(t, 0, heartbeats)
+		require.Equal(t, 1, messages)
+
+		require.NoError(t, h.beat(time.Now().Add(time.Minute)))
+		heartbeats, _ = streaming.counts()
+		require.Equal(t, 1, heartbeats)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		streaming := &countStreaming{}
+		h := newHeartbeatStreaming(streaming, 10*time.Millisecond)
+
+		stop := h.start(context.Background())
+		require.Eventually(t, func() bool {
+			heartbeats, _ := streaming.counts()
+			return heartbeats > 0
+		}, time.Second, 5*time.Millisecond)
+		stop()
+
+		// nothing is sent once stopped
+		heartbeats, _ := streaming.counts()
+		time.Sleep(50 * time.Millisecond)
+		after, _ := streaming.counts()
+		require.Equal(t, heartbeats, after)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		streaming := &countStreaming{}
+		h := newHeartbeatStreaming(streaming, 10*time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		stop := h.start(ctx)
+		time.Sleep(50 * time.Millisecond)
+		stop()
+
+		heartbeats, _ := streaming.counts()
+		require.LessOrEqual(t, heartbeats, 1)
+	})
+}
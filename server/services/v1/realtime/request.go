@@ -14,7 +14,12 @@
 
 package realtime
 
-import "github.com/tigrisdata/tigris/internal"
+import (
+	"time"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+)
 
 const (
 	msgPackEncodingStr = "msgpack"
@@ -37,3 +42,53 @@ func (params ConnectionParams) ToEncodingType() internal.UserDataEncType {
 	// default is msgpack
 	return internal.MsgpackEncoding
 }
+
+// ChannelMessages are the messages to publish to a single channel.
+type ChannelMessages struct {
+	Channel  string
+	Messages []*api.Message
+	// EventTimes are the optional client supplied event times of the messages, in the same order as the messages.
+	// The time of a message is the server time if it is missing or zero.
+	EventTimes []time.Time
+}
+
+// MultiChannelMessagesRequest publishes messages to multiple channels of a project in a single call.
+type MultiChannelMessagesRequest struct {
+	Project  string
+	Channels []*ChannelMessages
+}
+
+// ChannelPosition is a channel of a multi-channel read and the position it is read from. The read starts after the
+// position, or with the new messages of the channel if the position is not set.
+type ChannelPosition struct {
+	Channel string
+	Start   string
+}
+
+// MultiChannelReadRequest reads multiple channels of a project as a single stream, see MultiChannelReadRunner.
+type MultiChannelReadRequest struct {
+	Project  string
+	Channels []*ChannelPosition
+	Limit    int64
+}
+
+// SeekConsumerRequest moves the offset of a consumer of a channel, see Channel.SeekConsumer.
+type SeekConsumerRequest struct {
+	Project  string
+	Channel  string
+	Consumer string
+	Position string
+}
+
+// DeleteChannelRequest deletes a channel of a project along with its messages, see ChannelFactory.DropChannel.
+type DeleteChannelRequest struct {
+	Project string
+	Channel string
+}
+
+// ChannelConfigRequest reads the configuration of a channel of a project, or replaces it if the configuration is set.
+type ChannelConfigRequest struct {
+	Project string
+	Channel string
+	Config  *ChannelConfig
+}
@@ -20,6 +20,12 @@ type Streaming interface {
 	api.Realtime_ReadMessagesServer
 }
 
+const (
+	DeletedStatus string = "deleted"
+)
+
+// Response is a wrapper on api.Response, the Status is set by the runners that don't have an api response.
 type Response struct {
 	api.Response
+	Status string
 }
@@ -0,0 +1,180 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/keys"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// LocalNode is the node of the single-node routing table.
+const LocalNode = "local"
+
+// ringReplicas is the number of points of every node on the hash ring, the more points the more even the channels
+// are spread between the nodes.
+const ringReplicas = 128
+
+// RoutingTable is the source of the membership of the cluster serving the channels. It is consulted on every
+// routing, so an implementation backed by a membership service only has to keep its view up to date.
+type RoutingTable interface {
+	// Nodes returns the ids of the nodes of the cluster, in any order.
+	Nodes() []string
+	// Local returns the id of this node.
+	Local() string
+}
+
+// LocalRoutingTable is the routing table of a single node owning all the channels.
+type LocalRoutingTable struct{}
+
+func (LocalRoutingTable) Nodes() []string {
+	return []string{LocalNode}
+}
+
+func (LocalRoutingTable) Local() string {
+	return LocalNode
+}
+
+// staticRoutingTable is the routing table of a cluster with a fixed membership.
+type staticRoutingTable struct {
+	nodes []string
+	local string
+}
+
+// NewStaticRoutingTable returns the routing table of the cluster of the nodes, the local node being one of them.
+func NewStaticRoutingTable(nodes []string, local string) RoutingTable {
+	return &staticRoutingTable{
+		nodes: nodes,
+		local: local,
+	}
+}
+
+func (s *staticRoutingTable) Nodes() []string {
+	return s.nodes
+}
+
+func (s *staticRoutingTable) Local() string {
+	return s.local
+}
+
+// ChannelRouter maps every channel to the node owning it through a consistent hash ring of the nodes of the routing
+// table, so that a change of the membership only moves the channels of the nodes that joined or left. The ring is
+// rebuilt whenever the nodes of the routing table change.
+type ChannelRouter struct {
+	sync.Mutex
+
+	table  RoutingTable
+	nodes  []string
+	points []uint64
+	owners map[uint64]string
+}
+
+func NewChannelRouter(table RoutingTable) *ChannelRouter {
+	return &ChannelRouter{
+		table: table,
+	}
+}
+
+// SetTable replaces the routing table, the channels are then routed to the nodes of the new table.
+func (r *ChannelRouter) SetTable(table RoutingTable) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.table = table
+	r.nodes, r.points, r.owners = nil, nil, nil
+}
+
+// Owner returns the node owning the channel key and whether it is this node.
+func (r *ChannelRouter) Owner(key keys.Key) (string, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.refresh()
+	if len(r.points) == 0 {
+		// without any node, this node serves the channel as a single node would
+		return r.table.Local(), true
+	}
+
+	h := key.Hash()
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+
+	owner := r.owners[r.points[i]]
+	return owner, owner == r.table.Local()
+}
+
+// refresh rebuilds the ring if the nodes of the routing table changed since it was built.
+func (r *ChannelRouter) refresh() {
+	nodes := append([]string(nil), r.table.Nodes()...)
+	sort.Strings(nodes)
+	if sameNodes(nodes, r.nodes) && r.owners != nil {
+		return
+	}
+
+	r.nodes = nodes
+	r.points = make([]uint64, 0, len(nodes)*ringReplicas)
+	r.owners = make(map[uint64]string, len(nodes)*ringReplicas)
+	for _, node := range nodes {
+		for i := 0; i < ringReplicas; i++ {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(node + "#" + strconv.Itoa(i)))
+			point := h.Sum64()
+
+			// the nodes are sorted, so on a collision the smallest node wins and all the nodes build the same ring
+			if _, ok := r.owners[point]; ok {
+				continue
+			}
+			r.points = append(r.points, point)
+			r.owners[point] = node
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+func sameNodes(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ownerReason is the type of the precondition failure naming the node owning a channel.
+const ownerReason = "CHANNEL_OWNER"
+
+// notOwnerError returns the error of a request for a channel owned by another node. The request can't succeed on this
+// node, so it isn't retriable, and the error names the owner in its precondition failure so that the request can be
+// sent to it.
+func notOwnerError(channel string, owner string) error {
+	return api.Errorf(api.Code_FAILED_PRECONDITION, "channel '%s' is served by node '%s'", channel, owner).
+		WithDetails(&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        ownerReason,
+				Subject:     owner,
+				Description: "the node serving the channel",
+			}},
+		})
+}
@@ -0,0 +1,119 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/keys"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestChannelRouter(t *testing.T) {
+	channelKey := func(i int) keys.Key {
+		return keys.NewKey([]byte(fmt.Sprintf("rt:1:1:channel_%d", i)))
+	}
+
+	t.Run("local", func(t *testing.T) {
+		router := NewChannelRouter(LocalRoutingTable{})
+		for i := 0; i < 100; i++ {
+			owner, local := router.Owner(channelKey(i))
+			require.Equal(t, LocalNode, owner)
+			require.True(t, local)
+		}
+	})
+
+	t.Run("no_nodes", func(t *testing.T) {
+		owner, local := NewChannelRouter(&staticRoutingTable{local: "n1"}).Owner(channelKey(0))
+		require.Equal(t, "n1", owner)
+		require.True(t, local)
+	})
+
+	t.Run("spread", func(t *testing.T) {
+		table := &staticRoutingTable{nodes: []string{"n1", "n2", "n3"}, local: "n1"}
+		router := NewChannelRouter(table)
+
+		counts := map[string]int{}
+		owners := map[int]string{}
+		for i := 0; i < 3000; i++ {
+			owner, local := router.Owner(channelKey(i))
+			require.Equal(t, owner == "n1", local)
+			counts[owner]++
+			owners[i] = owner
+		}
+		for _, node := range table.nodes {
+			require.Greater(t, counts[node], 500, node)
+		}
+
+		// the ring doesn't depend on the order of the nodes
+		reordered := NewChannelRouter(&staticRoutingTable{nodes: []string{"n3", "n1", "n2"}, local: "n2"})
+		for i := 0; i < 3000; i++ {
+			owner, _ := reordered.Owner(channelKey(i))
+			require.Equal(t, owners[i], owner)
+		}
+
+		// a node joining only takes over channels, the other channels keep their owner
+		table.nodes = append(table.nodes, "n4")
+		moved := 0
+		for i := 0; i < 3000; i++ {
+			owner, _ := router.Owner(channelKey(i))
+			if owner != owners[i] {
+				require.Equal(t, "n4", owner)
+				moved++
+			}
+		}
+		require.Greater(t, moved, 0)
+		require.Less(t, moved, 1500)
+	})
+}
+
+func TestChannelRouting(t *testing.T) {
+	factory := newFactory(t)
+
+	// all the channels are local until the routing table is set
+	for i := 0; i < 10; i++ {
+		require.NoError(t, factory.RouteChannel(1, 1, fmt.Sprintf("ch_%d", i)))
+	}
+
+	factory.SetRoutingTable(NewStaticRoutingTable([]string{"n1", "n2"}, "n1"))
+	remote := 0
+	for i := 0; i < 100; i++ {
+		err := factory.RouteChannel(1, 1, fmt.Sprintf("ch_%d", i))
+		if err == nil {
+			continue
+		}
+		remote++
+
+		// the error isn't retriable on this node and names the owner
+		var tErr *api.TigrisError
+		require.ErrorAs(t, err, &tErr)
+		require.Equal(t, api.Code_FAILED_PRECONDITION, tErr.Code)
+		require.Equal(t, fmt.Sprintf("channel 'ch_%d' is served by node 'n2'", i), tErr.Message)
+		require.Len(t, tErr.Details, 1)
+		violation := tErr.Details[0].(*errdetails.PreconditionFailure).Violations[0]
+		require.Equal(t, ownerReason, violation.Type)
+		require.Equal(t, "n2", violation.Subject)
+	}
+	require.Greater(t, remote, 0)
+	require.Less(t, remote, 100)
+
+	factory.SetRoutingTable(LocalRoutingTable{})
+	for i := 0; i < 100; i++ {
+		require.NoError(t, factory.RouteChannel(1, 1, fmt.Sprintf("ch_%d", i)))
+	}
+}
@@ -17,14 +17,20 @@ package realtime
 import (
 	"context"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
+	xredis "github.com/go-redis/redis/v8"
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/store/cache"
+	grpcmd "google.golang.org/grpc/metadata"
 )
 
 // RTMRunner is used to run the realtime HTTP APIs related to channel like accessing a channel, subscribing to a channel, etc.
@@ -37,7 +43,8 @@ type RTMRunnerFactory struct {
 	factory *ChannelFactory
 }
 
-// NewRTMRunnerFactory returns RTMRunnerFactory object.
+// NewRTMRunnerFactory returns RTMRunnerFactory object. This node owns all the channels until a routing table of the
+// cluster is set.
 func NewRTMRunnerFactory(cache cache.Cache, factory *ChannelFactory) *RTMRunnerFactory {
 	return &RTMRunnerFactory{
 		cache:   cache,
@@ -45,6 +52,13 @@ func NewRTMRunnerFactory(cache cache.Cache, factory *ChannelFactory) *RTMRunnerF
 	}
 }
 
+// SetRoutingTable sets the membership of the cluster the channels are spread over, the publishes and the reads of a
+// channel owned by another node are rejected so that they are sent to its owner. The routing table is the one of the
+// channel factory, so it also applies to the device sessions sharing the factory.
+func (f *RTMRunnerFactory) SetRoutingTable(table RoutingTable) {
+	f.factory.SetRoutingTable(table)
+}
+
 func (f *RTMRunnerFactory) GetMessagesRunner(r *api.MessagesRequest) *MessagesRunner {
 	return &MessagesRunner{
 		baseRunner: newBaseRunner(f.cache, f.factory),
@@ -52,161 +66,1187 @@ func (f *RTMRunnerFactory) GetMessagesRunner(r *api.MessagesRequest) *MessagesRu
 	}
 }
 
-func (f *RTMRunnerFactory) GetReadMessagesRunner(r *api.ReadMessagesRequest, streaming Streaming) *ReadMessagesRunner {
-	return &ReadMessagesRunner{
-		baseRunner: newBaseRunner(f.cache, f.factory),
-		req:        r,
-		streaming:  streaming,
+func (f *RTMRunnerFactory) GetMultiChannelMessagesRunner(r *MultiChannelMessagesRequest) *MultiChannelMessagesRunner {
+	return &MultiChannelMessagesRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		req:        r,
+	}
+}
+
+func (f *RTMRunnerFactory) GetSeekConsumerRunner(r *SeekConsumerRequest) *SeekConsumerRunner {
+	return &SeekConsumerRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		req:        r,
+	}
+}
+
+func (f *RTMRunnerFactory) GetReadMessagesRunner(r *api.ReadMessagesRequest, streaming Streaming) *ReadMessagesRunner {
+	return &ReadMessagesRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		req:        r,
+		streaming:  streaming,
+	}
+}
+
+func (f *RTMRunnerFactory) GetMultiChannelReadRunner(r *MultiChannelReadRequest, streaming MultiChannelStreaming) *MultiChannelReadRunner {
+	return &MultiChannelReadRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		req:        r,
+		streaming:  streaming,
+	}
+}
+
+func (f *RTMRunnerFactory) GetChannelRunner() *ChannelRunner {
+	return &ChannelRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+	}
+}
+
+func (f *RTMRunnerFactory) GetDeleteChannelRunner(r *DeleteChannelRequest) *DeleteChannelRunner {
+	return &DeleteChannelRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		req:        r,
+	}
+}
+
+func (f *RTMRunnerFactory) GetChannelConfigRunner(r *ChannelConfigRequest) *ChannelConfigRunner {
+	return &ChannelConfigRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		req:        r,
+	}
+}
+
+func (f *RTMRunnerFactory) GetChannelStatsRunner(project string) *ChannelStatsRunner {
+	return &ChannelStatsRunner{
+		baseRunner: newBaseRunner(f.cache, f.factory),
+		project:    project,
+	}
+}
+
+type baseRunner struct {
+	cache   cache.Cache
+	factory *ChannelFactory
+}
+
+func newBaseRunner(cache cache.Cache, factory *ChannelFactory) *baseRunner {
+	return &baseRunner{
+		cache:   cache,
+		factory: factory,
+	}
+}
+
+// routeChannel rejects the request if the channel is owned by another node, the error names the owner so that the
+// request can be sent to it.
+func (runner *baseRunner) routeChannel(tenant *metadata.Tenant, project *metadata.Project, channel string) error {
+	return runner.factory.RouteChannel(tenant.GetNamespace().Id(), project.Id(), channel)
+}
+
+// getProject resolves the project of the request. The project derived from the request context is authoritative,
+// the project in the request body is only used when the context doesn't carry one and must otherwise match it.
+func (runner *baseRunner) getProject(ctx context.Context, tenant *metadata.Tenant, project string) (*metadata.Project, error) {
+	if ctxProject, err := request.GetProject(ctx); err == nil {
+		if len(project) > 0 && project != ctxProject {
+			return nil, errors.PermissionDenied("project '%s' doesn't match the project of the request", project)
+		}
+		project = ctxProject
+	}
+
+	proj, err := tenant.GetProject(project)
+	if err != nil {
+		return nil, createApiError(err)
+	}
+	return proj, nil
+}
+
+// getDeadLetter resolves the dead-letter channel set in the configuration of the source channel, it returns nil if
+// the source channel has none.
+func (runner *baseRunner) getDeadLetter(ctx context.Context, tenant *metadata.Tenant, project *metadata.Project,
+	source string,
+) (*deadLetter, error) {
+	name, err := runner.getDeadLetterChannel(ctx, tenant, project, source)
+	if err != nil || len(name) == 0 {
+		return nil, err
+	}
+
+	channel, err := runner.factory.GetOrCreateChannel(ctx, tenant.GetNamespace().Id(), project.Id(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deadLetter{source: source, channel: channel}, nil
+}
+
+// getDeadLetterChannel returns the name of the dead-letter channel of the source channel, empty if it has none.
+func (runner *baseRunner) getDeadLetterChannel(ctx context.Context, tenant *metadata.Tenant, project *metadata.Project,
+	source string,
+) (string, error) {
+	config, err := runner.factory.GetChannelConfig(ctx, tenant.GetNamespace().Id(), project.Id(), source)
+	if err != nil || len(config.DeadLetterChannel) == 0 {
+		return "", err
+	}
+	if err = runner.routeChannel(tenant, project, config.DeadLetterChannel); err != nil {
+		return "", err
+	}
+
+	return config.DeadLetterChannel, nil
+}
+
+// deadLetter is where the messages rejected by the source channel are published to, so that they can be inspected
+// and reprocessed instead of being lost.
+type deadLetter struct {
+	source  string
+	channel *Channel
+}
+
+// publish publishes the rejected message to the dead-letter channel. The name of the message is kept and the
+// rejection reason along with the source channel is stored in the metadata of the message. As the data of a
+// rejected message may not be valid JSON, it is stored as a string holding the data as it was received.
+func (dl *deadLetter) publish(ctx context.Context, m *api.Message, reason error) (string, error) {
+	data, err := EncodeAsMsgPack(string(m.Data))
+	if err != nil {
+		return "", err
+	}
+
+	md := NewStreamMessageMD(MessageChannelData, "", "", m.Name)
+	md.SourceChannel = dl.source
+	md.Error = reason.Error()
+
+	streamData, err := newStreamDataWithMD(md, internal.MsgpackEncoding, data)
+	if err != nil {
+		return "", err
+	}
+
+	return dl.channel.PublishMessage(ctx, streamData)
+}
+
+// MessagesRunner is to publish messages to a channel.
+type MessagesRunner struct {
+	*baseRunner
+
+	req             *api.MessagesRequest
+	idempotencyKeys string
+	eventTimes      string
+	atomic          bool
+	published       []string
+	timestamps      []string
+}
+
+// SetIdempotencyKeys sets the comma separated idempotency keys of the messages, in the order of the messages. A
+// message with a key that was already published to the channel within the configured window is not published
+// again, the id it was assigned is returned instead.
+func (runner *MessagesRunner) SetIdempotencyKeys(keys string) {
+	runner.idempotencyKeys = keys
+}
+
+// SetEventTimes sets the comma separated times of the events of the messages in unix milliseconds, in the order of
+// the messages. They are returned by the reads along with the time the server published the messages.
+func (runner *MessagesRunner) SetEventTimes(eventTimes string) {
+	runner.eventTimes = eventTimes
+}
+
+// SetAtomic publishes the messages all together or none of them. An atomic publish doesn't use the dead-letter
+// channel of the channel, a message rejected by the channel fails the whole batch.
+func (runner *MessagesRunner) SetAtomic(atomic bool) {
+	runner.atomic = atomic
+}
+
+// PublishedIds returns the ids of the messages that were published before the publish failed, so that the caller
+// can find out which messages of a failed batch landed. It is empty if the publish succeeded or was atomic.
+func (runner *MessagesRunner) PublishedIds() []string {
+	return runner.published
+}
+
+// PublishedTimestamps returns the times, in unix milliseconds, at which the server assigned the ids of the published
+// messages, in the order of the ids. The time is the one the channel orders the messages by, which is what the reads
+// of the channel return. The time of a message sent to the dead-letter channel is the one of the dead-letter channel.
+func (runner *MessagesRunner) PublishedTimestamps() []string {
+	return runner.timestamps
+}
+
+func (runner *MessagesRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	if err := validatePublishBatchSize(len(runner.req.Messages)); err != nil {
+		return Response{}, err
+	}
+	if err := validateMessageSizes(runner.req.Messages); err != nil {
+		return Response{}, err
+	}
+
+	idempotencyKeys, err := parseIdempotencyKeys(runner.idempotencyKeys, len(runner.req.Messages))
+	if err != nil {
+		return Response{}, err
+	}
+	eventTimes, err := parseEventTimes(runner.eventTimes, len(runner.req.Messages))
+	if err != nil {
+		return Response{}, err
+	}
+
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err = runner.routeChannel(tenant, project, runner.req.Channel); err != nil {
+		return Response{}, err
+	}
+
+	channel, err := runner.factory.GetOrCreateChannel(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel)
+	if err != nil {
+		return Response{}, err
+	}
+
+	dedupe := newPublishDedupe(runner.cache, channel.Name(), config.DefaultConfig.Realtime.IdempotencyWindow, idempotencyKeys)
+	if runner.atomic {
+		ids, err := publishMessagesAtomic(ctx, channel, runner.req.Messages, eventTimes, dedupe)
+		if err != nil {
+			return Response{}, err
+		}
+		runner.timestamps = publishTimestamps(ids)
+
+		return Response{
+			Response: &api.MessagesResponse{
+				Ids: ids,
+			},
+		}, nil
+	}
+
+	dl, err := runner.getDeadLetter(ctx, tenant, project, runner.req.Channel)
+	if err != nil {
+		return Response{}, err
+	}
+
+	ids, err := publishMessages(ctx, channel, runner.req.Messages, eventTimes, dl, dedupe)
+	if err != nil {
+		runner.published = ids
+		return Response{}, err
+	}
+	runner.timestamps = publishTimestamps(ids)
+
+	return Response{
+		Response: &api.MessagesResponse{
+			Ids: ids,
+		},
+	}, nil
+}
+
+// publishTimestamps returns the ingestion times of the messages, the millisecond part of their ids which the channel
+// orders the messages by. The time of an empty id is empty.
+func publishTimestamps(ids []string) []string {
+	timestamps := make([]string, 0, len(ids))
+	for _, id := range ids {
+		ts := ""
+		if pos, err := parseStreamPosition(id); err == nil {
+			ts = strconv.FormatInt(pos.ms, 10)
+		}
+		timestamps = append(timestamps, ts)
+	}
+	return timestamps
+}
+
+// parseEventTimes parses the comma separated event times of the messages in unix milliseconds. An empty time is a
+// message without an event time.
+func parseEventTimes(value string, messages int) ([]time.Time, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	values := strings.Split(value, ",")
+	if len(values) > messages {
+		return nil, errors.InvalidArgument("%d event times for %d messages", len(values), messages)
+	}
+	eventTimes := make([]time.Time, len(values))
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms <= 0 {
+			return nil, errors.InvalidArgument("invalid event time '%s' of the message at index %d", v, i)
+		}
+		eventTimes[i] = time.UnixMilli(ms)
+	}
+
+	return eventTimes, nil
+}
+
+// validatePublishBatchSize rejects a publish request carrying more messages than the configured maximum.
+func validatePublishBatchSize(count int) error {
+	limit := config.DefaultConfig.Realtime.MaxMessagesPerPublish
+	if limit > 0 && count > limit {
+		return errors.InvalidArgument("too many messages in a publish request, maximum allowed is %d, received %d", limit, count)
+	}
+	return nil
+}
+
+// validateMessageSizes rejects the messages if any of them is larger than the configured maximum once encoded as it
+// is stored, so that nothing is published. The messages that can't be encoded are left to be handled by the publish.
+func validateMessageSizes(messages []*api.Message) error {
+	limit := config.DefaultConfig.Realtime.MaxMessageSize
+	if limit <= 0 {
+		return nil
+	}
+
+	for i, m := range messages {
+		data, err := JsonByteToMsgPack(m.Data)
+		if err != nil {
+			continue
+		}
+		if len(data) > limit {
+			return errors.InvalidArgument("message at index %d is too large, maximum allowed size is %d bytes, received %d bytes",
+				i, limit, len(data))
+		}
+	}
+
+	return nil
+}
+
+// publishMessages publishes the messages in order and returns the ids of the messages. The eventTimes are the optional
+// client supplied times of the messages. On error, the ids of the messages that were published before the failure
+// are returned along with the error. If the dead-letter channel is set, a message rejected by the channel is
+// published to it instead of failing, and its id is the one in the dead-letter channel. If the dedupe is set, a message with an idempotency key
+// that was already published returns the id it was assigned instead of being published again.
+func publishMessages(ctx context.Context, channel *Channel, messages []*api.Message, eventTimes []time.Time,
+	dl *deadLetter, dedupe *publishDedupe,
+) ([]string, error) {
+	ids := make([]string, 0, len(messages))
+	for i, m := range messages {
+		key := dedupe.key(i)
+		if len(key) > 0 {
+			id, published, err := dedupe.Reserve(ctx, key)
+			if err != nil {
+				return ids, err
+			}
+			if published {
+				ids = append(ids, id)
+				continue
+			}
+		}
+
+		id, err := publishMessage(ctx, channel, m, i, eventTimes, dl)
+		if len(key) > 0 {
+			if err != nil {
+				dedupe.Release(ctx, key)
+			} else {
+				dedupe.Remember(ctx, key, id)
+			}
+		}
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// publishMessage publishes the i-th message of a publish request and returns its id, the id in the dead-letter
+// channel if it is rejected by the channel and published to the dead-letter channel.
+func publishMessage(ctx context.Context, channel *Channel, m *api.Message, i int, eventTimes []time.Time,
+	dl *deadLetter,
+) (string, error) {
+	// The data is a json encoded Byte.
+	// Convert that to a msgback bytes to store
+	data, err := JsonByteToMsgPack(m.Data)
+	if err != nil {
+		if dl == nil {
+			return "", err
+		}
+		return dl.publish(ctx, m, err)
+	}
+	m.Data = data
+
+	var eventTime time.Time
+	if i < len(eventTimes) {
+		eventTime = eventTimes[i]
+	}
+
+	streamData, err := NewEventDataFromMessageAt(internal.MsgpackEncoding, "", "", m.Name, m, eventTime)
+	if err != nil {
+		return "", err
+	}
+
+	return channel.PublishMessage(ctx, streamData)
+}
+
+// publishMessagesAtomic publishes the messages in a single transaction of the channel and returns the ids of the
+// messages, nothing is published if any message is rejected. A message with an idempotency key that was already
+// published is not part of the transaction and returns the id it was assigned. The keys of the other messages are
+// reserved before the transaction and released if it fails. The eventTimes are the optional client supplied times of
+// the messages.
+func publishMessagesAtomic(ctx context.Context, channel *Channel, messages []*api.Message, eventTimes []time.Time,
+	dedupe *publishDedupe,
+) ([]string, error) {
+	var (
+		ids      = make([]string, len(messages))
+		pending  = make([]int, 0, len(messages))
+		batch    = make([]*internal.StreamData, 0, len(messages))
+		reserved []string
+	)
+	release := func() {
+		for _, key := range reserved {
+			dedupe.Release(ctx, key)
+		}
+	}
+
+	for i, m := range messages {
+		if key := dedupe.key(i); len(key) > 0 {
+			id, published, err := dedupe.Reserve(ctx, key)
+			if err != nil {
+				release()
+				return nil, err
+			}
+			if published {
+				ids[i] = id
+				continue
+			}
+			reserved = append(reserved, key)
+		}
+
+		data, err := JsonByteToMsgPack(m.Data)
+		if err != nil {
+			release()
+			return nil, errors.InvalidArgument("message at index %d is invalid: %s", i, err.Error())
+		}
+		m.Data = data
+
+		var eventTime time.Time
+		if i < len(eventTimes) {
+			eventTime = eventTimes[i]
+		}
+
+		streamData, err := NewEventDataFromMessageAt(internal.MsgpackEncoding, "", "", m.Name, m, eventTime)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		pending = append(pending, i)
+		batch = append(batch, streamData)
+	}
+
+	if len(batch) > 0 {
+		added, err := channel.PublishMessages(ctx, batch)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		for j, i := range pending {
+			ids[i] = added[j]
+		}
+	}
+
+	for _, i := range pending {
+		if key := dedupe.key(i); len(key) > 0 {
+			dedupe.Remember(ctx, key, ids[i])
+		}
+	}
+
+	return ids, nil
+}
+
+// ChannelMessagesResult is the outcome of publishing the messages of a single channel of a
+// MultiChannelMessagesRequest.
+type ChannelMessagesResult struct {
+	Channel string
+	// Ids of the messages that were published, in the order of the messages of the request
+	Ids []string
+	// Err is set if publishing to the channel failed, the messages after the failed one are not published
+	Err error
+}
+
+// MultiChannelMessagesRunner is to publish messages to multiple channels. All the channels are resolved before
+// anything is published, so if any channel can't be resolved the request fails without publishing. After that, the
+// channels are published to independently, a failure in one channel doesn't stop publishing to the other channels
+// and is reported in the result of that channel. The results are available through Results once the runner has been
+// executed, in the order of the channels in the request.
+type MultiChannelMessagesRunner struct {
+	*baseRunner
+
+	req     *MultiChannelMessagesRequest
+	results []ChannelMessagesResult
+}
+
+func (runner *MultiChannelMessagesRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	count := 0
+	for _, c := range runner.req.Channels {
+		count += len(c.Messages)
+	}
+	if err := validatePublishBatchSize(count); err != nil {
+		return Response{}, err
+	}
+
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
+	if err != nil {
+		return Response{}, err
+	}
+
+	names := make([]string, 0, len(runner.req.Channels))
+	deadLetterChannels := make(map[string]string)
+	for _, c := range runner.req.Channels {
+		if err = runner.routeChannel(tenant, project, c.Channel); err != nil {
+			return Response{}, err
+		}
+
+		names = append(names, c.Channel)
+		deadLetterChannel, err := runner.getDeadLetterChannel(ctx, tenant, project, c.Channel)
+		if err != nil {
+			return Response{}, err
+		}
+		if len(deadLetterChannel) > 0 {
+			deadLetterChannels[c.Channel] = deadLetterChannel
+			names = append(names, deadLetterChannel)
+		}
+	}
+
+	channels, err := runner.factory.GetOrCreateChannels(ctx, tenant.GetNamespace().Id(), project.Id(), names)
+	if err != nil {
+		return Response{}, err
+	}
+
+	runner.results = make([]ChannelMessagesResult, len(runner.req.Channels))
+	for i, c := range runner.req.Channels {
+		var dl *deadLetter
+		if name, ok := deadLetterChannels[c.Channel]; ok {
+			dl = &deadLetter{source: c.Channel, channel: channels[name]}
+		}
+
+		// a message too large fails its channel before any of its messages is published, like MessagesRunner does
+		if err = validateMessageSizes(c.Messages); err != nil {
+			runner.results[i] = ChannelMessagesResult{Channel: c.Channel, Err: err}
+			continue
+		}
+
+		ids, err := publishMessages(ctx, channels[c.Channel], c.Messages, c.EventTimes, dl, nil)
+		runner.results[i] = ChannelMessagesResult{
+			Channel: c.Channel,
+			Ids:     ids,
+			Err:     err,
+		}
+	}
+
+	return Response{}, nil
+}
+
+func (runner *MultiChannelMessagesRunner) Results() []ChannelMessagesResult {
+	return runner.results
+}
+
+// SeekConsumerRunner is to rewind or fast-forward the offset of a consumer of a channel.
+type SeekConsumerRunner struct {
+	*baseRunner
+
+	req *SeekConsumerRequest
+}
+
+func (runner *SeekConsumerRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err = runner.routeChannel(tenant, project, runner.req.Channel); err != nil {
+		return Response{}, err
+	}
+
+	channel, err := runner.factory.GetChannel(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel)
+	if err != nil {
+		return Response{}, err
+	}
+
+	position, err := DecodePosition(runner.req.Position)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err = channel.SeekConsumer(ctx, runner.req.Consumer, position); err != nil {
+		return Response{}, err
+	}
+
+	return Response{}, nil
+}
+
+// reverseReadBatchSize is the maximum number of messages fetched from the channel in one reverse read.
+var reverseReadBatchSize = 100
+
+type ReadMessagesRunner struct {
+	*baseRunner
+
+	req       *api.ReadMessagesRequest
+	streaming Streaming
+	end       string
+	reverse   bool
+}
+
+// SetEnd sets the inclusive end of the read used when the request has no end, the read stops at the first message
+// past it. The end is either a message id "<ms>-<seq>" or a time "<ms>" in unix milliseconds, which includes all the
+// messages of that millisecond. If the start is past the end nothing is returned.
+func (runner *ReadMessagesRunner) SetEnd(end string) {
+	runner.end = end
+}
+
+// SetReverse reads the channel newest first, starting from the start if set or from the tail of the channel
+// otherwise. In reverse mode the end is the oldest message to read.
+func (runner *ReadMessagesRunner) SetReverse(reverse bool) {
+	runner.reverse = reverse
+}
+
+func (runner *ReadMessagesRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	var (
+		start string
+		err   error
+	)
+	if len(runner.req.GetStart()) > 0 {
+		if start, err = DecodePosition(runner.req.GetStart()); err != nil {
+			return Response{}, err
+		}
+	}
+
+	endParam := runner.req.GetEnd()
+	if len(endParam) == 0 {
+		endParam = runner.end
+	}
+
+	var end *streamPosition
+	if len(endParam) > 0 {
+		endID, err := DecodePosition(endParam)
+		if err != nil {
+			return Response{}, errors.InvalidArgument("invalid end '%s'", endParam)
+		}
+		pos, err := parseStreamPosition(endID)
+		if err != nil {
+			return Response{}, errors.InvalidArgument("invalid end '%s'", endParam)
+		}
+		end = &pos
+
+		if startPos, err := parseStreamPosition(start); err == nil && runner.pastEnd(startPos, *end) {
+			return Response{}, nil
+		}
+	}
+
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
+	if err != nil {
+		return Response{}, err
+	}
+	if err = runner.routeChannel(tenant, project, runner.req.Channel); err != nil {
+		return Response{}, err
+	}
+
+	channel, err := runner.factory.GetChannel(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if interval := config.DefaultConfig.Realtime.ReadHeartbeatInterval; interval > 0 {
+		heartbeat := newHeartbeatStreaming(runner.streaming, interval)
+		runner.streaming = heartbeat
+		defer heartbeat.start(ctx)()
+	}
+
+	if runner.reverse {
+		return runner.readReverse(ctx, channel, start, end)
+	}
+
+	return runner.readForward(ctx, channel, start, end)
+}
+
+// readForward walks the channel from the start, or from the new messages if the start is not set, and sends the
+// messages oldest first until the limit is reached, the end is crossed or there are no newer messages. It stops as
+// soon as the context is done, the client disconnected or the deadline of the request is exceeded.
+func (runner *ReadMessagesRunner) readForward(ctx context.Context, channel *Channel, start string, end *streamPosition) (Response, error) {
+	pos := start
+	if len(pos) == 0 {
+		pos = "$"
+	}
+
+	count := int64(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		default:
+		}
+
+		resp, exists, err := channel.Read(ctx, pos)
+		if err != nil {
+			return Response{}, err
+		}
+		if !exists {
+			return Response{}, nil
+		}
+
+		for _, m := range resp.Messages {
+			if end != nil {
+				if msgPos, err := parseStreamPosition(m.ID); err == nil && msgPos.after(*end) {
+					return Response{}, nil
+				}
+			}
+
+			if err = runner.send(resp, m); err != nil {
+				return Response{}, err
+			}
+
+			count++
+			if runner.req.GetLimit() > 0 && count == runner.req.GetLimit() {
+				return Response{}, nil
+			}
+		}
+
+		// the read returns the messages strictly after the position, so the next read resumes from the last id as
+		// is, there is no need to parse and increment it which would skip the message right after it
+		if len(resp.Messages) > 0 {
+			pos = resp.Messages[len(resp.Messages)-1].ID
+		}
+	}
+}
+
+// readReverse walks the channel backward from the start, or from the tail if the start is not set, and sends the
+// messages newest first until the limit is reached, the end is crossed or there are no older messages. Like
+// readForward, it stops as soon as the context is done.
+func (runner *ReadMessagesRunner) readReverse(ctx context.Context, channel *Channel, start string, end *streamPosition) (Response, error) {
+	pos := start
+	if len(pos) == 0 {
+		pos = "+"
+	}
+
+	count := int64(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		default:
+		}
+
+		batch := int64(reverseReadBatchSize)
+		if limit := runner.req.GetLimit(); limit > 0 && limit-count < batch {
+			batch = limit - count
+		}
+
+		resp, exists, err := channel.ReadReverse(ctx, pos, batch)
+		if err != nil {
+			return Response{}, err
+		}
+		if !exists {
+			return Response{}, nil
+		}
+
+		for _, m := range resp.Messages {
+			if end != nil {
+				if msgPos, err := parseStreamPosition(m.ID); err == nil && msgPos.before(*end) {
+					return Response{}, nil
+				}
+			}
+
+			if err = runner.send(resp, m); err != nil {
+				return Response{}, err
+			}
+
+			count++
+			if runner.req.GetLimit() > 0 && count == runner.req.GetLimit() {
+				return Response{}, nil
+			}
+		}
+
+		var ok bool
+		if pos, ok = prevStreamID(resp.Messages[len(resp.Messages)-1].ID); !ok {
+			return Response{}, nil
+		}
+	}
+}
+
+// send sends the message and appends its times to the HeaderReadMessagesEventTimes and the
+// HeaderReadMessagesIngestionTimes trailers, the response of a stream has no room for them.
+func (runner *ReadMessagesRunner) send(resp *cache.StreamMessages, m xredis.XMessage) error {
+	msg, times, err := newReadMessagesResponse(resp, m)
+	if err != nil {
+		return err
 	}
-}
 
-func (f *RTMRunnerFactory) GetChannelRunner() *ChannelRunner {
-	return &ChannelRunner{
-		baseRunner: newBaseRunner(f.cache, f.factory),
+	if err = runner.streaming.Send(msg); err != nil {
+		return err
 	}
-}
 
-type baseRunner struct {
-	cache   cache.Cache
-	factory *ChannelFactory
+	runner.streaming.SetTrailer(grpcmd.Pairs(
+		api.HeaderReadMessagesEventTimes, strconv.FormatInt(times.EventTime.UnixMilli(), 10),
+		api.HeaderReadMessagesIngestionTimes, strconv.FormatInt(times.IngestionTime.UnixMilli(), 10),
+	))
+
+	return nil
 }
 
-func newBaseRunner(cache cache.Cache, factory *ChannelFactory) *baseRunner {
-	return &baseRunner{
-		cache:   cache,
-		factory: factory,
+// newReadMessagesResponse returns the response of a message read from a channel along with its times.
+func newReadMessagesResponse(resp *cache.StreamMessages, m xredis.XMessage) (*api.ReadMessagesResponse, MessageTimes, error) {
+	data, err := resp.Decode(m)
+	if err != nil {
+		return nil, MessageTimes{}, err
 	}
-}
 
-func (runner *baseRunner) getProject(tenant *metadata.Tenant, project string) (*metadata.Project, error) {
-	proj, err := tenant.GetProject(project)
+	md, err := DecodeStreamMD(data.Md)
 	if err != nil {
-		return nil, createApiError(err)
+		return nil, MessageTimes{}, err
 	}
-	return proj, nil
+	times, err := md.Times(m.ID)
+	if err != nil {
+		return nil, MessageTimes{}, err
+	}
+	rawData, err := SanitizeUserData(internal.JsonEncoding, data)
+	if err != nil {
+		return nil, MessageTimes{}, err
+	}
+
+	// the position of the message is handed out as an opaque token, it is what the reads can be resumed from
+	id := EncodePosition(m.ID)
+
+	return &api.ReadMessagesResponse{
+		Message: &api.Message{
+			Id:   &id,
+			Name: md.EventName,
+			Data: rawData,
+		},
+	}, times, nil
 }
 
-// MessagesRunner is to publish messages to a channel.
-type MessagesRunner struct {
-	*baseRunner
+// pastEnd returns true if the start is already past the end in the direction of the read.
+func (runner *ReadMessagesRunner) pastEnd(start streamPosition, end streamPosition) bool {
+	if runner.reverse {
+		return start.before(end)
+	}
 
-	req *api.MessagesRequest
+	return start.after(end)
 }
 
-func (runner *MessagesRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
-	project, err := runner.getProject(tenant, runner.req.Project)
+// prevStreamID returns the id right before the message id. The sequence number of a stream id is an unsigned 64-bit
+// integer, so the id before "<ms>-0" is "<ms-1>-18446744073709551615". There is no id before "0-0".
+func prevStreamID(id string) (string, bool) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
 	if err != nil {
-		return Response{}, err
+		return "", false
 	}
-
-	channel, err := runner.factory.GetOrCreateChannel(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel)
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
 	if err != nil {
-		return Response{}, err
+		return "", false
 	}
 
-	ids := make([]string, len(runner.req.Messages))
-	for i, m := range runner.req.Messages {
-		// The data is a json encoded Byte.
-		// Convert that to a msgback bytes to store
-		m.Data, err = JsonByteToMsgPack(m.Data)
-		if err != nil {
-			return Response{}, err
-		}
+	switch {
+	case seq > 0:
+		return fmt.Sprintf("%d-%d", ms, seq-1), true
+	case ms > 0:
+		return fmt.Sprintf("%d-%d", ms-1, uint64(math.MaxUint64)), true
+	default:
+		return "", false
+	}
+}
 
-		streamData, err := NewEventDataFromMessage(internal.MsgpackEncoding, "", "", m.Name, m)
-		if err != nil {
-			return Response{}, err
-		}
+// streamPosition is a position in a channel, either a message id "<ms>-<seq>" or a time "<ms>" in unix milliseconds
+// which covers all the messages of that millisecond.
+type streamPosition struct {
+	ms     int64
+	seq    int64
+	hasSeq bool
+}
 
-		id, err := channel.PublishMessage(ctx, streamData)
-		if err != nil {
-			return Response{}, err
+func parseStreamPosition(pos string) (streamPosition, error) {
+	ms, seq, hasSeq := strings.Cut(pos, "-")
+
+	var (
+		p   streamPosition
+		err error
+	)
+	if p.ms, err = strconv.ParseInt(ms, 10, 64); err != nil {
+		return streamPosition{}, err
+	}
+	if hasSeq {
+		if p.seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+			return streamPosition{}, err
 		}
+		p.hasSeq = true
+	}
+
+	return p, nil
+}
 
-		ids[i] = id
+// after returns true if the position is past the inclusive end.
+func (p streamPosition) after(end streamPosition) bool {
+	if p.ms != end.ms || !end.hasSeq {
+		return p.ms > end.ms
 	}
 
-	return Response{
-		Response: &api.MessagesResponse{
-			Ids: ids,
-		},
-	}, nil
+	return p.seq > end.seq
 }
 
-type ReadMessagesRunner struct {
+// before returns true if the position is before the inclusive end of a reverse read.
+func (p streamPosition) before(end streamPosition) bool {
+	if p.ms != end.ms || !end.hasSeq {
+		return p.ms < end.ms
+	}
+
+	return p.seq < end.seq
+}
+
+// MultiChannelStreaming receives the messages of a multi-channel read along with the channel they were read from and
+// their times.
+type MultiChannelStreaming interface {
+	Send(channel string, resp *api.ReadMessagesResponse, times MessageTimes) error
+}
+
+// MultiChannelReadRunner reads several channels of a project as a single stream ordered by the ingestion time of the
+// messages, the millisecond part of their id. Every channel is read from its own position and the position token of
+// each message is the one of its channel, so that a client can resume every channel independently.
+//
+// If all the channels are read from a position the read ends once every channel is caught up. If any channel is read
+// from its new messages, the read follows all the channels until the limit is reached or the context is done.
+//
+// Messages of different channels ingested in the same millisecond are ordered round-robin, the channel that was sent
+// a message the least recently goes first and the order of the channels in the request breaks the remaining ties. So
+// a busy channel can't starve the other ones when the read is limited. The messages of a channel are always sent in
+// the order of the channel.
+type MultiChannelReadRunner struct {
 	*baseRunner
 
-	req       *api.ReadMessagesRequest
-	streaming Streaming
+	req       *MultiChannelReadRequest
+	streaming MultiChannelStreaming
 }
 
-func (runner *ReadMessagesRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
-	project, err := runner.getProject(tenant, runner.req.Project)
-	if err != nil {
-		return Response{}, err
+func (runner *MultiChannelReadRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	if len(runner.req.Channels) == 0 {
+		return Response{}, errors.InvalidArgument("at least one channel is required")
 	}
 
-	channel, err := runner.factory.GetChannel(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel)
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
 	if err != nil {
 		return Response{}, err
 	}
 
-	pos := runner.req.GetStart()
-	if len(pos) == 0 {
-		pos = "$"
-	}
+	seen := make(map[string]struct{}, len(runner.req.Channels))
+	cursors := make([]*channelCursor, 0, len(runner.req.Channels))
+	follow := false
+	for _, c := range runner.req.Channels {
+		if _, ok := seen[c.Channel]; ok {
+			return Response{}, errors.InvalidArgument("channel '%s' is listed more than once", c.Channel)
+		}
+		seen[c.Channel] = struct{}{}
 
-	count := int64(0)
-	for {
-		resp, exists, err := channel.Read(ctx, pos)
-		if !exists {
-			return Response{}, nil
+		if err = runner.routeChannel(tenant, project, c.Channel); err != nil {
+			return Response{}, err
 		}
+
+		channel, err := runner.factory.GetChannel(ctx, tenant.GetNamespace().Id(), project.Id(), c.Channel)
 		if err != nil {
 			return Response{}, err
 		}
 
-		var id string
-		for _, m := range resp.Messages {
-			data, err := resp.Decode(m)
-			if err != nil {
-				return Response{}, err
-			}
-
-			md, err := DecodeStreamMD(data.Md)
-			if err != nil {
+		var pos string
+		if len(c.Start) > 0 {
+			if pos, err = DecodePosition(c.Start); err != nil {
 				return Response{}, err
 			}
-			rawData, err := SanitizeUserData(internal.JsonEncoding, data)
-			if err != nil {
+		} else {
+			// the new messages are the ones after the tail of the channel at the start of the read, reading "$" again
+			// on every poll would miss the messages published in between
+			if pos, err = tailPosition(ctx, channel); err != nil {
 				return Response{}, err
 			}
+			follow = true
+		}
 
-			err = runner.streaming.Send(&api.ReadMessagesResponse{
-				Message: &api.Message{
-					Id:   &m.ID,
-					Name: md.EventName,
-					Data: rawData,
-				},
-			})
-			if err != nil {
-				return Response{}, err
+		cursors = append(cursors, &channelCursor{name: c.Channel, channel: channel, pos: pos})
+	}
+	for _, c := range cursors {
+		c.follow = follow
+	}
+
+	return Response{}, runner.merge(ctx, cursors)
+}
+
+// tailPosition returns the id of the newest message of the channel, so that a read from it returns the messages
+// published after it. It is "0" if the channel has no messages.
+func tailPosition(ctx context.Context, channel *Channel) (string, error) {
+	resp, exists, err := channel.ReadReverse(ctx, "+", 1)
+	if err != nil {
+		return "", err
+	}
+	if !exists || resp == nil || len(resp.Messages) == 0 {
+		return "0", nil
+	}
+
+	return resp.Messages[0].ID, nil
+}
+
+// merge sends the messages of the channels in ingestion order until the limit is reached or, unless the channels are
+// followed, none of the channels has newer messages. A message is only sent once every channel that isn't exhausted
+// either has a message buffered or was found idle by a read that started after the message was read, otherwise an
+// older message of a channel that wasn't read yet could follow it.
+func (runner *MultiChannelReadRunner) merge(ctx context.Context, cursors []*channelCursor) error {
+	count, tick, reads := int64(0), uint64(0), uint64(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := runner.fill(ctx, cursors, &reads); err != nil {
+			return err
+		}
+
+		next, err := nextCursor(cursors)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			if !following(cursors) {
+				return nil
 			}
+			// all the channels are idle, they are read again on the next iteration
+			for _, c := range cursors {
+				c.idle = false
+			}
+			continue
+		}
 
-			count++
-			if runner.req.GetLimit() > 0 && count == runner.req.GetLimit() {
-				return Response{}, nil
+		msg, times, err := newReadMessagesResponse(next.resp, next.buffered[0])
+		if err != nil {
+			return err
+		}
+		if err = runner.streaming.Send(next.name, msg, times); err != nil {
+			return err
+		}
+
+		tick++
+		next.buffered, next.lastSent = next.buffered[1:], tick
+
+		count++
+		if runner.req.Limit > 0 && count == runner.req.Limit {
+			return nil
+		}
+	}
+}
+
+// fill reads the channels that must be read before the next message is sent, all of them in a single read of the
+// cache which blocks until any of them has messages. A channel found idle must be read again if another channel got
+// messages in the same read, so the reads go on until no channel needs one.
+func (runner *MultiChannelReadRunner) fill(ctx context.Context, cursors []*channelCursor, reads *uint64) error {
+	for {
+		var pending []*channelCursor
+		for _, c := range cursors {
+			if c.needsRead(cursors) {
+				pending = append(pending, c)
 			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		names := make([]string, 0, len(pending))
+		positions := make([]string, 0, len(pending))
+		for _, c := range pending {
+			names = append(names, c.channel.Name())
+			positions = append(positions, c.pos)
+		}
+
+		streams, err := runner.cache.ReadStreams(ctx, names, positions)
+		if err != nil {
+			return err
+		}
+
+		read := make(map[string]*cache.StreamMessages, len(streams))
+		for _, stream := range streams {
+			read[stream.Stream] = stream
+		}
+
+		*reads++
+		for _, c := range pending {
+			c.fill(read[c.channel.Name()], *reads)
+		}
+	}
+}
+
+// following returns true if the read goes on once the channels are caught up.
+func following(cursors []*channelCursor) bool {
+	for _, c := range cursors {
+		if c.follow {
+			return true
+		}
+	}
+
+	return false
+}
+
+// channelCursor is the state of a channel in a multi-channel read.
+type channelCursor struct {
+	name    string
+	channel *Channel
+	// pos is the id of the last message read from the channel, the next read resumes after it.
+	pos      string
+	resp     *cache.StreamMessages
+	buffered []xredis.XMessage
+	// lastSent is the order in which the channel was last sent a message, zero if it wasn't sent any yet.
+	lastSent uint64
+	// follow keeps the channel open once it is caught up, it is then idle until a read returns new messages.
+	follow bool
+	idle   bool
+	// read is the order of the last read of the channel.
+	read uint64
+	done bool
+}
+
+// needsRead returns true if the channel must be read before the next message is sent. An idle channel doesn't need
+// to be read again as long as the messages buffered by the other channels were read before it was found idle, the
+// messages it gets next are published after them.
+func (c *channelCursor) needsRead(cursors []*channelCursor) bool {
+	if c.done || len(c.buffered) > 0 {
+		return false
+	}
+	if !c.idle {
+		return true
+	}
+
+	for _, o := range cursors {
+		if len(o.buffered) > 0 && o.read > c.read {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fill buffers the messages read from the channel, nil if it has no newer messages, read is the order of the read. The
+// channel is exhausted once it has no newer messages, or idle if it is followed.
+func (c *channelCursor) fill(resp *cache.StreamMessages, read uint64) {
+	c.read = read
+	if resp == nil || len(resp.Messages) == 0 {
+		c.idle, c.done = c.follow, !c.follow
+		return
+	}
+
+	c.resp, c.buffered, c.idle = resp, resp.Messages, false
+	c.pos = resp.Messages[len(resp.Messages)-1].ID
+}
+
+// nextCursor returns the cursor holding the next message to send, nil if all the cursors are drained.
+func nextCursor(cursors []*channelCursor) (*channelCursor, error) {
+	var (
+		next   *channelCursor
+		nextMs int64
+	)
+	for _, c := range cursors {
+		if len(c.buffered) == 0 {
+			continue
+		}
 
-			id = m.ID
+		pos, err := parseStreamPosition(c.buffered[0].ID)
+		if err != nil {
+			return nil, errors.Internal("unexpected message id '%s'", c.buffered[0].ID)
 		}
 
-		if len(id) > 0 {
-			split := strings.Split(id, "-")
-			incrId, _ := strconv.ParseInt(strings.Split(id, "-")[1], 10, 64)
-			pos = fmt.Sprintf("%s-%d", split[0], incrId+1)
+		if next == nil || pos.ms < nextMs || (pos.ms == nextMs && c.lastSent < next.lastSent) {
+			next, nextMs = c, pos.ms
 		}
 	}
+
+	return next, nil
 }
 
 type ChannelRunner struct {
@@ -215,6 +1255,11 @@ type ChannelRunner struct {
 	channelReq        *api.GetRTChannelRequest
 	channelsReq       *api.GetRTChannelsRequest
 	listSubscriptions *api.ListSubscriptionRequest
+	pageSize          string
+	pageToken         string
+	nextPageToken     string
+	pattern           string
+	channelsInfo      []ChannelInfo
 }
 
 func (runner *ChannelRunner) SetChannelReq(req *api.GetRTChannelRequest) {
@@ -225,6 +1270,32 @@ func (runner *ChannelRunner) SetChannelsReq(req *api.GetRTChannelsRequest) {
 	runner.channelsReq = req
 }
 
+// SetChannelsPage sets the page of the channels listed by a channels request, the page size is the maximum number
+// of channels returned and the page token is the token of the next page returned by the previous page. An empty page
+// size returns all the channels.
+func (runner *ChannelRunner) SetChannelsPage(pageSize string, pageToken string) {
+	runner.pageSize = pageSize
+	runner.pageToken = pageToken
+}
+
+// SetChannelsPattern sets the glob pattern of the channels listed by a channels request, like "chat-*" for the
+// channels starting with "chat-". An empty pattern lists all the channels.
+func (runner *ChannelRunner) SetChannelsPattern(pattern string) {
+	runner.pattern = pattern
+}
+
+// ChannelsInfo returns the activity of the channels returned by a channels request once the runner has been
+// executed, in the order of the channels of the response.
+func (runner *ChannelRunner) ChannelsInfo() []ChannelInfo {
+	return runner.channelsInfo
+}
+
+// NextPageToken returns the token of the page following the channels returned by a channels request once the runner
+// has been executed, it is empty on the last page.
+func (runner *ChannelRunner) NextPageToken() string {
+	return runner.nextPageToken
+}
+
 func (runner *ChannelRunner) SetListSubscriptionsReq(req *api.ListSubscriptionRequest) {
 	runner.listSubscriptions = req
 }
@@ -232,7 +1303,7 @@ func (runner *ChannelRunner) SetListSubscriptionsReq(req *api.ListSubscriptionRe
 func (runner *ChannelRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
 	switch {
 	case runner.listSubscriptions != nil:
-		project, err := runner.getProject(tenant, runner.listSubscriptions.Project)
+		project, err := runner.getProject(ctx, tenant, runner.listSubscriptions.Project)
 		if err != nil {
 			return Response{}, err
 		}
@@ -249,15 +1320,31 @@ func (runner *ChannelRunner) Run(ctx context.Context, tenant *metadata.Tenant) (
 			},
 		}, nil
 	case runner.channelsReq != nil:
-		project, err := runner.getProject(tenant, runner.channelsReq.Project)
+		project, err := runner.getProject(ctx, tenant, runner.channelsReq.Project)
+		if err != nil {
+			return Response{}, err
+		}
+
+		limit, err := parseChannelsPageSize(runner.pageSize)
 		if err != nil {
 			return Response{}, err
 		}
 
-		channels, err := runner.factory.ListChannels(ctx, tenant.GetNamespace().Id(), project.Id(), "*")
+		pattern := runner.pattern
+		if len(pattern) == 0 {
+			pattern = "*"
+		}
+
+		channels, next, err := runner.factory.ListChannelsPage(ctx, tenant.GetNamespace().Id(), project.Id(), pattern,
+			runner.pageToken, limit)
 		if err != nil {
 			return Response{}, err
 		}
+		runner.nextPageToken = next
+
+		if runner.channelsInfo, err = runner.factory.ChannelsInfo(ctx, tenant.GetNamespace().Id(), project.Id(), channels); err != nil {
+			return Response{}, err
+		}
 
 		var channelsResp []*api.ChannelMetadata
 		for _, c := range channels {
@@ -272,7 +1359,7 @@ func (runner *ChannelRunner) Run(ctx context.Context, tenant *metadata.Tenant) (
 			},
 		}, nil
 	default:
-		project, err := runner.getProject(tenant, runner.channelReq.Project)
+		project, err := runner.getProject(ctx, tenant, runner.channelReq.Project)
 		if err != nil {
 			return Response{}, err
 		}
@@ -293,3 +1380,102 @@ func (runner *ChannelRunner) Run(ctx context.Context, tenant *metadata.Tenant) (
 		}, nil
 	}
 }
+
+// parseChannelsPageSize parses the page size of a channels request, an empty page size is zero which means no limit.
+func parseChannelsPageSize(pageSize string) (int, error) {
+	if len(pageSize) == 0 {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(pageSize)
+	if err != nil || limit <= 0 {
+		return 0, errors.InvalidArgument("invalid page size '%s'", pageSize)
+	}
+
+	return limit, nil
+}
+
+type DeleteChannelRunner struct {
+	*baseRunner
+
+	req *DeleteChannelRequest
+}
+
+func (runner *DeleteChannelRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err = runner.factory.DropChannel(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel); err != nil {
+		return Response{}, err
+	}
+
+	return Response{
+		Status: DeletedStatus,
+	}, nil
+}
+
+// ChannelConfigRunner reads the configuration of a channel, after replacing it if the request has one. The
+// configuration is available through Config once the runner has been executed.
+type ChannelConfigRunner struct {
+	*baseRunner
+
+	req    *ChannelConfigRequest
+	config *ChannelConfig
+}
+
+func (runner *ChannelConfigRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	project, err := runner.getProject(ctx, tenant, runner.req.Project)
+	if err != nil {
+		return Response{}, err
+	}
+	if err = runner.routeChannel(tenant, project, runner.req.Channel); err != nil {
+		return Response{}, err
+	}
+
+	if runner.req.Config != nil {
+		err = runner.factory.SetChannelConfig(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel, runner.req.Config)
+		if err != nil {
+			return Response{}, err
+		}
+	}
+
+	runner.config, err = runner.factory.GetChannelConfig(ctx, tenant.GetNamespace().Id(), project.Id(), runner.req.Channel)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{}, nil
+}
+
+func (runner *ChannelConfigRunner) Config() *ChannelConfig {
+	return runner.config
+}
+
+// ChannelStatsRunner is used by the admin APIs to inspect the channels of a project. The stats are available through
+// Stats once the runner has been executed.
+type ChannelStatsRunner struct {
+	*baseRunner
+
+	project string
+	stats   ChannelStats
+}
+
+func (runner *ChannelStatsRunner) Run(ctx context.Context, tenant *metadata.Tenant) (Response, error) {
+	project, err := runner.getProject(ctx, tenant, runner.project)
+	if err != nil {
+		return Response{}, err
+	}
+
+	runner.stats, err = runner.factory.Stats(ctx, tenant.GetNamespace().Id(), project.Id())
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{}, nil
+}
+
+func (runner *ChannelStatsRunner) Stats() ChannelStats {
+	return runner.stats
+}
@@ -0,0 +1,799 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	xredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/store/cache"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestStreamPosition(t *testing.T) {
+	pos := func(s string) streamPosition {
+		p, err := parseStreamPosition(s)
+		require.NoError(t, err)
+		return p
+	}
+
+	cases := []struct {
+		id    string
+		end   string
+		after bool
+	}{
+		{"100-0", "100-0", false},
+		{"100-1", "100-0", true},
+		{"99-5", "100-0", false},
+		{"101-0", "100-5", true},
+		// a time end includes all the messages of that millisecond
+		{"100-7", "100", false},
+		{"101-0", "100", true},
+		{"99-0", "100", false},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.after, pos(c.id).after(pos(c.end)), "%s after %s", c.id, c.end)
+	}
+
+	beforeCases := []struct {
+		id     string
+		end    string
+		before bool
+	}{
+		{"100-0", "100-0", false},
+		{"100-0", "100-1", true},
+		{"101-0", "100-5", false},
+		{"99-5", "100-0", true},
+		// a time end includes all the messages of that millisecond
+		{"100-0", "100", false},
+		{"99-9", "100", true},
+	}
+	for _, c := range beforeCases {
+		require.Equal(t, c.before, pos(c.id).before(pos(c.end)), "%s before %s", c.id, c.end)
+	}
+
+	_, err := parseStreamPosition("$")
+	require.Error(t, err)
+	_, err = parseStreamPosition("100-x")
+	require.Error(t, err)
+}
+
+func TestValidatePublishBatchSize(t *testing.T) {
+	limit := config.DefaultConfig.Realtime.MaxMessagesPerPublish
+	defer func() { config.DefaultConfig.Realtime.MaxMessagesPerPublish = limit }()
+
+	config.DefaultConfig.Realtime.MaxMessagesPerPublish = 2
+	require.NoError(t, validatePublishBatchSize(2))
+	require.Equal(t, errors.InvalidArgument("too many messages in a publish request, maximum allowed is 2, received 3"), validatePublishBatchSize(3))
+
+	config.DefaultConfig.Realtime.MaxMessagesPerPublish = 0
+	require.NoError(t, validatePublishBatchSize(3))
+}
+
+func TestPublishTimestamps(t *testing.T) {
+	// the time is the one the channel orders the messages by, an id that can't be parsed has no time
+	require.Equal(t, []string{"1700000000123", "", "1700000000124"},
+		publishTimestamps([]string{"1700000000123-4", "", "1700000000124-0"}))
+	require.Empty(t, publishTimestamps(nil))
+}
+
+func TestParseEventTimes(t *testing.T) {
+	eventTimes, err := parseEventTimes("", 2)
+	require.NoError(t, err)
+	require.Nil(t, eventTimes)
+
+	// an empty time is a message without an event time
+	eventTimes, err = parseEventTimes("1700000000123, ,1700000000124", 3)
+	require.NoError(t, err)
+	require.Equal(t, []time.Time{time.UnixMilli(1700000000123), {}, time.UnixMilli(1700000000124)}, eventTimes)
+
+	_, err = parseEventTimes("1,2,3", 2)
+	require.Equal(t, errors.InvalidArgument("3 event times for 2 messages"), err)
+
+	_, err = parseEventTimes("1700000000123,abc", 2)
+	require.Equal(t, errors.InvalidArgument("invalid event time 'abc' of the message at index 1"), err)
+
+	_, err = parseEventTimes("-5", 1)
+	require.Equal(t, errors.InvalidArgument("invalid event time '-5' of the message at index 0"), err)
+}
+
+func TestValidateMessageSizes(t *testing.T) {
+	limit := config.DefaultConfig.Realtime.MaxMessageSize
+	defer func() { config.DefaultConfig.Realtime.MaxMessageSize = limit }()
+
+	messages := []*api.Message{
+		{Name: "ev", Data: []byte(`{"a": true}`)},
+		{Name: "ev", Data: []byte(fmt.Sprintf(`{"a": "%s"}`, strings.Repeat("x", 100)))},
+		{Name: "ev", Data: []byte(`not json`)},
+	}
+
+	config.DefaultConfig.Realtime.MaxMessageSize = 50
+	require.NoError(t, validateMessageSizes(messages[:1]))
+	// the message that can't be encoded is rejected by the publish itself
+	require.NoError(t, validateMessageSizes(messages[2:]))
+
+	err := validateMessageSizes(messages)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "message at index 1 is too large, maximum allowed size is 50 bytes")
+
+	config.DefaultConfig.Realtime.MaxMessageSize = 0
+	require.NoError(t, validateMessageSizes(messages))
+}
+
+func TestPrevStreamID(t *testing.T) {
+	cases := []struct {
+		id   string
+		prev string
+		ok   bool
+	}{
+		{"100-5", "100-4", true},
+		{"100-1", "100-0", true},
+		// crossing the millisecond boundary
+		{"100-0", "99-18446744073709551615", true},
+		{"1-0", "0-18446744073709551615", true},
+		{"0-1", "0-0", true},
+		{"0-0", "", false},
+		{"100", "", false},
+		{"$", "", false},
+		// malformed ids
+		{"1-2-3", "", false},
+		{"-1", "", false},
+		{"1-", "", false},
+		{"a-1", "", false},
+	}
+	for _, c := range cases {
+		prev, ok := prevStreamID(c.id)
+		require.Equal(t, c.ok, ok, c.id)
+		require.Equal(t, c.prev, prev, c.id)
+	}
+}
+
+func TestReadMessagesReverse(t *testing.T) {
+	batchSize := reverseReadBatchSize
+	reverseReadBatchSize = 2
+	defer func() { reverseReadBatchSize = batchSize }()
+
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+	_ = cacheS.DeleteStream(ctx, "ch_reverse")
+
+	stream, err := cacheS.CreateStream(ctx, "ch_reverse")
+	require.NoError(t, err)
+	channel := NewChannel("ch_reverse", stream)
+	defer channel.Close(ctx)
+
+	var messages []*api.Message
+	for i := 0; i < 5; i++ {
+		messages = append(messages, &api.Message{Name: "ev", Data: []byte(fmt.Sprintf(`{"a": %d}`, i))})
+	}
+	ids, err := publishMessages(ctx, channel, messages, nil, nil, nil)
+	require.NoError(t, err)
+
+	read := func(start string, end string, limit int64) []string {
+		streaming := &collectStreaming{}
+		runner := &ReadMessagesRunner{
+			req:       &api.ReadMessagesRequest{Limit: &limit},
+			streaming: streaming,
+			reverse:   true,
+		}
+
+		var endPos *streamPosition
+		if len(end) > 0 {
+			pos, err := parseStreamPosition(end)
+			require.NoError(t, err)
+			endPos = &pos
+		}
+
+		_, err := runner.readReverse(ctx, channel, start, endPos)
+		require.NoError(t, err)
+		return streaming.ids
+	}
+
+	require.Equal(t, []string{ids[4], ids[3], ids[2], ids[1], ids[0]}, read("", "", 0))
+	require.Equal(t, []string{ids[4], ids[3], ids[2]}, read("", "", 3))
+	require.Equal(t, []string{ids[2], ids[1], ids[0]}, read(ids[2], "", 0))
+	require.Equal(t, []string{ids[4], ids[3], ids[2], ids[1]}, read("", ids[1], 0))
+}
+
+func TestReadMessagesEnd(t *testing.T) {
+	run := func(start string, end string, headerEnd string) error {
+		req := &api.ReadMessagesRequest{Start: &start}
+		if len(end) > 0 {
+			req.End = &end
+		}
+		runner := &ReadMessagesRunner{req: req, streaming: &collectStreaming{}}
+		runner.SetEnd(headerEnd)
+
+		// both the invalid end and the start past the end return before the channel is read
+		_, err := runner.Run(context.TODO(), nil)
+		return err
+	}
+
+	require.NoError(t, run("10-0", "5", ""))
+	require.Equal(t, errors.InvalidArgument("invalid end 'bad'"), run("10-0", "bad", ""))
+	// the end of the request takes precedence over the header
+	require.NoError(t, run("10-0", "5", "bad"))
+	require.Equal(t, errors.InvalidArgument("invalid end 'bad'"), run("10-0", "bad", "5"))
+	require.NoError(t, run("10-0", "", "5"))
+}
+
+func TestReadMessagesTimes(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+	_ = cacheS.DeleteStream(ctx, "ch_times")
+
+	stream, err := cacheS.CreateStream(ctx, "ch_times")
+	require.NoError(t, err)
+	channel := NewChannel("ch_times", stream)
+	defer channel.Close(ctx)
+
+	eventTime := time.UnixMilli(1672531200000)
+	ids, err := publishMessages(ctx, channel, []*api.Message{
+		{Name: "ev", Data: []byte(`{"a": 1}`)},
+		{Name: "ev", Data: []byte(`{"a": 2}`)},
+	}, []time.Time{eventTime}, nil, nil)
+	require.NoError(t, err)
+
+	ingestion := publishTimestamps(ids)
+
+	streaming := &collectStreaming{}
+	runner := &ReadMessagesRunner{
+		req:       &api.ReadMessagesRequest{},
+		streaming: streaming,
+	}
+	_, err = runner.readForward(ctx, channel, "0", nil)
+	require.NoError(t, err)
+	require.Equal(t, ids, streaming.ids)
+
+	// the message without an event time has its ingestion time as the event time
+	require.Equal(t, []string{"1672531200000", ingestion[1]}, streaming.trailer.Get(api.HeaderReadMessagesEventTimes))
+	require.Equal(t, ingestion, streaming.trailer.Get(api.HeaderReadMessagesIngestionTimes))
+
+	multi := &collectMultiStreaming{}
+	multiRunner := &MultiChannelReadRunner{
+		baseRunner: newBaseRunner(cacheS, nil),
+		req:        &MultiChannelReadRequest{},
+		streaming:  multi,
+	}
+	require.NoError(t, multiRunner.merge(ctx, []*channelCursor{{name: "ch_times", channel: channel, pos: "0"}}))
+	require.Len(t, multi.times, 2)
+	require.Equal(t, eventTime, multi.times[0].EventTime)
+	require.Equal(t, ingestion[0], strconv.FormatInt(multi.times[0].IngestionTime.UnixMilli(), 10))
+	require.Equal(t, multi.times[1].IngestionTime, multi.times[1].EventTime)
+}
+
+func TestReadMessagesForward(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+	_ = cacheS.DeleteStream(ctx, "ch_forward")
+
+	stream, err := cacheS.CreateStream(ctx, "ch_forward")
+	require.NoError(t, err)
+	channel := NewChannel("ch_forward", stream)
+	defer channel.Close(ctx)
+
+	var messages []*api.Message
+	for i := 0; i < 5; i++ {
+		messages = append(messages, &api.Message{Name: "ev", Data: []byte(fmt.Sprintf(`{"a": %d}`, i))})
+	}
+	ids, err := publishMessages(ctx, channel, messages, nil, nil, nil)
+	require.NoError(t, err)
+
+	read := func(start string, end string, limit int64) []string {
+		streaming := &collectStreaming{}
+		runner := &ReadMessagesRunner{
+			req:       &api.ReadMessagesRequest{Limit: &limit},
+			streaming: streaming,
+		}
+
+		var endPos *streamPosition
+		if len(end) > 0 {
+			pos, err := parseStreamPosition(end)
+			require.NoError(t, err)
+			endPos = &pos
+		}
+
+		_, err := runner.readForward(ctx, channel, start, endPos)
+		require.NoError(t, err)
+		return streaming.ids
+	}
+
+	require.Equal(t, ids, read("0", "", 0))
+	require.Equal(t, ids[:3], read("0", "", 3))
+	// the start is exclusive
+	require.Equal(t, ids[3:], read(ids[2], "", 0))
+	require.Equal(t, ids[:2], read("0", ids[1], 0))
+}
+
+func TestReadMessagesCanceled(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+	_ = cacheS.DeleteStream(ctx, "ch_canceled")
+
+	stream, err := cacheS.CreateStream(ctx, "ch_canceled")
+	require.NoError(t, err)
+	channel := NewChannel("ch_canceled", stream)
+	defer channel.Close(ctx)
+
+	_, err = publishMessages(ctx, channel, []*api.Message{{Name: "ev", Data: []byte(`{"a": 1}`)}}, nil, nil, nil)
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	for _, reverse := range []bool{false, true} {
+		streaming := &collectStreaming{}
+		runner := &ReadMessagesRunner{
+			req:       &api.ReadMessagesRequest{},
+			streaming: streaming,
+			reverse:   reverse,
+		}
+
+		if reverse {
+			_, err = runner.readReverse(canceled, channel, "", nil)
+		} else {
+			_, err = runner.readForward(canceled, channel, "0", nil)
+		}
+		require.Equal(t, context.Canceled, err)
+		require.Empty(t, streaming.ids)
+	}
+}
+
+func TestMultiChannelRead(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+
+	channels := map[string]*Channel{}
+	for _, name := range []string{"ch_merge_a", "ch_merge_b"} {
+		_ = cacheS.DeleteStream(ctx, name)
+		stream, err := cacheS.CreateStream(ctx, name)
+		require.NoError(t, err)
+		channels[name] = NewChannel(name, stream)
+		defer channels[name].Close(ctx)
+	}
+
+	var expected []string
+	for _, name := range []string{"ch_merge_a", "ch_merge_b", "ch_merge_b", "ch_merge_a"} {
+		ids, err := publishMessages(ctx, channels[name], []*api.Message{{Name: "ev", Data: []byte(`{"a": 1}`)}}, nil, nil, nil)
+		require.NoError(t, err)
+		expected = append(expected, name+"/"+ids[0])
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	read := func(limit int64) []string {
+		streaming := &collectMultiStreaming{}
+		runner := &MultiChannelReadRunner{
+			baseRunner: newBaseRunner(cacheS, nil),
+			req:        &MultiChannelReadRequest{Limit: limit},
+			streaming:  streaming,
+		}
+
+		err := runner.merge(ctx, []*channelCursor{
+			{name: "ch_merge_a", channel: channels["ch_merge_a"], pos: "0"},
+			{name: "ch_merge_b", channel: channels["ch_merge_b"], pos: "0"},
+		})
+		require.NoError(t, err)
+		return streaming.ids
+	}
+
+	require.Equal(t, expected, read(0))
+	require.Equal(t, expected[:3], read(3))
+}
+
+func TestMultiChannelReadFollow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+
+	channels := map[string]*Channel{}
+	for _, name := range []string{"ch_follow_a", "ch_follow_b"} {
+		_ = cacheS.DeleteStream(ctx, name)
+		stream, err := cacheS.CreateStream(ctx, name)
+		require.NoError(t, err)
+		channels[name] = NewChannel(name, stream)
+		defer channels[name].Close(ctx)
+	}
+
+	// a message published before the read starts is not part of the new messages
+	_, err := publishMessages(ctx, channels["ch_follow_a"], []*api.Message{{Name: "ev", Data: []byte(`{"a": 1}`)}}, nil, nil, nil)
+	require.NoError(t, err)
+
+	var cursors []*channelCursor
+	for _, name := range []string{"ch_follow_a", "ch_follow_b"} {
+		pos, err := tailPosition(ctx, channels[name])
+		require.NoError(t, err)
+		cursors = append(cursors, &channelCursor{name: name, channel: channels[name], pos: pos, follow: true})
+	}
+
+	streaming := &collectMultiStreaming{}
+	runner := &MultiChannelReadRunner{
+		baseRunner: newBaseRunner(cacheS, nil),
+		req:        &MultiChannelReadRequest{Limit: 2},
+		streaming:  streaming,
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- runner.merge(ctx, cursors)
+	}()
+
+	// the channels stay open past the blocking time of a read
+	time.Sleep(1500 * time.Millisecond)
+	var expected []string
+	for _, name := range []string{"ch_follow_b", "ch_follow_a"} {
+		ids, err := publishMessages(ctx, channels[name], []*api.Message{{Name: "ev", Data: []byte(`{"a": 2}`)}}, nil, nil, nil)
+		require.NoError(t, err)
+		expected = append(expected, name+"/"+ids[0])
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	require.NoError(t, <-done)
+	require.Equal(t, expected, streaming.ids)
+}
+
+func TestNextCursor(t *testing.T) {
+	cursor := func(name string, lastSent uint64, ids ...string) *channelCursor {
+		c := &channelCursor{name: name, lastSent: lastSent}
+		for _, id := range ids {
+			c.buffered = append(c.buffered, xredis.XMessage{ID: id})
+		}
+		return c
+	}
+
+	next := func(cursors ...*channelCursor) string {
+		c, err := nextCursor(cursors)
+		require.NoError(t, err)
+		if c == nil {
+			return ""
+		}
+		return c.name
+	}
+
+	// the oldest message goes first, the sequence numbers of different channels are not compared
+	require.Equal(t, "b", next(cursor("a", 0, "10-0"), cursor("b", 0, "9-5")))
+	// in the same millisecond the channel sent a message the least recently goes first
+	require.Equal(t, "b", next(cursor("a", 2, "10-0"), cursor("b", 1, "10-3")))
+	// and then the order of the request
+	require.Equal(t, "a", next(cursor("a", 0, "10-1"), cursor("b", 0, "10-0")))
+	// drained channels are skipped
+	require.Equal(t, "b", next(cursor("a", 0), cursor("b", 3, "11-0")))
+	require.Equal(t, "", next(cursor("a", 0), cursor("b", 0)))
+}
+
+// failingStream fails the nth add, counting from one, to inject a failure in the middle of a publish.
+type failingStream struct {
+	cache.Stream
+
+	failAt int
+	adds   int
+}
+
+func (s *failingStream) Add(ctx context.Context, value *internal.StreamData) (string, error) {
+	s.adds++
+	if s.adds == s.failAt {
+		return "", fmt.Errorf("injected failure")
+	}
+	return s.Stream.Add(ctx, value)
+}
+
+// failingTxHook makes redis reject the nth command queued in a MULTI/EXEC transaction, counting from one, by renaming
+// it to an unknown command. Redis then aborts the whole transaction on EXEC.
+type failingTxHook struct {
+	failAt int
+}
+
+func (*failingTxHook) BeforeProcess(ctx context.Context, _ xredis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (*failingTxHook) AfterProcess(_ context.Context, _ xredis.Cmder) error {
+	return nil
+}
+
+func (h *failingTxHook) BeforeProcessPipeline(ctx context.Context, cmds []xredis.Cmder) (context.Context, error) {
+	// the queued commands are wrapped between MULTI and EXEC
+	if len(cmds) > 2 && cmds[0].Name() == "multi" && h.failAt < len(cmds)-1 {
+		cmds[h.failAt].Args()[0] = "injected_failure"
+	}
+	return ctx, nil
+}
+
+func (*failingTxHook) AfterProcessPipeline(_ context.Context, _ []xredis.Cmder) error {
+	return nil
+}
+
+func TestPublishMessagesPartialFailure(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+
+	newMessages := func() []*api.Message {
+		var messages []*api.Message
+		for i := 0; i < 5; i++ {
+			messages = append(messages, &api.Message{Name: "ev", Data: []byte(fmt.Sprintf(`{"a": %d}`, i))})
+		}
+		return messages
+	}
+
+	t.Run("non_atomic", func(t *testing.T) {
+		_ = cacheS.DeleteStream(ctx, "ch_partial")
+		stream, err := cacheS.CreateStream(ctx, "ch_partial")
+		require.NoError(t, err)
+		channel := NewChannel("ch_partial", &failingStream{Stream: stream, failAt: 3})
+		defer channel.Close(ctx)
+
+		ids, err := publishMessages(ctx, channel, newMessages(), nil, nil, nil)
+		require.Error(t, err)
+		// the ids of the messages published before the failure are returned
+		require.Len(t, ids, 2)
+
+		oldest, newest, exists, err := stream.Bounds(ctx)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, ids[0], oldest)
+		require.Equal(t, ids[1], newest)
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		_ = cacheS.DeleteStream(ctx, "ch_atomic")
+		stream, err := cacheS.CreateStream(ctx, "ch_atomic")
+		require.NoError(t, err)
+		channel := NewChannel("ch_atomic", stream)
+		defer channel.Close(ctx)
+
+		// a message rejected before the transaction is sent publishes nothing
+		messages := newMessages()
+		messages[2].Data = []byte(`not json`)
+		ids, err := publishMessagesAtomic(ctx, channel, messages, nil, nil)
+		require.Error(t, err)
+		require.Nil(t, ids)
+
+		_, _, exists, err := stream.Bounds(ctx)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		// and so does a message rejected by redis once the first messages of the transaction are queued
+		failingCache := cache.NewCache(config.GetTestCacheConfig())
+		failingCache.(interface{ AddHook(xredis.Hook) }).AddHook(&failingTxHook{failAt: 3})
+		failingStream, err := failingCache.CreateOrGetStream(ctx, "ch_atomic")
+		require.NoError(t, err)
+		ids, err = publishMessagesAtomic(ctx, NewChannel("ch_atomic", failingStream), newMessages(), nil, nil)
+		require.Error(t, err)
+		require.Nil(t, ids)
+
+		_, _, exists, err = stream.Bounds(ctx)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		ids, err = publishMessagesAtomic(ctx, channel, newMessages(), nil, nil)
+		require.NoError(t, err)
+		require.Len(t, ids, 5)
+
+		oldest, newest, exists, err := stream.Bounds(ctx)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, ids[0], oldest)
+		require.Equal(t, ids[4], newest)
+	})
+}
+
+type collectMultiStreaming struct {
+	ids   []string
+	times []MessageTimes
+}
+
+func (c *collectMultiStreaming) Send(channel string, resp *api.ReadMessagesResponse, times MessageTimes) error {
+	id, err := DecodePosition(resp.Message.GetId())
+	if err != nil {
+		return err
+	}
+
+	c.ids = append(c.ids, channel+"/"+id)
+	c.times = append(c.times, times)
+	return nil
+}
+
+type collectStreaming struct {
+	api.Realtime_ReadMessagesServer
+
+	ids     []string
+	trailer metadata.MD
+}
+
+func (c *collectStreaming) SetTrailer(md metadata.MD) {
+	c.trailer = metadata.Join(c.trailer, md)
+}
+
+func (c *collectStreaming) Send(resp *api.ReadMessagesResponse) error {
+	id, err := DecodePosition(resp.Message.GetId())
+	if err != nil {
+		return err
+	}
+
+	c.ids = append(c.ids, id)
+	return nil
+}
+
+func TestPublishMessagesDeadLetter(t *testing.T) {
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+
+	newChannel := func(name string) *Channel {
+		_ = cacheS.DeleteStream(ctx, name)
+		stream, err := cacheS.CreateStream(ctx, name)
+		require.NoError(t, err)
+		return NewChannel(name, stream)
+	}
+
+	channel := newChannel("ch_source")
+	defer channel.Close(ctx)
+	dlqChannel := newChannel("ch_source_dlq")
+	defer dlqChannel.Close(ctx)
+
+	messages := func() []*api.Message {
+		return []*api.Message{
+			{Name: "ev", Data: []byte(`{"a": 1}`)},
+			{Name: "bad", Data: []byte(`{"a": `)},
+			{Name: "ev", Data: []byte(`{"a": 3}`)},
+		}
+	}
+
+	t.Run("without_dead_letter", func(t *testing.T) {
+		ids, err := publishMessages(ctx, channel, messages(), nil, nil, nil)
+		require.Error(t, err)
+		require.Len(t, ids, 1)
+	})
+
+	t.Run("with_dead_letter", func(t *testing.T) {
+		dl := &deadLetter{source: "ch_source", channel: dlqChannel}
+		ids, err := publishMessages(ctx, channel, messages(), nil, dl, nil)
+		require.NoError(t, err)
+		require.Len(t, ids, 3)
+		require.NotEmpty(t, ids[0])
+		require.NotEmpty(t, ids[2])
+
+		resp, exists, err := dlqChannel.Read(ctx, "0")
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Len(t, resp.Messages, 1)
+		// the id of a dead-lettered message is the one in the dead-letter channel
+		require.Equal(t, resp.Messages[0].ID, ids[1])
+
+		data, err := resp.Decode(resp.Messages[0])
+		require.NoError(t, err)
+		md, err := DecodeStreamMD(data.Md)
+		require.NoError(t, err)
+		require.Equal(t, "bad", md.EventName)
+		require.Equal(t, "ch_source", md.SourceChannel)
+		require.NotEmpty(t, md.Error)
+
+		rawData, err := SanitizeUserData(internal.JsonEncoding, data)
+		require.NoError(t, err)
+		require.JSONEq(t, `"{\"a\": "`, string(rawData))
+	})
+}
+
+func TestPosition(t *testing.T) {
+	token := EncodePosition("1680000000000-3")
+	require.NotContains(t, token, "1680000000000")
+
+	for _, c := range []struct {
+		pos string
+		id  string
+	}{
+		{token, "1680000000000-3"},
+		{"1680000000000-3", "1680000000000-3"},
+		{"1680000000000", "1680000000000"},
+		{"$", "$"},
+	} {
+		id, err := DecodePosition(c.pos)
+		require.NoError(t, err)
+		require.Equal(t, c.id, id)
+	}
+
+	_, err := DecodePosition("not-a-position")
+	require.Equal(t, errors.InvalidArgument("invalid position 'not-a-position'"), err)
+
+	// a token of a version this server doesn't know about
+	_, err = DecodePosition("eyJ2IjoyLCJpZCI6IjEtMSJ9")
+	require.Equal(t, errors.InvalidArgument("unsupported position version '2'"), err)
+}
+
+func TestPublishMessagesIdempotencyKeys(t *testing.T) {
+	keys, err := parseIdempotencyKeys("k1, ,k3", 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"k1", "", "k3"}, keys)
+	_, err = parseIdempotencyKeys("k1,k2", 1)
+	require.Equal(t, errors.InvalidArgument("2 idempotency keys for 1 messages"), err)
+	_, err = parseIdempotencyKeys("k1,k1", 2)
+	require.Equal(t, errors.InvalidArgument("duplicate idempotency key 'k1'"), err)
+	require.Nil(t, newPublishDedupe(nil, "ch", 0, keys))
+
+	ctx := context.TODO()
+	cacheS := cache.NewCache(config.GetTestCacheConfig())
+	_ = cacheS.DeleteStream(ctx, "ch_idempotency")
+	_, _ = cacheS.Delete(ctx, idempotencyTable, "ch_idempotency:k1", "ch_idempotency:k2", "ch_idempotency:k3",
+		"ch_idempotency:k4")
+
+	stream, err := cacheS.CreateStream(ctx, "ch_idempotency")
+	require.NoError(t, err)
+	channel := NewChannel("ch_idempotency", stream)
+	defer channel.Close(ctx)
+
+	publish := func() []string {
+		dedupe := newPublishDedupe(cacheS, channel.Name(), time.Minute, []string{"k1"})
+		ids, err := publishMessages(ctx, channel, []*api.Message{{Name: "ev", Data: []byte(`{"a": 1}`)}}, nil, nil, dedupe)
+		require.NoError(t, err)
+		require.Len(t, ids, 1)
+		return ids
+	}
+
+	first := publish()
+	require.Equal(t, first, publish())
+
+	resp, exists, err := channel.Read(ctx, "0")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Len(t, resp.Messages, 1)
+	require.Equal(t, first[0], resp.Messages[0].ID)
+
+	// a key reserved by a publish in progress isn't published again
+	dedupe := newPublishDedupe(cacheS, channel.Name(), time.Minute, []string{"k2"})
+	_, published, err := dedupe.Reserve(ctx, "k2")
+	require.NoError(t, err)
+	require.False(t, published)
+	_, err = publishMessages(ctx, channel, []*api.Message{{Name: "ev", Data: []byte(`{"a": 2}`)}}, nil, nil, dedupe)
+	require.Equal(t, errors.Aborted("message with idempotency key 'k2' is being published"), err)
+
+	// the key of a message that failed to publish is released
+	dedupe = newPublishDedupe(cacheS, channel.Name(), time.Minute, []string{"k3"})
+	_, err = publishMessages(ctx, channel, []*api.Message{{Name: "ev", Data: []byte(`{"a":`)}}, nil, nil, dedupe)
+	require.Error(t, err)
+	_, published, err = dedupe.Reserve(ctx, "k3")
+	require.NoError(t, err)
+	require.False(t, published)
+
+	resp, _, err = channel.Read(ctx, "0")
+	require.NoError(t, err)
+	require.Len(t, resp.Messages, 1)
+
+	// a dead-lettered message isn't dead-lettered again, its id in the dead-letter channel is returned
+	_ = cacheS.DeleteStream(ctx, "ch_idempotency_dlq")
+	dlqStream, err := cacheS.CreateStream(ctx, "ch_idempotency_dlq")
+	require.NoError(t, err)
+	dl := &deadLetter{source: channel.Name(), channel: NewChannel("ch_idempotency_dlq", dlqStream)}
+	defer dl.channel.Close(ctx)
+
+	deadLettered := func() []string {
+		dedupe := newPublishDedupe(cacheS, channel.Name(), time.Minute, []string{"k4"})
+		ids, err := publishMessages(ctx, channel, []*api.Message{{Name: "ev", Data: []byte(`{"a":`)}}, nil, dl, dedupe)
+		require.NoError(t, err)
+		require.Len(t, ids, 1)
+		return ids
+	}
+	require.Equal(t, deadLettered(), deadLettered())
+
+	resp, _, err = dl.channel.Read(ctx, "0")
+	require.NoError(t, err)
+	require.Len(t, resp.Messages, 1)
+}
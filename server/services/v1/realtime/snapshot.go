@@ -0,0 +1,157 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/store/cache"
+)
+
+const channelSnapshotV1 = 1
+
+// ChannelSnapshot is the metadata of a channel exported by Snapshot, the messages of the channel are not part of it.
+type ChannelSnapshot struct {
+	Version   int                `json:"v"`
+	Name      string             `json:"name"`
+	Consumers []ConsumerSnapshot `json:"consumers,omitempty"`
+}
+
+// ConsumerSnapshot is a consumer of a channel along with the opaque position of the last message delivered to it.
+type ConsumerSnapshot struct {
+	Name     string `json:"name"`
+	Position string `json:"position"`
+}
+
+// Snapshot writes the metadata of all the channels of the project to w as newline-delimited JSON, one ChannelSnapshot
+// per line. The channels are read and written one at a time so that the snapshot is never buffered as a whole. It
+// returns the number of channels written.
+func (factory *ChannelFactory) Snapshot(ctx context.Context, tenantId uint32, projId uint32, w io.Writer) (int, error) {
+	names, err := factory.ListChannels(ctx, tenantId, projId, "*")
+	if err != nil {
+		return 0, err
+	}
+
+	enc := jsoniter.NewEncoder(w)
+	written := 0
+	for _, name := range names {
+		snapshot, err := factory.snapshotChannel(ctx, tenantId, projId, name)
+		if err == cache.ErrStreamNotFound {
+			// the channel is deleted after being listed
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+
+		if err = enc.Encode(snapshot); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	return written, nil
+}
+
+func (factory *ChannelFactory) snapshotChannel(ctx context.Context, tenantId uint32, projId uint32, name string) (*ChannelSnapshot, error) {
+	encStream, err := factory.encodeChannelName(tenantId, projId, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stream is read directly to not register the channel in the factory only for taking the snapshot
+	stream, err := factory.cache.GetStream(ctx, encStream)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := stream.GetConsumerGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &ChannelSnapshot{Version: channelSnapshotV1, Name: name}
+	for _, g := range groups {
+		snapshot.Consumers = append(snapshot.Consumers, ConsumerSnapshot{
+			Name:     g.Name,
+			Position: EncodePosition(g.LastDeliveredID),
+		})
+	}
+
+	return snapshot, nil
+}
+
+// Restore recreates the channels of a snapshot written by Snapshot in the project. The channels that already exist
+// are kept, and their consumers are moved to the position of the snapshot. The snapshot is decoded one channel at a
+// time. It returns the number of channels restored.
+func (factory *ChannelFactory) Restore(ctx context.Context, tenantId uint32, projId uint32, r io.Reader) (int, error) {
+	dec := jsoniter.NewDecoder(r)
+
+	restored := 0
+	for {
+		var snapshot ChannelSnapshot
+		if err := dec.Decode(&snapshot); err != nil {
+			if err == io.EOF {
+				return restored, nil
+			}
+			return restored, errors.InvalidArgument("invalid channel snapshot: %s", err.Error())
+		}
+
+		if err := factory.restoreChannel(ctx, tenantId, projId, &snapshot); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+}
+
+func (factory *ChannelFactory) restoreChannel(ctx context.Context, tenantId uint32, projId uint32, snapshot *ChannelSnapshot) error {
+	if snapshot.Version != channelSnapshotV1 {
+		return errors.InvalidArgument("unsupported channel snapshot version '%d'", snapshot.Version)
+	}
+	if len(snapshot.Name) == 0 {
+		return errors.InvalidArgument("channel snapshot is missing the channel name")
+	}
+
+	ch, err := factory.GetOrCreateChannel(ctx, tenantId, projId, snapshot.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, consumer := range snapshot.Consumers {
+		pos, err := DecodePosition(consumer.Position)
+		if err != nil {
+			return err
+		}
+
+		_, exists, err := ch.stream.GetConsumerGroup(ctx, consumer.Name)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			err = ch.stream.SetID(ctx, consumer.Name, pos)
+		} else {
+			err = ch.stream.CreateConsumerGroup(ctx, consumer.Name, pos)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
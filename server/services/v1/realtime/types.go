@@ -15,6 +15,11 @@
 package realtime
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/internal"
 )
@@ -31,6 +36,51 @@ type StreamMessageMD struct {
 	DataType string
 	// EventName is the named identifier of this message like in case of presence "enter"/"left", etc
 	EventName string
+	// EventTime is the client supplied time of the event in unix milliseconds, zero if the client didn't supply it.
+	// It is independent of the id of the message in the stream which carries the time the server ingested it.
+	EventTime int64
+	// SourceChannel is the channel a dead-lettered message was published to, empty for the other messages.
+	SourceChannel string
+	// Error is the reason the source channel rejected a dead-lettered message.
+	Error string
+}
+
+// MessageTimes are the times associated with a message read from a channel.
+type MessageTimes struct {
+	// IngestionTime is the time the server published the message, as carried by the stream id
+	IngestionTime time.Time
+	// EventTime is the client supplied time of the event, the ingestion time if the client didn't supply one
+	EventTime time.Time
+}
+
+// Times returns the ingestion and the event time of the message with the id.
+func (md *StreamMessageMD) Times(id string) (MessageTimes, error) {
+	ingestion, err := IngestionTime(id)
+	if err != nil {
+		return MessageTimes{}, err
+	}
+
+	times := MessageTimes{
+		IngestionTime: ingestion,
+		EventTime:     ingestion,
+	}
+	if md.EventTime != 0 {
+		times.EventTime = time.UnixMilli(md.EventTime)
+	}
+
+	return times, nil
+}
+
+// IngestionTime returns the time carried by the stream id of a message. The stream ids are of the form
+// "<unix milliseconds>-<sequence>".
+func IngestionTime(id string) (time.Time, error) {
+	ms, _, _ := strings.Cut(id, "-")
+	millis, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid message id '%s'", id)
+	}
+
+	return time.UnixMilli(millis), nil
 }
 
 func NewStreamMessageMD(dataType string, clientId string, socketId string, eventName string) *StreamMessageMD {
@@ -54,8 +104,22 @@ func NewEventDataFromMessage(encType internal.UserDataEncType, clientId string,
 	return newStreamData(MessageChannelData, encType, clientId, socketId, eventName, msg.Data)
 }
 
+// NewEventDataFromMessageAt is like NewEventDataFromMessage but also stores the client supplied time of the event.
+// A zero eventTime means that the client didn't supply it.
+func NewEventDataFromMessageAt(encType internal.UserDataEncType, clientId string, socketId string, eventName string, msg *api.Message, eventTime time.Time) (*internal.StreamData, error) {
+	md := NewStreamMessageMD(MessageChannelData, clientId, socketId, eventName)
+	if !eventTime.IsZero() {
+		md.EventTime = eventTime.UnixMilli()
+	}
+
+	return newStreamDataWithMD(md, encType, msg.Data)
+}
+
 func newStreamData(dataType string, encType internal.UserDataEncType, clientId string, socketId string, eventName string, rawData []byte) (*internal.StreamData, error) {
-	md := NewStreamMessageMD(dataType, clientId, socketId, eventName)
+	return newStreamDataWithMD(NewStreamMessageMD(dataType, clientId, socketId, eventName), encType, rawData)
+}
+
+func newStreamDataWithMD(md *StreamMessageMD, encType internal.UserDataEncType, rawData []byte) (*internal.StreamData, error) {
 	encMD, err := EncodeStreamMD(md)
 	if err != nil {
 		return nil, err
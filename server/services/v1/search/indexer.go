@@ -115,7 +115,10 @@ func (transformer *transformer) transformEnd(doc map[string]any) (map[string]any
 		}
 	}
 
-	doc = util.FlatMap(doc, doNotFlatten)
+	doc, err := util.FlatMap(doc, doNotFlatten)
+	if err != nil {
+		return nil, errors.InvalidArgument("%s", err.Error())
+	}
 
 	var nullKeys []string
 	// pack any date time or array fields here
@@ -248,7 +251,10 @@ func (transformer *transformer) inverseStart(doc map[string]any) (map[string]any
 	delete(doc, schema.ReservedFields[schema.UpdatedAt])
 
 	// unFlatten the map now
-	doc = util.UnFlatMap(doc)
+	doc, err := util.UnFlatMap(doc)
+	if err != nil {
+		return nil, nil, nil, errors.InvalidArgument("%s", err.Error())
+	}
 	return doc, createdAt, updatedAt, nil
 }
 
@@ -15,6 +15,7 @@
 package transaction
 
 import (
+	"bytes"
 	"context"
 	"sync"
 
@@ -42,13 +43,15 @@ type BaseTx interface {
 	Insert(ctx context.Context, key keys.Key, data *internal.TableData) error
 	Replace(ctx context.Context, key keys.Key, data *internal.TableData, isUpdate bool) error
 	Delete(ctx context.Context, key keys.Key) error
+	DeleteRange(ctx context.Context, lKey keys.Key, rKey keys.Key) error
 	Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
-	ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool) (kv.Iterator, error)
+	ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, opts ...kv.ReadOption) (kv.Iterator, error)
 	Get(ctx context.Context, key []byte, isSnapshot bool) (kv.Future, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	SetVersionstampedKey(ctx context.Context, key []byte, value []byte) error
 	AtomicAdd(ctx context.Context, key keys.Key, value int64) error
 	AtomicRead(ctx context.Context, key keys.Key) (int64, error)
+	AtomicReadMulti(ctx context.Context, keys []keys.Key) ([]int64, error)
 	RangeSize(ctx context.Context, table []byte, lKey keys.Key, rKey keys.Key) (size int64, err error)
 }
 
@@ -199,6 +202,21 @@ func (s *TxSession) Delete(ctx context.Context, key keys.Key) error {
 	return s.kTx.Delete(ctx, key.Table(), kv.BuildKey(key.IndexParts()...))
 }
 
+// DeleteRange deletes all the keys in the range [lKey, rKey), both the keys must be in the same table.
+func (s *TxSession) DeleteRange(ctx context.Context, lKey keys.Key, rKey keys.Key) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return err
+	}
+	if !bytes.Equal(lKey.Table(), rKey.Table()) {
+		return errors.InvalidArgument("both the keys of the range need to be in the same table")
+	}
+
+	return s.kTx.DeleteRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...))
+}
+
 func (s *TxSession) Read(ctx context.Context, key keys.Key) (kv.Iterator, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -210,7 +228,7 @@ func (s *TxSession) Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
 	return s.kTx.Read(ctx, key.Table(), kv.BuildKey(key.IndexParts()...))
 }
 
-func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool) (kv.Iterator, error) {
+func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, opts ...kv.ReadOption) (kv.Iterator, error) {
 	s.Lock()
 	defer s.Unlock()
 
@@ -219,12 +237,12 @@ func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key,
 	}
 
 	if rKey != nil && lKey != nil {
-		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...), isSnapshot)
+		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...), isSnapshot, opts...)
 	} else if lKey != nil {
-		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), nil, isSnapshot)
+		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), nil, isSnapshot, opts...)
 	}
 
-	return s.kTx.ReadRange(ctx, rKey.Table(), nil, kv.BuildKey(rKey.IndexParts()...), isSnapshot)
+	return s.kTx.ReadRange(ctx, rKey.Table(), nil, kv.BuildKey(rKey.IndexParts()...), isSnapshot, opts...)
 }
 
 func (s *TxSession) SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error {
@@ -271,6 +289,29 @@ func (s *TxSession) AtomicRead(ctx context.Context, key keys.Key) (int64, error)
 	return s.kTx.AtomicRead(ctx, key.Table(), kv.BuildKey(key.IndexParts()...))
 }
 
+// AtomicReadMulti reads the counters of the keys in a single round trip, all the keys must be in the same table.
+func (s *TxSession) AtomicReadMulti(ctx context.Context, keys []keys.Key) ([]int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	kvKeys := make([]kv.Key, len(keys))
+	for i, key := range keys {
+		if !bytes.Equal(key.Table(), keys[0].Table()) {
+			return nil, errors.InvalidArgument("all the keys need to be in the same table")
+		}
+		kvKeys[i] = kv.BuildKey(key.IndexParts()...)
+	}
+
+	return s.kTx.AtomicReadMulti(ctx, keys[0].Table(), kvKeys)
+}
+
 func (s *TxSession) Get(ctx context.Context, key []byte, isSnapshot bool) (kv.Future, error) {
 	s.Lock()
 	defer s.Unlock()
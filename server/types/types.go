@@ -30,4 +30,5 @@ var MyOrigin, _ = os.Hostname()
 type AccessToken struct {
 	Namespace string
 	Sub       string
+	Project   string
 }
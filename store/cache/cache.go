@@ -160,6 +160,13 @@ func (c *cache) ListStreams(ctx context.Context, streamNamePrefix string) ([]str
 	return c.Client.Keys(ctx, streamNamePrefix).Result()
 }
 
+func (c *cache) ScanStreams(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	if count > config.DefaultConfig.Cache.MaxScan {
+		count = config.DefaultConfig.Cache.MaxScan
+	}
+	return c.Client.ScanType(ctx, cursor, pattern, count, "stream").Result()
+}
+
 func (c *cache) GetStream(ctx context.Context, streamName string) (Stream, error) {
 	exists, err := c.Exists(ctx, streamName)
 	if err != nil {
@@ -178,6 +185,94 @@ func (c *cache) DeleteStream(ctx context.Context, streamName string) error {
 	return err
 }
 
+func (c *cache) GetStreamStats(ctx context.Context, streamNames ...string) ([]StreamStats, error) {
+	if len(streamNames) == 0 {
+		return nil, nil
+	}
+
+	pipe := c.Client.Pipeline()
+	lenCmds := make([]*xredis.IntCmd, len(streamNames))
+	memCmds := make([]*xredis.IntCmd, len(streamNames))
+	groupCmds := make([]*xredis.Cmd, len(streamNames))
+	lastCmds := make([]*xredis.XMessageSliceCmd, len(streamNames))
+	for i, name := range streamNames {
+		lenCmds[i] = pipe.XLen(ctx, name)
+		memCmds[i] = pipe.MemoryUsage(ctx, name)
+		// the groups are counted from the raw reply, its fields differ between the versions of the cache
+		groupCmds[i] = pipe.Do(ctx, "XINFO", "GROUPS", name)
+		lastCmds[i] = pipe.XRevRangeN(ctx, name, "+", "-", 1)
+	}
+
+	// the errors are checked per command, as a stream deleted after it is listed fails some of them
+	_, _ = pipe.Exec(ctx)
+
+	stats := make([]StreamStats, len(streamNames))
+	for i, name := range streamNames {
+		if err := lenCmds[i].Err(); err != nil {
+			return nil, err
+		}
+		// a deleted stream returns nil for memory usage, that is reported as zero bytes
+		if err := memCmds[i].Err(); err != nil && err != xredis.Nil {
+			return nil, err
+		}
+
+		stats[i] = StreamStats{
+			Name:   name,
+			Length: lenCmds[i].Val(),
+			Bytes:  memCmds[i].Val(),
+		}
+
+		// a deleted stream has no groups
+		if groups, ok := groupCmds[i].Val().([]interface{}); ok {
+			stats[i].Groups = int64(len(groups))
+		} else if err := groupCmds[i].Err(); err != nil && !strings.Contains(err.Error(), "no such key") {
+			return nil, err
+		}
+
+		if err := lastCmds[i].Err(); err != nil {
+			return nil, err
+		}
+		if last := lastCmds[i].Val(); len(last) > 0 {
+			stats[i].LastID = last[0].ID
+		}
+	}
+
+	return stats, nil
+}
+
+func (c *cache) ReadStreams(ctx context.Context, streamNames []string, positions []string) ([]*StreamMessages, error) {
+	if len(streamNames) == 0 {
+		return nil, nil
+	}
+	if len(positions) != len(streamNames) {
+		return nil, fmt.Errorf("%d positions for %d streams", len(positions), len(streamNames))
+	}
+
+	args := make([]string, 0, 2*len(streamNames))
+	args = append(args, streamNames...)
+	args = append(args, positions...)
+
+	streams, err := c.Client.XRead(ctx, &xredis.XReadArgs{
+		Streams: args,
+		Block:   1 * time.Second,
+	}).Result()
+	if err == xredis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*StreamMessages, 0, len(streams))
+	for _, stream := range streams {
+		if len(stream.Messages) > 0 {
+			messages = append(messages, &StreamMessages{XStream: stream})
+		}
+	}
+
+	return messages, nil
+}
+
 // CreateOrGetStream will create a stream in Redis. 'streamName' is full qualified name similar to tableName in other
 // Apis i.e. caller should be responsible for prepending it with tenant/project etc.
 func (c *cache) CreateOrGetStream(ctx context.Context, streamName string) (Stream, error) {
@@ -27,8 +27,14 @@ type Stream interface {
 	Name() string
 	// Add is to add streamData to a stream
 	Add(ctx context.Context, value *internal.StreamData) (string, error)
+	// AddAll adds the values to the stream in a single MULTI/EXEC transaction and returns their ids in order. If a
+	// value can't be encoded or the transaction can't be queued, none of the values is added.
+	AddAll(ctx context.Context, values []*internal.StreamData) ([]string, error)
 	// Read data from the stream, returns data ID greater than position. To read from current use "$"
 	Read(ctx context.Context, pos string) (*StreamMessages, bool, error)
+	// ReadReverse reads at most count messages with ID less than or equal to position, newest first. To read from the
+	// tail of the stream use "+". Returns false if there are no such messages.
+	ReadReverse(ctx context.Context, pos string, count int64) (*StreamMessages, bool, error)
 	// ReadGroup is similar to Read but with support for reading from a group. We don't have multiple consumers in a
 	// single group. Currently, it creates an internal _tigris_consumer.
 	ReadGroup(ctx context.Context, group string, pos ReadGroupPos) (*StreamMessages, bool, error)
@@ -41,6 +47,9 @@ type Stream interface {
 	GetConsumerGroups(ctx context.Context) ([]xredis.XInfoGroup, error)
 	// GetConsumerGroup returns only information about the consumer group passed in the API.
 	GetConsumerGroup(ctx context.Context, group string) (*xredis.XInfoGroup, bool, error)
+	// Bounds returns the ids of the oldest and the newest message held by the stream. Returns false if the stream
+	// has no messages.
+	Bounds(ctx context.Context) (string, string, bool, error)
 	// SetID is used to set the position of the group again.
 	SetID(ctx context.Context, group string, pos string) error
 	// Ack is to acknowledge messages once they are read by the consumer group. This is required to be called in case
@@ -50,6 +59,20 @@ type Stream interface {
 	Delete(ctx context.Context) error
 }
 
+// StreamStats is the metadata of a stream as tracked by the cache, it doesn't require reading the messages.
+type StreamStats struct {
+	// Name is the stream name
+	Name string
+	// Length is the number of messages currently held by the stream
+	Length int64
+	// Bytes is the memory used by the stream as reported by the cache
+	Bytes int64
+	// Groups is the number of consumer groups of the stream
+	Groups int64
+	// LastID is the id of the newest message held by the stream, empty if it has no messages
+	LastID string
+}
+
 type SetOptions struct {
 	// NX is SetIfNotExists i.e. only set the key if it does not already exist.
 	NX bool
@@ -88,8 +111,19 @@ type Cache interface {
 	GetStream(ctx context.Context, streamName string) (Stream, error)
 	// ListStreams returns the all the streams with the prefix
 	ListStreams(ctx context.Context, streamNamePrefix string) ([]string, error)
+	// ScanStreams returns a batch of the streams matching the pattern from the cursor, of about count streams, along
+	// with the cursor of the next batch. The cursor is zero once all the streams have been scanned.
+	ScanStreams(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error)
 	// DeleteStream to delete a stream if exists
 	DeleteStream(ctx context.Context, streamName string) error
+	// GetStreamStats returns the length, memory footprint, consumer groups and newest message id of the streams in a
+	// single round trip. It is served from the key metadata so the cost doesn't depend on the number of messages in
+	// the streams.
+	GetStreamStats(ctx context.Context, streamNames ...string) ([]StreamStats, error)
+	// ReadStreams reads the messages after the position of every stream in a single read, the positions are in the
+	// order of the streams. The read blocks until any of the streams has messages or the blocking time is over, only
+	// the streams with messages are returned.
+	ReadStreams(ctx context.Context, streamNames []string, positions []string) ([]*StreamMessages, error)
 }
 
 func NewCache(cfg *config.CacheConfig) Cache {
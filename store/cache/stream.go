@@ -87,6 +87,32 @@ func (s *stream) Add(ctx context.Context, value *internal.StreamData) (string, e
 	return cmd.Result()
 }
 
+func (s *stream) AddAll(ctx context.Context, values []*internal.StreamData) ([]string, error) {
+	cmds := make([]*xredis.StringCmd, 0, len(values))
+	if _, err := s.cache.Client.TxPipelined(ctx, func(pipe xredis.Pipeliner) error {
+		for _, value := range values {
+			data, err := encodeToStreamValue(value)
+			if err != nil {
+				return err
+			}
+
+			cmds = append(cmds, pipe.XAdd(ctx, &xredis.XAddArgs{
+				Stream: s.name,
+				Values: data,
+			}))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		ids = append(ids, cmd.Val())
+	}
+	return ids, nil
+}
+
 func (s *stream) Read(ctx context.Context, pos string) (*StreamMessages, bool, error) {
 	resp := s.cache.Client.XRead(ctx, &xredis.XReadArgs{
 		Streams: []string{s.name, pos},
@@ -109,6 +135,23 @@ func (s *stream) Read(ctx context.Context, pos string) (*StreamMessages, bool, e
 	}, true, nil
 }
 
+func (s *stream) ReadReverse(ctx context.Context, pos string, count int64) (*StreamMessages, bool, error) {
+	messages, err := s.cache.Client.XRevRangeN(ctx, s.name, pos, "-", count).Result()
+	if err != nil {
+		return nil, true, err
+	}
+	if len(messages) == 0 {
+		return nil, false, nil
+	}
+
+	return &StreamMessages{
+		XStream: xredis.XStream{
+			Stream:   s.name,
+			Messages: messages,
+		},
+	}, true, nil
+}
+
 func (s *stream) ReadGroup(ctx context.Context, group string, pos ReadGroupPos) (*StreamMessages, bool, error) {
 	resp := s.cache.Client.XReadGroup(ctx, &xredis.XReadGroupArgs{
 		Group:    group,
@@ -173,6 +216,26 @@ func (s *stream) GetConsumerGroup(ctx context.Context, group string) (*xredis.XI
 	return nil, false, nil
 }
 
+func (s *stream) Bounds(ctx context.Context) (string, string, bool, error) {
+	first, err := s.cache.Client.XRangeN(ctx, s.name, "-", "+", 1).Result()
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(first) == 0 {
+		return "", "", false, nil
+	}
+
+	last, err := s.cache.Client.XRevRangeN(ctx, s.name, "+", "-", 1).Result()
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(last) == 0 {
+		return "", "", false, nil
+	}
+
+	return first[0].ID, last[0].ID, true, nil
+}
+
 func (s *stream) SetID(ctx context.Context, group string, pos string) error {
 	_, err := s.cache.Client.XGroupSetID(ctx, s.name, group, pos).Result()
 	return err
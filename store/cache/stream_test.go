@@ -53,6 +53,61 @@ func TestStream(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, rawI, rawO.RawData)
 	})
+	t.Run("read_reverse", func(t *testing.T) {
+		stream, err := r.CreateOrGetStream(context.TODO(), "test")
+		require.NoError(t, err)
+		defer func() {
+			_ = stream.Delete(ctx)
+		}()
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := stream.Add(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(fmt.Sprintf("%d", i))))
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+
+		messages, exists, err := stream.ReadReverse(ctx, "+", 2)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, 2, len(messages.XStream.Messages))
+		require.Equal(t, ids[2], messages.XStream.Messages[0].ID)
+		require.Equal(t, ids[1], messages.XStream.Messages[1].ID)
+
+		messages, exists, err = stream.ReadReverse(ctx, ids[0], 2)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, 1, len(messages.XStream.Messages))
+		require.Equal(t, ids[0], messages.XStream.Messages[0].ID)
+
+		_, exists, err = stream.ReadReverse(ctx, "0-0", 2)
+		require.NoError(t, err)
+		require.False(t, exists)
+	})
+	t.Run("bounds", func(t *testing.T) {
+		stream, err := r.CreateOrGetStream(context.TODO(), "test")
+		require.NoError(t, err)
+		defer func() {
+			_ = stream.Delete(ctx)
+		}()
+
+		_, _, exists, err := stream.Bounds(ctx)
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := stream.Add(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte(fmt.Sprintf("%d", i))))
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+
+		first, last, exists, err := stream.Bounds(ctx)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, ids[0], first)
+		require.Equal(t, ids[2], last)
+	})
 	t.Run("consumer_groups", func(t *testing.T) {
 		stream, err := r.CreateOrGetStream(context.TODO(), "test")
 		require.NoError(t, err)
@@ -70,6 +125,60 @@ func TestStream(t *testing.T) {
 		require.Equal(t, "first", groups[1].Name)
 		require.Equal(t, "second", groups[2].Name)
 	})
+	t.Run("scan_streams", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			stream, err := r.CreateOrGetStream(ctx, fmt.Sprintf("scan_stream_%d", i))
+			require.NoError(t, err)
+			defer func() {
+				_ = stream.Delete(ctx)
+			}()
+		}
+		// only the streams are returned
+		require.NoError(t, r.Set(ctx, "scan_stream_table", "key", internal.NewCacheData([]byte(`{}`)), nil))
+		defer func() {
+			_, _ = r.Delete(ctx, "scan_stream_table", "key")
+		}()
+
+		var (
+			names  []string
+			cursor uint64
+		)
+		for {
+			batch, next, err := r.ScanStreams(ctx, "scan_stream*", cursor, 2)
+			require.NoError(t, err)
+			names = append(names, batch...)
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+		require.ElementsMatch(t, []string{"scan_stream_0", "scan_stream_1", "scan_stream_2", "scan_stream_3", "scan_stream_4"}, names)
+	})
+	t.Run("read_streams", func(t *testing.T) {
+		first, err := r.CreateOrGetStream(ctx, "read_stream_a")
+		require.NoError(t, err)
+		defer func() {
+			_ = first.Delete(ctx)
+		}()
+		second, err := r.CreateOrGetStream(ctx, "read_stream_b")
+		require.NoError(t, err)
+		defer func() {
+			_ = second.Delete(ctx)
+		}()
+
+		id, err := first.Add(ctx, internal.NewStreamData(internal.JsonEncoding, nil, []byte("hello")))
+		require.NoError(t, err)
+
+		// only the streams with messages are returned
+		messages, err := r.ReadStreams(ctx, []string{"read_stream_a", "read_stream_b"}, []string{"0", "0"})
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.Equal(t, "read_stream_a", messages[0].Stream)
+		require.Equal(t, id, messages[0].Messages[0].ID)
+
+		messages, err = r.ReadStreams(ctx, []string{"read_stream_a", "read_stream_b"}, []string{id, "0"})
+		require.NoError(t, err)
+		require.Empty(t, messages)
+	})
 }
 
 func TestBenchmarkingStreams(t *testing.T) {
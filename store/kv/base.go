@@ -29,11 +29,12 @@ type baseKV interface {
 	Replace(ctx context.Context, table []byte, key Key, data []byte, isUpdate bool) error
 	Delete(ctx context.Context, table []byte, key Key) error
 	Read(ctx context.Context, table []byte, key Key) (baseIterator, error)
-	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (baseIterator, error)
+	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, opts ...ReadOption) (baseIterator, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	Get(ctx context.Context, key []byte, isSnapshot bool) (Future, error)
 	AtomicAdd(ctx context.Context, table []byte, key Key, value int64) error
 	AtomicRead(ctx context.Context, table []byte, key Key) (int64, error)
+	AtomicReadMulti(ctx context.Context, table []byte, keys []Key) ([]int64, error)
 	AtomicReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (AtomicIterator, error)
 }
 
@@ -146,8 +146,8 @@ func (tx *ChunkTx) Read(ctx context.Context, table []byte, key Key) (Iterator, e
 	}, nil
 }
 
-func (tx *ChunkTx) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (Iterator, error) {
-	iterator, err := tx.KeyValueTx.ReadRange(ctx, table, lKey, rKey, isSnapshot)
+func (tx *ChunkTx) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, opts ...ReadOption) (Iterator, error) {
+	iterator, err := tx.KeyValueTx.ReadRange(ctx, table, lKey, rKey, isSnapshot, opts...)
 	if err != nil {
 		return nil, err
 	}
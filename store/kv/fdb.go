@@ -32,6 +32,9 @@ import (
 
 const (
 	fdbAPIVersion = 710
+	// estimateSampleRows is the maximum number of rows scanned by EstimateRangeSize when FDB has no estimate for the
+	// range.
+	estimateSampleRows = 10000
 )
 
 // fdbkv is an implementation of kv on top of FoundationDB.
@@ -87,12 +90,12 @@ func (d *fdbkv) Read(ctx context.Context, table []byte, key Key) (baseIterator,
 	return &fdbIteratorTxCloser{ctx, it, tx}, nil
 }
 
-func (d *fdbkv) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
+func (d *fdbkv) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, opts ...ReadOption) (baseIterator, error) {
 	tx, err := d.BeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
-	it, err := tx.ReadRange(ctx, table, lKey, rKey, isSnapshot)
+	it, err := tx.ReadRange(ctx, table, lKey, rKey, isSnapshot, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +194,16 @@ func (d *fdbkv) AtomicRead(ctx context.Context, table []byte, key Key) (int64, e
 	return val.(int64), err
 }
 
+func (d *fdbkv) AtomicReadMulti(ctx context.Context, table []byte, keys []Key) ([]int64, error) {
+	val, err := d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
+		return (&ftx{d: d, tx: &tr}).AtomicReadMulti(ctx, table, keys)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]int64), nil
+}
+
 func (d *fdbkv) AtomicReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (AtomicIterator, error) {
 	tx, err := d.BeginTx(ctx)
 	if err != nil {
@@ -247,6 +260,44 @@ func (d *fdbkv) TableSize(ctx context.Context, name []byte) (int64, error) {
 	return sz, err
 }
 
+// EstimateRangeSize returns the approximate size in bytes of the range from lKey to rKey. It relies on the estimate
+// that FDB maintains, which doesn't account for ranges smaller than about 3MB. So if the estimate is not available
+// or is zero, the size is computed by scanning at most estimateSampleRows rows of the range, in which case the size
+// is a lower bound for larger ranges.
+func (d *fdbkv) EstimateRangeSize(ctx context.Context, table []byte, lKey Key, rKey Key) (int64, error) {
+	kr := getFDBKeyRange(table, lKey, rKey)
+
+	sz, err := d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
+		return tr.GetEstimatedRangeSizeBytes(kr).Get()
+	})
+	if err == nil && sz.(int64) > 0 {
+		return sz.(int64), nil
+	}
+	if err != nil {
+		log.Debug().Err(err).Str("table", string(table)).Msg("range size estimate not available, sampling the range")
+	}
+
+	sz, err = d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
+		it := tr.Snapshot().GetRange(kr, fdb.RangeOptions{Limit: estimateSampleRows, Mode: fdb.StreamingModeWantAll}).Iterator()
+
+		var size int64
+		for it.Advance() {
+			kv, err := it.Get()
+			if err != nil {
+				return nil, err
+			}
+			size += int64(len(kv.Key) + len(kv.Value))
+		}
+
+		return size, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return sz.(int64), nil
+}
+
 func (d *fdbkv) BeginTx(ctx context.Context) (baseTx, error) {
 	tx, err := d.db.CreateTransaction()
 	if ulog.E(err) {
@@ -330,21 +381,9 @@ func (t *ftx) Read(_ context.Context, table []byte, key Key) (baseIterator, erro
 	return &fdbIterator{it: r.Iterator(), subspace: subspace.FromBytes(table)}, nil
 }
 
-func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
-	lk := getFDBKey(table, lKey)
-	var rk fdb.Key
-	if rKey == nil {
-		// add a table boundary
-		rk1 := make([]byte, len(table)+1)
-		copy(rk1, table)
-		rk1[len(rk1)-1] = byte(0xFF)
-		rk = rk1
-	} else {
-		rk = getFDBKey(table, rKey)
-	}
-
-	kr := fdb.KeyRange{Begin: lk, End: rk}
-	ro := fdb.RangeOptions{}
+func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, opts ...ReadOption) (baseIterator, error) {
+	kr := getFDBKeyRange(table, lKey, rKey)
+	ro := fdb.RangeOptions{Mode: toFDBStreamingMode(buildReadOptions(opts).Mode)}
 
 	var r fdb.RangeResult
 	if isSnapshot {
@@ -395,6 +434,30 @@ func (t *ftx) AtomicRead(_ context.Context, table []byte, key Key) (int64, error
 	return fdbByteToInt64(&raw)
 }
 
+// AtomicReadMulti issues the reads for all the keys before waiting on any of them so that they are pipelined.
+func (t *ftx) AtomicReadMulti(_ context.Context, table []byte, keys []Key) ([]int64, error) {
+	futures := make([]fdb.FutureByteSlice, len(keys))
+	for i, key := range keys {
+		futures[i] = t.tx.Get(getFDBKey(table, key))
+	}
+
+	values := make([]int64, len(keys))
+	for i, f := range futures {
+		raw, err := f.Get()
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+		if values[i], err = fdbByteToInt64(&raw); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
 func (t *ftx) AtomicReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (AtomicIterator, error) {
 	iter, err := t.ReadRange(ctx, table, lkey, rkey, isSnapshot)
 	if err != nil {
@@ -414,19 +477,7 @@ func (t *ftx) Get(_ context.Context, key []byte, isSnapshot bool) (Future, error
 // RangeSize calculates approximate range table size in bytes - this is an estimate
 // and a range smaller than 3mb will not be that accurate.
 func (t *ftx) RangeSize(ctx context.Context, table []byte, lKey Key, rKey Key) (int64, error) {
-	lk := getFDBKey(table, lKey)
-	var rk fdb.Key
-	if rKey == nil {
-		// add a table boundary
-		rk1 := make([]byte, len(table)+1)
-		copy(rk1, table)
-		rk1[len(rk1)-1] = byte(0xFF)
-		rk = rk1
-	} else {
-		rk = getFDBKey(table, rKey)
-	}
-
-	kr := fdb.KeyRange{Begin: lk, End: rk}
+	kr := getFDBKeyRange(table, lKey, rKey)
 	sz, err := t.tx.GetEstimatedRangeSizeBytes(kr).Get()
 	log.Trace().Str("table", string(table)).Interface("lKey", lKey).Interface("rKey", rKey).Int64("size", sz).Msg("tx range size")
 	if err != nil {
@@ -533,12 +584,52 @@ func (i *fdbIteratorTxCloser) Next(kv *baseKeyValue) bool {
 	return true
 }
 
+// getFDBKeyRange returns the range from lKey to rKey, a nil rKey means till the end of the table.
+func getFDBKeyRange(table []byte, lKey Key, rKey Key) fdb.KeyRange {
+	lk := getFDBKey(table, lKey)
+	var rk fdb.Key
+	if rKey == nil {
+		// add a table boundary
+		rk1 := make([]byte, len(table)+1)
+		copy(rk1, table)
+		rk1[len(rk1)-1] = byte(0xFF)
+		rk = rk1
+	} else {
+		rk = getFDBKey(table, rKey)
+	}
+
+	return fdb.KeyRange{Begin: lk, End: rk}
+}
+
+func toFDBStreamingMode(mode StreamingMode) fdb.StreamingMode {
+	switch mode {
+	case StreamingModeSmall:
+		return fdb.StreamingModeSmall
+	case StreamingModeMedium:
+		return fdb.StreamingModeMedium
+	case StreamingModeLarge:
+		return fdb.StreamingModeLarge
+	case StreamingModeWantAll:
+		return fdb.StreamingModeWantAll
+	default:
+		return fdb.StreamingModeIterator
+	}
+}
+
 func getFDBKey(table []byte, key Key) fdb.Key {
 	s := subspace.FromBytes(table)
 	var k fdb.Key
-	if len(key) == 0 {
+	switch {
+	case len(key) == 0:
 		k = s.FDBKey()
-	} else {
+	case key[len(key)-1] == keyEnd{}:
+		prefix := key[:len(key)-1]
+		p := unsafe.Pointer(&prefix)
+		k = s.Pack(*(*tuple.Tuple)(p))
+		if end, err := fdb.Strinc(k); err == nil {
+			k = end
+		}
+	default:
 		p := unsafe.Pointer(&key)
 		k = s.Pack(*(*tuple.Tuple)(p))
 	}
@@ -46,13 +46,19 @@ type KV interface {
 	Insert(ctx context.Context, table []byte, key Key, data *internal.TableData) error
 	Replace(ctx context.Context, table []byte, key Key, data *internal.TableData, isUpdate bool) error
 	Delete(ctx context.Context, table []byte, key Key) error
+	// DeleteRange deletes all the keys in the range [lKey, rKey) of the table. Unlike Delete, no event is emitted for
+	// the deleted keys as they are not known.
+	DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) error
 	Read(ctx context.Context, table []byte, key Key) (Iterator, error)
-	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error)
+	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, opts ...ReadOption) (Iterator, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	SetVersionstampedKey(ctx context.Context, key []byte, value []byte) error
 	Get(ctx context.Context, key []byte, isSnapshot bool) (Future, error)
 	AtomicAdd(ctx context.Context, table []byte, key Key, value int64) error
 	AtomicRead(ctx context.Context, table []byte, key Key) (int64, error)
+	// AtomicReadMulti reads the counters for all the keys at once, the values are returned in the order of the keys
+	// with zero for the counters that don't exist.
+	AtomicReadMulti(ctx context.Context, table []byte, keys []Key) ([]int64, error)
 	AtomicReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (AtomicIterator, error)
 }
 
@@ -70,6 +76,47 @@ type TxStore interface {
 	DropTable(ctx context.Context, name []byte) error
 	GetInternalDatabase() (interface{}, error) // TODO: CDC remove workaround
 	TableSize(ctx context.Context, name []byte) (int64, error)
+	// EstimateRangeSize returns an approximate size in bytes of the range without reading every key of the range.
+	EstimateRangeSize(ctx context.Context, table []byte, lkey Key, rkey Key) (int64, error)
+}
+
+// StreamingMode controls how many rows a range read fetches from the store ahead of the iterator.
+type StreamingMode int
+
+const (
+	// StreamingModeIterator is the default. The first batch is small and every following batch is bigger, so a scan
+	// that stops after a few rows, like a query with a limit pushed down to the scan, doesn't read much more than it
+	// returns while a long scan still ends up fetching in large batches.
+	StreamingModeIterator StreamingMode = iota
+	// StreamingModeSmall fetches small batches, for scans that are known to stop early.
+	StreamingModeSmall
+	StreamingModeMedium
+	// StreamingModeLarge fetches large batches from the start, for scans that read the whole range like exports.
+	StreamingModeLarge
+	// StreamingModeWantAll fetches the whole range in as few batches as possible. It shouldn't be used for a scan
+	// with a limit as the rows past the limit are read anyway.
+	StreamingModeWantAll
+)
+
+type ReadOptions struct {
+	Mode StreamingMode
+}
+
+type ReadOption func(*ReadOptions)
+
+// WithStreamingMode sets the prefetch behavior of the range read, StreamingModeIterator is used if not set.
+func WithStreamingMode(mode StreamingMode) ReadOption {
+	return func(o *ReadOptions) {
+		o.Mode = mode
+	}
+}
+
+func buildReadOptions(opts []ReadOption) ReadOptions {
+	var o ReadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
 type Iterator interface {
@@ -240,6 +240,94 @@ func testKeyValueStoreFullScan(t *testing.T, kv TxStore) {
 	require.NoError(t, err)
 }
 
+func testKeyValueStoreResumeReadRange(t *testing.T, kv TxStore) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	table := []byte("t1")
+	err := kv.DropTable(ctx, table)
+	require.NoError(t, err)
+
+	err = kv.CreateTable(ctx, table)
+	require.NoError(t, err)
+
+	tx := getTx(t, ctx, kv)
+	for i := 0; i < 5; i++ {
+		err = tx.Insert(ctx, table, BuildKey("p1", i+1), internal.NewTableData([]byte(fmt.Sprintf("value%d", i+1))))
+		require.NoError(t, err)
+	}
+	_ = tx.Commit(ctx)
+
+	lKey, rKey := BuildKey("p1", 2), BuildKey("p1", 5)
+
+	// stop the scan after the first two keys
+	tx = getTx(t, ctx, kv)
+	it, err := tx.ReadRange(ctx, table, lKey, rKey, false)
+	require.NoError(t, err)
+	rit := NewResumableIterator(it)
+	require.Nil(t, rit.LastKey())
+	resumeL, _ := rit.ResumeRange(lKey, rKey)
+	require.Equal(t, lKey, resumeL)
+
+	var kvp KeyValue
+	require.True(t, rit.Next(&kvp))
+	require.True(t, rit.Next(&kvp))
+	require.Equal(t, BuildKey("p1", int64(3)), rit.LastKey())
+	_ = tx.Rollback(ctx)
+
+	// resume in a new transaction, already processed keys are not read again
+	tx = getTx(t, ctx, kv)
+	resumeL, resumeR := rit.ResumeRange(lKey, rKey)
+	require.Equal(t, BuildKey("p1", int64(3), keyEnd{}), resumeL)
+	require.Equal(t, rKey, resumeR)
+	it, err = tx.ReadRange(ctx, table, resumeL, resumeR, false)
+	require.NoError(t, err)
+
+	v := readAllUsingIterator(t, it)
+	require.Len(t, v, 1)
+	require.Equal(t, BuildKey("p1", int64(4)), v[0].Key)
+	_ = tx.Commit(ctx)
+
+	err = kv.DropTable(ctx, table)
+	require.NoError(t, err)
+}
+
+func testKeyValueStoreEstimateRangeSize(t *testing.T, kv TxStore) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	table := []byte("t1")
+	err := kv.DropTable(ctx, table)
+	require.NoError(t, err)
+
+	err = kv.CreateTable(ctx, table)
+	require.NoError(t, err)
+
+	size, err := kv.EstimateRangeSize(ctx, table, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), size)
+
+	tx := getTx(t, ctx, kv)
+	for i := 0; i < 5; i++ {
+		err = tx.Insert(ctx, table, BuildKey("p1", i+1), internal.NewTableData([]byte(fmt.Sprintf("value%d", i+1))))
+		require.NoError(t, err)
+	}
+	_ = tx.Commit(ctx)
+
+	// the range is too small to have an estimate, so it is sampled
+	all, err := kv.EstimateRangeSize(ctx, table, nil, nil)
+	require.NoError(t, err)
+	require.Greater(t, all, int64(0))
+
+	part, err := kv.EstimateRangeSize(ctx, table, BuildKey("p1", 1), BuildKey("p1", 3))
+	require.NoError(t, err)
+	require.Greater(t, part, int64(0))
+	require.Less(t, part, all)
+
+	err = kv.DropTable(ctx, table)
+	require.NoError(t, err)
+}
+
 type TestCollection struct {
 	Key    string `json:"key"`
 	Field1 []byte `json:"field1"`
@@ -661,6 +749,11 @@ func testKVAddAtomicValue(t *testing.T, kv baseKVStore) {
 	}
 
 	require.Equal(t, 2, count)
+
+	vals, err := tx.AtomicReadMulti(ctx, table, []Key{key2, BuildKey([]byte("foo-absent")), key})
+	require.NoError(t, err)
+	require.Equal(t, []int64{5, 0, 11}, vals)
+
 	err = tx.Commit(ctx)
 	require.NoError(t, err)
 }
@@ -694,6 +787,12 @@ func TestKVFDB(t *testing.T) {
 	t.Run("TestKVFDBFullScan", func(t *testing.T) {
 		testKeyValueStoreFullScan(t, kvStore)
 	})
+	t.Run("TestKVFDBResumeReadRange", func(t *testing.T) {
+		testKeyValueStoreResumeReadRange(t, kvStore)
+	})
+	t.Run("TestKVFDBEstimateRangeSize", func(t *testing.T) {
+		testKeyValueStoreEstimateRangeSize(t, kvStore)
+	})
 	t.Run("TestKVFDBIterator", func(t *testing.T) {
 		testFDBKVIterator(t, kv)
 	})
@@ -706,6 +805,15 @@ func TestKVFDB(t *testing.T) {
 	})
 }
 
+func TestReadOptions(t *testing.T) {
+	require.Equal(t, fdb.StreamingModeIterator, toFDBStreamingMode(buildReadOptions(nil).Mode))
+	require.Equal(t, fdb.StreamingModeSmall, toFDBStreamingMode(buildReadOptions([]ReadOption{WithStreamingMode(StreamingModeSmall)}).Mode))
+	require.Equal(t, fdb.StreamingModeWantAll, toFDBStreamingMode(buildReadOptions([]ReadOption{
+		WithStreamingMode(StreamingModeLarge),
+		WithStreamingMode(StreamingModeWantAll),
+	}).Mode))
+}
+
 func TestGetCtxTimeout(t *testing.T) {
 	// FIXME: time.Now dependent, may be flaky on slow machine
 	// positive timeout set in the context
@@ -104,6 +104,14 @@ func (m *TxStoreWithMetrics) TableSize(ctx context.Context, name []byte) (size i
 	return
 }
 
+func (m *TxStoreWithMetrics) EstimateRangeSize(ctx context.Context, table []byte, lkey Key, rkey Key) (size int64, err error) {
+	m.measure(ctx, "EstimateRangeSize", func() error {
+		size, err = m.kv.EstimateRangeSize(ctx, table, lkey, rkey)
+		return err
+	})
+	return
+}
+
 func (m *TxStoreWithMetrics) BeginTx(ctx context.Context) (Tx, error) {
 	// This needs to be a special case in order to have the tx metrics as well
 	var btx Tx
@@ -172,6 +180,22 @@ func (m *TxImplWithMetrics) AtomicRead(ctx context.Context, table []byte, key Ke
 	return
 }
 
+func (m *TxImplWithMetrics) DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) (err error) {
+	m.measure(ctx, "DeleteRange", func() error {
+		err = m.tx.DeleteRange(ctx, table, lKey, rKey)
+		return err
+	})
+	return
+}
+
+func (m *TxImplWithMetrics) AtomicReadMulti(ctx context.Context, table []byte, keys []Key) (values []int64, err error) {
+	m.measure(ctx, "AtomicReadMulti", func() error {
+		values, err = m.tx.AtomicReadMulti(ctx, table, keys)
+		return err
+	})
+	return
+}
+
 func (m *TxImplWithMetrics) AtomicReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (iter AtomicIterator, err error) {
 	m.measure(ctx, "AtomicReadRange", func() error {
 		iter, err = m.tx.AtomicReadRange(ctx, table, lkey, rkey, isSnapshot)
@@ -253,9 +277,9 @@ func (m *TxImplWithMetrics) Read(ctx context.Context, table []byte, key Key) (it
 	return
 }
 
-func (m *TxImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (it Iterator, err error) {
+func (m *TxImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, opts ...ReadOption) (it Iterator, err error) {
 	m.measure(ctx, "ReadRange", func() error {
-		kvIt, err := m.tx.ReadRange(ctx, table, lkey, rkey, isSnapshot)
+		kvIt, err := m.tx.ReadRange(ctx, table, lkey, rkey, isSnapshot, opts...)
 		it = NewKeyValueIteratorWithMetrics(ctx, kvIt)
 		return err
 	})
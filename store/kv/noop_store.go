@@ -49,6 +49,9 @@ func (n *NoopKVStore) CreateTable(_ context.Context, _ []byte) error        { re
 func (n *NoopKVStore) DropTable(_ context.Context, _ []byte) error          { return nil }
 func (n *NoopKVStore) GetInternalDatabase() (interface{}, error)            { return nil, nil }
 func (n *NoopKVStore) TableSize(_ context.Context, _ []byte) (int64, error) { return 0, nil }
+func (n *NoopKVStore) EstimateRangeSize(_ context.Context, _ []byte, _ Key, _ Key) (int64, error) {
+	return 0, nil
+}
 
 type NoopKV struct{}
 
@@ -60,11 +63,14 @@ func (n *NoopKV) Replace(ctx context.Context, table []byte, key Key, data *inter
 	return nil
 }
 func (n *NoopKV) Delete(ctx context.Context, table []byte, key Key) error { return nil }
+func (n *NoopKV) DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) error {
+	return nil
+}
 func (n *NoopKV) Read(ctx context.Context, table []byte, key Key) (Iterator, error) {
 	return &NoopIterator{}, nil
 }
 
-func (n *NoopKV) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error) {
+func (n *NoopKV) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, opts ...ReadOption) (Iterator, error) {
 	return &NoopIterator{}, nil
 }
 
@@ -84,6 +90,10 @@ func (n *NoopKV) AtomicRead(ctx context.Context, table []byte, key Key) (int64,
 	return 0, nil
 }
 
+func (n *NoopKV) AtomicReadMulti(ctx context.Context, table []byte, keys []Key) ([]int64, error) {
+	return make([]int64, len(keys)), nil
+}
+
 func (n *NoopKV) AtomicReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (AtomicIterator, error) {
 	return &NoopFDBTypeIterator{}, nil
 }
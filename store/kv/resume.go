@@ -0,0 +1,66 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kv
+
+// keyEnd when used as the last part of a key addresses the first key that sorts after all the keys prefixed by the
+// rest of the parts. It is what allows a scan to be resumed right after the last key it yielded without re-reading
+// the chunks of the last value.
+type keyEnd struct{}
+
+// ResumableIterator tracks the last key yielded by the wrapped iterator so that the scan can be continued from where
+// it stopped when the iterator fails with a retriable error or the context is canceled.
+type ResumableIterator struct {
+	Iterator
+
+	lastKey Key
+}
+
+func NewResumableIterator(it Iterator) *ResumableIterator {
+	return &ResumableIterator{Iterator: it}
+}
+
+func (it *ResumableIterator) Next(value *KeyValue) bool {
+	if value == nil {
+		value = &KeyValue{}
+	}
+	if !it.Iterator.Next(value) {
+		return false
+	}
+
+	it.lastKey = value.Key
+	return true
+}
+
+// LastKey returns the key of the last value yielded by the iterator, nil if nothing was yielded yet.
+func (it *ResumableIterator) LastKey() Key {
+	return it.lastKey
+}
+
+// ResumeRange returns the keys to pass to ReadRange to continue the scan of [lKey, rKey) right after the last key
+// yielded by the iterator. If nothing was yielded yet, the original range is returned.
+func (it *ResumableIterator) ResumeRange(lKey Key, rKey Key) (Key, Key) {
+	if it.lastKey == nil {
+		return lKey, rKey
+	}
+
+	return KeyAfter(it.lastKey), rKey
+}
+
+// KeyAfter returns a key that sorts right after the key and all the keys that have it as a prefix.
+func KeyAfter(key Key) Key {
+	after := make(Key, 0, len(key)+1)
+	after = append(after, key...)
+	return append(after, keyEnd{})
+}
@@ -16,6 +16,7 @@ package util
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"strings"
 	"text/template"
@@ -29,6 +30,12 @@ const (
 	ObjFlattenDelimiter = "."
 )
 
+// MaxFlattenDepth is the maximum nesting of objects accepted by FlatMap and implied by the keys passed to UnFlatMap,
+// deeper documents are rejected with ErrMaxFlattenDepth to protect the server from pathological inputs.
+var MaxFlattenDepth = 64
+
+var ErrMaxFlattenDepth = errors.New("document exceeds the maximum nesting depth")
+
 // Version of this build.
 var Version string
 
@@ -73,13 +80,28 @@ func JSONToMap(data []byte) (map[string]any, error) {
 	return decoded, nil
 }
 
-func FlatMap(data map[string]any, notFlat container.HashSet) map[string]any {
+func FlatMap(data map[string]any, notFlat container.HashSet) (map[string]any, error) {
 	resp := make(map[string]any)
-	flatMap("", data, resp, notFlat)
-	return resp
+	if err := FlatMapFunc(data, notFlat, func(key string, value any) {
+		resp[key] = value
+	}); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-func flatMap(key string, obj map[string]any, resp map[string]any, notFlat container.HashSet) {
+// FlatMapFunc is the streaming version of FlatMap, it calls fn with the flattened key of every leaf instead of
+// collecting them into a map. The objects in notFlat are passed to fn as is. ErrMaxFlattenDepth is returned if
+// the objects are nested deeper than MaxFlattenDepth, fn may have been called for some of the leaves by then.
+func FlatMapFunc(data map[string]any, notFlat container.HashSet, fn func(key string, value any)) error {
+	return flatMap("", data, 1, notFlat, fn)
+}
+
+func flatMap(key string, obj map[string]any, depth int, notFlat container.HashSet, fn func(key string, value any)) error {
+	if depth > MaxFlattenDepth {
+		return ErrMaxFlattenDepth
+	}
+
 	if key != "" {
 		key += ObjFlattenDelimiter
 	}
@@ -88,19 +110,30 @@ func flatMap(key string, obj map[string]any, resp map[string]any, notFlat contai
 		switch vMap := v.(type) {
 		case map[string]any:
 			if notFlat.Contains(key + k) {
-				resp[key+k] = v
-			} else {
-				flatMap(key+k, vMap, resp, notFlat)
+				fn(key+k, v)
+			} else if err := flatMap(key+k, vMap, depth+1, notFlat, fn); err != nil {
+				return err
 			}
 		default:
-			resp[key+k] = v
+			fn(key+k, v)
 		}
 	}
+
+	return nil
 }
 
-func UnFlatMap(flat map[string]any) map[string]any {
+// UnFlatMap builds the nested objects back from the flattened keys. ErrMaxFlattenDepth is returned if any of the keys
+// implies nesting deeper than MaxFlattenDepth.
+func UnFlatMap(flat map[string]any) (map[string]any, error) {
 	result := make(map[string]any)
 
+	for k := range flat {
+		// checked before splitting so that a crafted key doesn't cause a large allocation
+		if strings.Count(k, ObjFlattenDelimiter) >= MaxFlattenDepth {
+			return nil, ErrMaxFlattenDepth
+		}
+	}
+
 	for k, v := range flat {
 		keys := strings.Split(k, ObjFlattenDelimiter)
 		m := result
@@ -118,5 +151,5 @@ func UnFlatMap(flat map[string]any) map[string]any {
 		}
 	}
 
-	return result
+	return result, nil
 }
@@ -15,16 +15,79 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/lib/container"
 )
 
+func TestFlatMapFunc(t *testing.T) {
+	input := map[string]any{
+		"a": 1,
+		"b": map[string]any{
+			"c": "foo",
+			"d": map[string]any{
+				"e": true,
+			},
+		},
+		"f": map[string]any{
+			"g": 2,
+		},
+	}
+
+	leaves := make(map[string]any)
+	err := FlatMapFunc(input, container.NewHashSet("f"), func(key string, value any) {
+		_, ok := leaves[key]
+		require.False(t, ok, "key %s visited twice", key)
+		leaves[key] = value
+	})
+	require.NoError(t, err)
+
+	expected := map[string]any{
+		"a":     1,
+		"b.c":   "foo",
+		"b.d.e": true,
+		"f":     map[string]any{"g": 2},
+	}
+	require.Equal(t, expected, leaves)
+	flattened, err := FlatMap(input, container.NewHashSet("f"))
+	require.NoError(t, err)
+	require.Equal(t, expected, flattened)
+}
+
+func TestFlatMapMaxDepth(t *testing.T) {
+	nested := func(depth int) map[string]any {
+		doc := map[string]any{"leaf": 1}
+		for i := 1; i < depth; i++ {
+			doc = map[string]any{"n": doc}
+		}
+		return doc
+	}
+
+	flattened, err := FlatMap(nested(MaxFlattenDepth), container.NewHashSet())
+	require.NoError(t, err)
+	require.Len(t, flattened, 1)
+
+	_, err = FlatMap(nested(MaxFlattenDepth+1), container.NewHashSet())
+	require.ErrorIs(t, err, ErrMaxFlattenDepth)
+
+	_, err = FlatMap(nested(100000), container.NewHashSet())
+	require.ErrorIs(t, err, ErrMaxFlattenDepth)
+
+	// objects that are not flattened are not walked
+	notFlat := container.NewHashSet("n")
+	flattened, err = FlatMap(nested(MaxFlattenDepth+1), notFlat)
+	require.NoError(t, err)
+	require.Len(t, flattened, 1)
+}
+
 func TestUnFlatMap(t *testing.T) {
 	input := make(map[string]any)
 	input["app_metadata"] = nil
 	input["app_metadata.provider"] = "foo"
-	output := UnFlatMap(input)
+	output, err := UnFlatMap(input)
+	require.NoError(t, err)
 
 	require.Equal(t, 1, len(output))
 
@@ -32,3 +95,19 @@ func TestUnFlatMap(t *testing.T) {
 	expected["provider"] = "foo"
 	require.Equal(t, expected, output["app_metadata"])
 }
+
+func TestUnFlatMapMaxDepth(t *testing.T) {
+	key := strings.Repeat("n.", MaxFlattenDepth-1) + "leaf"
+	output, err := UnFlatMap(map[string]any{key: 1})
+	require.NoError(t, err)
+
+	flattened, err := FlatMap(output, container.NewHashSet())
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{key: 1}, flattened)
+
+	_, err = UnFlatMap(map[string]any{"a": 1, "n." + key: 1})
+	require.ErrorIs(t, err, ErrMaxFlattenDepth)
+
+	_, err = UnFlatMap(map[string]any{strings.Repeat(".", 100000): 1})
+	require.ErrorIs(t, err, ErrMaxFlattenDepth)
+}